@@ -1,10 +1,24 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/exec"
+	"os/user"
+	"slices"
+	"sort"
 	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
 
+	"composer/internal/cache"
+	"composer/internal/graph"
 	"composer/internal/orchestrator"
 	"composer/internal/workflow"
 )
@@ -15,18 +29,33 @@ func main() {
 		os.Exit(1)
 	}
 
+	limits, err := orchestrator.LimitsFromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	orchestrator.SetLimits(limits)
+
+	store, err := workflow.OpenStore(os.Getenv("COMPOSER_STORE"), workflow.GetDataDir())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to open run store: %v\n", err)
+		os.Exit(1)
+	}
+
 	command := os.Args[1]
 
 	switch command {
 	case "run":
-		if len(os.Args) < 4 {
+		fs := flag.NewFlagSet("run", flag.ExitOnError)
+		params := paramFlag{}
+		fs.Var(params, "param", "set a workflow parameter as name=value (repeatable)")
+		args := parseFlags(fs, os.Args[2:])
+		if len(args) < 2 {
 			fmt.Fprintf(os.Stderr, "Error: both workflow id and run id are required\n\n")
 			printUsage()
 			os.Exit(1)
 		}
-		workflowID := os.Args[2]
-		runID := os.Args[3]
-		runWorkflow(workflowID, runID)
+		runWorkflow(store, args[0], args[1], params)
 	case "tick":
 		if len(os.Args) < 3 {
 			fmt.Fprintf(os.Stderr, "Error: run id is required\n\n")
@@ -34,7 +63,7 @@ func main() {
 			os.Exit(1)
 		}
 		runID := os.Args[2]
-		tickWorkflow(runID)
+		tickWorkflow(store, runID)
 	case "tasks":
 		if len(os.Args) < 3 {
 			fmt.Fprintf(os.Stderr, "Error: run id is required\n\n")
@@ -42,16 +71,168 @@ func main() {
 			os.Exit(1)
 		}
 		runID := os.Args[2]
-		listTasks(runID)
+		listTasks(store, runID)
 	case "do":
-		if len(os.Args) < 4 {
+		fs := flag.NewFlagSet("do", flag.ExitOnError)
+		actor := fs.String("as", defaultActor(), "who is completing the task")
+		file := fs.String("file", "", "read the output from a file")
+		stdin := fs.Bool("stdin", false, "read the output from standard input")
+		edit := fs.Bool("edit", false, "write the output in $EDITOR, starting from the prefill")
+		contentType := fs.String("type", "", "content type of the output")
+		outcome := fs.String("outcome", "", "the decision, for tasks with outcomes")
+		comment := fs.String("comment", "", "a comment on the decision")
+		args := parseFlags(fs, os.Args[2:])
+		if len(args) < 2 {
 			fmt.Fprintf(os.Stderr, "Error: run id and task index are required\n\n")
 			printUsage()
 			os.Exit(1)
 		}
+		sources := 0
+		for _, set := range []bool{*file != "", *stdin, *edit} {
+			if set {
+				sources++
+			}
+		}
+		if sources > 1 {
+			fmt.Fprintf(os.Stderr, "Error: --file, --stdin, and --edit are mutually exclusive\n")
+			os.Exit(1)
+		}
+		decision := orchestrator.Decision{Outcome: *outcome, Comment: *comment}
+		doTask(store, args[0], args[1], *actor, decision, taskOutput{
+			File:        *file,
+			Stdin:       *stdin,
+			Edit:        *edit,
+			ContentType: *contentType,
+		})
+	case "claim":
+		fs := flag.NewFlagSet("claim", flag.ExitOnError)
+		actor := fs.String("as", defaultActor(), "who is claiming the task")
+		lease := fs.Duration("for", orchestrator.DefaultLease, "how long the claim lasts")
+		args := parseFlags(fs, os.Args[2:])
+		if len(args) < 2 {
+			fmt.Fprintf(os.Stderr, "Error: run id and task index are required\n\n")
+			printUsage()
+			os.Exit(1)
+		}
+		claimTask(store, args[0], args[1], *actor, *lease)
+	case "release":
+		fs := flag.NewFlagSet("release", flag.ExitOnError)
+		actor := fs.String("as", defaultActor(), "who is releasing the task")
+		args := parseFlags(fs, os.Args[2:])
+		if len(args) < 2 {
+			fmt.Fprintf(os.Stderr, "Error: run id and task index are required\n\n")
+			printUsage()
+			os.Exit(1)
+		}
+		releaseTask(store, args[0], args[1], *actor)
+	case "comment":
+		fs := flag.NewFlagSet("comment", flag.ExitOnError)
+		var opts commentOptions
+		fs.StringVar(&opts.Actor, "as", defaultActor(), "who is commenting")
+		fs.StringVar(&opts.Task, "task", "", "index of the waiting task to comment on")
+		fs.StringVar(&opts.Step, "step", "", "step to comment on")
+		fs.IntVar(&opts.ReplyTo, "reply-to", 0, "id of the comment to answer")
+		fs.StringVar(&opts.Artifact, "artifact", "", "artifact the comment refers to")
+		fs.IntVar(&opts.Version, "version", 0, "artifact version (default latest)")
+		fs.StringVar(&opts.Lines, "lines", "", "line range of the artifact, e.g. 12-20")
+		args := parseFlags(fs, os.Args[2:])
+		if len(args) < 1 {
+			fmt.Fprintf(os.Stderr, "Error: run id is required\n\n")
+			printUsage()
+			os.Exit(1)
+		}
+		commentOnRun(store, args[0], strings.Join(args[1:], " "), opts)
+	case "cancel", "pause", "resume":
+		if len(os.Args) < 3 {
+			fmt.Fprintf(os.Stderr, "Error: run id is required\n\n")
+			printUsage()
+			os.Exit(1)
+		}
 		runID := os.Args[2]
-		taskIndex := os.Args[3]
-		doTask(runID, taskIndex)
+		controlRun(store, command, runID)
+	case "rerun":
+		if len(os.Args) < 4 {
+			fmt.Fprintf(os.Stderr, "Error: run id and step name are required\n\n")
+			printUsage()
+			os.Exit(1)
+		}
+		runID := os.Args[2]
+		stepName := os.Args[3]
+		rerunStep(store, runID, stepName)
+	case "fork":
+		fs := flag.NewFlagSet("fork", flag.ExitOnError)
+		fromStep := fs.String("from", "", "step to reset the fork from")
+		args := parseFlags(fs, os.Args[2:])
+		if len(args) < 2 || *fromStep == "" {
+			fmt.Fprintf(os.Stderr, "Error: run id, new run id, and --from step are required\n\n")
+			printUsage()
+			os.Exit(1)
+		}
+		forkRun(store, args[0], args[1], *fromStep)
+	case "cache":
+		if len(os.Args) < 3 {
+			fmt.Fprintf(os.Stderr, "Error: cache subcommand is required\n\n")
+			printUsage()
+			os.Exit(1)
+		}
+		switch os.Args[2] {
+		case "ls":
+			listCache()
+		case "prune":
+			fs := flag.NewFlagSet("cache prune", flag.ExitOnError)
+			olderThan := fs.Duration("older-than", 0, "only remove entries unused for this long")
+			parseFlags(fs, os.Args[3:])
+			pruneCache(*olderThan)
+		default:
+			fmt.Fprintf(os.Stderr, "Error: unknown cache subcommand '%s'\n\n", os.Args[2])
+			printUsage()
+			os.Exit(1)
+		}
+	case "artifact":
+		if len(os.Args) < 5 || os.Args[2] != "history" {
+			fmt.Fprintf(os.Stderr, "Error: usage is 'artifact history <run-id> <name>'\n\n")
+			printUsage()
+			os.Exit(1)
+		}
+		artifactHistory(store, os.Args[3], os.Args[4])
+	case "lineage":
+		fs := flag.NewFlagSet("lineage", flag.ExitOnError)
+		version := fs.Int("version", 0, "artifact version to trace (default current)")
+		args := parseFlags(fs, os.Args[2:])
+		if len(args) < 2 {
+			fmt.Fprintf(os.Stderr, "Error: run id and artifact name are required\n\n")
+			printUsage()
+			os.Exit(1)
+		}
+		showLineage(store, args[0], args[1], *version)
+	case "store":
+		if len(os.Args) < 3 || os.Args[2] != "migrate" {
+			fmt.Fprintf(os.Stderr, "Error: store subcommand must be 'migrate'\n\n")
+			printUsage()
+			os.Exit(1)
+		}
+		fs := flag.NewFlagSet("store migrate", flag.ExitOnError)
+		from := fs.String("from", "", "store to copy runs from: fs or sqlite")
+		to := fs.String("to", "", "store to copy runs to: fs or sqlite")
+		parseFlags(fs, os.Args[3:])
+		if *from == "" || *to == "" || *from == *to {
+			fmt.Fprintf(os.Stderr, "Error: --from and --to must name two different stores\n\n")
+			printUsage()
+			os.Exit(1)
+		}
+		migrateStore(*from, *to)
+	case "graph":
+		fs := flag.NewFlagSet("graph", flag.ExitOnError)
+		format := fs.String("format", "dot", "graph format: dot or mermaid")
+		args := parseFlags(fs, os.Args[2:])
+		if len(args) < 1 {
+			fmt.Fprintf(os.Stderr, "Error: workflow id or run id is required\n\n")
+			printUsage()
+			os.Exit(1)
+		}
+		renderGraph(store, args[0], *format)
+	case "schedules":
+		listSchedules(store)
 	default:
 		fmt.Fprintf(os.Stderr, "Error: unknown command '%s'\n\n", command)
 		printUsage()
@@ -64,12 +245,92 @@ func printUsage() {
 	fmt.Println()
 	fmt.Println("Commands:")
 	fmt.Println("  run <workflow-id> <run-id>       Create and start a workflow run")
+	fmt.Println("        [--param <name>=<value> ...]")
 	fmt.Println("  tick <run-id>                    Execute one tick of a workflow run")
 	fmt.Println("  tasks <run-id>                   List waiting tasks for human intervention")
 	fmt.Println("  do <run-id> <task-index>         Complete a waiting task")
+	fmt.Println("        [--as <name>] [--file <path> | --stdin | --edit]")
+	fmt.Println("        [--type <content-type>] [--outcome <outcome>] [--comment <text>]")
+	fmt.Println("  claim <run-id> <task-index>      Hold a waiting task so no one else completes it")
+	fmt.Println("        [--as <name>] [--for <duration>]")
+	fmt.Println("  release <run-id> <task-index>    Drop your claim on a waiting task")
+	fmt.Println("        [--as <name>]")
+	fmt.Println("  comment <run-id> [text]          Comment on a run, or list its comments")
+	fmt.Println("        [--as <name>] [--task <task-index> | --step <step>] [--reply-to <id>]")
+	fmt.Println("        [--artifact <name> [--version <n>] [--lines <from>-<to>]]")
+	fmt.Println("  cancel <run-id>                  Cancel a run and its unfinished steps")
+	fmt.Println("  pause <run-id>                   Pause a run so ticks are ignored")
+	fmt.Println("  resume <run-id>                  Resume a paused run")
+	fmt.Println("  rerun <run-id> <step>            Reset a step and everything downstream")
+	fmt.Println("  fork <run-id> <new-run-id>       Copy a run, resetting from a step onward")
+	fmt.Println("        --from <step>")
+	fmt.Println("  graph <workflow-id|run-id>       Render a workflow or run as a graph")
+	fmt.Println("        [--format dot|mermaid]")
+	fmt.Println("  schedules                        List schedules with their last and next fire")
+	fmt.Println("  cache ls                         List cached step outputs")
+	fmt.Println("  cache prune                      Remove cached step outputs")
+	fmt.Println("        [--older-than <duration>]")
+	fmt.Println("  artifact history <run-id> <name> List every version of an artifact")
+	fmt.Println("  lineage <run-id> <name>          Trace an artifact back to its root content")
+	fmt.Println("        [--version <n>]")
+	fmt.Println("  store migrate                    Copy runs between storage backends")
+	fmt.Println("        --from fs|sqlite --to fs|sqlite")
+}
+
+// parseFlags parses fs from args, allowing flags to appear before, between,
+// or after positional arguments. Returns the positional arguments in order.
+func parseFlags(fs *flag.FlagSet, args []string) []string {
+	positional := []string{}
+	for {
+		fs.Parse(args)
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// defaultActor names who is running the command, for provenance records:
+// $COMPOSER_ACTOR when set, otherwise the current OS user
+func defaultActor() string {
+	if actor := os.Getenv("COMPOSER_ACTOR"); actor != "" {
+		return actor
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return ""
 }
 
-func runWorkflow(workflowID, runID string) {
+// paramFlag collects repeated --param name=value flags
+type paramFlag map[string]string
+
+func (p paramFlag) String() string {
+	pairs := make([]string, 0, len(p))
+	for name, value := range p {
+		pairs = append(pairs, name+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (p paramFlag) Set(value string) error {
+	name, v, ok := strings.Cut(value, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("expected name=value, got '%s'", value)
+	}
+	p[name] = v
+	return nil
+}
+
+func runWorkflow(store workflow.RunStore, workflowID, runID string, params map[string]string) {
+	if err := workflow.ValidateRunID(runID); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Load the workflow
 	wf, path, err := workflow.LoadWorkflow(workflowID)
 	if err != nil {
@@ -89,7 +350,7 @@ func runWorkflow(workflowID, runID string) {
 	fmt.Println()
 
 	// Create the run
-	if err := orchestrator.CreateRun(wf, runID, runID); err != nil {
+	if err := orchestrator.CreateRunWithParams(store, wf, runID, runID, params); err != nil {
 		fmt.Fprintf(os.Stderr, "Error creating run: %v\n", err)
 		os.Exit(1)
 	}
@@ -101,7 +362,7 @@ func runWorkflow(workflowID, runID string) {
 	fmt.Println("Executing first tick...")
 	fmt.Println()
 
-	complete, err := orchestrator.Tick(wf, runID)
+	complete, err := orchestrator.Tick(store, wf, runID)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error executing tick: %v\n", err)
 		os.Exit(1)
@@ -114,9 +375,9 @@ func runWorkflow(workflowID, runID string) {
 	}
 }
 
-func tickWorkflow(runID string) {
+func tickWorkflow(store workflow.RunStore, runID string) {
 	// Load the run state to get the workflow ID
-	state, err := workflow.LoadState(runID)
+	state, err := store.LoadRun(runID)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading run state: %v\n", err)
 		fmt.Fprintf(os.Stderr, "Make sure the run '%s' exists.\n", runID)
@@ -130,8 +391,17 @@ func tickWorkflow(runID string) {
 		os.Exit(1)
 	}
 
+	switch state.Status {
+	case workflow.RunPaused:
+		fmt.Printf("Run '%s' is paused. Run 'composer resume %s' to continue.\n", runID, runID)
+		return
+	case workflow.RunCancelled:
+		fmt.Printf("Run '%s' was cancelled.\n", runID)
+		return
+	}
+
 	// Execute tick
-	complete, err := orchestrator.Tick(wf, runID)
+	complete, err := orchestrator.Tick(store, wf, runID)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error executing tick: %v\n", err)
 		os.Exit(1)
@@ -144,9 +414,9 @@ func tickWorkflow(runID string) {
 	}
 }
 
-func listTasks(runID string) {
+func listTasks(store workflow.RunStore, runID string) {
 	// Load the run state to get the workflow ID
-	state, err := workflow.LoadState(runID)
+	state, err := store.LoadRun(runID)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading run state: %v\n", err)
 		fmt.Fprintf(os.Stderr, "Make sure the run '%s' exists.\n", runID)
@@ -161,7 +431,7 @@ func listTasks(runID string) {
 	}
 
 	// Get waiting tasks
-	tasks, err := orchestrator.ListWaitingTasks(wf, runID)
+	tasks, err := orchestrator.ListWaitingTasks(store, wf, runID)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error listing tasks: %v\n", err)
 		os.Exit(1)
@@ -183,20 +453,455 @@ func listTasks(runID string) {
 			fmt.Printf("    Inputs: %v\n", task.Inputs)
 		}
 		fmt.Printf("    Output: %s\n", task.Output)
+		if len(task.Outcomes) > 0 {
+			fmt.Printf("    Outcomes: %s\n", strings.Join(task.Outcomes, ", "))
+		}
+		if task.Assignee != "" {
+			fmt.Printf("    Assignee: %s\n", task.Assignee)
+		}
+		if len(task.Assignees) > 0 {
+			fmt.Printf("    Assignees: %s\n", strings.Join(task.Assignees, ", "))
+		}
+		if task.ApprovalsRequired > 0 {
+			fmt.Printf("    Approvals: %s\n", approvalTally(task.Approvals, task.ApprovalsRequired))
+			for _, vote := range task.Approvals {
+				line := fmt.Sprintf("    Vote: %s chose %s", vote.Actor, vote.Outcome)
+				if vote.Comment != "" {
+					line += fmt.Sprintf(" (%s)", vote.Comment)
+				}
+				fmt.Println(line)
+			}
+		}
+		if task.Role != "" {
+			fmt.Printf("    Role: %s\n", task.Role)
+		}
+		if task.ClaimedBy != "" {
+			fmt.Printf("    Claimed by: %s until %s\n", task.ClaimedBy, task.ClaimExpires.Local().Format(time.Kitchen))
+		}
+		if task.Due != nil {
+			due := task.Due.Local().Format("Jan 2 3:04PM")
+			switch {
+			case task.EscalatedAt != nil:
+				due += " (overdue, escalated)"
+			case task.Overdue:
+				due += " (overdue)"
+			}
+			fmt.Printf("    Due: %s\n", due)
+		}
+		if task.Loop != "" {
+			fmt.Printf("    Loop: %s, iteration %d of %d\n", task.Loop, task.Iteration, task.MaxIterations)
+		}
+		for _, feedback := range task.Feedback {
+			line := fmt.Sprintf("    Decision: %s chose %s", feedback.Step, feedback.Outcome)
+			if feedback.Comment != "" {
+				line += fmt.Sprintf(" (%s)", feedback.Comment)
+			}
+			fmt.Println(line)
+		}
+		if len(task.Form) > 0 {
+			fields := make([]string, len(task.Form))
+			for i, field := range task.Form {
+				fields[i] = fmt.Sprintf("%s (%s)", field.Name, field.FieldType())
+			}
+			fmt.Printf("    Form: %s\n", strings.Join(fields, ", "))
+		}
+		fmt.Println()
+	}
+}
+
+// taskOutput says where the output of a completed task comes from. With no
+// source set, the task's prefill is written as is.
+type taskOutput struct {
+	File        string
+	Stdin       bool
+	Edit        bool
+	ContentType string
+}
+
+// commentOptions places a comment on a task, in a thread, or against part of
+// an artifact
+type commentOptions struct {
+	Actor    string
+	Task     string
+	Step     string
+	ReplyTo  int
+	Artifact string
+	Version  int
+	Lines    string
+}
+
+// commentOnRun adds a comment to a run, or lists its comments when the text
+// is empty. Comments are listed for the whole run, or for one task with
+// --task or --step.
+func commentOnRun(store workflow.RunStore, runID, text string, opts commentOptions) {
+	state, err := store.LoadRun(runID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading run state: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Make sure the run '%s' exists.\n", runID)
+		os.Exit(1)
+	}
+
+	step := opts.Step
+	if opts.Task != "" {
+		if step != "" {
+			fmt.Fprintf(os.Stderr, "Error: use either --task or --step\n")
+			os.Exit(1)
+		}
+		_, task := resolveTask(store, runID, opts.Task)
+		step = task.Name
+	}
+
+	if text == "" {
+		comments, err := orchestrator.ListComments(store, runID, step)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error listing comments: %v\n", err)
+			os.Exit(1)
+		}
+		printComments(comments)
+		return
+	}
+
+	wf, _, err := workflow.LoadWorkflow(state.WorkflowName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading workflow '%s': %v\n", state.WorkflowName, err)
+		os.Exit(1)
+	}
+
+	comment := workflow.Comment{Author: opts.Actor, Body: text, Step: step, ReplyTo: opts.ReplyTo}
+	if opts.Artifact != "" {
+		comment.Artifact = &workflow.ArtifactRef{Name: opts.Artifact, Version: opts.Version}
+	}
+	if opts.Lines != "" {
+		lines, err := workflow.ParseLineRange(opts.Lines)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		comment.Lines = &lines
+	}
+
+	comment, err = orchestrator.AddComment(store, wf, runID, comment)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error adding comment: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Comment #%d added.\n", comment.ID)
+}
+
+// printComments lists comments with what each is on, oldest first
+func printComments(comments []workflow.Comment) {
+	if len(comments) == 0 {
+		fmt.Println("No comments.")
+		return
+	}
+
+	for _, c := range comments {
+		fmt.Printf("#%d %s, %s\n", c.ID, c.Author, c.At.Local().Format("Jan 2 3:04PM"))
+		if about := commentContext(c); about != "" {
+			fmt.Printf("    %s\n", about)
+		}
+		for _, line := range strings.Split(c.Body, "\n") {
+			fmt.Printf("    %s\n", line)
+		}
 		fmt.Println()
 	}
 }
 
-func doTask(runID, taskIndexStr string) {
+// commentContext describes what a comment is on, e.g. "On review, reply to
+// #2, draft v3 lines 4-6"
+func commentContext(c workflow.Comment) string {
+	parts := []string{}
+	if c.Step != "" {
+		parts = append(parts, "On "+c.Step)
+	}
+	if c.ReplyTo != 0 {
+		parts = append(parts, fmt.Sprintf("reply to #%d", c.ReplyTo))
+	}
+	if c.Artifact != nil {
+		ref := fmt.Sprintf("%s v%d", c.Artifact.Name, c.Artifact.Version)
+		if c.Lines != nil {
+			ref += " " + c.Lines.Describe()
+		}
+		parts = append(parts, ref)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// resolveTask loads a run's workflow and the waiting task at the given
+// index, exiting on error
+func resolveTask(store workflow.RunStore, runID, taskIndexStr string) (*workflow.Workflow, orchestrator.WaitingTask) {
+	wf, task, err := findTask(store, runID, taskIndexStr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return wf, task
+}
+
+// findTask loads a run's workflow and the waiting task at the given index
+func findTask(store workflow.RunStore, runID, taskIndexStr string) (*workflow.Workflow, orchestrator.WaitingTask, error) {
 	// Parse task index
 	taskIndex, err := strconv.Atoi(taskIndexStr)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: invalid task index '%s'\n", taskIndexStr)
+		return nil, orchestrator.WaitingTask{}, fmt.Errorf("invalid task index '%s'", taskIndexStr)
+	}
+
+	// Load the run state to get the workflow ID
+	state, err := store.LoadRun(runID)
+	if err != nil {
+		return nil, orchestrator.WaitingTask{}, fmt.Errorf("failed to load run '%s': %w", runID, err)
+	}
+
+	// Load the workflow
+	wf, _, err := workflow.LoadWorkflow(state.WorkflowName)
+	if err != nil {
+		return nil, orchestrator.WaitingTask{}, fmt.Errorf("failed to load workflow '%s': %w", state.WorkflowName, err)
+	}
+
+	// Resolve the task index to its step
+	tasks, err := orchestrator.ListWaitingTasks(store, wf, runID)
+	if err != nil {
+		return nil, orchestrator.WaitingTask{}, fmt.Errorf("failed to list tasks: %w", err)
+	}
+	if taskIndex < 0 || taskIndex >= len(tasks) {
+		return nil, orchestrator.WaitingTask{}, fmt.Errorf("invalid task index: %d (must be between 0 and %d)", taskIndex, len(tasks)-1)
+	}
+	return wf, tasks[taskIndex], nil
+}
+
+// claimTask holds a waiting task for the actor until the lease expires
+func claimTask(store workflow.RunStore, runID, taskIndexStr, actor string, lease time.Duration) {
+	wf, task := resolveTask(store, runID, taskIndexStr)
+
+	state, err := orchestrator.ClaimTask(store, wf, runID, task.Name, actor, lease)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error claiming task: %v\n", err)
+		os.Exit(1)
+	}
+
+	expires := state.StepStates[task.Name].ClaimExpires
+	fmt.Printf("Task %s claimed by %s until %s.\n", taskIndexStr, actor, expires.Local().Format(time.Kitchen))
+}
+
+// releaseTask drops the actor's claim on a waiting task
+func releaseTask(store workflow.RunStore, runID, taskIndexStr, actor string) {
+	wf, task := resolveTask(store, runID, taskIndexStr)
+
+	if _, err := orchestrator.ReleaseTask(store, wf, runID, task.Name, actor); err != nil {
+		fmt.Fprintf(os.Stderr, "Error releasing task: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Task %s released.\n", taskIndexStr)
+}
+
+func doTask(store workflow.RunStore, runID, taskIndexStr, actor string, decision orchestrator.Decision, output taskOutput) {
+	wf, task := resolveTask(store, runID, taskIndexStr)
+	if len(task.Outcomes) > 0 && !slices.Contains(task.Outcomes, decision.Outcome) {
+		fmt.Fprintf(os.Stderr, "Error: %s needs --outcome %s\n", task.Name, strings.Join(task.Outcomes, "|"))
+		os.Exit(1)
+	}
+
+	// Read the human-authored output
+	var content io.Reader
+	switch {
+	case output.File != "":
+		f, err := os.Open(output.File)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		content = f
+	case output.Stdin:
+		content = os.Stdin
+	case len(task.Form) > 0:
+		if output.Edit {
+			fmt.Fprintf(os.Stderr, "Error: %s is completed with a form; omit --edit to be prompted for each field\n", task.Name)
+			os.Exit(1)
+		}
+		values, err := promptForm(task.Form, bufio.NewReader(os.Stdin))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		data, err := json.Marshal(values)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		content = bytes.NewReader(data)
+	case output.Edit:
+		edited, err := editContent(task.Prefill)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		content = strings.NewReader(edited)
+	}
+
+	// Complete the task
+	meta := workflow.ArtifactMeta{ContentType: output.ContentType}
+	state, err := orchestrator.CompleteStep(store, wf, runID, task.Name, actor, decision, meta, content)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error completing task: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Votes on a step collecting approvals leave it waiting until decided
+	if current := state.StepStates[task.Name]; current.Status == workflow.StatusReady {
+		fmt.Printf("Vote recorded on task %s (%s).\n", taskIndexStr, approvalTally(current.Approvals, task.ApprovalsRequired))
+		return
+	}
+	fmt.Printf("Task %s completed successfully.\n", taskIndexStr)
+	fmt.Printf("Run 'composer tick %s' to continue the workflow.\n", runID)
+}
+
+// approvalTally summarizes the votes on a step collecting approvals, e.g.
+// "1 of 2 approved, 1 rejected"
+func approvalTally(votes []workflow.Approval, required int) string {
+	approved, rejected := 0, 0
+	for _, vote := range votes {
+		if vote.Outcome == workflow.OutcomeApprove {
+			approved++
+		} else {
+			rejected++
+		}
+	}
+
+	tally := fmt.Sprintf("%d of %d approved", approved, required)
+	if rejected > 0 {
+		tally += fmt.Sprintf(", %d rejected", rejected)
+	}
+	return tally
+}
+
+// promptForm asks for each form field in turn, repeating a question until
+// the answer is valid. An empty answer takes the field's default.
+func promptForm(fields []workflow.FormField, in *bufio.Reader) (map[string]any, error) {
+	values := map[string]any{}
+	for _, field := range fields {
+		for {
+			fmt.Print(formQuestion(field))
+			line, err := in.ReadString('\n')
+			if err != nil && (err != io.EOF || line == "") {
+				return nil, fmt.Errorf("failed to read %s: %w", field.Name, err)
+			}
+
+			value, err := field.Parse(line)
+			if err == nil {
+				if value == nil {
+					value = field.Default
+				}
+				value, err = field.Validate(value)
+			}
+			if err != nil {
+				fmt.Printf("  %s %v\n", field.DisplayLabel(), err)
+				continue
+			}
+
+			if value != nil {
+				values[field.Name] = value
+			}
+			break
+		}
+	}
+	return values, nil
+}
+
+// formQuestion returns the prompt for a form field, such as
+// "Verdict (approve/reject) [approve]: "
+func formQuestion(field workflow.FormField) string {
+	question := field.DisplayLabel()
+	switch field.FieldType() {
+	case "select":
+		question += " (" + strings.Join(field.Options, "/") + ")"
+	case "boolean":
+		question += " (y/n)"
+	case "number":
+		question += " (number)"
+	}
+	if field.Required {
+		question += " *"
+	}
+	if field.Default != nil {
+		question += fmt.Sprintf(" [%v]", field.Default)
+	}
+	if field.Hint != "" {
+		question = "  " + field.Hint + "\n" + question
+	}
+	return question + ": "
+}
+
+// editContent opens the initial content in $VISUAL or $EDITOR, falling back
+// to vi, and returns the saved result
+func editContent(initial string) (string, error) {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	f, err := os.CreateTemp("", "composer-task-*.txt")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString(initial)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to write temp file: %w", err)
+	}
+
+	// The editor may carry its own arguments, like "code --wait"
+	args := append(strings.Fields(editor), f.Name())
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor %s failed: %w", args[0], err)
+	}
+
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", fmt.Errorf("failed to read edited file: %w", err)
+	}
+	return string(data), nil
+}
+
+func controlRun(store workflow.RunStore, command, runID string) {
+	var (
+		state *workflow.RunState
+		err   error
+	)
+	switch command {
+	case "cancel":
+		state, err = orchestrator.CancelRun(store, runID)
+	case "pause":
+		state, err = orchestrator.PauseRun(store, runID)
+	case "resume":
+		state, err = orchestrator.ResumeRun(store, runID)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
+	fmt.Printf("Run '%s' is now %s.\n", runID, state.Status)
+	if state.Status == workflow.RunActive {
+		fmt.Printf("Run 'composer tick %s' to continue.\n", runID)
+	}
+}
+
+func rerunStep(store workflow.RunStore, runID, stepName string) {
 	// Load the run state to get the workflow ID
-	state, err := workflow.LoadState(runID)
+	state, err := store.LoadRun(runID)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading run state: %v\n", err)
 		fmt.Fprintf(os.Stderr, "Make sure the run '%s' exists.\n", runID)
@@ -210,12 +915,258 @@ func doTask(runID, taskIndexStr string) {
 		os.Exit(1)
 	}
 
-	// Complete the task
-	if err := orchestrator.CompleteTask(wf, runID, taskIndex); err != nil {
-		fmt.Fprintf(os.Stderr, "Error completing task: %v\n", err)
+	_, reset, err := orchestrator.RerunStep(store, wf, runID, stepName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error rerunning step: %v\n", err)
 		os.Exit(1)
 	}
 
-	fmt.Printf("Task %d completed successfully.\n", taskIndex)
-	fmt.Printf("Run 'composer tick %s' to continue the workflow.\n", runID)
+	fmt.Printf("Reset %d step(s) to pending:\n", len(reset))
+	for _, name := range reset {
+		fmt.Printf("  %s\n", name)
+	}
+	fmt.Printf("Run 'composer tick %s' to recompute them.\n", runID)
+}
+
+func forkRun(store workflow.RunStore, runID, newRunID, fromStep string) {
+	// Load the run state to get the workflow ID
+	state, err := store.LoadRun(runID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading run state: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Make sure the run '%s' exists.\n", runID)
+		os.Exit(1)
+	}
+
+	// Load the workflow
+	wf, _, err := workflow.LoadWorkflow(state.WorkflowName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading workflow '%s': %v\n", state.WorkflowName, err)
+		os.Exit(1)
+	}
+
+	if _, err := orchestrator.ForkRun(store, wf, runID, newRunID, fromStep); err != nil {
+		fmt.Fprintf(os.Stderr, "Error forking run: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Forked run '%s' from '%s' at step '%s'.\n", newRunID, runID, fromStep)
+	fmt.Printf("Run 'composer tick %s' to continue.\n", newRunID)
+}
+
+func renderGraph(store workflow.RunStore, id, formatName string) {
+	format, err := graph.ParseFormat(formatName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Prefer a run with this id so nodes are colored by step status,
+	// otherwise fall back to the workflow definition
+	var state *workflow.RunState
+	workflowID := id
+	if runState, err := store.LoadRun(id); err == nil {
+		state = runState
+		workflowID = runState.WorkflowName
+	}
+
+	wf, _, err := workflow.LoadWorkflow(workflowID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	out, err := graph.Render(wf, state, format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error rendering graph: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Print(out)
+}
+
+// listSchedules prints every schedule with when it last started a run and
+// when it fires next
+func listSchedules(store workflow.RunStore) {
+	statuses, err := orchestrator.ListScheduleStatuses(store, time.Now())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(statuses) == 0 {
+		fmt.Println("No schedules.")
+		return
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tWORKFLOW\tCRON\tLAST FIRED\tNEXT")
+	for _, s := range statuses {
+		last := "never"
+		if s.LastFired != nil {
+			last = s.LastFired.Local().Format("Jan 2 3:04PM")
+		}
+		next := "never"
+		if !s.Next.IsZero() {
+			next = s.Next.Local().Format("Jan 2 3:04PM")
+		}
+		if s.Error != "" {
+			next = "invalid"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", s.Name, s.Workflow, s.Cron, last, next)
+	}
+	tw.Flush()
+
+	for _, s := range statuses {
+		if s.Error != "" {
+			fmt.Fprintf(os.Stderr, "Warning: schedule '%s': %s\n", s.Name, s.Error)
+		}
+	}
+}
+
+func listCache() {
+	entries, err := cache.List()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing cache: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("Step output cache is empty.")
+		return
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tWORKFLOW\tSTEP\tSIZE\tLAST USED")
+	for _, entry := range entries {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n",
+			entry.Key[:12],
+			entry.Workflow,
+			entry.Step,
+			entry.Size,
+			entry.LastUsedAt.Local().Format(time.DateTime),
+		)
+	}
+	tw.Flush()
+}
+
+func artifactHistory(store workflow.RunStore, runID, name string) {
+	versions, err := store.Artifacts().Versions(runID, name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading artifact history: %v\n", err)
+		os.Exit(1)
+	}
+
+	current := 0
+	if info, err := store.Artifacts().Stat(runID, name); err == nil {
+		current = info.Version
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tCREATED\tSTEP\tATTEMPT\tSIZE\tSHA256")
+	for _, info := range versions {
+		marker := ""
+		if info.Version == current {
+			marker = "(current)"
+		}
+		step, attempt := "-", "-"
+		if info.Step != "" {
+			step = info.Step
+		}
+		if info.Attempt > 0 {
+			attempt = strconv.Itoa(info.Attempt)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			info.Version,
+			info.CreatedAt.Local().Format(time.DateTime),
+			step,
+			attempt,
+			info.Size,
+			shortDigest(info.SHA256),
+			marker,
+		)
+	}
+	tw.Flush()
+}
+
+func showLineage(store workflow.RunStore, runID, name string, version int) {
+	node, err := workflow.Lineage(store.Artifacts(), runID, name, version)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error tracing artifact lineage: %v\n", err)
+		os.Exit(1)
+	}
+
+	printLineage(*node, 0)
+}
+
+// printLineage prints an artifact version and, indented below it, the input
+// versions it was produced from
+func printLineage(node workflow.LineageNode, depth int) {
+	info := node.Artifact
+	line := fmt.Sprintf("%s%s v%d  %s", strings.Repeat("  ", depth), info.Name, info.Version, shortDigest(info.SHA256))
+
+	switch {
+	case node.Missing:
+		line += "  (no longer stored)"
+	case info.Step == "" && info.Handler == "":
+		line += "  (no recorded producer)"
+	default:
+		if info.Step != "" {
+			line += fmt.Sprintf("  step %s attempt %d, %s handler %s", info.Step, info.Attempt, info.Handler, shortDigest(info.HandlerVersion))
+		} else {
+			line += "  " + info.Handler
+		}
+		if info.Actor != "" {
+			line += " by " + info.Actor
+		}
+		line += "  " + info.CreatedAt.Local().Format(time.DateTime)
+	}
+	fmt.Println(line)
+
+	for _, source := range node.Sources {
+		printLineage(source, depth+1)
+	}
+}
+
+// shortDigest abbreviates a hex digest for display
+func shortDigest(digest string) string {
+	if len(digest) > 12 {
+		return digest[:12]
+	}
+	return digest
+}
+
+func pruneCache(olderThan time.Duration) {
+	removed, err := cache.Prune(olderThan)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error pruning cache: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Removed %d cache entries.\n", len(removed))
+}
+
+func migrateStore(fromKind, toKind string) {
+	dataDir := workflow.GetDataDir()
+
+	from, err := workflow.OpenStore(fromKind, dataDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening source store: %v\n", err)
+		os.Exit(1)
+	}
+	to, err := workflow.OpenStore(toKind, dataDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening destination store: %v\n", err)
+		os.Exit(1)
+	}
+
+	migrated, skipped, err := workflow.MigrateRuns(from, to)
+	for _, runID := range skipped {
+		fmt.Printf("Skipped run '%s': already exists in %s store\n", runID, toKind)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error migrating runs: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Migrated %d runs from %s to %s.\n", len(migrated), fromKind, toKind)
 }
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"composer/internal/orchestrator"
+	"composer/internal/workflow"
+)
+
+func TestFindTaskInvalidIndex(t *testing.T) {
+	os.Chdir(t.TempDir())
+
+	wf := &workflow.Workflow{
+		ID: "test",
+		Steps: []workflow.Step{
+			{Name: "manual", Handler: "human", Prompt: "Task", Content: "data", Output: "out"},
+		},
+	}
+	if err := workflow.SaveWorkflow(wf); err != nil {
+		t.Fatalf("SaveWorkflow failed: %v", err)
+	}
+
+	store := workflow.NewFSStore(workflow.GetDataDir())
+	runID := "test-run"
+	if err := orchestrator.CreateRun(store, wf, runID, runID); err != nil {
+		t.Fatalf("CreateRun failed: %v", err)
+	}
+	if _, err := orchestrator.Tick(store, wf, runID); err != nil {
+		t.Fatalf("Tick failed: %v", err)
+	}
+
+	if _, task, err := findTask(store, runID, "0"); err != nil || task.Name != "manual" {
+		t.Errorf("Expected task 0 to be 'manual', got %q, %v", task.Name, err)
+	}
+
+	for _, index := range []string{"5", "-1", "first"} {
+		if _, _, err := findTask(store, runID, index); err == nil {
+			t.Errorf("Expected error for task index %q", index)
+		}
+	}
+}
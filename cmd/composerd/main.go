@@ -1,35 +1,133 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"composer/internal/api"
+	"composer/internal/orchestrator"
 	"composer/internal/ui"
+	"composer/internal/workflow"
 )
 
 func main() {
 	addr := "0.0.0.0:8080"
 
+	limits, err := orchestrator.LimitsFromEnv()
+	if err != nil {
+		log.Fatalf("failed to configure concurrency limits: %v", err)
+	}
+	orchestrator.SetLimits(limits)
+
 	uiServer, err := ui.Init(resolveUIMode())
 	if err != nil {
 		log.Fatalf("failed to initialize UI: %v", err)
 	}
 
-	apiMux := api.BuildRouter()
-	uiMux := uiServer.BuildRouter()
+	dataDir := workflow.GetDataDir()
+	store, err := workflow.OpenStore(os.Getenv("COMPOSER_STORE"), dataDir)
+	if err != nil {
+		log.Fatalf("failed to open run store: %v", err)
+	}
+	fmt.Printf("Serving runs from %s\n", dataDir)
+
+	interval, err := orchestrator.EscalationIntervalFromEnv()
+	if err != nil {
+		log.Fatalf("failed to configure escalation: %v", err)
+	}
+	schedule, err := orchestrator.SchedulerConfigFromEnv()
+	if err != nil {
+		log.Fatalf("failed to configure scheduler: %v", err)
+	}
+
+	watchInterval, err := orchestrator.WatchIntervalFromEnv()
+	if err != nil {
+		log.Fatalf("failed to configure watch triggers: %v", err)
+	}
+
+	// Stop on SIGINT or SIGTERM, letting steps in flight finish
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var background sync.WaitGroup
+	if interval > 0 {
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			escalatePeriodically(ctx, store, interval)
+		}()
+	}
+	if schedule.Concurrency > 0 {
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			orchestrator.NewScheduler(store, schedule).Run(ctx)
+		}()
+	}
+
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		orchestrator.NewScheduleRunner(store).Run(ctx)
+	}()
+
+	if watchInterval > 0 {
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			orchestrator.NewWatcher(store).Run(ctx, watchInterval)
+		}()
+	}
+
+	apiMux := api.BuildRouter(store)
+	uiMux := uiServer.BuildRouter(store)
 
 	mux := http.NewServeMux()
 	mux.Handle("/", uiMux)
 	mux.Handle("/api/", apiMux)
 
+	server := &http.Server{Addr: addr, Handler: mux}
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		<-ctx.Done()
+		fmt.Println("Shutting down, waiting for running steps to finish")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
 	fmt.Printf("Starting composerd on http://%s\n", addr)
-	if err := http.ListenAndServe(addr, mux); err != nil {
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		log.Fatalf("Server failed: %v", err)
 	}
+	background.Wait()
+}
+
+// escalatePeriodically escalates overdue human tasks across every run once
+// per interval until ctx is cancelled
+func escalatePeriodically(ctx context.Context, store workflow.RunStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := orchestrator.EscalateRuns(ctx, store, now, orchestrator.PostNotification); err != nil {
+				log.Printf("escalation failed: %v", err)
+			}
+		}
+	}
 }
 
 func resolveUIMode() ui.Mode {
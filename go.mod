@@ -1,11 +1,24 @@
 module composer
 
-go 1.23
+go 1.23.0
 
 require (
 	github.com/pelletier/go-toml/v2 v2.2.4
 	gotest.tools/v3 v3.5.2
 	maragu.dev/gomponents v1.2.0
+	modernc.org/sqlite v1.39.0
 )
 
-require github.com/google/go-cmp v0.5.9 // indirect
+require (
+	github.com/dustin/go-humanize v1.0.1 // indirect
+	github.com/google/go-cmp v0.6.0 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/ncruces/go-strftime v0.1.9 // indirect
+	github.com/remyoudompheng/bigfft v0.0.0-20230129092748-24d4a6f8daec // indirect
+	golang.org/x/exp v0.0.0-20250620022241-b7579e27df2b // indirect
+	golang.org/x/sys v0.34.0 // indirect
+	modernc.org/libc v1.66.3 // indirect
+	modernc.org/mathutil v1.7.1 // indirect
+	modernc.org/memory v1.11.0 // indirect
+)
@@ -2,7 +2,11 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+
+	"composer/internal/graph"
+	"composer/internal/workflow"
 )
 
 // APIResponse is the standardized response wrapper for all API endpoints
@@ -17,13 +21,17 @@ type APIError struct {
 	Message string `json:"message"`
 }
 
-// BuildRouter creates and configures the main HTTP router
-func BuildRouter() *http.ServeMux {
+// BuildRouter creates and configures the main HTTP router, serving runs
+// from the given store
+func BuildRouter(store workflow.RunStore) *http.ServeMux {
 	mux := http.NewServeMux()
 
 	// Delegate to resource-specific routers
 	buildWorkflowsRouter(mux)
-	buildRunsRouter(mux)
+	buildRunsRouter(mux, store)
+	buildArtifactsRouter(mux, store)
+	buildSchedulesRouter(mux, store)
+	buildTriggersRouter(mux, store)
 
 	return mux
 }
@@ -58,6 +66,30 @@ func writeData(
 	}
 }
 
+// GraphResponse is the payload returned by the graph endpoints
+type GraphResponse struct {
+	Format graph.Format `json:"format"`
+	Graph  string       `json:"graph"`
+}
+
+// writeGraph renders the workflow graph and writes it as a success response
+func writeGraph(
+	w http.ResponseWriter,
+	wf *workflow.Workflow,
+	state *workflow.RunState,
+	format graph.Format,
+) {
+	out, err := graph.Render(wf, state, format)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to render graph: %v", err))
+		return
+	}
+	writeData(w, http.StatusOK, GraphResponse{
+		Format: format,
+		Graph:  out,
+	})
+}
+
 // writeJSON writes a JSON response
 func writeJSON(
 	w http.ResponseWriter,
@@ -34,7 +34,13 @@ type apiError struct {
 
 // setupRouter creates a fresh router with all API routes registered
 func setupRouter() *http.ServeMux {
-	return api.BuildRouter()
+	return api.BuildRouter(testStore())
+}
+
+// testStore returns a filesystem store rooted in the test environment's
+// .composer directory
+func testStore() workflow.RunStore {
+	return workflow.NewFSStore(workflow.GetDataDir())
 }
 
 // setupTestEnv creates a temporary directory and changes to it for testing.
@@ -164,7 +170,7 @@ func createRunFixture(t *testing.T, runID, workflowID string) {
 
 	// Create run state
 	state := workflow.NewRunState(wf, runID, runID)
-	if err := state.Save(); err != nil {
+	if err := testStore().SaveRun(state); err != nil {
 		t.Fatalf("Failed to save run fixture: %v", err)
 	}
 }
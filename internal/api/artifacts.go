@@ -0,0 +1,329 @@
+package api
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"composer/internal/orchestrator"
+	"composer/internal/workflow"
+)
+
+// buildArtifactsRouter registers run artifact routes
+func buildArtifactsRouter(mux *http.ServeMux, store workflow.RunStore) {
+	mux.HandleFunc("GET /api/run/{id}/artifacts", handleGetArtifacts(store))
+	mux.HandleFunc("GET /api/run/{id}/artifact/{name}", handleGetArtifact(store))
+	mux.HandleFunc("PUT /api/run/{id}/artifact/{name}", handlePutArtifact(store))
+	mux.HandleFunc("GET /api/run/{id}/artifact/{name}/versions", handleGetArtifactVersions(store))
+	mux.HandleFunc("GET /api/run/{id}/artifact/{name}/lineage", handleGetArtifactLineage(store))
+}
+
+// ArtifactContent is the JSON form of an artifact version, served to clients
+// that accept JSON but not the artifact's own content type
+type ArtifactContent struct {
+	Artifact workflow.ArtifactInfo `json:"artifact"`
+	// Content is the artifact text, or base64 when Encoding is "base64"
+	Content  string `json:"content"`
+	Encoding string `json:"encoding"`
+}
+
+// ArtifactUpload is the payload returned after uploading an artifact
+type ArtifactUpload struct {
+	Artifact workflow.ArtifactInfo `json:"artifact"`
+	// Reset lists the downstream steps reset to pending, in workflow order
+	Reset []string `json:"reset"`
+}
+
+// handleGetArtifacts returns a run's current artifacts ordered by name
+func handleGetArtifacts(store workflow.RunStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		if _, err := store.LoadRun(id); err != nil {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("Run not found: %v", err))
+			return
+		}
+
+		artifacts, err := store.Artifacts().List(id)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to list artifacts: %v", err))
+			return
+		}
+		writeData(w, http.StatusOK, artifacts)
+	}
+}
+
+// handleGetArtifact serves the content of a run's artifact, the current
+// version unless the version query parameter selects another. The raw
+// content is served with its content type and supports range and
+// conditional requests; clients that only accept JSON get an
+// ArtifactContent instead. The download query parameter serves the content
+// as an attachment.
+func handleGetArtifact(store workflow.RunStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		name := r.PathValue("name")
+
+		version, err := parseVersion(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if _, err := store.LoadRun(id); err != nil {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("Run not found: %v", err))
+			return
+		}
+
+		artifacts := store.Artifacts()
+		info, err := findArtifactVersion(artifacts, id, name, version)
+		if err != nil {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("Artifact not found: %v", err))
+			return
+		}
+
+		asJSON, ok := negotiateArtifact(r.Header.Get("Accept"), info.ContentType)
+		if !ok {
+			writeError(w, http.StatusNotAcceptable, fmt.Sprintf("Artifact is %s", info.ContentType))
+			return
+		}
+
+		content, err := artifacts.OpenBlob(info.SHA256)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to read artifact: %v", err))
+			return
+		}
+		defer content.Close()
+
+		if asJSON {
+			data, err := io.ReadAll(content)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to read artifact: %v", err))
+				return
+			}
+
+			payload := ArtifactContent{Artifact: info, Content: string(data), Encoding: "utf-8"}
+			if !utf8.Valid(data) {
+				payload.Content = base64.StdEncoding.EncodeToString(data)
+				payload.Encoding = "base64"
+			}
+			writeData(w, http.StatusOK, payload)
+			return
+		}
+
+		// Range requests need to seek; blobs that cannot are buffered
+		seeker, ok := content.(io.ReadSeeker)
+		if !ok {
+			data, err := io.ReadAll(content)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to read artifact: %v", err))
+				return
+			}
+			seeker = bytes.NewReader(data)
+		}
+
+		w.Header().Set("Content-Type", info.ContentType)
+		w.Header().Set("ETag", strconv.Quote(info.SHA256))
+		if download, _ := strconv.ParseBool(r.URL.Query().Get("download")); download {
+			w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+		}
+		http.ServeContent(w, r, name, info.CreatedAt, seeker)
+	}
+}
+
+// handlePutArtifact uploads the request body as a new version of a run's
+// artifact, overriding the producing step's output. The request's
+// Content-Type is recorded, or detected when absent, and the
+// X-Composer-Actor header names the uploader in the provenance. With the
+// invalidate query parameter, steps downstream of the artifact are reset.
+func handlePutArtifact(store workflow.RunStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		name := r.PathValue("name")
+
+		invalidate := false
+		if value := r.URL.Query().Get("invalidate"); value != "" {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid invalidate '%s': expected a boolean", value))
+				return
+			}
+			invalidate = parsed
+		}
+
+		state, err := store.LoadRun(id)
+		if err != nil {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("Run not found: %v", err))
+			return
+		}
+
+		wf, _, err := workflow.LoadWorkflow(state.WorkflowName)
+		if err != nil {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("Workflow not found: %v", err))
+			return
+		}
+
+		meta := workflow.ArtifactMeta{
+			ContentType: r.Header.Get("Content-Type"),
+			Provenance: workflow.Provenance{
+				Handler: "upload",
+				Actor:   r.Header.Get("X-Composer-Actor"),
+			},
+		}
+		info, reset, err := orchestrator.PutArtifact(store, wf, id, name, meta, r.Body, invalidate)
+		if errors.Is(err, orchestrator.ErrNotOverridable) {
+			writeError(w, http.StatusConflict, fmt.Sprintf("Failed to upload artifact: %v", err))
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to upload artifact: %v", err))
+			return
+		}
+
+		writeData(w, http.StatusOK, ArtifactUpload{Artifact: info, Reset: reset})
+	}
+}
+
+// handleGetArtifactVersions returns every version of a run's artifact, oldest
+// first
+func handleGetArtifactVersions(store workflow.RunStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		name := r.PathValue("name")
+
+		if _, err := store.LoadRun(id); err != nil {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("Run not found: %v", err))
+			return
+		}
+
+		versions, err := store.Artifacts().Versions(id, name)
+		if err != nil {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("Artifact not found: %v", err))
+			return
+		}
+
+		writeData(w, http.StatusOK, versions)
+	}
+}
+
+// handleGetArtifactLineage returns the provenance chain of a run's artifact
+// back to its root content. The version query parameter selects a version
+// other than the current one.
+func handleGetArtifactLineage(store workflow.RunStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		name := r.PathValue("name")
+
+		version, err := parseVersion(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if _, err := store.LoadRun(id); err != nil {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("Run not found: %v", err))
+			return
+		}
+
+		lineage, err := workflow.Lineage(store.Artifacts(), id, name, version)
+		if err != nil {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("Artifact not found: %v", err))
+			return
+		}
+
+		writeData(w, http.StatusOK, lineage)
+	}
+}
+
+// parseVersion reads the artifact version from the version query parameter,
+// or 0 for the current version
+func parseVersion(r *http.Request) (int, error) {
+	value := r.URL.Query().Get("version")
+	if value == "" {
+		return 0, nil
+	}
+
+	version, err := strconv.Atoi(value)
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("invalid version '%s': expected a positive integer", value)
+	}
+	return version, nil
+}
+
+// findArtifactVersion returns a version of a run's artifact, or the current
+// version for 0
+func findArtifactVersion(artifacts workflow.ArtifactStore, runID, name string, version int) (workflow.ArtifactInfo, error) {
+	if version == 0 {
+		return artifacts.Stat(runID, name)
+	}
+
+	versions, err := artifacts.Versions(runID, name)
+	if err != nil {
+		return workflow.ArtifactInfo{}, err
+	}
+	for _, info := range versions {
+		if info.Version == version {
+			return info, nil
+		}
+	}
+	return workflow.ArtifactInfo{}, fmt.Errorf("artifact %s has no version %d", name, version)
+}
+
+// negotiateArtifact picks how to serve an artifact for an Accept header:
+// the raw content when the header accepts its content type, otherwise JSON
+// when the header accepts that. Media ranges are tried in order of quality.
+// Reports false when neither is acceptable.
+func negotiateArtifact(accept, contentType string) (asJSON bool, ok bool) {
+	if strings.TrimSpace(accept) == "" {
+		return false, true
+	}
+
+	artifactType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		artifactType = contentType
+	}
+
+	type mediaRange struct {
+		value   string
+		quality float64
+	}
+	ranges := []mediaRange{}
+	for _, part := range strings.Split(accept, ",") {
+		value, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		if quality > 0 {
+			ranges = append(ranges, mediaRange{value, quality})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].quality > ranges[j].quality
+	})
+
+	for _, mr := range ranges {
+		switch {
+		case mr.value == "*/*" || mr.value == artifactType:
+			return false, true
+		case strings.HasSuffix(mr.value, "/*") && strings.HasPrefix(artifactType, strings.TrimSuffix(mr.value, "*")):
+			return false, true
+		case mr.value == "application/json":
+			return true, true
+		}
+	}
+	return false, false
+}
@@ -0,0 +1,319 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"composer/internal/api"
+	"composer/internal/workflow"
+)
+
+// serve performs a request with the given headers and returns the raw
+// response
+func serve(
+	router *http.ServeMux,
+	method string,
+	url string,
+	body string,
+	headers map[string]string,
+) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, url, strings.NewReader(body))
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	return res
+}
+
+// setupArtifactRun creates a run whose step has produced its artifact
+func setupArtifactRun(t *testing.T) *http.ServeMux {
+	t.Helper()
+
+	createWorkflowFixture(t, "test-workflow", "Test Workflow")
+	createRunFixture(t, "test-run", "test-workflow")
+
+	router := setupRouter()
+	post(router, "/api/run/test-run/tick", "", nil)
+	return router
+}
+
+// TestGetArtifacts tests listing a run's current artifacts
+func TestGetArtifacts(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	router := setupArtifactRun(t)
+
+	var response struct {
+		Error *apiError               `json:"error"`
+		Data  []workflow.ArtifactInfo `json:"data"`
+	}
+	result := get(router, "/api/run/test-run/artifacts", &response)
+
+	if err := expectStatus(http.StatusOK, result); err != nil {
+		t.Fatalf("%v\n%v", err, response)
+	}
+	if len(response.Data) != 1 || response.Data[0].Name != "result1" || response.Data[0].Size != 14 {
+		t.Errorf("Expected result1 of 14 bytes, got %+v", response.Data)
+	}
+}
+
+// TestGetArtifact_Raw tests reading an artifact's content
+func TestGetArtifact_Raw(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	router := setupArtifactRun(t)
+	res := serve(router, "GET", "/api/run/test-run/artifact/result1", "", nil)
+
+	if res.Code != http.StatusOK || res.Body.String() != "Step 1 content" {
+		t.Fatalf("Expected 200 'Step 1 content', got %d %q", res.Code, res.Body.String())
+	}
+	if contentType := res.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain") {
+		t.Errorf("Expected text/plain content, got %s", contentType)
+	}
+	if res.Header().Get("ETag") == "" {
+		t.Error("Expected an ETag header")
+	}
+}
+
+// TestGetArtifact_Range tests reading part of an artifact
+func TestGetArtifact_Range(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	router := setupArtifactRun(t)
+	res := serve(router, "GET", "/api/run/test-run/artifact/result1", "", map[string]string{"Range": "bytes=0-3"})
+
+	if res.Code != http.StatusPartialContent || res.Body.String() != "Step" {
+		t.Errorf("Expected 206 'Step', got %d %q", res.Code, res.Body.String())
+	}
+}
+
+// TestGetArtifact_Download tests serving an artifact as an attachment
+func TestGetArtifact_Download(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	router := setupArtifactRun(t)
+	res := serve(router, "GET", "/api/run/test-run/artifact/result1?download=1", "", nil)
+
+	if disposition := res.Header().Get("Content-Disposition"); disposition != `attachment; filename=result1` {
+		t.Errorf("Expected an attachment named result1, got %q", disposition)
+	}
+}
+
+// TestGetArtifact_JSON tests reading an artifact as JSON
+func TestGetArtifact_JSON(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	router := setupArtifactRun(t)
+	res := serve(router, "GET", "/api/run/test-run/artifact/result1", "", map[string]string{"Accept": "application/json"})
+
+	var response struct {
+		Error *apiError           `json:"error"`
+		Data  api.ArtifactContent `json:"data"`
+	}
+	if err := json.Unmarshal(res.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode JSON: %v\n%s", err, res.Body.String())
+	}
+	if res.Code != http.StatusOK || response.Data.Content != "Step 1 content" || response.Data.Encoding != "utf-8" {
+		t.Errorf("Expected JSON with the artifact text, got %d %+v", res.Code, response)
+	}
+	if response.Data.Artifact.Step != "step1" {
+		t.Errorf("Expected artifact metadata, got %+v", response.Data.Artifact)
+	}
+}
+
+// TestGetArtifact_NotAcceptable tests requesting an unavailable content type
+func TestGetArtifact_NotAcceptable(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	router := setupArtifactRun(t)
+	res := serve(router, "GET", "/api/run/test-run/artifact/result1", "", map[string]string{"Accept": "image/png"})
+
+	if res.Code != http.StatusNotAcceptable {
+		t.Errorf("Expected 406, got %d", res.Code)
+	}
+}
+
+// TestPutArtifact tests uploading a new version of an artifact
+func TestPutArtifact(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	router := setupArtifactRun(t)
+	res := serve(router, "PUT", "/api/run/test-run/artifact/result1?invalidate=true", "Fixed content", map[string]string{
+		"Content-Type":     "text/markdown",
+		"X-Composer-Actor": "admin",
+	})
+
+	var response struct {
+		Error *apiError          `json:"error"`
+		Data  api.ArtifactUpload `json:"data"`
+	}
+	if err := json.Unmarshal(res.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode JSON: %v\n%s", err, res.Body.String())
+	}
+	if res.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %+v", res.Code, response.Error)
+	}
+	artifact := response.Data.Artifact
+	if artifact.Version != 2 || artifact.ContentType != "text/markdown" || artifact.Handler != "upload" || artifact.Actor != "admin" {
+		t.Errorf("Unexpected uploaded artifact: %+v", artifact)
+	}
+
+	res = serve(router, "GET", "/api/run/test-run/artifact/result1", "", nil)
+	if res.Body.String() != "Fixed content" {
+		t.Errorf("Expected uploaded content, got %q", res.Body.String())
+	}
+	res = serve(router, "GET", "/api/run/test-run/artifact/result1?version=1", "", nil)
+	if res.Body.String() != "Step 1 content" {
+		t.Errorf("Expected version 1 to keep the original content, got %q", res.Body.String())
+	}
+}
+
+// TestPutArtifact_RunNotFound tests uploading to a missing run
+func TestPutArtifact_RunNotFound(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	router := setupRouter()
+	res := serve(router, "PUT", "/api/run/missing/artifact/doc", "content", nil)
+
+	if res.Code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", res.Code)
+	}
+}
+
+// TestPutArtifact_Errors tests uploading to a cancelled run, and failing to
+// store the upload
+func TestPutArtifact_Errors(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	router := setupArtifactRun(t)
+	createRunFixture(t, "cancelled-run", "test-workflow")
+	post(router, "/api/run/cancelled-run/cancel", "", nil)
+
+	res := serve(router, "PUT", "/api/run/cancelled-run/artifact/result1", "content", nil)
+	if res.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for a cancelled run, got %d", res.Code)
+	}
+
+	// Blobs cannot be written where a file is in the way
+	blobs := filepath.Join(workflow.GetDataDir(), "blobs")
+	os.RemoveAll(blobs)
+	if err := os.WriteFile(blobs, nil, 0644); err != nil {
+		t.Fatalf("Failed to block the blobs directory: %v", err)
+	}
+	res = serve(router, "PUT", "/api/run/test-run/artifact/result1", "content", nil)
+	if res.Code != http.StatusInternalServerError {
+		t.Errorf("Expected 500 when the upload cannot be stored, got %d: %s", res.Code, res.Body.String())
+	}
+}
+
+// TestGetArtifactVersions tests listing the versions written by a rerun step
+func TestGetArtifactVersions(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	createWorkflowFixture(t, "test-workflow", "Test Workflow")
+	createRunFixture(t, "test-run", "test-workflow")
+
+	router := setupRouter()
+	post(router, "/api/run/test-run/tick", "", nil)
+	post(router, "/api/run/test-run/step/step1/rerun", "", nil)
+	post(router, "/api/run/test-run/tick", "", nil)
+
+	var response struct {
+		Error *apiError               `json:"error"`
+		Data  []workflow.ArtifactInfo `json:"data"`
+	}
+	result := get(router, "/api/run/test-run/artifact/result1/versions", &response)
+
+	if err := expectStatus(http.StatusOK, result); err != nil {
+		t.Fatalf("%v\n%v", err, response)
+	}
+	if len(response.Data) != 2 {
+		t.Fatalf("Expected 2 versions, got %+v", response.Data)
+	}
+	for i, info := range response.Data {
+		if info.Version != i+1 || info.Step != "step1" || info.Attempt != i+1 {
+			t.Errorf("Expected version %d from step1 attempt %d, got %+v", i+1, i+1, info)
+		}
+	}
+}
+
+// TestGetArtifactVersions_NotFound tests listing versions of a missing artifact
+func TestGetArtifactVersions_NotFound(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	createWorkflowFixture(t, "test-workflow", "Test Workflow")
+	createRunFixture(t, "test-run", "test-workflow")
+
+	router := setupRouter()
+
+	var response apiResponse
+	result := get(router, "/api/run/test-run/artifact/missing/versions", &response)
+
+	if err := expectStatus(http.StatusNotFound, result); err != nil {
+		t.Fatalf("%v\n%v", err, response)
+	}
+}
+
+// TestGetArtifactLineage tests tracing an artifact back to its root content
+func TestGetArtifactLineage(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	createWorkflowFixture(t, "test-workflow", "Test Workflow")
+	createRunFixture(t, "test-run", "test-workflow")
+
+	router := setupRouter()
+	post(router, "/api/run/test-run/tick", "", nil)
+
+	var response struct {
+		Error *apiError            `json:"error"`
+		Data  workflow.LineageNode `json:"data"`
+	}
+	result := get(router, "/api/run/test-run/artifact/result1/lineage", &response)
+
+	if err := expectStatus(http.StatusOK, result); err != nil {
+		t.Fatalf("%v\n%v", err, response)
+	}
+	artifact := response.Data.Artifact
+	if artifact.Name != "result1" || artifact.Step != "step1" || artifact.Handler != "tool" {
+		t.Errorf("Expected result1 produced by step1's tool handler, got %+v", artifact)
+	}
+	if len(response.Data.Sources) != 0 {
+		t.Errorf("Expected inline content to have no sources, got %+v", response.Data.Sources)
+	}
+}
+
+// TestGetArtifactLineage_InvalidVersion tests tracing a malformed version
+func TestGetArtifactLineage_InvalidVersion(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	createWorkflowFixture(t, "test-workflow", "Test Workflow")
+	createRunFixture(t, "test-run", "test-workflow")
+
+	router := setupRouter()
+
+	var response apiResponse
+	result := get(router, "/api/run/test-run/artifact/result1/lineage?version=first", &response)
+
+	if err := expectStatus(http.StatusBadRequest, result); err != nil {
+		t.Fatalf("%v\n%v", err, response)
+	}
+}
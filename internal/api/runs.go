@@ -1,173 +1,626 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
+	"time"
 
+	"composer/internal/graph"
 	"composer/internal/orchestrator"
 	"composer/internal/workflow"
 )
 
 // buildRunsRouter registers run-related routes
-func buildRunsRouter(mux *http.ServeMux) {
-	mux.HandleFunc("GET /api/runs", handleGetRuns)
-	mux.HandleFunc("GET /api/runs/tasks", handleGetRunsTasks)
-	mux.HandleFunc("GET /api/run/{id}", handleGetRun)
-	mux.HandleFunc("POST /api/run/{id}", handlePostRun)
-	mux.HandleFunc("GET /api/run/{id}/tasks", handleGetRunTasks)
-	mux.HandleFunc("POST /api/run/{id}/tick", handlePostRunTick)
-}
-
-// handleGetRuns returns a list of all runs
-func handleGetRuns(w http.ResponseWriter, r *http.Request) {
-	runs, err := workflow.ListRuns()
-	if err != nil {
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to list runs: %v", err))
-		return
+func buildRunsRouter(mux *http.ServeMux, store workflow.RunStore) {
+	mux.HandleFunc("GET /api/runs", handleGetRuns(store))
+	mux.HandleFunc("GET /api/runs/tasks", handleGetRunsTasks(store))
+	mux.HandleFunc("GET /api/run/{id}", handleGetRun(store))
+	mux.HandleFunc("POST /api/run/{id}", handlePostRun(store))
+	mux.HandleFunc("GET /api/run/{id}/tasks", handleGetRunTasks(store))
+	mux.HandleFunc("POST /api/run/{id}/tick", handlePostRunTick(store))
+	mux.HandleFunc("GET /api/run/{id}/graph", handleGetRunGraph(store))
+	mux.HandleFunc("POST /api/run/{id}/cancel", handlePostRunControl(store, orchestrator.CancelRun))
+	mux.HandleFunc("POST /api/run/{id}/pause", handlePostRunControl(store, orchestrator.PauseRun))
+	mux.HandleFunc("POST /api/run/{id}/resume", handlePostRunControl(store, orchestrator.ResumeRun))
+	mux.HandleFunc("POST /api/run/{id}/step/{name}/rerun", handlePostStepRerun(store))
+	mux.HandleFunc("POST /api/run/{id}/task/{step}/complete", handlePostTaskComplete(store))
+	mux.HandleFunc("POST /api/run/{id}/task/{step}/claim", handlePostTaskClaim(store))
+	mux.HandleFunc("POST /api/run/{id}/task/{step}/release", handlePostTaskRelease(store))
+	mux.HandleFunc("GET /api/run/{id}/comments", handleGetRunComments(store))
+	mux.HandleFunc("POST /api/run/{id}/comments", handlePostRunComment(store))
+}
+
+// handleGetRuns returns a list of all runs, optionally filtered by the
+// workflow, status, since, and until query parameters
+func handleGetRuns(store workflow.RunStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query, err := parseRunQuery(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		runs, err := store.QueryRuns(query)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to list runs: %v", err))
+			return
+		}
+		writeData(w, http.StatusOK, runs)
 	}
-	writeData(w, http.StatusOK, runs)
+}
+
+// parseRunQuery reads run filters from the request's query parameters. Times
+// are RFC 3339.
+func parseRunQuery(r *http.Request) (workflow.RunQuery, error) {
+	params := r.URL.Query()
+	query := workflow.RunQuery{
+		Workflow: params.Get("workflow"),
+		Status:   workflow.RunStatus(params.Get("status")),
+	}
+
+	for name, dst := range map[string]*time.Time{"since": &query.Since, "until": &query.Until} {
+		value := params.Get(name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return workflow.RunQuery{}, fmt.Errorf("invalid %s '%s': expected RFC 3339 time", name, value)
+		}
+		*dst = t
+	}
+
+	return query, nil
 }
 
 // handleGetRun returns a specific run by name
-func handleGetRun(w http.ResponseWriter, r *http.Request) {
-	id := r.PathValue("id")
+func handleGetRun(store workflow.RunStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
 
-	state, err := workflow.LoadState(id)
-	if err != nil {
-		writeError(w, http.StatusNotFound, fmt.Sprintf("Run not found: %v", err))
-		return
+		state, err := store.LoadRun(id)
+		if err != nil {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("Run not found: %v", err))
+			return
+		}
+		writeData(w, http.StatusOK, state)
 	}
-	writeData(w, http.StatusOK, state)
 }
 
 // handlePostRun creates a new run from a workflow
-func handlePostRun(w http.ResponseWriter, r *http.Request) {
-	id := r.PathValue("id")
+func handlePostRun(store workflow.RunStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		if err := workflow.ValidateRunID(id); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		var req struct {
+			WorkflowId     string            `json:"workflow_id"`
+			RunDisplayName string            `json:"name"`
+			Params         map[string]string `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
+			return
+		}
 
-	var req struct {
-		WorkflowId     string `json:"workflow_id"`
-		RunDisplayName string `json:"name"`
+		if req.WorkflowId == "" {
+			writeError(w, http.StatusBadRequest, "workflow_name is required")
+			return
+		}
+		if req.RunDisplayName == "" {
+			writeError(w, http.StatusBadRequest, "name is required")
+			return
+		}
+
+		// Load the workflow
+		wf, _, err := workflow.LoadWorkflow(req.WorkflowId)
+		if err != nil {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("Workflow not found: %v", err))
+			return
+		}
+
+		// Create the run
+		err = orchestrator.CreateRunWithParams(store, wf, id, req.RunDisplayName, req.Params)
+		if errors.Is(err, workflow.ErrUnknownParams) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to create run: %v", err))
+			return
+		}
+
+		// Load and return the created run state
+		state, err := store.LoadRun(id)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to load created run: %v", err))
+			return
+		}
+
+		writeData(w, http.StatusOK, state)
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
-		return
+}
+
+// handleGetRunTasks returns all tasks waiting for human intervention
+func handleGetRunTasks(store workflow.RunStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		// Load the run state to get the workflow ID
+		state, err := store.LoadRun(id)
+		if err != nil {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("Run not found: %v", err))
+			return
+		}
+
+		// Load the workflow
+		wf, _, err := workflow.LoadWorkflow(state.WorkflowName)
+		if err != nil {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("Workflow not found: %v", err))
+			return
+		}
+
+		// Get waiting tasks
+		tasks, err := orchestrator.ListWaitingTasks(store, wf, id)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to list tasks: %v", err))
+			return
+		}
+
+		writeData(w, http.StatusOK, tasks)
 	}
+}
 
-	if req.WorkflowId == "" {
-		writeError(w, http.StatusBadRequest, "workflow_name is required")
-		return
+// handleGetRunsTasks returns waiting tasks grouped by run name. The actor
+// and roles (comma-separated) query parameters keep only that actor's tasks.
+func handleGetRunsTasks(store workflow.RunStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		runs, err := store.ListRuns()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to list runs: %v", err))
+			return
+		}
+
+		result, err := orchestrator.ListWaitingTasksByRun(store, runs)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to list waiting tasks: %v", err))
+			return
+		}
+
+		writeData(w, http.StatusOK, orchestrator.FilterTasks(result, orchestrator.NewTaskFilter(
+			r.URL.Query().Get("actor"),
+			r.URL.Query().Get("roles"),
+		)))
 	}
-	if req.RunDisplayName == "" {
-		writeError(w, http.StatusBadRequest, "name is required")
-		return
+}
+
+// handlePostRunTick executes a single tick for the specified run
+func handlePostRunTick(store workflow.RunStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		// Load current run state to identify its workflow
+		state, err := store.LoadRun(id)
+		if err != nil {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("Run not found: %v", err))
+			return
+		}
+
+		// Load workflow associated with this run
+		wf, _, err := workflow.LoadWorkflow(state.WorkflowName)
+		if err != nil {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("Workflow not found: %v", err))
+			return
+		}
+
+		// Execute tick
+		complete, err := orchestrator.Tick(store, wf, id)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to tick run: %v", err))
+			return
+		}
+
+		// Reload state to include updates from tick
+		updatedState, err := store.LoadRun(id)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to load updated run state: %v", err))
+			return
+		}
+
+		writeData(w, http.StatusOK, struct {
+			Complete bool               `json:"complete"`
+			State    *workflow.RunState `json:"state"`
+		}{
+			Complete: complete,
+			State:    updatedState,
+		})
 	}
+}
 
-	// Load the workflow
-	wf, _, err := workflow.LoadWorkflow(req.WorkflowId)
-	if err != nil {
-		writeError(w, http.StatusNotFound, fmt.Sprintf("Workflow not found: %v", err))
-		return
+// handleGetRunGraph renders a run's workflow as a graph colored by step status
+func handleGetRunGraph(store workflow.RunStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		format, err := graph.ParseFormat(r.URL.Query().Get("format"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		state, err := store.LoadRun(id)
+		if err != nil {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("Run not found: %v", err))
+			return
+		}
+
+		wf, _, err := workflow.LoadWorkflow(state.WorkflowName)
+		if err != nil {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("Workflow not found: %v", err))
+			return
+		}
+
+		writeGraph(w, wf, state, format)
 	}
+}
 
-	// Create the run
-	if err := orchestrator.CreateRun(wf, id, req.RunDisplayName); err != nil {
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to create run: %v", err))
-		return
+// handlePostRunControl applies a run-level status change (cancel, pause, or
+// resume) and returns the updated run state
+func handlePostRunControl(
+	store workflow.RunStore,
+	control func(store workflow.RunStore, runID string) (*workflow.RunState, error),
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		if _, err := store.LoadRun(id); err != nil {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("Run not found: %v", err))
+			return
+		}
+
+		state, err := control(store, id)
+		if err != nil {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+
+		writeData(w, http.StatusOK, state)
 	}
+}
 
-	// Load and return the created run state
-	state, err := workflow.LoadState(id)
-	if err != nil {
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to load created run: %v", err))
-		return
+// handlePostStepRerun resets a step and its transitive dependents to pending
+func handlePostStepRerun(store workflow.RunStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		name := r.PathValue("name")
+
+		wf, ok := loadTaskWorkflow(w, store, id, name)
+		if !ok {
+			return
+		}
+
+		updatedState, reset, err := orchestrator.RerunStep(store, wf, id, name)
+		if errors.Is(err, orchestrator.ErrNotRerunnable) {
+			writeError(w, http.StatusConflict, fmt.Sprintf("Failed to rerun step: %v", err))
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to rerun step: %v", err))
+			return
+		}
+
+		writeData(w, http.StatusOK, struct {
+			Reset []string           `json:"reset"`
+			State *workflow.RunState `json:"state"`
+		}{
+			Reset: reset,
+			State: updatedState,
+		})
 	}
+}
 
-	writeData(w, http.StatusOK, state)
+// TaskCompletion is the optional body of a task completion request. Without
+// content the step's prefill is written as the output. Steps with a form are
+// completed with values instead, and steps with outcomes need one.
+type TaskCompletion struct {
+	Content     *string        `json:"content"`
+	ContentType string         `json:"content_type"`
+	Values      map[string]any `json:"values"`
+	Outcome     string         `json:"outcome"`
+	Comment     string         `json:"comment"`
+	Tick        bool           `json:"tick"`
 }
 
-// handleGetRunTasks returns all tasks waiting for human intervention
-func handleGetRunTasks(w http.ResponseWriter, r *http.Request) {
-	id := r.PathValue("id")
+// handlePostTaskComplete completes a human task, addressed by step name, with
+// the supplied artifact content. The X-Composer-Actor header names who
+// completed it. With tick, the run is advanced once afterwards.
+func handlePostTaskComplete(store workflow.RunStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		name := r.PathValue("step")
 
-	// Load the run state to get the workflow ID
-	state, err := workflow.LoadState(id)
-	if err != nil {
-		writeError(w, http.StatusNotFound, fmt.Sprintf("Run not found: %v", err))
-		return
+		var req TaskCompletion
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
+			return
+		}
+
+		wf, ok := loadTaskWorkflow(w, store, id, name)
+		if !ok {
+			return
+		}
+
+		if req.Content != nil && req.Values != nil {
+			writeError(w, http.StatusBadRequest, "content and values cannot both be set")
+			return
+		}
+
+		meta := workflow.ArtifactMeta{ContentType: req.ContentType}
+		var content io.Reader
+		if req.Content != nil {
+			content = strings.NewReader(*req.Content)
+		}
+		if req.Values != nil {
+			data, err := json.Marshal(req.Values)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid values: %v", err))
+				return
+			}
+			content = bytes.NewReader(data)
+		}
+
+		actor := r.Header.Get("X-Composer-Actor")
+		decision := orchestrator.Decision{Outcome: req.Outcome, Comment: req.Comment}
+		updatedState, err := orchestrator.CompleteStep(store, wf, id, name, actor, decision, meta, content)
+		var formErrs workflow.FormErrors
+		if errors.As(err, &formErrs) {
+			writeError(w, http.StatusBadRequest, formErrs.Error())
+			return
+		}
+		if errors.Is(err, orchestrator.ErrTaskNotWaiting) ||
+			errors.Is(err, orchestrator.ErrActorNotAllowed) ||
+			errors.Is(err, workflow.ErrInvalidOutcome) {
+			writeError(w, http.StatusConflict, fmt.Sprintf("Failed to complete task: %v", err))
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to complete task: %v", err))
+			return
+		}
+
+		complete := updatedState.AllStepsCompleted()
+		if req.Tick {
+			complete, err = orchestrator.Tick(store, wf, id)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to tick run: %v", err))
+				return
+			}
+
+			updatedState, err = store.LoadRun(id)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to load updated run state: %v", err))
+				return
+			}
+		}
+
+		writeData(w, http.StatusOK, struct {
+			Complete bool               `json:"complete"`
+			State    *workflow.RunState `json:"state"`
+		}{
+			Complete: complete,
+			State:    updatedState,
+		})
 	}
+}
 
-	// Load the workflow
-	wf, _, err := workflow.LoadWorkflow(state.WorkflowName)
-	if err != nil {
-		writeError(w, http.StatusNotFound, fmt.Sprintf("Workflow not found: %v", err))
-		return
+// TaskClaim is the optional body of a task claim request. The lease is a Go
+// duration such as "15m"; without one the claim lasts
+// orchestrator.DefaultLease.
+type TaskClaim struct {
+	Lease string `json:"lease"`
+}
+
+// handlePostTaskClaim holds a human task, addressed by step name, for the
+// actor named by the X-Composer-Actor header until the lease expires
+func handlePostTaskClaim(store workflow.RunStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		name := r.PathValue("step")
+
+		var req TaskClaim
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
+			return
+		}
+
+		var lease time.Duration
+		if req.Lease != "" {
+			var err error
+			lease, err = time.ParseDuration(req.Lease)
+			if err != nil || lease <= 0 {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid lease: %s", req.Lease))
+				return
+			}
+		}
+
+		actor := r.Header.Get("X-Composer-Actor")
+		if actor == "" {
+			writeError(w, http.StatusBadRequest, "X-Composer-Actor header is required")
+			return
+		}
+
+		wf, ok := loadTaskWorkflow(w, store, id, name)
+		if !ok {
+			return
+		}
+
+		state, err := orchestrator.ClaimTask(store, wf, id, name, actor, lease)
+		if isLeaseConflict(err) {
+			writeError(w, http.StatusConflict, fmt.Sprintf("Failed to claim task: %v", err))
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to claim task: %v", err))
+			return
+		}
+		writeData(w, http.StatusOK, state)
 	}
+}
 
-	// Get waiting tasks
-	tasks, err := orchestrator.ListWaitingTasks(wf, id)
-	if err != nil {
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to list tasks: %v", err))
-		return
+// handlePostTaskRelease drops the claim the actor named by the
+// X-Composer-Actor header holds on a human task
+func handlePostTaskRelease(store workflow.RunStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		name := r.PathValue("step")
+
+		actor := r.Header.Get("X-Composer-Actor")
+		if actor == "" {
+			writeError(w, http.StatusBadRequest, "X-Composer-Actor header is required")
+			return
+		}
+
+		wf, ok := loadTaskWorkflow(w, store, id, name)
+		if !ok {
+			return
+		}
+
+		state, err := orchestrator.ReleaseTask(store, wf, id, name, actor)
+		if isLeaseConflict(err) {
+			writeError(w, http.StatusConflict, fmt.Sprintf("Failed to release task: %v", err))
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to release task: %v", err))
+			return
+		}
+		writeData(w, http.StatusOK, state)
 	}
+}
 
-	writeData(w, http.StatusOK, tasks)
+// isLeaseConflict reports whether a claim or release was refused because of
+// the task's state or who holds it, rather than failing to load or save it
+func isLeaseConflict(err error) bool {
+	return errors.Is(err, orchestrator.ErrTaskNotWaiting) ||
+		errors.Is(err, orchestrator.ErrActorNotAllowed) ||
+		errors.Is(err, orchestrator.ErrTaskNotClaimed) ||
+		errors.Is(err, orchestrator.ErrTaskNotClaimable)
 }
 
-// handleGetRunsTasks returns waiting tasks grouped by run name
-func handleGetRunsTasks(w http.ResponseWriter, r *http.Request) {
-	runs, err := workflow.ListRuns()
-	if err != nil {
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to list runs: %v", err))
-		return
-	}
+// handleGetRunComments returns a run's comments, oldest first. The step
+// query parameter keeps only the comments on that task.
+func handleGetRunComments(store workflow.RunStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
 
-	result, err := orchestrator.ListWaitingTasksByRun(runs)
-	if err != nil {
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to list waiting tasks: %v", err))
-		return
+		if _, err := store.LoadRun(id); err != nil {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("Run not found: %v", err))
+			return
+		}
+
+		comments, err := orchestrator.ListComments(store, id, r.URL.Query().Get("step"))
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to list comments: %v", err))
+			return
+		}
+		writeData(w, http.StatusOK, comments)
 	}
+}
 
-	writeData(w, http.StatusOK, result)
+// CommentRequest is the body of a new comment. Step puts it on a task, and
+// ReplyTo answers an earlier comment by ID. Artifact references an artifact,
+// at Version or its latest version, and Lines a line range of it such as
+// "12-20".
+type CommentRequest struct {
+	Body     string `json:"body"`
+	Step     string `json:"step"`
+	ReplyTo  int    `json:"reply_to"`
+	Artifact string `json:"artifact"`
+	Version  int    `json:"version"`
+	Lines    string `json:"lines"`
 }
 
-// handlePostRunTick executes a single tick for the specified run
-func handlePostRunTick(w http.ResponseWriter, r *http.Request) {
-	id := r.PathValue("id")
+// handlePostRunComment adds a comment to a run, or to one of its tasks, by
+// the actor named by the X-Composer-Actor header
+func handlePostRunComment(store workflow.RunStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
 
-	// Load current run state to identify its workflow
-	state, err := workflow.LoadState(id)
+		var req CommentRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
+			return
+		}
+
+		actor := r.Header.Get("X-Composer-Actor")
+		if actor == "" {
+			writeError(w, http.StatusBadRequest, "X-Composer-Actor header is required")
+			return
+		}
+
+		comment := workflow.Comment{Author: actor, Body: req.Body, Step: req.Step, ReplyTo: req.ReplyTo}
+		if req.Artifact != "" {
+			comment.Artifact = &workflow.ArtifactRef{Name: req.Artifact, Version: req.Version}
+		}
+		if req.Lines != "" {
+			lines, err := workflow.ParseLineRange(req.Lines)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			comment.Lines = &lines
+		}
+
+		state, err := store.LoadRun(id)
+		if err != nil {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("Run not found: %v", err))
+			return
+		}
+		wf, _, err := workflow.LoadWorkflow(state.WorkflowName)
+		if err != nil {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("Workflow not found: %v", err))
+			return
+		}
+
+		comment, err = orchestrator.AddComment(store, wf, id, comment)
+		if errors.Is(err, orchestrator.ErrInvalidComment) {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Failed to add comment: %v", err))
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to add comment: %v", err))
+			return
+		}
+		writeData(w, http.StatusOK, comment)
+	}
+}
+
+// loadTaskWorkflow loads the workflow of a run for a request addressing one
+// of its steps, writing a 404 when the run, workflow, or step is missing
+func loadTaskWorkflow(w http.ResponseWriter, store workflow.RunStore, id, name string) (*workflow.Workflow, bool) {
+	state, err := store.LoadRun(id)
 	if err != nil {
 		writeError(w, http.StatusNotFound, fmt.Sprintf("Run not found: %v", err))
-		return
+		return nil, false
 	}
 
-	// Load workflow associated with this run
 	wf, _, err := workflow.LoadWorkflow(state.WorkflowName)
 	if err != nil {
 		writeError(w, http.StatusNotFound, fmt.Sprintf("Workflow not found: %v", err))
-		return
-	}
-
-	// Execute tick
-	complete, err := orchestrator.Tick(wf, id)
-	if err != nil {
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to tick run: %v", err))
-		return
+		return nil, false
 	}
 
-	// Reload state to include updates from tick
-	updatedState, err := workflow.LoadState(id)
-	if err != nil {
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to load updated run state: %v", err))
-		return
+	if _, ok := state.StepStates[name]; !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Step not found: %s", name))
+		return nil, false
 	}
-
-	writeData(w, http.StatusOK, struct {
-		Complete bool               `json:"complete"`
-		State    *workflow.RunState `json:"state"`
-	}{
-		Complete: complete,
-		State:    updatedState,
-	})
+	return wf, true
 }
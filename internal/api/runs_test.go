@@ -1,10 +1,17 @@
 package api_test
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"composer/internal/api"
 	"composer/internal/orchestrator"
 	"composer/internal/workflow"
 )
@@ -82,6 +89,85 @@ func TestGetRuns_Multiple(t *testing.T) {
 }
 
 // TestGetRun_Success tests retrieving a specific run
+// TestGetRuns_InjectedStore tests that runs are served from the store passed to the router
+func TestGetRuns_InjectedStore(t *testing.T) {
+	store := workflow.NewMemoryStore()
+	wf := &workflow.Workflow{ID: "test-workflow", Steps: []workflow.Step{{Name: "step1", Output: "out1"}}}
+	if err := store.SaveRun(workflow.NewRunState(wf, "memory-run", "Memory Run")); err != nil {
+		t.Fatalf("Failed to save run: %v", err)
+	}
+
+	router := api.BuildRouter(store)
+
+	// get runs
+	var response struct {
+		Error *apiError           `json:"error"`
+		Data  []workflow.RunState `json:"data"`
+	}
+	result := get(router, "/api/runs", &response)
+
+	// verify result
+	err := expectStatus(http.StatusOK, result)
+	if err != nil {
+		t.Fatalf("%v\n%v", err, response)
+	}
+
+	// validate response
+	if len(response.Data) != 1 || response.Data[0].ID != "memory-run" {
+		t.Errorf("Expected only memory-run, got %v", response.Data)
+	}
+}
+
+// TestGetRuns_Filtered tests filtering runs by workflow, status, and creation time
+func TestGetRuns_Filtered(t *testing.T) {
+	store := workflow.NewMemoryStore()
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, id := range []string{"alpha-1", "alpha-2", "beta-1"} {
+		state := workflow.NewRunState(&workflow.Workflow{ID: strings.Split(id, "-")[0]}, id, id)
+		state.CreatedAt = base.Add(time.Duration(i) * time.Hour)
+		if id == "alpha-2" {
+			state.Status = workflow.RunPaused
+		}
+		store.SaveRun(state)
+	}
+
+	router := api.BuildRouter(store)
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{"?workflow=alpha", []string{"alpha-1", "alpha-2"}},
+		{"?status=paused", []string{"alpha-2"}},
+		{"?since=2025-01-01T01:00:00Z", []string{"alpha-2", "beta-1"}},
+		{"?workflow=alpha&until=2025-01-01T01:00:00Z", []string{"alpha-1"}},
+	}
+	for _, tt := range tests {
+		var response struct {
+			Error *apiError           `json:"error"`
+			Data  []workflow.RunState `json:"data"`
+		}
+		result := get(router, "/api/runs"+tt.query, &response)
+		if err := expectStatus(http.StatusOK, result); err != nil {
+			t.Fatalf("%s: %v\n%v", tt.query, err, response)
+		}
+
+		ids := []string{}
+		for _, run := range response.Data {
+			ids = append(ids, run.ID)
+		}
+		if strings.Join(ids, ",") != strings.Join(tt.expected, ",") {
+			t.Errorf("%s: expected %v, got %v", tt.query, tt.expected, ids)
+		}
+	}
+
+	// Invalid times are rejected
+	result := get(router, "/api/runs?since=yesterday", nil)
+	if err := expectStatus(http.StatusBadRequest, result); err != nil {
+		t.Error(err)
+	}
+}
+
 func TestGetRun_Success(t *testing.T) {
 	cleanup := setupTestEnv(t)
 	defer cleanup()
@@ -184,7 +270,7 @@ func TestPostRun_Create(t *testing.T) {
 	}
 
 	// Verify run was actually created
-	state, err2 := workflow.LoadState("new-run")
+	state, err2 := testStore().LoadRun("new-run")
 	if err2 != nil {
 		t.Errorf("Failed to load created run: %v", err2)
 	}
@@ -268,6 +354,34 @@ func TestPostRun_WorkflowNotFound(t *testing.T) {
 	}
 }
 
+// TestPostRun_InvalidID tests creating runs whose IDs cannot name a run's
+// files
+func TestPostRun_InvalidID(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	createWorkflowFixture(t, "test-workflow", "Test Workflow")
+	router := setupRouter()
+
+	body := `{"workflow_id": "test-workflow", "name": "Bad Run"}`
+	for _, id := range []string{"a/b", `a\b`} {
+		var response apiResponse
+		result := post(router, "/api/run/"+url.PathEscape(id), body, &response)
+		if err := expectStatus(http.StatusBadRequest, result); err != nil {
+			t.Errorf("run ID %q: %v\n%v", id, err, response)
+		}
+	}
+
+	// The router redirects ".." away before it names a run
+	res := serve(router, "POST", "/api/run/%2E%2E", body, nil)
+	if res.Code == http.StatusOK {
+		t.Errorf("Expected run ID '..' to be refused, got %d", res.Code)
+	}
+	if _, err := os.Stat(filepath.Join(workflow.GetDataDir(), "state.json")); !os.IsNotExist(err) {
+		t.Errorf("Expected no run state outside the runs directory")
+	}
+}
+
 // TestPostRun_InvalidJSON tests creating a run with malformed JSON
 func TestPostRun_InvalidJSON(t *testing.T) {
 	cleanup := setupTestEnv(t)
@@ -430,12 +544,12 @@ func TestGetRunsTasks_GroupedByRun(t *testing.T) {
 	createRunFixture(t, "run-empty", "test-workflow")
 
 	// Mark the first run's step as ready
-	state, err := workflow.LoadState("run-ready")
+	state, err := testStore().LoadRun("run-ready")
 	if err != nil {
 		t.Fatalf("Failed to load run state: %v", err)
 	}
 	state.StepStates["step1"] = workflow.StepState{Status: workflow.StatusReady}
-	if err := state.Save(); err != nil {
+	if err := testStore().SaveRun(state); err != nil {
 		t.Fatalf("Failed to save updated run state: %v", err)
 	}
 
@@ -499,3 +613,664 @@ func TestGetRunTasks_WorkflowNotFound(t *testing.T) {
 
 	// Actually, let's keep this simpler and just test the happy path above
 }
+
+// TestGetRunGraph_ColoredByStatus tests rendering a run graph with step colors
+func TestGetRunGraph_ColoredByStatus(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	createWorkflowFixture(t, "test-workflow", "Test Workflow")
+	createRunFixture(t, "test-run", "test-workflow")
+
+	router := setupRouter()
+
+	var response struct {
+		Error *apiError         `json:"error"`
+		Data  api.GraphResponse `json:"data"`
+	}
+	result := get(router, "/api/run/test-run/graph", &response)
+
+	if err := expectStatus(http.StatusOK, result); err != nil {
+		t.Fatalf("%v\n%v", err, response)
+	}
+
+	if response.Data.Format != "dot" {
+		t.Errorf("Expected default format 'dot', got '%s'", response.Data.Format)
+	}
+	if !strings.Contains(response.Data.Graph, "fillcolor") {
+		t.Errorf("Expected run graph to be colored, got:\n%s", response.Data.Graph)
+	}
+}
+
+// TestGetRunGraph_RunNotFound tests rendering a graph for a missing run
+func TestGetRunGraph_RunNotFound(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	router := setupRouter()
+
+	var response apiResponse
+	result := get(router, "/api/run/missing/graph", &response)
+
+	if err := expectStatus(http.StatusNotFound, result); err != nil {
+		t.Fatalf("%v\n%v", err, response)
+	}
+}
+
+// TestPostRunPauseResume tests pausing and resuming a run
+func TestPostRunPauseResume(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	createWorkflowFixture(t, "test-workflow", "Test Workflow")
+	createRunFixture(t, "test-run", "test-workflow")
+
+	router := setupRouter()
+
+	var response struct {
+		Error *apiError         `json:"error"`
+		Data  workflow.RunState `json:"data"`
+	}
+	result := post(router, "/api/run/test-run/pause", "", &response)
+	if err := expectStatus(http.StatusOK, result); err != nil {
+		t.Fatalf("%v\n%v", err, response)
+	}
+	if response.Data.Status != workflow.RunPaused {
+		t.Errorf("Expected status 'paused', got '%s'", response.Data.Status)
+	}
+
+	// Pausing again conflicts with the current status
+	var conflict apiResponse
+	result = post(router, "/api/run/test-run/pause", "", &conflict)
+	if err := expectStatus(http.StatusConflict, result); err != nil {
+		t.Fatalf("%v\n%v", err, conflict)
+	}
+
+	result = post(router, "/api/run/test-run/resume", "", &response)
+	if err := expectStatus(http.StatusOK, result); err != nil {
+		t.Fatalf("%v\n%v", err, response)
+	}
+	if response.Data.Status != workflow.RunActive {
+		t.Errorf("Expected status 'active', got '%s'", response.Data.Status)
+	}
+}
+
+// TestPostRunCancel tests cancelling a run
+func TestPostRunCancel(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	createWorkflowFixture(t, "test-workflow", "Test Workflow")
+	createRunFixture(t, "test-run", "test-workflow")
+
+	router := setupRouter()
+
+	var response struct {
+		Error *apiError         `json:"error"`
+		Data  workflow.RunState `json:"data"`
+	}
+	result := post(router, "/api/run/test-run/cancel", "", &response)
+	if err := expectStatus(http.StatusOK, result); err != nil {
+		t.Fatalf("%v\n%v", err, response)
+	}
+	if response.Data.Status != workflow.RunCancelled {
+		t.Errorf("Expected status 'cancelled', got '%s'", response.Data.Status)
+	}
+	if response.Data.StepStates["step1"].Status != workflow.StatusCancelled {
+		t.Errorf("Expected step1 to be cancelled, got '%s'", response.Data.StepStates["step1"].Status)
+	}
+}
+
+// TestPostRunCancel_RunNotFound tests cancelling a missing run
+func TestPostRunCancel_RunNotFound(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	router := setupRouter()
+
+	var response apiResponse
+	result := post(router, "/api/run/missing/cancel", "", &response)
+	if err := expectStatus(http.StatusNotFound, result); err != nil {
+		t.Fatalf("%v\n%v", err, response)
+	}
+}
+
+// TestPostStepRerun tests resetting a completed step back to pending
+func TestPostStepRerun(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	createWorkflowFixture(t, "test-workflow", "Test Workflow")
+	createRunFixture(t, "test-run", "test-workflow")
+
+	router := setupRouter()
+	post(router, "/api/run/test-run/tick", "", nil)
+
+	var response struct {
+		Error *apiError `json:"error"`
+		Data  struct {
+			Reset []string          `json:"reset"`
+			State workflow.RunState `json:"state"`
+		} `json:"data"`
+	}
+	result := post(router, "/api/run/test-run/step/step1/rerun", "", &response)
+
+	if err := expectStatus(http.StatusOK, result); err != nil {
+		t.Fatalf("%v\n%v", err, response)
+	}
+	if len(response.Data.Reset) != 1 || response.Data.Reset[0] != "step1" {
+		t.Errorf("Expected [step1] to be reset, got %v", response.Data.Reset)
+	}
+	if response.Data.State.StepStates["step1"].Status != workflow.StatusPending {
+		t.Errorf("Expected step1 to be pending, got %s", response.Data.State.StepStates["step1"].Status)
+	}
+}
+
+// TestPostStepRerun_Errors tests rerunning a step of a cancelled run, and
+// failing to archive its output
+func TestPostStepRerun_Errors(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	createWorkflowFixture(t, "test-workflow", "Test Workflow")
+	createRunFixture(t, "test-run", "test-workflow")
+	createRunFixture(t, "cancelled-run", "test-workflow")
+
+	router := setupRouter()
+	post(router, "/api/run/test-run/tick", "", nil)
+	post(router, "/api/run/cancelled-run/cancel", "", nil)
+
+	var response apiResponse
+	result := post(router, "/api/run/cancelled-run/step/step1/rerun", "", &response)
+	if err := expectStatus(http.StatusConflict, result); err != nil {
+		t.Fatalf("%v\n%v", err, response)
+	}
+
+	// Failing to archive the output is the server's fault
+	manifest := filepath.Join(workflow.GetDataDir(), "runs", "test-run", "artifacts.json")
+	if err := os.WriteFile(manifest, []byte("not json"), 0644); err != nil {
+		t.Fatalf("Failed to corrupt the artifact manifest: %v", err)
+	}
+	result = post(router, "/api/run/test-run/step/step1/rerun", "", &response)
+	if err := expectStatus(http.StatusInternalServerError, result); err != nil {
+		t.Fatalf("%v\n%v", err, response)
+	}
+}
+
+// TestPostStepRerun_StepNotFound tests rerunning a step that does not exist
+func TestPostStepRerun_StepNotFound(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	createWorkflowFixture(t, "test-workflow", "Test Workflow")
+	createRunFixture(t, "test-run", "test-workflow")
+
+	router := setupRouter()
+
+	var response apiResponse
+	result := post(router, "/api/run/test-run/step/missing/rerun", "", &response)
+
+	if err := expectStatus(http.StatusNotFound, result); err != nil {
+		t.Fatalf("%v\n%v", err, response)
+	}
+}
+
+// markStepReady sets a run's step to ready, as if it were waiting for a human
+func markStepReady(t *testing.T, runID, stepName string) {
+	t.Helper()
+
+	state, err := testStore().LoadRun(runID)
+	if err != nil {
+		t.Fatalf("Failed to load run state: %v", err)
+	}
+	state.StepStates[stepName] = workflow.StepState{Status: workflow.StatusReady}
+	if err := testStore().SaveRun(state); err != nil {
+		t.Fatalf("Failed to save updated run state: %v", err)
+	}
+}
+
+// TestPostTaskComplete tests completing a human task with supplied content
+func TestPostTaskComplete(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	createWorkflowFixture(t, "test-workflow", "Test Workflow")
+	createRunFixture(t, "test-run", "test-workflow")
+	markStepReady(t, "test-run", "step1")
+
+	router := setupRouter()
+
+	body := `{"content": "# Reviewed", "content_type": "text/markdown", "tick": true}`
+	res := serve(router, "POST", "/api/run/test-run/task/step1/complete", body,
+		map[string]string{"X-Composer-Actor": "alice"})
+
+	var response struct {
+		Error *apiError `json:"error"`
+		Data  struct {
+			Complete bool              `json:"complete"`
+			State    workflow.RunState `json:"state"`
+		} `json:"data"`
+	}
+	if res.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", res.Code, res.Body.String())
+	}
+	if err := json.Unmarshal(res.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if !response.Data.Complete {
+		t.Error("Expected the follow-up tick to complete the run")
+	}
+	if response.Data.State.StepStates["step1"].Status != workflow.StatusSucceeded {
+		t.Errorf("Expected step1 to succeed, got %s", response.Data.State.StepStates["step1"].Status)
+	}
+
+	info, err := testStore().Artifacts().Stat("test-run", "result1")
+	if err != nil {
+		t.Fatalf("Failed to stat artifact: %v", err)
+	}
+	if info.ContentType != "text/markdown" || info.Actor != "alice" || info.Size != 10 {
+		t.Errorf("Expected 10 bytes of markdown from alice, got %+v", info)
+	}
+}
+
+// TestPostTaskComplete_EmptyBody tests completing a task with the handler's
+// default output
+func TestPostTaskComplete_EmptyBody(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	createWorkflowFixture(t, "test-workflow", "Test Workflow")
+	createRunFixture(t, "test-run", "test-workflow")
+	markStepReady(t, "test-run", "step1")
+
+	router := setupRouter()
+
+	var response apiResponse
+	result := post(router, "/api/run/test-run/task/step1/complete", "", &response)
+
+	if err := expectStatus(http.StatusOK, result); err != nil {
+		t.Fatalf("%v\n%v", err, response)
+	}
+
+	info, err := testStore().Artifacts().Stat("test-run", "result1")
+	if err != nil {
+		t.Fatalf("Failed to stat artifact: %v", err)
+	}
+	if info.Size != 14 {
+		t.Errorf("Expected the step's 14 byte content, got %d bytes", info.Size)
+	}
+}
+
+// TestPostTaskComplete_NotReady tests completing a step that is not waiting
+func TestPostTaskComplete_NotReady(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	createWorkflowFixture(t, "test-workflow", "Test Workflow")
+	createRunFixture(t, "test-run", "test-workflow")
+
+	router := setupRouter()
+
+	var response apiResponse
+	result := post(router, "/api/run/test-run/task/step1/complete", `{"content": "x"}`, &response)
+
+	if err := expectStatus(http.StatusConflict, result); err != nil {
+		t.Fatalf("%v\n%v", err, response)
+	}
+}
+
+// TestPostTaskComplete_StorageError tests that failing to store the output
+// is reported as a server error
+func TestPostTaskComplete_StorageError(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	createWorkflowFixture(t, "test-workflow", "Test Workflow")
+	createRunFixture(t, "test-run", "test-workflow")
+	markStepReady(t, "test-run", "step1")
+
+	// Blobs cannot be written where a file is in the way
+	blobs := filepath.Join(workflow.GetDataDir(), "blobs")
+	os.RemoveAll(blobs)
+	if err := os.WriteFile(blobs, nil, 0644); err != nil {
+		t.Fatalf("Failed to block the blobs directory: %v", err)
+	}
+
+	router := setupRouter()
+
+	var response apiResponse
+	result := post(router, "/api/run/test-run/task/step1/complete", `{"content": "x"}`, &response)
+	if err := expectStatus(http.StatusInternalServerError, result); err != nil {
+		t.Fatalf("%v\n%v", err, response)
+	}
+}
+
+// TestPostTaskComplete_StepNotFound tests completing a step that does not exist
+func TestPostTaskComplete_StepNotFound(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	createWorkflowFixture(t, "test-workflow", "Test Workflow")
+	createRunFixture(t, "test-run", "test-workflow")
+
+	router := setupRouter()
+
+	var response apiResponse
+	result := post(router, "/api/run/test-run/task/missing/complete", "", &response)
+
+	if err := expectStatus(http.StatusNotFound, result); err != nil {
+		t.Fatalf("%v\n%v", err, response)
+	}
+}
+
+// TestPostTaskComplete_FormValues tests validating submitted form values
+func TestPostTaskComplete_FormValues(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	content := `display_name = "Form Workflow"
+
+[[steps]]
+name = "review"
+handler = "human"
+output = "review"
+
+[[steps.form]]
+name = "verdict"
+type = "select"
+options = ["approve", "reject"]
+required = true
+
+[[steps.form]]
+name = "score"
+type = "number"
+max = 5
+`
+	if err := os.WriteFile(".composer/workflows/form-workflow.toml", []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create workflow fixture: %v", err)
+	}
+	createRunFixture(t, "test-run", "form-workflow")
+	markStepReady(t, "test-run", "review")
+
+	router := setupRouter()
+
+	var response apiResponse
+	result := post(router, "/api/run/test-run/task/review/complete", `{"values": {"verdict": "maybe", "score": 7}}`, &response)
+	if err := expectStatus(http.StatusBadRequest, result); err != nil {
+		t.Fatalf("%v\n%v", err, response)
+	}
+	if msg := response.Error.Message; !strings.Contains(msg, "verdict") || !strings.Contains(msg, "score") {
+		t.Errorf("Expected errors for verdict and score, got %q", msg)
+	}
+
+	result = post(router, "/api/run/test-run/task/review/complete", `{"values": {"verdict": "approve", "score": 4}}`, &response)
+	if err := expectStatus(http.StatusOK, result); err != nil {
+		t.Fatalf("%v\n%v", err, response)
+	}
+
+	info, err := testStore().Artifacts().Stat("test-run", "review")
+	if err != nil {
+		t.Fatalf("Failed to stat artifact: %v", err)
+	}
+	if info.ContentType != "application/json" {
+		t.Errorf("Expected a JSON artifact, got %s", info.ContentType)
+	}
+}
+
+// TestPostTaskComplete_Outcome tests recording a review decision
+func TestPostTaskComplete_Outcome(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	content := `display_name = "Review Workflow"
+
+[[steps]]
+name = "review"
+handler = "human"
+content = "draft"
+output = "review"
+outcomes = ["approve", "reject"]
+`
+	if err := os.WriteFile(".composer/workflows/review-workflow.toml", []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create workflow fixture: %v", err)
+	}
+	createRunFixture(t, "test-run", "review-workflow")
+	markStepReady(t, "test-run", "review")
+
+	router := setupRouter()
+
+	// A missing outcome conflicts with the step's declared outcomes
+	var response apiResponse
+	result := post(router, "/api/run/test-run/task/review/complete", `{}`, &response)
+	if err := expectStatus(http.StatusConflict, result); err != nil {
+		t.Fatalf("%v\n%v", err, response)
+	}
+
+	var completed struct {
+		Error *apiError `json:"error"`
+		Data  struct {
+			State workflow.RunState `json:"state"`
+		} `json:"data"`
+	}
+	result = post(router, "/api/run/test-run/task/review/complete", `{"outcome": "approve", "comment": "LGTM"}`, &completed)
+	if err := expectStatus(http.StatusOK, result); err != nil {
+		t.Fatalf("%v\n%v", err, completed)
+	}
+	review := completed.Data.State.StepStates["review"]
+	if review.Outcome != "approve" || review.Comment != "LGTM" {
+		t.Errorf("Expected the decision to be recorded, got %+v", review)
+	}
+}
+
+// TestPostRun_Params tests creating a run with parameters
+func TestPostRun_Params(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	content := `display_name = "Review Workflow"
+
+[params]
+reviewer = "alice"
+
+[[steps]]
+name = "review"
+handler = "human"
+content = "draft"
+output = "review"
+assignee = "{{.reviewer}}"
+`
+	if err := os.WriteFile(".composer/workflows/review-workflow.toml", []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create workflow fixture: %v", err)
+	}
+
+	router := setupRouter()
+
+	var response struct {
+		Error *apiError         `json:"error"`
+		Data  workflow.RunState `json:"data"`
+	}
+	body := `{"workflow_id": "review-workflow", "name": "Review", "params": {"reviewer": "bob"}}`
+	result := post(router, "/api/run/review-run", body, &response)
+	if err := expectStatus(http.StatusOK, result); err != nil {
+		t.Fatalf("%v\n%v", err, response)
+	}
+	if response.Data.Params["reviewer"] != "bob" {
+		t.Errorf("Expected reviewer bob, got %v", response.Data.Params)
+	}
+
+	body = `{"workflow_id": "review-workflow", "name": "Review", "params": {"owner": "bob"}}`
+	result = post(router, "/api/run/other-run", body, &response)
+	if err := expectStatus(http.StatusBadRequest, result); err != nil {
+		t.Fatalf("%v\n%v", err, response)
+	}
+}
+
+// TestPostTaskClaim tests claiming and releasing a human task
+func TestPostTaskClaim(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	createWorkflowFixture(t, "test-workflow", "Test Workflow")
+	createRunFixture(t, "test-run", "test-workflow")
+	markStepReady(t, "test-run", "step1")
+
+	router := setupRouter()
+	as := func(actor string) map[string]string {
+		return map[string]string{"X-Composer-Actor": actor}
+	}
+
+	res := serve(router, "POST", "/api/run/test-run/task/step1/claim", `{"lease": "10m"}`, nil)
+	if res.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400 without an actor, got %d", res.Code)
+	}
+	res = serve(router, "POST", "/api/run/test-run/task/step1/claim", `{"lease": "soon"}`, as("alice"))
+	if res.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for an invalid lease, got %d", res.Code)
+	}
+
+	res = serve(router, "POST", "/api/run/test-run/task/step1/claim", `{"lease": "10m"}`, as("alice"))
+	if res.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", res.Code, res.Body.String())
+	}
+	var claimed struct {
+		Data workflow.RunState `json:"data"`
+	}
+	if err := json.Unmarshal(res.Body.Bytes(), &claimed); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if claimed.Data.StepStates["step1"].ClaimedBy != "alice" {
+		t.Errorf("Expected alice to hold the claim, got %+v", claimed.Data.StepStates["step1"])
+	}
+
+	// A second reviewer can neither claim, release, nor complete the task
+	for _, action := range []string{"claim", "release", "complete"} {
+		res = serve(router, "POST", "/api/run/test-run/task/step1/"+action, "", as("bob"))
+		if res.Code != http.StatusConflict {
+			t.Errorf("Expected 409 for bob's %s, got %d", action, res.Code)
+		}
+	}
+
+	// The claimant's tasks are filtered for them
+	var tasks struct {
+		Data map[string][]orchestrator.WaitingTask `json:"data"`
+	}
+	result := get(router, "/api/runs/tasks?actor=alice", &tasks)
+	if err := expectStatus(http.StatusOK, result); err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks.Data["test-run"]) != 1 || tasks.Data["test-run"][0].ClaimedBy != "alice" {
+		t.Errorf("Expected alice's claimed task, got %+v", tasks.Data)
+	}
+	tasks.Data = nil
+	get(router, "/api/runs/tasks?actor=bob", &tasks)
+	if len(tasks.Data) != 0 {
+		t.Errorf("Expected no tasks for bob, got %+v", tasks.Data)
+	}
+
+	res = serve(router, "POST", "/api/run/test-run/task/step1/release", "", as("alice"))
+	if res.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", res.Code, res.Body.String())
+	}
+	res = serve(router, "POST", "/api/run/test-run/task/step1/complete", "", as("bob"))
+	if res.Code != http.StatusOK {
+		t.Errorf("Expected the released task to be completed, got %d: %s", res.Code, res.Body.String())
+	}
+}
+
+// failingSaveStore is a store whose run states cannot be saved
+type failingSaveStore struct {
+	workflow.RunStore
+}
+
+func (failingSaveStore) SaveRun(*workflow.RunState) error {
+	return errors.New("disk full")
+}
+
+// TestPostTaskClaim_StorageError tests that failing to save a claim or
+// release is reported as a server error
+func TestPostTaskClaim_StorageError(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	createWorkflowFixture(t, "test-workflow", "Test Workflow")
+	createRunFixture(t, "test-run", "test-workflow")
+	markStepReady(t, "test-run", "step1")
+
+	router := api.BuildRouter(failingSaveStore{testStore()})
+	as := map[string]string{"X-Composer-Actor": "alice"}
+
+	res := serve(router, "POST", "/api/run/test-run/task/step1/claim", "", as)
+	if res.Code != http.StatusInternalServerError {
+		t.Errorf("Expected 500 for a failed claim, got %d: %s", res.Code, res.Body.String())
+	}
+
+	// Releasing an unclaimed task conflicts before anything is saved
+	res = serve(router, "POST", "/api/run/test-run/task/step1/release", "", as)
+	if res.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for releasing an unclaimed task, got %d", res.Code)
+	}
+
+	res = serve(setupRouter(), "POST", "/api/run/test-run/task/step1/claim", "", as)
+	if res.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", res.Code, res.Body.String())
+	}
+	res = serve(router, "POST", "/api/run/test-run/task/step1/release", "", as)
+	if res.Code != http.StatusInternalServerError {
+		t.Errorf("Expected 500 for a failed release, got %d: %s", res.Code, res.Body.String())
+	}
+}
+
+func TestRunComments(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	createWorkflowFixture(t, "test-workflow", "Test Workflow")
+	createRunFixture(t, "test-run", "test-workflow")
+
+	router := setupRouter()
+	as := map[string]string{"X-Composer-Actor": "alice"}
+
+	res := serve(router, "POST", "/api/run/test-run/comments", `{"body": "Looks off"}`, nil)
+	if res.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400 without an actor, got %d", res.Code)
+	}
+	res = serve(router, "POST", "/api/run/test-run/comments", `{"body": "Looks off", "step": "missing"}`, as)
+	if res.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for an unknown step, got %d", res.Code)
+	}
+	res = serve(router, "POST", "/api/run/test-run/comments", `{"body": "Looks off", "artifact": "result1", "lines": "3-1"}`, as)
+	if res.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for an invalid line range, got %d", res.Code)
+	}
+	res = serve(router, "POST", "/api/run/missing/comments", `{"body": "Hello"}`, as)
+	if res.Code != http.StatusNotFound {
+		t.Fatalf("Expected 404 for a missing run, got %d", res.Code)
+	}
+
+	for _, body := range []string{`{"body": "Kicking this off"}`, `{"body": "Check the input", "step": "step1"}`, `{"body": "Done", "reply_to": 2}`} {
+		res = serve(router, "POST", "/api/run/test-run/comments", body, as)
+		if res.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", res.Code, res.Body.String())
+		}
+	}
+
+	var comments struct {
+		Data []workflow.Comment `json:"data"`
+	}
+	result := get(router, "/api/run/test-run/comments?step=step1", &comments)
+	if err := expectStatus(http.StatusOK, result); err != nil {
+		t.Fatal(err)
+	}
+	if len(comments.Data) != 2 || comments.Data[1].ReplyTo != 2 || comments.Data[1].Author != "alice" {
+		t.Errorf("Expected the task comment and its reply, got %+v", comments.Data)
+	}
+
+	// Failing to store the comment is the server's fault, not the request's
+	commentsFile := filepath.Join(workflow.GetDataDir(), "runs", "test-run", "comments.jsonl")
+	os.Remove(commentsFile)
+	os.Mkdir(commentsFile, 0755)
+	res = serve(router, "POST", "/api/run/test-run/comments", `{"body": "Hello"}`, as)
+	if res.Code != http.StatusInternalServerError {
+		t.Errorf("Expected 500 when the comment cannot be stored, got %d: %s", res.Code, res.Body.String())
+	}
+}
@@ -0,0 +1,27 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"composer/internal/orchestrator"
+	"composer/internal/workflow"
+)
+
+// buildSchedulesRouter registers schedule-related routes
+func buildSchedulesRouter(mux *http.ServeMux, store workflow.RunStore) {
+	mux.HandleFunc("GET /api/schedules", handleGetSchedules(store))
+}
+
+// handleGetSchedules lists every schedule with its last and next fire time
+func handleGetSchedules(store workflow.RunStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		statuses, err := orchestrator.ListScheduleStatuses(store, time.Now())
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to list schedules: %v", err))
+			return
+		}
+		writeData(w, http.StatusOK, statuses)
+	}
+}
@@ -0,0 +1,51 @@
+package api_test
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"composer/internal/orchestrator"
+)
+
+func TestGetSchedules(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	// setup
+	createWorkflowFixture(t, "workflow1", "First Workflow")
+	os.MkdirAll(filepath.Join(".composer", "schedules"), 0755)
+	os.WriteFile(filepath.Join(".composer", "schedules", "nightly.toml"), []byte("workflow = \"workflow1\"\ncron = \"@daily\"\n"), 0644)
+	os.WriteFile(filepath.Join(".composer", "schedules", "broken.toml"), []byte("workflow = \"workflow1\"\ncron = \"daily\"\n"), 0644)
+
+	router := setupRouter()
+
+	// get schedules
+	var response struct {
+		Error *apiError                     `json:"error"`
+		Data  []orchestrator.ScheduleStatus `json:"data"`
+	}
+	result := get(router, "/api/schedules", &response)
+
+	// verify result
+	if err := expectStatus(http.StatusOK, result); err != nil {
+		t.Fatalf("%v\n%v", err, response)
+	}
+	if len(response.Data) != 2 {
+		t.Fatalf("Expected 2 schedules, got %d", len(response.Data))
+	}
+
+	if broken := response.Data[0]; broken.Name != "broken" || broken.Error == "" {
+		t.Errorf("Expected the broken schedule to carry its error, got %+v", broken)
+	}
+
+	schedule := response.Data[1]
+	if schedule.Name != "nightly" || schedule.Workflow != "workflow1" || schedule.LastFired != nil {
+		t.Errorf("Unexpected schedule: %+v", schedule)
+	}
+	if !schedule.Next.After(time.Now()) || schedule.Next.After(time.Now().Add(24*time.Hour)) {
+		t.Errorf("Expected the next fire within a day, got %v", schedule.Next)
+	}
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"composer/internal/orchestrator"
+	"composer/internal/workflow"
+)
+
+// maxTriggerPayload bounds the size of a webhook payload
+const maxTriggerPayload = 16 << 20
+
+// buildTriggersRouter registers trigger-related routes
+func buildTriggersRouter(mux *http.ServeMux, store workflow.RunStore) {
+	mux.HandleFunc("POST /api/trigger/{name}", handlePostTrigger(store))
+}
+
+// handlePostTrigger starts a run of the trigger's workflow from a webhook
+// payload, checking its signature when the trigger has a secret
+func handlePostTrigger(store workflow.RunStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		trigger, err := workflow.LoadTrigger(r.PathValue("name"))
+		if err != nil {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("Trigger not found: %v", err))
+			return
+		}
+		if trigger.Kind() != workflow.TriggerWebhook {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("Trigger '%s' is not a webhook", trigger.Name))
+			return
+		}
+
+		payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxTriggerPayload))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Failed to read payload: %v", err))
+			return
+		}
+
+		err = trigger.VerifySignature(payload, r.Header.Get(trigger.Header()))
+		if errors.Is(err, workflow.ErrInvalidSignature) {
+			writeError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		params, contents, err := trigger.MapPayload(payload)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		wf, _, err := workflow.LoadWorkflow(trigger.Workflow)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("Workflow not found: %v", err))
+			return
+		}
+		if _, err := wf.ResolveParams(params); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		now := time.Now().UTC()
+		runID, err := trigger.ExpandRunID(params, now)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if _, err := store.LoadRun(runID); err == nil {
+			writeError(w, http.StatusConflict, fmt.Sprintf("Run '%s' already exists", runID))
+			return
+		}
+
+		// The whole payload keeps the request's content type; extracted
+		// values are detected from their content
+		seeds := make([]orchestrator.Seed, 0, len(contents))
+		for name, content := range contents {
+			seed := orchestrator.Seed{Name: name, Content: bytes.NewReader(content)}
+			if trigger.Artifacts[name] == "." {
+				seed.ContentType = r.Header.Get("Content-Type")
+			}
+			seeds = append(seeds, seed)
+		}
+		sort.Slice(seeds, func(i, j int) bool { return seeds[i].Name < seeds[j].Name })
+
+		source := workflow.Trigger{Kind: workflow.TriggerWebhook, Name: trigger.Name, At: now}
+		state, err := orchestrator.CreateTriggeredRun(store, wf, runID, params, source, seeds)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to create run: %v", err))
+			return
+		}
+
+		writeData(w, http.StatusOK, state)
+	}
+}
@@ -0,0 +1,128 @@
+package api_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"composer/internal/workflow"
+)
+
+// createTriggerFixture writes a ticket workflow and a signed webhook trigger
+// that starts it
+func createTriggerFixture(t *testing.T) {
+	t.Helper()
+
+	os.WriteFile(filepath.Join(".composer", "workflows", "ticket.toml"), []byte(`
+[params]
+ticket = ""
+
+[[steps]]
+name = "triage"
+handler = "human"
+inputs = ["description"]
+output = "triaged"
+`), 0644)
+
+	os.MkdirAll(filepath.Join(".composer", "triggers"), 0755)
+	os.WriteFile(filepath.Join(".composer", "triggers", "tickets.toml"), []byte(`
+workflow = "ticket"
+run_id = "ticket-{{.ticket}}"
+secret_env = "TEST_TRIGGER_SECRET"
+
+[params]
+ticket = "issue.key"
+
+[artifacts]
+description = "issue.fields.description"
+payload = "."
+`), 0644)
+}
+
+// sign returns the payload's signature header value under the secret
+func sign(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestPostTrigger(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+	t.Setenv("TEST_TRIGGER_SECRET", "s3cret")
+
+	createTriggerFixture(t)
+	router := setupRouter()
+
+	payload := `{"issue": {"key": "OPS-7", "fields": {"description": "Disk is full"}}}`
+	headers := map[string]string{
+		"Content-Type":         "application/json",
+		"X-Composer-Signature": sign("s3cret", payload),
+	}
+
+	res := serve(router, "POST", "/api/trigger/tickets", payload, headers)
+	if res.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", res.Code, res.Body.String())
+	}
+	var response struct {
+		Data workflow.RunState `json:"data"`
+	}
+	json.Unmarshal(res.Body.Bytes(), &response)
+	state := response.Data
+	if state.ID != "ticket-OPS-7" || state.Params["ticket"] != "OPS-7" {
+		t.Errorf("Expected run ticket-OPS-7 with its ticket param, got %+v", state)
+	}
+	if state.Trigger == nil || state.Trigger.Kind != workflow.TriggerWebhook || state.Trigger.Name != "tickets" {
+		t.Errorf("Expected the run to record its trigger, got %+v", state.Trigger)
+	}
+
+	artifacts := testStore().Artifacts()
+	if content, _ := workflow.ReadArtifact(artifacts, "ticket-OPS-7", "description"); string(content) != "Disk is full" {
+		t.Errorf("Expected the description seeded, got %q", content)
+	}
+	info, _ := artifacts.Stat("ticket-OPS-7", "payload")
+	if info.ContentType != "application/json" || info.Handler != workflow.TriggerWebhook {
+		t.Errorf("Expected the whole payload seeded as JSON by the webhook, got %+v", info)
+	}
+
+	// The same ticket maps to the same run ID
+	if res := serve(router, "POST", "/api/trigger/tickets", payload, headers); res.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for a run that already exists, got %d", res.Code)
+	}
+}
+
+func TestPostTriggerRejectsBadRequests(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+	t.Setenv("TEST_TRIGGER_SECRET", "s3cret")
+
+	createTriggerFixture(t)
+	router := setupRouter()
+
+	payload := `{"issue": {"key": "OPS-8"}}`
+	tests := []struct {
+		name      string
+		url       string
+		payload   string
+		signature string
+		want      int
+	}{
+		{"unknown trigger", "/api/trigger/missing", payload, sign("s3cret", payload), http.StatusNotFound},
+		{"missing signature", "/api/trigger/tickets", payload, "", http.StatusUnauthorized},
+		{"wrong secret", "/api/trigger/tickets", payload, sign("guess", payload), http.StatusUnauthorized},
+		{"missing field", "/api/trigger/tickets", payload, sign("s3cret", payload), http.StatusBadRequest},
+		{"not JSON", "/api/trigger/tickets", "key=OPS-8", sign("s3cret", "key=OPS-8"), http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		res := serve(router, "POST", tt.url, tt.payload, map[string]string{"X-Composer-Signature": tt.signature})
+		if res.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d: %s", tt.name, tt.want, res.Code, res.Body.String())
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"composer/internal/graph"
 	"composer/internal/workflow"
 )
 
@@ -13,6 +14,7 @@ func buildWorkflowsRouter(mux *http.ServeMux) {
 	mux.HandleFunc("GET /api/workflows", handleGetWorkflows)
 	mux.HandleFunc("GET /api/workflow/{id}", handleGetWorkflow)
 	mux.HandleFunc("POST /api/workflow/{id}", handlePostWorkflow)
+	mux.HandleFunc("GET /api/workflow/{id}/graph", handleGetWorkflowGraph)
 }
 
 // handleGetWorkflows returns a list of all workflows
@@ -57,3 +59,22 @@ func handlePostWorkflow(w http.ResponseWriter, r *http.Request) {
 
 	writeData(w, http.StatusOK, wf)
 }
+
+// handleGetWorkflowGraph renders a workflow as a DOT or Mermaid graph
+func handleGetWorkflowGraph(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	format, err := graph.ParseFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	wf, _, err := workflow.LoadWorkflow(id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Workflow not found: %v", err))
+		return
+	}
+
+	writeGraph(w, wf, nil, format)
+}
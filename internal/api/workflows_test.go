@@ -2,8 +2,10 @@ package api_test
 
 import (
 	"net/http"
+	"strings"
 	"testing"
 
+	"composer/internal/api"
 	"composer/internal/workflow"
 )
 
@@ -260,3 +262,47 @@ func TestPostWorkflow_Update(t *testing.T) {
 		t.Errorf("Updated workflow has wrong display name: %s", wf.DisplayName)
 	}
 }
+
+// TestGetWorkflowGraph_Mermaid tests rendering a workflow as a Mermaid graph
+func TestGetWorkflowGraph_Mermaid(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	createWorkflowFixture(t, "test-workflow", "Test Workflow")
+
+	router := setupRouter()
+
+	var response struct {
+		Error *apiError         `json:"error"`
+		Data  api.GraphResponse `json:"data"`
+	}
+	result := get(router, "/api/workflow/test-workflow/graph?format=mermaid", &response)
+
+	if err := expectStatus(http.StatusOK, result); err != nil {
+		t.Fatalf("%v\n%v", err, response)
+	}
+
+	if response.Data.Format != "mermaid" {
+		t.Errorf("Expected format 'mermaid', got '%s'", response.Data.Format)
+	}
+	if !strings.Contains(response.Data.Graph, `step0["step1"]`) {
+		t.Errorf("Expected step1 node in graph, got:\n%s", response.Data.Graph)
+	}
+}
+
+// TestGetWorkflowGraph_InvalidFormat tests rejecting an unknown graph format
+func TestGetWorkflowGraph_InvalidFormat(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	createWorkflowFixture(t, "test-workflow", "Test Workflow")
+
+	router := setupRouter()
+
+	var response apiResponse
+	result := get(router, "/api/workflow/test-workflow/graph?format=png", &response)
+
+	if err := expectStatus(http.StatusBadRequest, result); err != nil {
+		t.Fatalf("%v\n%v", err, response)
+	}
+}
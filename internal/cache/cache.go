@@ -0,0 +1,227 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"composer/internal/workflow"
+)
+
+// keyVersion is mixed into every key so a change to the key layout
+// invalidates all previously stored entries
+const keyVersion = "composer-step-cache-v2"
+
+// Entry describes a cached step output
+type Entry struct {
+	Key        string    `json:"key"`
+	Workflow   string    `json:"workflow"`
+	Step       string    `json:"step"`
+	Size       int64     `json:"size"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+}
+
+// Key computes the content address for a step execution from the parts of
+// the step definition that affect its output, its handler, and the SHA-256
+// digests of its input artifacts in declaration order
+func Key(step workflow.Step, inputDigests []string) string {
+	definition, _ := json.Marshal(struct {
+		Version string   `json:"version"`
+		Handler string   `json:"handler"`
+		Prompt  string   `json:"prompt"`
+		Content string   `json:"content"`
+		Inputs  []string `json:"inputs"`
+	}{
+		Version: keyVersion,
+		Handler: step.HandlerType(),
+		Prompt:  step.Prompt,
+		Content: step.Content,
+		Inputs:  step.Inputs,
+	})
+
+	h := sha256.New()
+	h.Write(definition)
+	for _, digest := range inputDigests {
+		fmt.Fprintf(h, "\n%s", digest)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Lookup opens the cached output for key, if present, and records the use.
+// Entries without metadata are still being stored and miss. The caller must
+// close the returned reader.
+func Lookup(key string) (io.ReadCloser, bool, error) {
+	entryDir := filepath.Join(workflow.GetCacheDir(), key)
+
+	entry, err := readEntry(entryDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	f, err := os.Open(filepath.Join(entryDir, "output"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to read cache entry %s: %w", key, err)
+	}
+
+	entry.LastUsedAt = time.Now().UTC()
+	if err := writeEntry(entryDir, entry); err != nil {
+		f.Close()
+		return nil, false, err
+	}
+
+	return f, true, nil
+}
+
+// Store streams a step output into the cache under key. The output is
+// written to a temporary file and renamed into place, and the metadata is
+// written last, so readers never see a partial entry.
+func Store(key, workflowID, stepName string, content io.Reader) error {
+	entryDir := filepath.Join(workflow.GetCacheDir(), key)
+
+	if err := os.MkdirAll(entryDir, 0755); err != nil {
+		return fmt.Errorf("failed to create cache entry directory: %w", err)
+	}
+
+	f, err := os.CreateTemp(entryDir, "output-*")
+	if err != nil {
+		return fmt.Errorf("failed to write cache entry %s: %w", key, err)
+	}
+	size, err := io.Copy(f, content)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filepath.Join(entryDir, "output"))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("failed to write cache entry %s: %w", key, err)
+	}
+
+	now := time.Now().UTC()
+	return writeEntry(entryDir, &Entry{
+		Key:        key,
+		Workflow:   workflowID,
+		Step:       stepName,
+		Size:       size,
+		CreatedAt:  now,
+		LastUsedAt: now,
+	})
+}
+
+// List returns all cache entries, most recently used first
+func List() ([]Entry, error) {
+	cacheDir := workflow.GetCacheDir()
+
+	// Check if cache directory exists
+	if _, err := os.Stat(cacheDir); os.IsNotExist(err) {
+		return []Entry{}, nil
+	}
+
+	dirEntries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading cache directory: %w", err)
+	}
+
+	entries := []Entry{}
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() {
+			continue
+		}
+
+		entry, err := readEntry(filepath.Join(cacheDir, dirEntry.Name()))
+		if err != nil {
+			// Skip entries that can't be loaded (might be incomplete or corrupted)
+			continue
+		}
+		entries = append(entries, *entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].LastUsedAt.After(entries[j].LastUsedAt)
+	})
+
+	return entries, nil
+}
+
+// Prune removes cache entries that have not been used within olderThan.
+// A zero duration removes every entry. Returns the removed entries.
+func Prune(olderThan time.Duration) ([]Entry, error) {
+	entries, err := List()
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := time.Now().UTC().Add(-olderThan)
+	removed := []Entry{}
+	for _, entry := range entries {
+		if olderThan > 0 && entry.LastUsedAt.After(cutoff) {
+			continue
+		}
+
+		if err := os.RemoveAll(filepath.Join(workflow.GetCacheDir(), entry.Key)); err != nil {
+			return removed, fmt.Errorf("failed to remove cache entry %s: %w", entry.Key, err)
+		}
+		removed = append(removed, entry)
+	}
+
+	return removed, nil
+}
+
+// readEntry loads the metadata for a cache entry directory
+func readEntry(entryDir string) (*Entry, error) {
+	data, err := os.ReadFile(filepath.Join(entryDir, "entry.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache entry metadata: %w", err)
+	}
+
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cache entry metadata: %w", err)
+	}
+
+	return &entry, nil
+}
+
+// writeEntry saves the metadata for a cache entry directory
+func writeEntry(entryDir string, entry *Entry) error {
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache entry metadata: %w", err)
+	}
+
+	// Replace the metadata in one step so concurrent lookups read it whole
+	f, err := os.CreateTemp(entryDir, "entry-*")
+	if err != nil {
+		return fmt.Errorf("failed to write cache entry metadata: %w", err)
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filepath.Join(entryDir, "entry.json"))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("failed to write cache entry metadata: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package cache
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"composer/internal/workflow"
+)
+
+func TestKey(t *testing.T) {
+	step := workflow.Step{Name: "combine", Inputs: []string{"a", "b"}, Output: "out"}
+
+	base := Key(step, []string{"aaa", "bbb"})
+	if base != Key(step, []string{"aaa", "bbb"}) {
+		t.Error("Key should be deterministic")
+	}
+
+	// Renaming a step or its output does not change what it computes
+	renamed := step
+	renamed.Name = "merge"
+	renamed.Output = "merged"
+	if Key(renamed, []string{"aaa", "bbb"}) != base {
+		t.Error("Key should not depend on step name or output name")
+	}
+
+	// Input digests, their order, and the definition all change the key
+	variants := map[string]string{
+		"different input digest": Key(step, []string{"aaa", "ccc"}),
+		"swapped inputs":         Key(step, []string{"bbb", "aaa"}),
+		"different handler":      Key(workflow.Step{Handler: "human", Inputs: step.Inputs}, []string{"aaa", "bbb"}),
+		"different prompt":       Key(workflow.Step{Prompt: "x", Inputs: step.Inputs}, []string{"aaa", "bbb"}),
+	}
+	for name, key := range variants {
+		if key == base {
+			t.Errorf("Key should change for %s", name)
+		}
+	}
+}
+
+func TestStoreAndLookup(t *testing.T) {
+	tempDir := t.TempDir()
+	os.Chdir(tempDir)
+
+	if _, ok, err := Lookup("missing"); ok || err != nil {
+		t.Errorf("Lookup of missing key should miss cleanly, got %v, %v", ok, err)
+	}
+
+	if err := Store("abc", "wf", "step", strings.NewReader("cached output")); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	r, ok, err := Lookup("abc")
+	if err != nil || !ok {
+		t.Fatalf("Lookup should hit, got %v, %v", ok, err)
+	}
+	data, _ := io.ReadAll(r)
+	r.Close()
+	if content := string(data); content != "cached output" {
+		t.Errorf("Expected 'cached output', got '%s'", content)
+	}
+
+	entries, err := List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+	if entries[0].Workflow != "wf" || entries[0].Step != "step" || entries[0].Size != int64(len("cached output")) {
+		t.Errorf("Unexpected entry: %+v", entries[0])
+	}
+}
+
+func TestStoreFailureKeepsEntry(t *testing.T) {
+	os.Chdir(t.TempDir())
+
+	if err := Store("abc", "wf", "step", strings.NewReader("cached output")); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	// A store that fails partway leaves the earlier output in place
+	failing := io.MultiReader(strings.NewReader("partial"), iotest.ErrReader(errors.New("handler crashed")))
+	if err := Store("abc", "wf", "step", failing); err == nil {
+		t.Fatal("Expected the failed store to be reported")
+	}
+	r, ok, err := Lookup("abc")
+	if err != nil || !ok {
+		t.Fatalf("Lookup should hit, got %v, %v", ok, err)
+	}
+	data, _ := io.ReadAll(r)
+	r.Close()
+	if string(data) != "cached output" {
+		t.Errorf("Expected the earlier output, got %q", data)
+	}
+
+	// An output without metadata is still being stored
+	os.MkdirAll(filepath.Join(workflow.GetCacheDir(), "pending"), 0755)
+	os.WriteFile(filepath.Join(workflow.GetCacheDir(), "pending", "output"), []byte("partial"), 0644)
+	if _, ok, err := Lookup("pending"); ok || err != nil {
+		t.Errorf("Expected an entry without metadata to miss, got %v, %v", ok, err)
+	}
+
+	leftovers, _ := filepath.Glob(filepath.Join(workflow.GetCacheDir(), "abc", "*-*"))
+	if len(leftovers) != 0 {
+		t.Errorf("Expected temporary files to be removed, got %v", leftovers)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	tempDir := t.TempDir()
+	os.Chdir(tempDir)
+
+	Store("old", "wf", "step", strings.NewReader("old output"))
+	Store("new", "wf", "step", strings.NewReader("new output"))
+
+	// Backdate the old entry
+	entryDir := filepath.Join(workflow.GetCacheDir(), "old")
+	entry, _ := readEntry(entryDir)
+	entry.LastUsedAt = time.Now().UTC().Add(-48 * time.Hour)
+	writeEntry(entryDir, entry)
+
+	removed, err := Prune(24 * time.Hour)
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if len(removed) != 1 || removed[0].Key != "old" {
+		t.Errorf("Expected only 'old' to be pruned, got %+v", removed)
+	}
+	if r, ok, _ := Lookup("new"); !ok {
+		t.Error("Recently used entry should survive pruning")
+	} else {
+		r.Close()
+	}
+
+	removed, _ = Prune(0)
+	if len(removed) != 1 {
+		t.Errorf("Prune(0) should remove all remaining entries, removed %d", len(removed))
+	}
+}
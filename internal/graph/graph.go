@@ -0,0 +1,275 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+
+	"composer/internal/workflow"
+)
+
+// Format identifies a graph output syntax
+type Format string
+
+const (
+	FormatDOT     Format = "dot"
+	FormatMermaid Format = "mermaid"
+)
+
+// ParseFormat converts a user-supplied format name into a Format
+func ParseFormat(name string) (Format, error) {
+	switch Format(strings.ToLower(strings.TrimSpace(name))) {
+	case "", FormatDOT:
+		return FormatDOT, nil
+	case FormatMermaid:
+		return FormatMermaid, nil
+	default:
+		return "", fmt.Errorf("unsupported graph format '%s' (must be dot or mermaid)", name)
+	}
+}
+
+// node is a step rendered as a graph vertex
+type node struct {
+	ID      string
+	Label   string
+	Handler string
+	Status  workflow.StepStatus
+}
+
+// edge is an artifact flowing from a producing step to a consuming step
+type edge struct {
+	From     string
+	To       string
+	Artifact string
+}
+
+// cluster is a loop drawn as a box around its steps
+type cluster struct {
+	ID    string
+	Label string
+	Nodes []string
+}
+
+// Render draws the workflow as a graph in the given format. Steps become nodes
+// shaped by handler type and artifacts become edges between the producing and
+// consuming steps. Loops are drawn as boxes around their steps. When state is
+// non-nil, nodes are colored by step status.
+func Render(wf *workflow.Workflow, state *workflow.RunState, format Format) (string, error) {
+	nodes, edges := build(wf, state)
+	clusters := buildClusters(wf, state)
+
+	switch format {
+	case FormatDOT:
+		return renderDOT(wf, nodes, edges, clusters), nil
+	case FormatMermaid:
+		return renderMermaid(nodes, edges, clusters), nil
+	default:
+		return "", fmt.Errorf("unsupported graph format '%s'", format)
+	}
+}
+
+// build converts the workflow steps into nodes and the artifact dependencies
+// into edges. Inputs with no producing step are drawn as standalone artifact
+// nodes so the graph never references a missing vertex.
+func build(wf *workflow.Workflow, state *workflow.RunState) ([]node, []edge) {
+	nodes := make([]node, 0, len(wf.Steps))
+	producers := make(map[string][]string)
+
+	for i, step := range wf.Steps {
+		n := node{
+			ID:      fmt.Sprintf("step%d", i),
+			Label:   step.Name,
+			Handler: step.HandlerType(),
+		}
+		if state != nil {
+			if stepState, ok := state.StepStates[step.Name]; ok {
+				n.Status = stepState.Status
+			}
+		}
+		nodes = append(nodes, n)
+
+		if step.Output != "" {
+			producers[step.Output] = append(producers[step.Output], n.ID)
+		}
+	}
+
+	edges := []edge{}
+	external := make(map[string]string)
+	for i, step := range wf.Steps {
+		for _, input := range step.Inputs {
+			from, ok := producers[input]
+			if !ok {
+				id, seen := external[input]
+				if !seen {
+					id = fmt.Sprintf("artifact%d", len(external))
+					external[input] = id
+					nodes = append(nodes, node{ID: id, Label: input})
+				}
+				from = []string{id}
+			}
+
+			for _, producer := range from {
+				edges = append(edges, edge{
+					From:     producer,
+					To:       nodes[i].ID,
+					Artifact: input,
+				})
+			}
+		}
+	}
+
+	return nodes, edges
+}
+
+// buildClusters groups the step nodes of each loop, labelled with the loop's
+// iteration limit, or its current iteration when state is non-nil
+func buildClusters(wf *workflow.Workflow, state *workflow.RunState) []cluster {
+	clusters := []cluster{}
+	for i, loop := range wf.Loops {
+		c := cluster{
+			ID:    fmt.Sprintf("loop%d", i),
+			Label: fmt.Sprintf("%s (max %d)", loop.Name, loop.Limit()),
+		}
+		if state != nil {
+			c.Label = fmt.Sprintf("%s (iteration %d of %d)", loop.Name, state.Loop(loop.Name).Iteration, loop.Limit())
+		}
+		for j, step := range wf.Steps {
+			if loop.Has(step.Name) {
+				c.Nodes = append(c.Nodes, fmt.Sprintf("step%d", j))
+			}
+		}
+		clusters = append(clusters, c)
+	}
+	return clusters
+}
+
+// renderDOT renders nodes and edges in Graphviz DOT syntax
+func renderDOT(wf *workflow.Workflow, nodes []node, edges []edge, clusters []cluster) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "digraph %s {\n", dotQuote(wf.ID))
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [fontname=\"Helvetica\"];\n")
+	b.WriteString("  edge [fontname=\"Helvetica\", fontsize=10];\n")
+
+	for _, n := range nodes {
+		attrs := []string{
+			"label=" + dotQuote(n.Label),
+			"shape=" + dotShape(n.Handler),
+		}
+		if n.Handler == "" {
+			attrs = append(attrs, "style=dashed")
+		} else if n.Status != "" {
+			attrs = append(attrs, "style=filled", "fillcolor="+dotQuote(statusColor(n.Status)))
+		}
+		fmt.Fprintf(&b, "  %s [%s];\n", n.ID, strings.Join(attrs, ", "))
+	}
+
+	for _, c := range clusters {
+		fmt.Fprintf(&b, "  subgraph cluster_%s {\n", c.ID)
+		fmt.Fprintf(&b, "    label=%s;\n", dotQuote(c.Label))
+		b.WriteString("    style=dashed;\n")
+		for _, id := range c.Nodes {
+			fmt.Fprintf(&b, "    %s;\n", id)
+		}
+		b.WriteString("  }\n")
+	}
+
+	for _, e := range edges {
+		fmt.Fprintf(&b, "  %s -> %s [label=%s];\n", e.From, e.To, dotQuote(e.Artifact))
+	}
+
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// renderMermaid renders nodes and edges as a Mermaid flowchart
+func renderMermaid(nodes []node, edges []edge, clusters []cluster) string {
+	var b strings.Builder
+
+	b.WriteString("flowchart LR\n")
+
+	for _, n := range nodes {
+		open, close := mermaidShape(n.Handler)
+		fmt.Fprintf(&b, "  %s%s%s%s\n", n.ID, open, mermaidQuote(n.Label), close)
+	}
+
+	for _, c := range clusters {
+		fmt.Fprintf(&b, "  subgraph %s[%s]\n", c.ID, mermaidQuote(c.Label))
+		for _, id := range c.Nodes {
+			fmt.Fprintf(&b, "    %s\n", id)
+		}
+		b.WriteString("  end\n")
+	}
+
+	for _, e := range edges {
+		fmt.Fprintf(&b, "  %s -->|%s| %s\n", e.From, mermaidQuote(e.Artifact), e.To)
+	}
+
+	for _, n := range nodes {
+		if n.Handler != "" && n.Status != "" {
+			fmt.Fprintf(&b, "  style %s fill:%s\n", n.ID, statusColor(n.Status))
+		}
+	}
+
+	return b.String()
+}
+
+// dotShape returns the Graphviz node shape for a handler type
+func dotShape(handler string) string {
+	switch handler {
+	case "":
+		return "note"
+	case "tool":
+		return "box"
+	case "human":
+		return "ellipse"
+	default:
+		return "hexagon"
+	}
+}
+
+// mermaidShape returns the Mermaid node delimiters for a handler type
+func mermaidShape(handler string) (string, string) {
+	switch handler {
+	case "":
+		return ">", "]"
+	case "tool":
+		return "[", "]"
+	case "human":
+		return "([", "])"
+	default:
+		return "{{", "}}"
+	}
+}
+
+// statusColor returns the fill color used for a step status
+func statusColor(status workflow.StepStatus) string {
+	switch status {
+	case workflow.StatusPending:
+		return "#ffd966"
+	case workflow.StatusReady:
+		return "#6cb4ff"
+	case workflow.StatusSucceeded:
+		return "#65d57c"
+	case workflow.StatusFailed:
+		return "#ff6b6b"
+	case workflow.StatusCancelled:
+		return "#8c8c8c"
+	case workflow.StatusSkipped:
+		return "#d9d9d9"
+	default:
+		return "#bbbbbb"
+	}
+}
+
+// dotQuote returns s as a double-quoted DOT string
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
+
+// mermaidQuote returns s as a double-quoted Mermaid label
+func mermaidQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
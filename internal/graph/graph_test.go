@@ -0,0 +1,146 @@
+package graph
+
+import (
+	"strings"
+	"testing"
+
+	"composer/internal/workflow"
+)
+
+func testWorkflow() *workflow.Workflow {
+	return &workflow.Workflow{
+		ID: "review",
+		Steps: []workflow.Step{
+			{Name: "draft", Content: "text", Output: "draft-doc"},
+			{Name: "review", Handler: "human", Inputs: []string{"draft-doc"}, Output: "reviewed"},
+			{Name: "publish", Inputs: []string{"reviewed", "style-guide"}, Output: "published"},
+		},
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Format
+		wantErr  bool
+	}{
+		{input: "", expected: FormatDOT},
+		{input: "dot", expected: FormatDOT},
+		{input: "Mermaid", expected: FormatMermaid},
+		{input: "svg", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		format, err := ParseFormat(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseFormat(%q) should fail", tt.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseFormat(%q) failed: %v", tt.input, err)
+		}
+		if format != tt.expected {
+			t.Errorf("ParseFormat(%q) = %s, want %s", tt.input, format, tt.expected)
+		}
+	}
+}
+
+func TestRenderDOT(t *testing.T) {
+	out, err := Render(testWorkflow(), nil, FormatDOT)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	expected := []string{
+		`digraph "review" {`,
+		`step0 [label="draft", shape=box];`,
+		`step1 [label="review", shape=ellipse];`,
+		`artifact0 [label="style-guide", shape=note, style=dashed];`,
+		`step0 -> step1 [label="draft-doc"];`,
+		`step1 -> step2 [label="reviewed"];`,
+		`artifact0 -> step2 [label="style-guide"];`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(out, line) {
+			t.Errorf("DOT output missing %q:\n%s", line, out)
+		}
+	}
+	if strings.Contains(out, "fillcolor") {
+		t.Errorf("workflow graph should not be colored:\n%s", out)
+	}
+}
+
+func TestRenderMermaidWithRunState(t *testing.T) {
+	wf := testWorkflow()
+	state := workflow.NewRunState(wf, "run", "run")
+	state.StepStates["draft"] = workflow.StepState{Status: workflow.StatusSucceeded}
+	state.StepStates["review"] = workflow.StepState{Status: workflow.StatusReady}
+
+	out, err := Render(wf, state, FormatMermaid)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	expected := []string{
+		"flowchart LR",
+		`step0["draft"]`,
+		`step1(["review"])`,
+		`step0 -->|"draft-doc"| step1`,
+		"style step0 fill:" + statusColor(workflow.StatusSucceeded),
+		"style step1 fill:" + statusColor(workflow.StatusReady),
+		"style step2 fill:" + statusColor(workflow.StatusPending),
+	}
+	for _, line := range expected {
+		if !strings.Contains(out, line) {
+			t.Errorf("Mermaid output missing %q:\n%s", line, out)
+		}
+	}
+}
+
+func TestRenderEscapesLabels(t *testing.T) {
+	wf := &workflow.Workflow{
+		ID: "quotes",
+		Steps: []workflow.Step{
+			{Name: `say "hi"`, Output: "out"},
+		},
+	}
+
+	dot, _ := Render(wf, nil, FormatDOT)
+	if !strings.Contains(dot, `label="say \"hi\""`) {
+		t.Errorf("DOT label not escaped:\n%s", dot)
+	}
+
+	mermaid, _ := Render(wf, nil, FormatMermaid)
+	if !strings.Contains(mermaid, `"say #quot;hi#quot;"`) {
+		t.Errorf("Mermaid label not escaped:\n%s", mermaid)
+	}
+}
+
+func TestRenderLoops(t *testing.T) {
+	wf := testWorkflow()
+	wf.Loops = []workflow.Loop{{
+		Name:  "revision",
+		Steps: []string{"draft", "review"},
+		Until: workflow.LoopCondition{Step: "review", Outcome: "approve"},
+	}}
+
+	dot, _ := Render(wf, nil, FormatDOT)
+	for _, line := range []string{
+		"subgraph cluster_loop0 {",
+		`label="revision (max 3)";`,
+		"    step0;\n    step1;\n  }",
+	} {
+		if !strings.Contains(dot, line) {
+			t.Errorf("DOT output missing %q:\n%s", line, dot)
+		}
+	}
+
+	state := workflow.NewRunState(wf, "run", "run")
+	state.SetLoop("revision", workflow.LoopState{Status: workflow.LoopRunning, Iteration: 2})
+	mermaid, _ := Render(wf, state, FormatMermaid)
+	if !strings.Contains(mermaid, "subgraph loop0[\"revision (iteration 2 of 3)\"]\n    step0\n    step1\n  end") {
+		t.Errorf("Mermaid output missing the loop:\n%s", mermaid)
+	}
+}
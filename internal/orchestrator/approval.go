@@ -0,0 +1,50 @@
+package orchestrator
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"composer/internal/workflow"
+)
+
+// recordApproval records the actor's vote on a step collecting approvals and
+// returns the outcome the votes decide, or "" while the step still waits for
+// more. Each person votes once.
+func recordApproval(state *workflow.RunState, step workflow.Step, actor string, decision Decision) (string, error) {
+	if actor == "" {
+		return "", fmt.Errorf("%w: voting on step %s needs an actor", ErrActorNotAllowed, step.Name)
+	}
+
+	current := state.StepStates[step.Name]
+	if slices.ContainsFunc(current.Approvals, func(a workflow.Approval) bool { return a.Actor == actor }) {
+		return "", fmt.Errorf("%w: %s has already voted on step %s", ErrActorNotAllowed, actor, step.Name)
+	}
+
+	current.Approvals = append(current.Approvals, workflow.Approval{
+		Actor:   actor,
+		Outcome: decision.Outcome,
+		Comment: decision.Comment,
+		At:      time.Now().UTC(),
+	})
+	state.StepStates[step.Name] = current
+
+	return workflow.Quorum(current.Approvals, step.ApprovalsRequired, len(step.Assignees)), nil
+}
+
+// hasVoted reports whether the actor has voted on the task
+func hasVoted(task WaitingTask, actor string) bool {
+	return slices.ContainsFunc(task.Approvals, func(a workflow.Approval) bool { return a.Actor == actor })
+}
+
+// pendingVoters returns the step's assignees who have not voted yet
+func pendingVoters(step workflow.Step, state *workflow.RunState) ([]string, error) {
+	assignees, err := stepAssignees(step, state)
+	if err != nil {
+		return nil, err
+	}
+	votes := state.StepStates[step.Name].Approvals
+	return slices.DeleteFunc(assignees, func(actor string) bool {
+		return slices.ContainsFunc(votes, func(a workflow.Approval) bool { return a.Actor == actor })
+	}), nil
+}
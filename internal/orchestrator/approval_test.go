@@ -0,0 +1,112 @@
+package orchestrator
+
+import (
+	"testing"
+
+	"composer/internal/workflow"
+)
+
+// signOffWorkflow needs two of three approvers to sign off a release
+func signOffWorkflow() *workflow.Workflow {
+	return &workflow.Workflow{
+		ID: "test",
+		Steps: []workflow.Step{
+			{
+				Name:              "sign-off",
+				Handler:           "human",
+				Content:           "release notes",
+				Output:            "approval",
+				Outcomes:          []string{"approve", "reject"},
+				Assignees:         []string{"alice", "bob", "carol"},
+				ApprovalsRequired: 2,
+			},
+			{Name: "ship", Inputs: []string{"approval"}, Output: "shipped", When: &workflow.OutcomeCondition{Step: "sign-off", Outcome: "approve"}},
+		},
+	}
+}
+
+// vote casts the actor's vote on the sign-off step
+func vote(t *testing.T, store workflow.RunStore, wf *workflow.Workflow, runID, actor, outcome string) *workflow.RunState {
+	t.Helper()
+	state, err := CompleteStep(store, wf, runID, "sign-off", actor, Decision{Outcome: outcome, Comment: actor + " says " + outcome}, workflow.ArtifactMeta{}, nil)
+	if err != nil {
+		t.Fatalf("Vote by %s failed: %v", actor, err)
+	}
+	return state
+}
+
+func TestApprovalsReachQuorum(t *testing.T) {
+	store := workflow.NewMemoryStore()
+
+	wf := signOffWorkflow()
+	runID := "test-run"
+	CreateRun(store, wf, runID, runID)
+	Tick(store, wf, runID)
+
+	state := vote(t, store, wf, runID, "alice", "approve")
+	if state.StepStates["sign-off"].Status != workflow.StatusReady || hasArtifact(store, runID, "approval") {
+		t.Fatalf("Expected the sign-off to wait for a second approval, got %+v", state.StepStates["sign-off"])
+	}
+
+	if _, err := CompleteStep(store, wf, runID, "sign-off", "alice", Decision{Outcome: "approve"}, workflow.ArtifactMeta{}, nil); err == nil {
+		t.Error("Expected a second vote by the same person to fail")
+	}
+	if _, err := CompleteStep(store, wf, runID, "sign-off", "dave", Decision{Outcome: "approve"}, workflow.ArtifactMeta{}, nil); err == nil {
+		t.Error("Expected a vote by someone not listed to fail")
+	}
+
+	tasks, _ := ListWaitingTasks(store, wf, runID)
+	filter := TaskFilter{Actor: "alice"}
+	if filter.Matches(tasks[0]) || !(TaskFilter{Actor: "bob"}).Matches(tasks[0]) {
+		t.Error("Expected the task to be listed only for approvers who have not voted")
+	}
+
+	vote(t, store, wf, runID, "bob", "reject")
+	state = vote(t, store, wf, runID, "carol", "approve")
+
+	signOff := state.StepStates["sign-off"]
+	if signOff.Status != workflow.StatusSucceeded || signOff.Outcome != "approve" || len(signOff.Approvals) != 3 {
+		t.Fatalf("Expected the sign-off approved with every vote recorded, got %+v", signOff)
+	}
+	if signOff.Approvals[1].Actor != "bob" || signOff.Approvals[1].Comment != "bob says reject" {
+		t.Errorf("Expected bob's rejection recorded, got %+v", signOff.Approvals[1])
+	}
+	info, _ := store.Artifacts().Stat(runID, "approval")
+	if info.Provenance.Actor != "carol" {
+		t.Errorf("Expected the deciding voter as the actor, got %q", info.Provenance.Actor)
+	}
+}
+
+func TestApprovalsRejectedWhenQuorumUnreachable(t *testing.T) {
+	store := workflow.NewMemoryStore()
+
+	wf := signOffWorkflow()
+	runID := "test-run"
+	CreateRun(store, wf, runID, runID)
+	Tick(store, wf, runID)
+
+	vote(t, store, wf, runID, "alice", "reject")
+	state := vote(t, store, wf, runID, "bob", "reject")
+
+	if signOff := state.StepStates["sign-off"]; signOff.Status != workflow.StatusSucceeded || signOff.Outcome != "reject" {
+		t.Fatalf("Expected the sign-off rejected, got %+v", signOff)
+	}
+	complete, _ := Tick(store, wf, runID)
+	state, _ = store.LoadRun(runID)
+	if !complete || state.StepStates["ship"].Status != workflow.StatusSkipped {
+		t.Errorf("Expected ship to be skipped, got %+v", state.StepStates["ship"])
+	}
+}
+
+func TestApprovalStepsCannotBeClaimed(t *testing.T) {
+	store := workflow.NewMemoryStore()
+
+	wf := signOffWorkflow()
+	runID := "test-run"
+	CreateRun(store, wf, runID, runID)
+	Tick(store, wf, runID)
+
+	if _, err := ClaimTask(store, wf, runID, "sign-off", "alice", DefaultLease); err == nil {
+		t.Error("Expected a claim on an approval step to fail")
+	}
+}
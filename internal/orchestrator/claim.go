@@ -0,0 +1,242 @@
+package orchestrator
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"composer/internal/workflow"
+)
+
+// DefaultLease is how long a claim holds a task when no lease is given
+const DefaultLease = 30 * time.Minute
+
+var (
+	// ErrTaskNotWaiting reports acting on a step that is not waiting for a
+	// human
+	ErrTaskNotWaiting = errors.New("task not waiting")
+	// ErrActorNotAllowed reports acting on a task assigned to or claimed by
+	// someone else
+	ErrActorNotAllowed = errors.New("actor not allowed")
+	// ErrTaskNotClaimed reports releasing a task no one holds
+	ErrTaskNotClaimed = errors.New("task not claimed")
+	// ErrTaskNotClaimable reports claiming a task that collects approvals,
+	// which every assignee decides
+	ErrTaskNotClaimable = errors.New("task cannot be claimed")
+)
+
+// TaskFilter selects the waiting tasks that belong to an actor: tasks the
+// actor has claimed or is assigned, and unassigned tasks for one of the
+// actor's roles. Tasks claimed by someone else never match. The zero filter
+// matches every task.
+type TaskFilter struct {
+	Actor string
+	Roles []string
+}
+
+// NewTaskFilter returns the filter for an actor and a comma-separated list
+// of their roles
+func NewTaskFilter(actor, roles string) TaskFilter {
+	filter := TaskFilter{Actor: strings.TrimSpace(actor)}
+	for _, role := range strings.Split(roles, ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			filter.Roles = append(filter.Roles, role)
+		}
+	}
+	return filter
+}
+
+// Matches reports whether the task belongs to the filter's actor
+func (f TaskFilter) Matches(task WaitingTask) bool {
+	if f.Actor == "" && len(f.Roles) == 0 {
+		return true
+	}
+
+	switch {
+	case task.ClaimedBy != "":
+		return task.ClaimedBy == f.Actor
+	case len(task.Assignees) > 0:
+		return slices.Contains(task.Assignees, f.Actor) && !hasVoted(task, f.Actor)
+	case task.Assignee != "":
+		return task.Assignee == f.Actor
+	default:
+		return task.Role != "" && slices.Contains(f.Roles, task.Role)
+	}
+}
+
+// FilterTasks returns the tasks of each run that match the filter, leaving
+// out runs with none. The zero filter returns every run unchanged.
+func FilterTasks(tasksByRun map[string][]WaitingTask, filter TaskFilter) map[string][]WaitingTask {
+	if filter.Actor == "" && len(filter.Roles) == 0 {
+		return tasksByRun
+	}
+
+	filtered := make(map[string][]WaitingTask, len(tasksByRun))
+	for runID, tasks := range tasksByRun {
+		matching := []WaitingTask{}
+		for _, task := range tasks {
+			if filter.Matches(task) {
+				matching = append(matching, task)
+			}
+		}
+		if len(matching) > 0 {
+			filtered[runID] = matching
+		}
+	}
+	return filtered
+}
+
+// ClaimTask holds a waiting step for the actor for the lease, so no one else
+// can claim or complete it until the lease expires or the actor releases it.
+// Claiming a step the actor already holds renews the lease. Returns the
+// updated state.
+func ClaimTask(
+	store workflow.RunStore,
+	wf *workflow.Workflow,
+	runID string,
+	stepName string,
+	actor string,
+	lease time.Duration,
+) (*workflow.RunState, error) {
+	if actor == "" {
+		return nil, fmt.Errorf("claiming a task needs an actor")
+	}
+	if lease <= 0 {
+		lease = DefaultLease
+	}
+
+	unlock := lockRun(runID)
+	defer unlock()
+
+	state, step, err := loadWaitingStep(store, wf, runID, stepName)
+	if err != nil {
+		return nil, err
+	}
+	if step.ApprovalsRequired > 0 {
+		return nil, fmt.Errorf("%w: step %s collects approvals", ErrTaskNotClaimable, step.Name)
+	}
+	if err := checkActor(*step, state, actor); err != nil {
+		return nil, err
+	}
+
+	expires := time.Now().UTC().Add(lease)
+	claimed := state.StepStates[stepName]
+	claimed.ClaimedBy = actor
+	claimed.ClaimExpires = &expires
+	state.StepStates[stepName] = claimed
+
+	if err := store.SaveRun(state); err != nil {
+		return nil, fmt.Errorf("failed to save state: %w", err)
+	}
+	return state, nil
+}
+
+// ReleaseTask drops the actor's claim on a waiting step. Returns the updated
+// state.
+func ReleaseTask(store workflow.RunStore, wf *workflow.Workflow, runID string, stepName string, actor string) (*workflow.RunState, error) {
+	unlock := lockRun(runID)
+	defer unlock()
+
+	state, _, err := loadWaitingStep(store, wf, runID, stepName)
+	if err != nil {
+		return nil, err
+	}
+
+	current := state.StepStates[stepName]
+	claimant := current.Claimant(time.Now())
+	if claimant == "" {
+		return nil, fmt.Errorf("%w: step %s", ErrTaskNotClaimed, stepName)
+	}
+	if claimant != actor {
+		return nil, fmt.Errorf("%w: step %s is claimed by %s", ErrActorNotAllowed, stepName, claimant)
+	}
+
+	state.StepStates[stepName] = current.Unclaimed()
+	if err := store.SaveRun(state); err != nil {
+		return nil, fmt.Errorf("failed to save state: %w", err)
+	}
+	return state, nil
+}
+
+// loadWaitingStep loads the run and the named step, which must be waiting
+// for a human
+func loadWaitingStep(store workflow.RunStore, wf *workflow.Workflow, runID, stepName string) (*workflow.RunState, *workflow.Step, error) {
+	state, err := store.LoadRun(runID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load state: %w", err)
+	}
+
+	step := findStep(wf, stepName)
+	if step == nil {
+		return nil, nil, fmt.Errorf("step %s not found in workflow", stepName)
+	}
+	if status := state.StepStates[stepName].Status; status != workflow.StatusReady {
+		return nil, nil, fmt.Errorf("%w: step %s is %s", ErrTaskNotWaiting, stepName, status)
+	}
+	return state, step, nil
+}
+
+// checkActor returns an error unless the actor may act on the step: steps
+// assigned to someone else, or claimed by someone else under a live lease,
+// are refused
+func checkActor(step workflow.Step, state *workflow.RunState, actor string) error {
+	assignee, _, err := stepAssignment(step, state)
+	if err != nil {
+		return err
+	}
+	if assignee != "" && assignee != actor {
+		return fmt.Errorf("%w: step %s is assigned to %s", ErrActorNotAllowed, step.Name, assignee)
+	}
+	assignees, err := stepAssignees(step, state)
+	if err != nil {
+		return err
+	}
+	if len(assignees) > 0 && !slices.Contains(assignees, actor) {
+		return fmt.Errorf("%w: step %s is assigned to %s", ErrActorNotAllowed, step.Name, strings.Join(assignees, ", "))
+	}
+
+	current := state.StepStates[step.Name]
+	if claimant := current.Claimant(time.Now()); claimant != "" && claimant != actor {
+		return fmt.Errorf("%w: step %s is claimed by %s until %s", ErrActorNotAllowed, step.Name, claimant, current.ClaimExpires.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// stepAssignment returns the step's assignee and role with the run's
+// parameters filled in. Steps reassigned by an escalation go to the
+// escalation's role alone.
+func stepAssignment(step workflow.Step, state *workflow.RunState) (string, string, error) {
+	if role := state.StepStates[step.Name].Role; role != "" {
+		return "", role, nil
+	}
+
+	assignee, err := workflow.ExpandParams(step.Assignee, state.Params)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to resolve assignee of %s: %w", step.Name, err)
+	}
+	role, err := workflow.ExpandParams(step.Role, state.Params)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to resolve role of %s: %w", step.Name, err)
+	}
+	return assignee, role, nil
+}
+
+// stepAssignees returns the step's assignees with the run's parameters
+// filled in, or none once an escalation reassigned the step
+func stepAssignees(step workflow.Step, state *workflow.RunState) ([]string, error) {
+	if len(step.Assignees) == 0 || state.StepStates[step.Name].Role != "" {
+		return nil, nil
+	}
+
+	assignees := make([]string, len(step.Assignees))
+	for i, assignee := range step.Assignees {
+		expanded, err := workflow.ExpandParams(assignee, state.Params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve assignees of %s: %w", step.Name, err)
+		}
+		assignees[i] = expanded
+	}
+	return assignees, nil
+}
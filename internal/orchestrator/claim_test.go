@@ -0,0 +1,142 @@
+package orchestrator
+
+import (
+	"testing"
+	"time"
+
+	"composer/internal/workflow"
+)
+
+// assignedWorkflow has a review step assigned from the run's reviewer
+// parameter and an edit step open to the editor role
+func assignedWorkflow() *workflow.Workflow {
+	return &workflow.Workflow{
+		ID:     "test",
+		Params: map[string]string{"reviewer": "alice"},
+		Steps: []workflow.Step{
+			{Name: "review", Handler: "human", Content: "draft", Output: "reviewed", Assignee: "{{.reviewer}}"},
+			{Name: "edit", Handler: "human", Content: "draft", Output: "edited", Role: "editor"},
+		},
+	}
+}
+
+func TestWaitingTasksResolveAssignees(t *testing.T) {
+	store := workflow.NewMemoryStore()
+
+	wf := assignedWorkflow()
+	runID := "test-run"
+	if err := CreateRunWithParams(store, wf, runID, runID, map[string]string{"reviewer": "bob"}); err != nil {
+		t.Fatalf("CreateRunWithParams failed: %v", err)
+	}
+	Tick(store, wf, runID)
+
+	tasks, err := ListWaitingTasks(store, wf, runID)
+	if err != nil {
+		t.Fatalf("ListWaitingTasks failed: %v", err)
+	}
+	if tasks[0].Assignee != "bob" || tasks[1].Role != "editor" {
+		t.Errorf("Expected review assigned to bob and edit for editors, got %+v", tasks)
+	}
+
+	if err := CreateRunWithParams(store, wf, "other", "other", map[string]string{"approver": "bob"}); err == nil {
+		t.Error("Expected an undeclared parameter to be rejected")
+	}
+}
+
+func TestClaimTask(t *testing.T) {
+	store := workflow.NewMemoryStore()
+
+	wf := assignedWorkflow()
+	runID := "test-run"
+	CreateRun(store, wf, runID, runID)
+	Tick(store, wf, runID)
+
+	if _, err := ClaimTask(store, wf, runID, "review", "carol", time.Hour); err == nil {
+		t.Error("Expected a claim by someone other than the assignee to fail")
+	}
+
+	state, err := ClaimTask(store, wf, runID, "edit", "carol", time.Hour)
+	if err != nil {
+		t.Fatalf("ClaimTask failed: %v", err)
+	}
+	if state.StepStates["edit"].ClaimedBy != "carol" {
+		t.Errorf("Expected carol to hold the claim, got %+v", state.StepStates["edit"])
+	}
+
+	// A second reviewer can neither claim nor complete the held task
+	if _, err := ClaimTask(store, wf, runID, "edit", "dave", time.Hour); err == nil {
+		t.Error("Expected a second claim to fail")
+	}
+	if _, err := CompleteStep(store, wf, runID, "edit", "dave", Decision{}, workflow.ArtifactMeta{}, nil); err == nil {
+		t.Error("Expected completion by someone else to fail")
+	}
+	if _, err := ReleaseTask(store, wf, runID, "edit", "dave"); err == nil {
+		t.Error("Expected release by someone else to fail")
+	}
+
+	if _, err := ReleaseTask(store, wf, runID, "edit", "carol"); err != nil {
+		t.Fatalf("ReleaseTask failed: %v", err)
+	}
+	if _, err := ClaimTask(store, wf, runID, "edit", "dave", time.Hour); err != nil {
+		t.Fatalf("Expected the released task to be claimable: %v", err)
+	}
+	state, err = CompleteStep(store, wf, runID, "edit", "dave", Decision{}, workflow.ArtifactMeta{}, nil)
+	if err != nil {
+		t.Fatalf("CompleteStep failed: %v", err)
+	}
+	if state.StepStates["edit"].ClaimedBy != "" {
+		t.Error("Expected completion to drop the claim")
+	}
+}
+
+func TestClaimExpires(t *testing.T) {
+	store := workflow.NewMemoryStore()
+
+	wf := assignedWorkflow()
+	runID := "test-run"
+	CreateRun(store, wf, runID, runID)
+	Tick(store, wf, runID)
+
+	state, _ := ClaimTask(store, wf, runID, "edit", "carol", time.Hour)
+	expired := time.Now().Add(-time.Minute)
+	edit := state.StepStates["edit"]
+	edit.ClaimExpires = &expired
+	state.StepStates["edit"] = edit
+	store.SaveRun(state)
+
+	tasks, _ := ListWaitingTasks(store, wf, runID)
+	if tasks[1].ClaimedBy != "" {
+		t.Errorf("Expected the expired claim to be hidden, got %+v", tasks[1])
+	}
+	if _, err := ClaimTask(store, wf, runID, "edit", "dave", time.Hour); err != nil {
+		t.Errorf("Expected the expired claim to be taken over: %v", err)
+	}
+}
+
+func TestTaskFilter(t *testing.T) {
+	tasks := map[string][]WaitingTask{
+		"run-a": {
+			{Name: "review", Assignee: "alice"},
+			{Name: "edit", Role: "editor"},
+			{Name: "held", Role: "editor", ClaimedBy: "bob"},
+			{Name: "open"},
+		},
+		"run-b": {
+			{Name: "sign", Assignee: "bob", ClaimedBy: "bob"},
+		},
+	}
+
+	mine := FilterTasks(tasks, TaskFilter{Actor: "alice", Roles: []string{"editor"}})
+	if len(mine) != 1 || len(mine["run-a"]) != 2 || mine["run-a"][0].Name != "review" || mine["run-a"][1].Name != "edit" {
+		t.Errorf("Expected alice's review and the editor task, got %+v", mine)
+	}
+
+	bobs := FilterTasks(tasks, TaskFilter{Actor: "bob"})
+	if len(bobs["run-a"]) != 1 || len(bobs["run-b"]) != 1 {
+		t.Errorf("Expected bob's claimed tasks, got %+v", bobs)
+	}
+
+	if all := FilterTasks(tasks, TaskFilter{}); len(all["run-a"]) != 4 {
+		t.Errorf("Expected the zero filter to keep every task, got %+v", all)
+	}
+}
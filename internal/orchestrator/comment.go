@@ -0,0 +1,147 @@
+package orchestrator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"composer/internal/workflow"
+)
+
+// ErrInvalidComment reports a comment that cannot be added as given, as
+// opposed to a failure to read or write the run's comments
+var ErrInvalidComment = errors.New("invalid comment")
+
+// AddComment adds a comment to a run, or to one of its steps, and returns it
+// as stored. The comment needs an author and a body. A reply stays on the
+// step of the comment it answers. An artifact reference without a version
+// points at the latest version. Line ranges need an artifact and must fall
+// within its content. Comments that fail these checks are reported as
+// ErrInvalidComment.
+func AddComment(store workflow.RunStore, wf *workflow.Workflow, runID string, c workflow.Comment) (workflow.Comment, error) {
+	c.Author = strings.TrimSpace(c.Author)
+	c.Body = strings.TrimSpace(c.Body)
+	if c.Author == "" {
+		return workflow.Comment{}, fmt.Errorf("%w: a comment needs an author", ErrInvalidComment)
+	}
+	if c.Body == "" {
+		return workflow.Comment{}, fmt.Errorf("%w: a comment needs a body", ErrInvalidComment)
+	}
+
+	// Hold the run's lock so concurrent comments are numbered in turn
+	unlock := lockRun(runID)
+	defer unlock()
+
+	if _, err := store.LoadRun(runID); err != nil {
+		return workflow.Comment{}, fmt.Errorf("failed to load state: %w", err)
+	}
+	existing, err := store.Comments(runID)
+	if err != nil {
+		return workflow.Comment{}, fmt.Errorf("failed to load comments: %w", err)
+	}
+
+	if c.ReplyTo != 0 {
+		if c.ReplyTo < 0 || c.ReplyTo > len(existing) {
+			return workflow.Comment{}, fmt.Errorf("%w: comment %d not found", ErrInvalidComment, c.ReplyTo)
+		}
+		if c.Step == "" {
+			c.Step = existing[c.ReplyTo-1].Step
+		}
+	}
+	if c.Step != "" && findStep(wf, c.Step) == nil {
+		return workflow.Comment{}, fmt.Errorf("%w: step %s not found in workflow", ErrInvalidComment, c.Step)
+	}
+
+	if c.Lines != nil && c.Artifact == nil {
+		return workflow.Comment{}, fmt.Errorf("%w: a line range needs an artifact", ErrInvalidComment)
+	}
+	if c.Artifact != nil {
+		ref, err := resolveCommentArtifact(store.Artifacts(), runID, *c.Artifact, c.Lines)
+		if err != nil {
+			return workflow.Comment{}, err
+		}
+		c.Artifact = &ref
+	}
+
+	c.ID = len(existing) + 1
+	c.At = time.Now().UTC()
+	if err := store.AddComment(runID, c); err != nil {
+		return workflow.Comment{}, fmt.Errorf("failed to save comment: %w", err)
+	}
+	return c, nil
+}
+
+// ListComments returns the run's comments, or only those on the given step
+// when it is not empty
+func ListComments(store workflow.RunStore, runID string, step string) ([]workflow.Comment, error) {
+	comments, err := store.Comments(runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load comments: %w", err)
+	}
+	if step == "" {
+		return comments, nil
+	}
+
+	onStep := []workflow.Comment{}
+	for _, c := range comments {
+		if c.Step == step {
+			onStep = append(onStep, c)
+		}
+	}
+	return onStep, nil
+}
+
+// resolveCommentArtifact pins a comment's artifact reference to a stored
+// version, checking that the line range falls within its content
+func resolveCommentArtifact(
+	artifacts workflow.ArtifactStore,
+	runID string,
+	ref workflow.ArtifactRef,
+	lines *workflow.LineRange,
+) (workflow.ArtifactRef, error) {
+	versions, err := artifacts.Versions(runID, ref.Name)
+	if err != nil || len(versions) == 0 {
+		return workflow.ArtifactRef{}, fmt.Errorf("%w: artifact %s not found", ErrInvalidComment, ref.Name)
+	}
+
+	info := versions[len(versions)-1]
+	if ref.Version != 0 {
+		found := false
+		for _, v := range versions {
+			if v.Version == ref.Version {
+				info, found = v, true
+				break
+			}
+		}
+		if !found {
+			return workflow.ArtifactRef{}, fmt.Errorf("%w: artifact %s has no version %d", ErrInvalidComment, ref.Name, ref.Version)
+		}
+	}
+
+	if lines != nil {
+		if err := lines.Validate(); err != nil {
+			return workflow.ArtifactRef{}, fmt.Errorf("%w: %w", ErrInvalidComment, err)
+		}
+		r, err := artifacts.OpenBlob(info.SHA256)
+		if err != nil {
+			return workflow.ArtifactRef{}, fmt.Errorf("failed to read artifact %s: %w", ref.Name, err)
+		}
+		content, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return workflow.ArtifactRef{}, fmt.Errorf("failed to read artifact %s: %w", ref.Name, err)
+		}
+		count := bytes.Count(content, []byte("\n"))
+		if len(content) > 0 && content[len(content)-1] != '\n' {
+			count++
+		}
+		if lines.End > count {
+			return workflow.ArtifactRef{}, fmt.Errorf("%w: artifact %s version %d has %d lines", ErrInvalidComment, ref.Name, info.Version, count)
+		}
+	}
+
+	return info.Ref(), nil
+}
@@ -0,0 +1,141 @@
+package orchestrator
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"composer/internal/workflow"
+)
+
+// draftWorkflow has a human review of a draft
+func draftWorkflow() *workflow.Workflow {
+	return &workflow.Workflow{
+		ID: "test",
+		Steps: []workflow.Step{
+			{Name: "step1", Handler: "human", Content: "draft", Output: "draft"},
+		},
+	}
+}
+
+func TestAddComment(t *testing.T) {
+	store := workflow.NewMemoryStore()
+
+	wf := draftWorkflow()
+	runID := "test-run"
+	CreateRun(store, wf, runID, runID)
+
+	if _, err := AddComment(store, wf, runID, workflow.Comment{Body: "no author"}); err == nil {
+		t.Error("Expected a comment without an author to fail")
+	}
+	if _, err := AddComment(store, wf, runID, workflow.Comment{Author: "alice", Body: "  "}); err == nil {
+		t.Error("Expected a comment without a body to fail")
+	}
+	if _, err := AddComment(store, wf, "missing", workflow.Comment{Author: "alice", Body: "hi"}); err == nil {
+		t.Error("Expected a comment on a missing run to fail")
+	}
+	if _, err := AddComment(store, wf, runID, workflow.Comment{Author: "alice", Body: "hi", Step: "nope"}); err == nil {
+		t.Error("Expected a comment on an unknown step to fail")
+	}
+	if _, err := AddComment(store, wf, runID, workflow.Comment{Author: "alice", Body: "hi", ReplyTo: 1}); err == nil {
+		t.Error("Expected a reply to a missing comment to fail")
+	}
+
+	first, err := AddComment(store, wf, runID, workflow.Comment{Author: "alice", Body: "Check this", Step: "step1"})
+	if err != nil {
+		t.Fatalf("AddComment failed: %v", err)
+	}
+	if first.ID != 1 || first.At.IsZero() {
+		t.Errorf("Expected comment 1 with a timestamp, got %+v", first)
+	}
+
+	// Replies stay on the step of the comment they answer
+	reply, err := AddComment(store, wf, runID, workflow.Comment{Author: "bob", Body: "Done", ReplyTo: first.ID})
+	if err != nil {
+		t.Fatalf("Reply failed: %v", err)
+	}
+	if reply.ID != 2 || reply.Step != "step1" {
+		t.Errorf("Expected reply 2 on step1, got %+v", reply)
+	}
+
+	AddComment(store, wf, runID, workflow.Comment{Author: "carol", Body: "On the run"})
+	onStep, _ := ListComments(store, runID, "step1")
+	all, _ := ListComments(store, runID, "")
+	if len(onStep) != 2 || len(all) != 3 {
+		t.Errorf("Expected 2 comments on step1 and 3 in all, got %d and %d", len(onStep), len(all))
+	}
+}
+
+func TestAddCommentOnArtifactLines(t *testing.T) {
+	store := workflow.NewMemoryStore()
+
+	wf := draftWorkflow()
+	runID := "test-run"
+	CreateRun(store, wf, runID, runID)
+	artifacts := store.Artifacts()
+	workflow.WriteArtifact(artifacts, runID, "draft", workflow.ArtifactMeta{}, strings.NewReader("one\ntwo\nthree\n"))
+	workflow.WriteArtifact(artifacts, runID, "draft", workflow.ArtifactMeta{}, strings.NewReader("one\n"))
+
+	comment := func(version int, lines *workflow.LineRange) (workflow.Comment, error) {
+		return AddComment(store, wf, runID, workflow.Comment{
+			Author:   "alice",
+			Body:     "Reword this",
+			Artifact: &workflow.ArtifactRef{Name: "draft", Version: version},
+			Lines:    lines,
+		})
+	}
+
+	// Without a version the comment is pinned to the latest one
+	latest, err := comment(0, nil)
+	if err != nil {
+		t.Fatalf("AddComment failed: %v", err)
+	}
+	if latest.Artifact.Version != 2 || latest.Artifact.SHA256 == "" {
+		t.Errorf("Expected the comment pinned to version 2, got %+v", latest.Artifact)
+	}
+
+	pinned, err := comment(1, &workflow.LineRange{Start: 2, End: 3})
+	if err != nil {
+		t.Fatalf("AddComment on version 1 failed: %v", err)
+	}
+	if pinned.Artifact.Version != 1 || pinned.Lines.String() != "2-3" {
+		t.Errorf("Expected version 1 lines 2-3, got %+v %v", pinned.Artifact, pinned.Lines)
+	}
+
+	if _, err := comment(0, &workflow.LineRange{Start: 2, End: 3}); err == nil {
+		t.Error("Expected lines past the end of version 2 to fail")
+	}
+	if _, err := comment(3, nil); err == nil {
+		t.Error("Expected a missing version to fail")
+	}
+	if _, err := AddComment(store, wf, runID, workflow.Comment{Author: "alice", Body: "x", Lines: &workflow.LineRange{Start: 1, End: 1}}); err == nil {
+		t.Error("Expected lines without an artifact to fail")
+	}
+}
+
+func TestAddCommentConcurrently(t *testing.T) {
+	store := workflow.NewMemoryStore()
+
+	wf := draftWorkflow()
+	runID := "test-run"
+	CreateRun(store, wf, runID, runID)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := AddComment(store, wf, runID, workflow.Comment{Author: "alice", Body: "hi"}); err != nil {
+				t.Errorf("AddComment failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	comments, _ := store.Comments(runID)
+	for i, c := range comments {
+		if c.ID != i+1 {
+			t.Fatalf("Expected comments numbered in turn, got ID %d at position %d", c.ID, i+1)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"composer/internal/workflow"
+)
+
+// tick is an in-flight Tick that can be interrupted
+type tick struct {
+	cancel context.CancelFunc
+}
+
+var (
+	ticksMu sync.Mutex
+	ticks   = make(map[string]map[*tick]struct{})
+)
+
+// registerTick records an in-flight tick for runID so CancelRun can interrupt
+// its handlers. The returned function unregisters the tick.
+func registerTick(runID string) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t := &tick{cancel: cancel}
+
+	ticksMu.Lock()
+	if ticks[runID] == nil {
+		ticks[runID] = make(map[*tick]struct{})
+	}
+	ticks[runID][t] = struct{}{}
+	ticksMu.Unlock()
+
+	return ctx, func() {
+		ticksMu.Lock()
+		delete(ticks[runID], t)
+		if len(ticks[runID]) == 0 {
+			delete(ticks, runID)
+		}
+		ticksMu.Unlock()
+		cancel()
+	}
+}
+
+// interruptTicks cancels the context of every in-flight tick for runID
+func interruptTicks(runID string) {
+	ticksMu.Lock()
+	defer ticksMu.Unlock()
+
+	for t := range ticks[runID] {
+		t.cancel()
+	}
+}
+
+// CancelRun stops a run for good. Unfinished steps are marked cancelled and
+// any handlers running in this process are interrupted.
+func CancelRun(store workflow.RunStore, runID string) (*workflow.RunState, error) {
+	unlock := lockRun(runID)
+	defer unlock()
+
+	state, err := store.LoadRun(runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+
+	if state.Status == workflow.RunCancelled {
+		return nil, fmt.Errorf("run '%s' is already cancelled", runID)
+	}
+	if state.AllStepsCompleted() {
+		return nil, fmt.Errorf("run '%s' is already complete", runID)
+	}
+
+	state.Status = workflow.RunCancelled
+	state.CancelUnfinishedSteps()
+
+	if err := store.SaveRun(state); err != nil {
+		return nil, fmt.Errorf("failed to save state: %w", err)
+	}
+
+	interruptTicks(runID)
+
+	return state, nil
+}
+
+// PauseRun stops an active run from advancing until it is resumed
+func PauseRun(store workflow.RunStore, runID string) (*workflow.RunState, error) {
+	return setRunStatus(store, runID, workflow.RunActive, workflow.RunPaused)
+}
+
+// ResumeRun lets a paused run advance on the next tick
+func ResumeRun(store workflow.RunStore, runID string) (*workflow.RunState, error) {
+	state, err := setRunStatus(store, runID, workflow.RunPaused, workflow.RunActive)
+	if err == nil {
+		wakeRun(runID)
+	}
+	return state, err
+}
+
+// setRunStatus moves a run from the expected status to the next one
+func setRunStatus(store workflow.RunStore, runID string, from, to workflow.RunStatus) (*workflow.RunState, error) {
+	unlock := lockRun(runID)
+	defer unlock()
+
+	state, err := store.LoadRun(runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+
+	if state.Status != from {
+		return nil, fmt.Errorf("run '%s' is %s, expected %s", runID, state.Status, from)
+	}
+
+	state.Status = to
+
+	if err := store.SaveRun(state); err != nil {
+		return nil, fmt.Errorf("failed to save state: %w", err)
+	}
+
+	return state, nil
+}
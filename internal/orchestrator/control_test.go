@@ -0,0 +1,171 @@
+package orchestrator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"composer/internal/workflow"
+)
+
+func controlWorkflow() *workflow.Workflow {
+	return &workflow.Workflow{
+		ID: "test",
+		Steps: []workflow.Step{
+			{Name: "step1", Content: "content", Output: "out1"},
+			{Name: "step2", Inputs: []string{"out1"}, Output: "out2"},
+		},
+	}
+}
+
+func TestPauseAndResumeRun(t *testing.T) {
+	store := workflow.NewMemoryStore()
+
+	wf := controlWorkflow()
+	runID := "test-run"
+	CreateRun(store, wf, runID, runID)
+
+	state, err := PauseRun(store, runID)
+	if err != nil {
+		t.Fatalf("PauseRun failed: %v", err)
+	}
+	if state.Status != workflow.RunPaused {
+		t.Errorf("Expected run to be paused, got %s", state.Status)
+	}
+
+	// A paused run ignores ticks
+	complete, err := Tick(store, wf, runID)
+	if err != nil {
+		t.Fatalf("Tick on paused run failed: %v", err)
+	}
+	if complete {
+		t.Error("Paused run should not be complete")
+	}
+	state, _ = store.LoadRun(runID)
+	if state.StepStates["step1"].Status != workflow.StatusPending {
+		t.Errorf("step1 should stay pending while paused, got %s", state.StepStates["step1"].Status)
+	}
+
+	// Pausing twice is rejected
+	if _, err := PauseRun(store, runID); err == nil {
+		t.Error("Expected error pausing an already paused run")
+	}
+
+	if _, err := ResumeRun(store, runID); err != nil {
+		t.Fatalf("ResumeRun failed: %v", err)
+	}
+
+	Tick(store, wf, runID)
+	state, _ = store.LoadRun(runID)
+	if state.StepStates["step1"].Status != workflow.StatusSucceeded {
+		t.Errorf("step1 should run after resume, got %s", state.StepStates["step1"].Status)
+	}
+
+	// Resuming an active run is rejected
+	if _, err := ResumeRun(store, runID); err == nil {
+		t.Error("Expected error resuming an active run")
+	}
+}
+
+func TestCancelRun(t *testing.T) {
+	store := workflow.NewMemoryStore()
+
+	wf := controlWorkflow()
+	runID := "test-run"
+	CreateRun(store, wf, runID, runID)
+	Tick(store, wf, runID)
+
+	state, err := CancelRun(store, runID)
+	if err != nil {
+		t.Fatalf("CancelRun failed: %v", err)
+	}
+	if state.Status != workflow.RunCancelled {
+		t.Errorf("Expected run to be cancelled, got %s", state.Status)
+	}
+
+	state, _ = store.LoadRun(runID)
+	if state.StepStates["step1"].Status != workflow.StatusSucceeded {
+		t.Errorf("Finished step should keep its status, got %s", state.StepStates["step1"].Status)
+	}
+	if state.StepStates["step2"].Status != workflow.StatusCancelled {
+		t.Errorf("Unfinished step should be cancelled, got %s", state.StepStates["step2"].Status)
+	}
+
+	// Cancelled runs are finished and cannot be resumed or cancelled again
+	complete, err := Tick(store, wf, runID)
+	if err != nil || !complete {
+		t.Errorf("Tick on cancelled run should report complete, got %v, %v", complete, err)
+	}
+	if _, err := ResumeRun(store, runID); err == nil {
+		t.Error("Expected error resuming a cancelled run")
+	}
+	if _, err := CancelRun(store, runID); err == nil {
+		t.Error("Expected error cancelling a cancelled run")
+	}
+}
+
+func TestCancelRunInterruptsInFlightTick(t *testing.T) {
+	store := workflow.NewMemoryStore()
+
+	// Hold the only slot so the tick's step blocks waiting to execute
+	SetLimits(Limits{MaxConcurrency: 1})
+	defer SetLimits(Limits{})
+	release, _ := acquireSlots(context.Background(), workflow.Step{Name: "holder"})
+	defer release()
+
+	wf := controlWorkflow()
+	runID := "test-run"
+	CreateRun(store, wf, runID, runID)
+
+	type tickResult struct {
+		complete bool
+		err      error
+	}
+	result := make(chan tickResult)
+	go func() {
+		complete, err := Tick(store, wf, runID)
+		result <- tickResult{complete, err}
+	}()
+
+	// Wait for the tick to register before cancelling
+	deadline := time.Now().Add(time.Second)
+	for {
+		ticksMu.Lock()
+		registered := len(ticks[runID]) > 0
+		ticksMu.Unlock()
+		if registered {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Tick never registered")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if _, err := CancelRun(store, runID); err != nil {
+		t.Fatalf("CancelRun failed: %v", err)
+	}
+
+	select {
+	case r := <-result:
+		if r.err != nil {
+			t.Fatalf("Interrupted tick failed: %v", r.err)
+		}
+		if !r.complete {
+			t.Error("Interrupted tick should report the cancelled run as complete")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Tick was not interrupted by CancelRun")
+	}
+
+	state, _ := store.LoadRun(runID)
+	if state.Status != workflow.RunCancelled {
+		t.Errorf("Expected run to stay cancelled, got %s", state.Status)
+	}
+	if state.StepStates["step1"].Status != workflow.StatusCancelled {
+		t.Errorf("In-flight step should be cancelled, got %s", state.StepStates["step1"].Status)
+	}
+	if hasArtifact(store, runID, "out1") {
+		t.Error("Interrupted step should not write its artifact")
+	}
+}
@@ -0,0 +1,253 @@
+package orchestrator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"composer/internal/workflow"
+)
+
+// EscalationActor is recorded as the actor of steps completed by an
+// escalation
+const EscalationActor = "escalation"
+
+// DefaultEscalationInterval is how often composerd evaluates escalations when
+// COMPOSER_ESCALATION_INTERVAL is unset
+const DefaultEscalationInterval = time.Minute
+
+// Notification describes an overdue task sent to an escalation's notify URL
+type Notification struct {
+	Event    string    `json:"event"`
+	RunID    string    `json:"run_id"`
+	Workflow string    `json:"workflow"`
+	Step     string    `json:"step"`
+	Due      time.Time `json:"due"`
+	Assignee string    `json:"assignee,omitempty"`
+	// Assignees lists who may still vote on a step collecting approvals
+	Assignees []string `json:"assignees,omitempty"`
+	Role      string   `json:"role,omitempty"`
+}
+
+// Notifier delivers a notification to a URL
+type Notifier func(ctx context.Context, url string, n Notification) error
+
+// PostNotification delivers a notification as a JSON POST, failing on any
+// status other than 2xx
+func PostNotification(ctx context.Context, url string, n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create notification request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send notification: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("notification to %s failed with status %d", url, resp.StatusCode)
+	}
+	return nil
+}
+
+// EscalationIntervalFromEnv reads how often to evaluate escalations from
+// COMPOSER_ESCALATION_INTERVAL, a duration such as "30s". Zero disables
+// escalation.
+func EscalationIntervalFromEnv() (time.Duration, error) {
+	value := os.Getenv("COMPOSER_ESCALATION_INTERVAL")
+	if value == "" {
+		return DefaultEscalationInterval, nil
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval < 0 {
+		return 0, fmt.Errorf("invalid COMPOSER_ESCALATION_INTERVAL '%s'", value)
+	}
+	return interval, nil
+}
+
+// EscalateRun escalates the run's waiting steps that are still waiting past
+// their deadline and grace period at the given time. Each step escalates
+// once: it is announced to the notify URL, reassigned to the escalation's
+// role (dropping any claim), and completed with the escalation's outcome, in
+// that order. The escalation is recorded on the run's state as it is after
+// the notification, so steps completed meanwhile are left alone. A failed
+// notification leaves the step to be escalated again later, and the first
+// failure is returned after the other steps are escalated. Paused and
+// cancelled runs are left alone. Returns the names of the escalated steps.
+func EscalateRun(
+	ctx context.Context,
+	store workflow.RunStore,
+	wf *workflow.Workflow,
+	runID string,
+	now time.Time,
+	notify Notifier,
+) ([]string, error) {
+	state, err := store.LoadRun(runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+	if state.Status != workflow.RunActive {
+		return nil, nil
+	}
+
+	var first error
+	escalated := []string{}
+	for _, step := range wf.Steps {
+		current := state.StepStates[step.Name]
+		if !escalationDue(step, current, now) {
+			continue
+		}
+
+		esc := step.Escalation
+		if esc.Notify != "" {
+			due, _ := step.Deadline(*current.ReadyAt)
+			n := Notification{Event: "task.overdue", RunID: runID, Workflow: wf.ID, Step: step.Name, Due: due}
+			n.Assignee, n.Role, err = stepAssignment(step, state)
+			if err == nil {
+				n.Assignees, err = pendingVoters(step, state)
+			}
+			if err == nil {
+				err = notify(ctx, esc.Notify, n)
+			}
+			if err != nil {
+				if first == nil {
+					first = fmt.Errorf("failed to escalate %s: %w", step.Name, err)
+				}
+				continue
+			}
+		}
+
+		ok, err := recordEscalation(store, wf, runID, step, *current.ReadyAt, now)
+		if ok {
+			escalated = append(escalated, step.Name)
+		}
+		if err != nil && first == nil {
+			first = fmt.Errorf("failed to escalate %s: %w", step.Name, err)
+		}
+	}
+	return escalated, first
+}
+
+// escalationDue reports whether the step waits unescalated past its
+// escalation time
+func escalationDue(step workflow.Step, current workflow.StepState, now time.Time) bool {
+	if current.Status != workflow.StatusReady || current.ReadyAt == nil || current.EscalatedAt != nil {
+		return false
+	}
+	at, ok := step.EscalatesAt(*current.ReadyAt)
+	return ok && !now.Before(at)
+}
+
+// recordEscalation escalates the step on the run's current state, unless it
+// is no longer the wait that became ready at readyAt, and saves the run.
+// Reports whether the step was escalated.
+func recordEscalation(
+	store workflow.RunStore,
+	wf *workflow.Workflow,
+	runID string,
+	step workflow.Step,
+	readyAt time.Time,
+	now time.Time,
+) (bool, error) {
+	unlock := lockRun(runID)
+	defer unlock()
+
+	state, err := store.LoadRun(runID)
+	if err != nil {
+		return false, fmt.Errorf("failed to load state: %w", err)
+	}
+	current := state.StepStates[step.Name]
+	if state.Status != workflow.RunActive || !escalationDue(step, current, now) || !current.ReadyAt.Equal(readyAt) {
+		return false, nil
+	}
+
+	esc := step.Escalation
+	escalatedAt := now.UTC()
+	current.EscalatedAt = &escalatedAt
+	if esc.Role != "" {
+		role, err := workflow.ExpandParams(esc.Role, state.Params)
+		if err != nil {
+			return false, fmt.Errorf("failed to resolve escalation role: %w", err)
+		}
+		current = current.Unclaimed()
+		current.Role = role
+	}
+	state.StepStates[step.Name] = current
+
+	if esc.Outcome != "" {
+		due, _ := step.Deadline(readyAt)
+		decision := Decision{Outcome: esc.Outcome, Comment: "Escalated: overdue since " + due.Format(time.RFC3339)}
+		if _, err := completeLoadedStep(store, wf, state, step, EscalationActor, decision, workflow.ArtifactMeta{}, nil); err != nil {
+			// Keep the escalation so the step is not announced again
+			state.StepStates[step.Name] = current
+			if err := store.SaveRun(state); err != nil {
+				return false, fmt.Errorf("failed to save state: %w", err)
+			}
+			return true, err
+		}
+		return true, nil
+	}
+
+	if err := store.SaveRun(state); err != nil {
+		return false, fmt.Errorf("failed to save state: %w", err)
+	}
+	return true, nil
+}
+
+// EscalateRuns escalates the overdue steps of every active run in the store,
+// loading each run's workflow. A run that fails to escalate does not stop
+// the others; the first error is returned after all runs are tried.
+func EscalateRuns(ctx context.Context, store workflow.RunStore, now time.Time, notify Notifier) error {
+	runs, err := store.QueryRuns(workflow.RunQuery{Status: workflow.RunActive})
+	if err != nil {
+		return fmt.Errorf("failed to list runs: %w", err)
+	}
+
+	var first error
+	workflows := map[string]*workflow.Workflow{}
+	for _, run := range runs {
+		if !hasReadySteps(&run) {
+			continue
+		}
+
+		wf, ok := workflows[run.WorkflowName]
+		if !ok {
+			wf, _, err = workflow.LoadWorkflow(run.WorkflowName)
+			if err != nil {
+				if first == nil {
+					first = fmt.Errorf("load workflow '%s' for run '%s': %w", run.WorkflowName, run.ID, err)
+				}
+				continue
+			}
+			workflows[run.WorkflowName] = wf
+		}
+
+		if _, err := EscalateRun(ctx, store, wf, run.ID, now, notify); err != nil && first == nil {
+			first = fmt.Errorf("escalate run '%s': %w", run.ID, err)
+		}
+	}
+	return first
+}
+
+// hasReadySteps reports whether any step of the run waits for a human
+func hasReadySteps(state *workflow.RunState) bool {
+	for _, step := range state.StepStates {
+		if step.Status == workflow.StatusReady {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,180 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"composer/internal/workflow"
+)
+
+// deadlineWorkflow has a review due within an hour, escalated with the given
+// action after a grace period of ten minutes
+func deadlineWorkflow(esc workflow.Escalation) *workflow.Workflow {
+	esc.After = "10m"
+	return &workflow.Workflow{
+		ID: "test",
+		Steps: []workflow.Step{
+			{
+				Name:       "review",
+				Handler:    "human",
+				Content:    "draft",
+				Output:     "notes",
+				Outcomes:   []string{"approve", "reject"},
+				Assignee:   "alice",
+				DueIn:      "1h",
+				Escalation: &esc,
+			},
+		},
+	}
+}
+
+// recordNotifications returns a notifier that records what it sends
+func recordNotifications(sent *[]Notification) Notifier {
+	return func(_ context.Context, _ string, n Notification) error {
+		*sent = append(*sent, n)
+		return nil
+	}
+}
+
+func TestWaitingTasksFlagOverdue(t *testing.T) {
+	store := workflow.NewMemoryStore()
+
+	wf := deadlineWorkflow(workflow.Escalation{Role: "leads"})
+	runID := "test-run"
+	CreateRun(store, wf, runID, runID)
+	Tick(store, wf, runID)
+
+	tasks, _ := ListWaitingTasks(store, wf, runID)
+	if tasks[0].Due == nil || tasks[0].Overdue {
+		t.Fatalf("Expected a deadline that has not passed, got %+v", tasks[0])
+	}
+
+	// Move the step's readiness back past its deadline
+	state, _ := store.LoadRun(runID)
+	current := state.StepStates["review"]
+	readyAt := time.Now().Add(-2 * time.Hour)
+	current.ReadyAt = &readyAt
+	state.StepStates["review"] = current
+	store.SaveRun(state)
+
+	tasks, _ = ListWaitingTasks(store, wf, runID)
+	if !tasks[0].Overdue || !tasks[0].Due.Equal(readyAt.Add(time.Hour)) {
+		t.Errorf("Expected the task to be overdue, got %+v", tasks[0])
+	}
+}
+
+func TestEscalateRunReassignsAndNotifies(t *testing.T) {
+	store := workflow.NewMemoryStore()
+
+	wf := deadlineWorkflow(workflow.Escalation{Role: "leads", Notify: "http://example.test/hook"})
+	runID := "test-run"
+	CreateRun(store, wf, runID, runID)
+	Tick(store, wf, runID)
+	ClaimTask(store, wf, runID, "review", "alice", 4*time.Hour)
+
+	var sent []Notification
+	notify := recordNotifications(&sent)
+	ctx := context.Background()
+
+	// Within the grace period nothing happens
+	escalated, err := EscalateRun(ctx, store, wf, runID, time.Now().Add(65*time.Minute), notify)
+	if err != nil || len(escalated) != 0 {
+		t.Fatalf("Expected no escalation during the grace period, got %v, %v", escalated, err)
+	}
+
+	escalated, err = EscalateRun(ctx, store, wf, runID, time.Now().Add(75*time.Minute), notify)
+	if err != nil || len(escalated) != 1 {
+		t.Fatalf("Expected the review to escalate, got %v, %v", escalated, err)
+	}
+	if len(sent) != 1 || sent[0].Step != "review" || sent[0].Assignee != "alice" {
+		t.Errorf("Expected a notification naming the assignee, got %+v", sent)
+	}
+
+	tasks, _ := ListWaitingTasks(store, wf, runID)
+	if tasks[0].Role != "leads" || tasks[0].Assignee != "" || tasks[0].ClaimedBy != "" || tasks[0].EscalatedAt == nil {
+		t.Errorf("Expected the review reassigned to leads, got %+v", tasks[0])
+	}
+	if _, err := CompleteStep(store, wf, runID, "review", "bob", Decision{Outcome: "approve"}, workflow.ArtifactMeta{}, nil); err != nil {
+		t.Errorf("Expected someone else to complete the reassigned review: %v", err)
+	}
+
+	// Steps escalate once
+	escalated, _ = EscalateRun(ctx, store, wf, runID, time.Now().Add(3*time.Hour), notify)
+	if len(escalated) != 0 || len(sent) != 1 {
+		t.Errorf("Expected no second escalation, got %v", escalated)
+	}
+}
+
+func TestEscalateRunCompletesWithOutcome(t *testing.T) {
+	store := workflow.NewMemoryStore()
+
+	wf := deadlineWorkflow(workflow.Escalation{Outcome: "approve"})
+	runID := "test-run"
+	CreateRun(store, wf, runID, runID)
+	Tick(store, wf, runID)
+
+	if _, err := EscalateRun(context.Background(), store, wf, runID, time.Now().Add(2*time.Hour), nil); err != nil {
+		t.Fatalf("EscalateRun failed: %v", err)
+	}
+
+	state, _ := store.LoadRun(runID)
+	review := state.StepStates["review"]
+	if review.Status != workflow.StatusSucceeded || review.Outcome != "approve" {
+		t.Fatalf("Expected the review approved by escalation, got %+v", review)
+	}
+	info, _ := store.Artifacts().Stat(runID, "notes")
+	if info.Provenance.Actor != EscalationActor {
+		t.Errorf("Expected the escalation as the actor, got %q", info.Provenance.Actor)
+	}
+}
+
+func TestEscalateRunRetriesFailedNotification(t *testing.T) {
+	store := workflow.NewMemoryStore()
+
+	wf := deadlineWorkflow(workflow.Escalation{Notify: "http://example.test/hook"})
+	runID := "test-run"
+	CreateRun(store, wf, runID, runID)
+	Tick(store, wf, runID)
+
+	failing := func(context.Context, string, Notification) error { return fmt.Errorf("unreachable") }
+	if _, err := EscalateRun(context.Background(), store, wf, runID, time.Now().Add(2*time.Hour), failing); err == nil {
+		t.Fatal("Expected the failed notification to be reported")
+	}
+
+	var sent []Notification
+	escalated, err := EscalateRun(context.Background(), store, wf, runID, time.Now().Add(2*time.Hour), recordNotifications(&sent))
+	if err != nil || len(escalated) != 1 || len(sent) != 1 {
+		t.Errorf("Expected the escalation to be retried, got %v, %v", escalated, err)
+	}
+}
+
+func TestEscalateRunKeepsCompletionDuringNotification(t *testing.T) {
+	store := workflow.NewMemoryStore()
+
+	wf := deadlineWorkflow(workflow.Escalation{Role: "leads", Notify: "http://example.test/hook"})
+	runID := "test-run"
+	CreateRun(store, wf, runID, runID)
+	Tick(store, wf, runID)
+
+	// Alice completes the review while the notification is being sent
+	notify := func(_ context.Context, _ string, _ Notification) error {
+		_, err := CompleteStep(store, wf, runID, "review", "alice", Decision{Outcome: "approve"}, workflow.ArtifactMeta{}, nil)
+		return err
+	}
+
+	escalated, err := EscalateRun(context.Background(), store, wf, runID, time.Now().Add(2*time.Hour), notify)
+	if err != nil {
+		t.Fatalf("EscalateRun failed: %v", err)
+	}
+	if len(escalated) != 0 {
+		t.Errorf("Expected the completed step not to escalate, got %v", escalated)
+	}
+
+	state, _ := store.LoadRun(runID)
+	review := state.StepStates["review"]
+	if review.Status != workflow.StatusSucceeded || review.Outcome != "approve" || review.Role != "" {
+		t.Errorf("Expected alice's completion to stand, got %+v", review)
+	}
+}
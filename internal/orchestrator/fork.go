@@ -0,0 +1,118 @@
+package orchestrator
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"composer/internal/workflow"
+)
+
+// ForkRun creates a new run from an existing one with the same parameters
+// and trigger. Steps upstream of fromStep keep their succeeded state, and
+// every artifact but the outputs of fromStep and everything downstream of it
+// is copied, including seed and uploaded artifacts; those steps start over
+// as pending. The new run records its parent.
+func ForkRun(store workflow.RunStore, wf *workflow.Workflow, sourceRunID, newRunID, fromStep string) (*workflow.RunState, error) {
+	if err := workflow.ValidateRunID(newRunID); err != nil {
+		return nil, err
+	}
+
+	source, err := store.LoadRun(sourceRunID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load source run: %w", err)
+	}
+
+	if source.WorkflowName != wf.ID {
+		return nil, fmt.Errorf("run '%s' belongs to workflow '%s', not '%s'", sourceRunID, source.WorkflowName, wf.ID)
+	}
+	if findStep(wf, fromStep) == nil {
+		return nil, fmt.Errorf("step %s not found in workflow", fromStep)
+	}
+	unlock := lockRun(newRunID)
+	defer unlock()
+	if _, err := store.LoadRun(newRunID); err == nil {
+		return nil, fmt.Errorf("run '%s' already exists", newRunID)
+	}
+
+	reset := make(map[string]bool)
+	for _, step := range downstreamSteps(wf, fromStep) {
+		reset[step.Name] = true
+	}
+
+	artifacts := store.Artifacts()
+	present, err := workflow.ArtifactNames(artifacts, sourceRunID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list artifacts: %w", err)
+	}
+
+	state := workflow.NewRunState(wf, newRunID, newRunID)
+	state.ParentRunID = sourceRunID
+	state.ForkedFromStep = fromStep
+	state.Params = maps.Clone(source.Params)
+	if source.Trigger != nil {
+		trigger := *source.Trigger
+		state.Trigger = &trigger
+	}
+
+	// Copy every current artifact except the outputs of reset steps, so
+	// seed and uploaded artifacts come along. Artifacts are written before
+	// the state so the run only appears once it is complete.
+	resetOutputs := make(map[string]bool)
+	for _, step := range wf.Steps {
+		if reset[step.Name] && step.Output != "" {
+			resetOutputs[step.Output] = true
+		}
+	}
+	names := slices.Sorted(maps.Keys(present))
+	for _, name := range names {
+		if resetOutputs[name] {
+			continue
+		}
+		if err := copyArtifact(artifacts, sourceRunID, newRunID, name); err != nil {
+			return nil, fmt.Errorf("failed to copy artifact %s: %w", name, err)
+		}
+	}
+
+	// Keep upstream results that succeeded in the source run, with their
+	// decisions so outcome gates downstream still hold
+	for _, step := range wf.Steps {
+		if reset[step.Name] || source.StepStates[step.Name].Status != workflow.StatusSucceeded {
+			continue
+		}
+		state.StepStates[step.Name] = workflow.StepState{
+			Status:  workflow.StatusSucceeded,
+			Attempt: source.StepStates[step.Name].Attempt,
+			Outcome: source.StepStates[step.Name].Outcome,
+			Comment: source.StepStates[step.Name].Comment,
+		}
+	}
+
+	// Loops with no reset steps keep their iterations
+	for _, loop := range wf.Loops {
+		if !slices.ContainsFunc(loop.Steps, func(name string) bool { return reset[name] }) {
+			state.SetLoop(loop.Name, source.Loop(loop.Name))
+		}
+	}
+
+	if err := store.SaveRun(state); err != nil {
+		return nil, fmt.Errorf("failed to save state: %w", err)
+	}
+
+	wakeRun(newRunID)
+	return state, nil
+}
+
+// copyArtifact copies a run's current artifact into another run. Identical
+// content is stored once, so the copy only adds a reference to it.
+func copyArtifact(artifacts workflow.ArtifactStore, fromRunID, toRunID, name string) error {
+	r, info, err := artifacts.Open(fromRunID, name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	meta := workflow.ArtifactMeta{ContentType: info.ContentType, Provenance: info.Provenance}
+	_, err = workflow.WriteArtifact(artifacts, toRunID, name, meta, r)
+	return err
+}
@@ -0,0 +1,142 @@
+package orchestrator
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"composer/internal/workflow"
+)
+
+func TestForkRun(t *testing.T) {
+	store := workflow.NewMemoryStore()
+
+	wf := rerunWorkflow()
+	CreateRun(store, wf, "source", "source")
+	for i := 0; i < 3; i++ {
+		Tick(store, wf, "source")
+	}
+
+	state, err := ForkRun(store, wf, "source", "fork", "process")
+	if err != nil {
+		t.Fatalf("ForkRun failed: %v", err)
+	}
+
+	if state.ParentRunID != "source" || state.ForkedFromStep != "process" {
+		t.Errorf("Expected parent source/process, got %s/%s", state.ParentRunID, state.ForkedFromStep)
+	}
+
+	// Reload to verify what was persisted
+	state, err = store.LoadRun("fork")
+	if err != nil {
+		t.Fatalf("Failed to load forked run: %v", err)
+	}
+
+	expected := map[string]workflow.StepStatus{
+		"fetch":   workflow.StatusSucceeded,
+		"other":   workflow.StatusSucceeded,
+		"process": workflow.StatusPending,
+		"combine": workflow.StatusPending,
+	}
+	for name, status := range expected {
+		if state.StepStates[name].Status != status {
+			t.Errorf("Step %s status is %s, expected %s", name, state.StepStates[name].Status, status)
+		}
+	}
+
+	content, err := readArtifact(store, "fork", "raw")
+	if err != nil || content != "data" {
+		t.Errorf("Expected copied artifact 'raw' to contain 'data', got %q, %v", content, err)
+	}
+	if hasArtifact(store, "fork", "processed") || hasArtifact(store, "fork", "combined") {
+		t.Error("Artifacts from the fork point onward should not be copied")
+	}
+
+	// The fork completes independently of its parent
+	Tick(store, wf, "fork")
+	complete, _ := Tick(store, wf, "fork")
+	if !complete {
+		t.Error("Forked run should complete after recomputing from the fork point")
+	}
+}
+
+func TestForkRunExistingTarget(t *testing.T) {
+	store := workflow.NewMemoryStore()
+
+	wf := rerunWorkflow()
+	CreateRun(store, wf, "source", "source")
+	CreateRun(store, wf, "taken", "taken")
+
+	if _, err := ForkRun(store, wf, "source", "taken", "process"); err == nil {
+		t.Error("Expected error forking into an existing run")
+	}
+	if _, err := ForkRun(store, wf, "source", "fork", "missing"); err == nil {
+		t.Error("Expected error forking from an unknown step")
+	}
+	for _, runID := range []string{"..", "a/b"} {
+		if _, err := ForkRun(store, wf, "source", runID, "process"); err == nil {
+			t.Errorf("Expected error forking into run ID %q", runID)
+		}
+	}
+}
+
+func TestForkRunKeepsParams(t *testing.T) {
+	store := workflow.NewMemoryStore()
+
+	wf := assignedWorkflow()
+	CreateRunWithParams(store, wf, "source", "source", map[string]string{"reviewer": "bob"})
+	Tick(store, wf, "source")
+
+	if _, err := ForkRun(store, wf, "source", "fork", "review"); err != nil {
+		t.Fatalf("ForkRun failed: %v", err)
+	}
+	Tick(store, wf, "fork")
+
+	tasks, err := ListWaitingTasks(store, wf, "fork")
+	if err != nil {
+		t.Fatalf("ListWaitingTasks failed: %v", err)
+	}
+	if len(tasks) != 2 || tasks[0].Assignee != "bob" {
+		t.Errorf("Expected the fork's review assigned to bob, got %+v", tasks)
+	}
+}
+
+func TestForkRunKeepsSeedArtifacts(t *testing.T) {
+	store := workflow.NewMemoryStore()
+
+	wf := &workflow.Workflow{
+		ID: "test",
+		Steps: []workflow.Step{
+			{Name: "summarize", Inputs: []string{"document"}, Output: "summary"},
+			{Name: "review", Handler: "human", Inputs: []string{"summary"}, Output: "reviewed"},
+		},
+	}
+	trigger := workflow.Trigger{Kind: workflow.TriggerWebhook, Name: "intake", At: time.Now().UTC()}
+	seeds := []Seed{{Name: "document", Content: strings.NewReader("report")}}
+	if _, err := CreateTriggeredRun(store, wf, "source", nil, trigger, seeds); err != nil {
+		t.Fatalf("CreateTriggeredRun failed: %v", err)
+	}
+	Tick(store, wf, "source")
+
+	state, err := ForkRun(store, wf, "source", "fork", "summarize")
+	if err != nil {
+		t.Fatalf("ForkRun failed: %v", err)
+	}
+	if state.Trigger == nil || state.Trigger.Name != "intake" {
+		t.Errorf("Expected the fork to keep the trigger, got %+v", state.Trigger)
+	}
+	if content, err := readArtifact(store, "fork", "document"); err != nil || content != "report" {
+		t.Errorf("Expected the seed artifact to be copied, got %q, %v", content, err)
+	}
+	if hasArtifact(store, "fork", "summary") {
+		t.Error("The reset step's output should not be copied")
+	}
+
+	// The fork recomputes from its seed
+	Tick(store, wf, "fork")
+	Tick(store, wf, "fork")
+	state, _ = store.LoadRun("fork")
+	if state.StepStates["summarize"].Status != workflow.StatusSucceeded || state.StepStates["review"].Status != workflow.StatusReady {
+		t.Errorf("Expected the fork to progress, got %+v", state.StepStates)
+	}
+}
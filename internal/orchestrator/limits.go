@@ -0,0 +1,170 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"composer/internal/workflow"
+)
+
+// Limits bounds how many steps execute concurrently across every run in this
+// process. Steps over a limit wait within the same tick for a free slot.
+type Limits struct {
+	// MaxConcurrency caps executing steps across all handlers (0 = unlimited)
+	MaxConcurrency int
+	// HandlerLimits caps executing steps per handler type (0 or missing = unlimited)
+	HandlerLimits map[string]int
+}
+
+// semaphore is a counting semaphore backed by a buffered channel
+type semaphore chan struct{}
+
+var (
+	limitsMu      sync.Mutex
+	globalSlots   semaphore
+	handlerSlots  map[string]semaphore
+	currentLimits Limits
+)
+
+// SetLimits replaces the process-wide concurrency limits. Steps already
+// holding a slot release it against the limits they acquired it under.
+func SetLimits(l Limits) {
+	limitsMu.Lock()
+	defer limitsMu.Unlock()
+
+	currentLimits = l
+	globalSlots = nil
+	if l.MaxConcurrency > 0 {
+		globalSlots = make(semaphore, l.MaxConcurrency)
+	}
+
+	handlerSlots = make(map[string]semaphore)
+	for handler, n := range l.HandlerLimits {
+		if n > 0 {
+			handlerSlots[handler] = make(semaphore, n)
+		}
+	}
+}
+
+// GetLimits returns the process-wide concurrency limits currently in effect
+func GetLimits() Limits {
+	limitsMu.Lock()
+	defer limitsMu.Unlock()
+	return currentLimits
+}
+
+// LimitsFromEnv reads concurrency limits from COMPOSER_MAX_CONCURRENCY (an
+// integer) and COMPOSER_HANDLER_LIMITS (comma separated handler=n pairs)
+func LimitsFromEnv() (Limits, error) {
+	var l Limits
+
+	if value := strings.TrimSpace(os.Getenv("COMPOSER_MAX_CONCURRENCY")); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return Limits{}, fmt.Errorf("invalid COMPOSER_MAX_CONCURRENCY '%s'", value)
+		}
+		l.MaxConcurrency = n
+	}
+
+	handlerLimits, err := ParseHandlerLimits(os.Getenv("COMPOSER_HANDLER_LIMITS"))
+	if err != nil {
+		return Limits{}, fmt.Errorf("invalid COMPOSER_HANDLER_LIMITS: %w", err)
+	}
+	l.HandlerLimits = handlerLimits
+
+	return l, nil
+}
+
+// ParseHandlerLimits parses a comma separated list of handler=n pairs
+// (e.g. "tool=4,human=1") into a map of handler limits
+func ParseHandlerLimits(spec string) (map[string]int, error) {
+	limits := make(map[string]int)
+
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		handler, value, ok := strings.Cut(pair, "=")
+		handler = strings.TrimSpace(handler)
+		if !ok || handler == "" {
+			return nil, fmt.Errorf("expected handler=n, got '%s'", pair)
+		}
+
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid limit for handler '%s': '%s'", handler, value)
+		}
+		limits[handler] = n
+	}
+
+	return limits, nil
+}
+
+// acquireSlots blocks until the step may execute under the global and
+// per-handler limits, or until ctx is cancelled. The returned function
+// releases the acquired slots.
+func acquireSlots(ctx context.Context, step workflow.Step) (func(), error) {
+	limitsMu.Lock()
+	global := globalSlots
+	handler := handlerSlots[step.HandlerType()]
+	limitsMu.Unlock()
+
+	// Acquire the handler slot first so a step blocked on its handler
+	// limit does not hold a global slot other handlers could use
+	if handler != nil {
+		select {
+		case handler <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if global != nil {
+		select {
+		case global <- struct{}{}:
+		case <-ctx.Done():
+			if handler != nil {
+				<-handler
+			}
+			return nil, ctx.Err()
+		}
+	}
+
+	return func() {
+		if global != nil {
+			<-global
+		}
+		if handler != nil {
+			<-handler
+		}
+	}, nil
+}
+
+// limitRunnableSteps applies the workflow's max_parallel setting, returning the
+// steps that may start this tick given how many of the run's steps are
+// already running. Human steps only change status and never count against
+// the limit. Remaining steps stay pending for a later tick.
+func limitRunnableSteps(wf *workflow.Workflow, steps []workflow.Step, running int) []workflow.Step {
+	if wf.MaxParallel <= 0 {
+		return steps
+	}
+
+	limited := make([]workflow.Step, 0, len(steps))
+	started := running
+	for _, step := range steps {
+		if step.HandlerType() != "human" {
+			if started >= wf.MaxParallel {
+				continue
+			}
+			started++
+		}
+		limited = append(limited, step)
+	}
+
+	return limited
+}
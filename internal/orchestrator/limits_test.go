@@ -0,0 +1,158 @@
+package orchestrator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"composer/internal/workflow"
+)
+
+func TestParseHandlerLimits(t *testing.T) {
+	limits, err := ParseHandlerLimits(" tool=4, human=1 ,")
+	if err != nil {
+		t.Fatalf("ParseHandlerLimits failed: %v", err)
+	}
+	if limits["tool"] != 4 || limits["human"] != 1 || len(limits) != 2 {
+		t.Errorf("Unexpected limits: %v", limits)
+	}
+
+	for _, spec := range []string{"tool", "=3", "tool=x", "tool=-1"} {
+		if _, err := ParseHandlerLimits(spec); err == nil {
+			t.Errorf("ParseHandlerLimits(%q) should fail", spec)
+		}
+	}
+}
+
+func TestLimitsFromEnv(t *testing.T) {
+	t.Setenv("COMPOSER_MAX_CONCURRENCY", "8")
+	t.Setenv("COMPOSER_HANDLER_LIMITS", "tool=2")
+
+	limits, err := LimitsFromEnv()
+	if err != nil {
+		t.Fatalf("LimitsFromEnv failed: %v", err)
+	}
+	if limits.MaxConcurrency != 8 {
+		t.Errorf("Expected MaxConcurrency 8, got %d", limits.MaxConcurrency)
+	}
+	if limits.HandlerLimits["tool"] != 2 {
+		t.Errorf("Expected tool limit 2, got %d", limits.HandlerLimits["tool"])
+	}
+
+	t.Setenv("COMPOSER_MAX_CONCURRENCY", "lots")
+	if _, err := LimitsFromEnv(); err == nil {
+		t.Error("Expected error for invalid COMPOSER_MAX_CONCURRENCY")
+	}
+}
+
+func TestAcquireSlotsBlocksAtLimit(t *testing.T) {
+	SetLimits(Limits{MaxConcurrency: 1})
+	defer SetLimits(Limits{})
+
+	ctx := context.Background()
+	step := workflow.Step{Name: "step"}
+	release, _ := acquireSlots(ctx, step)
+
+	acquired := make(chan func())
+	go func() {
+		releaseSecond, _ := acquireSlots(ctx, step)
+		acquired <- releaseSecond
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("Second step should wait while the only slot is held")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	release()
+
+	select {
+	case releaseSecond := <-acquired:
+		releaseSecond()
+	case <-time.After(time.Second):
+		t.Fatal("Second step should acquire the slot once it is released")
+	}
+}
+
+func TestAcquireSlotsHandlerLimit(t *testing.T) {
+	SetLimits(Limits{HandlerLimits: map[string]int{"tool": 1}})
+	defer SetLimits(Limits{})
+
+	ctx := context.Background()
+	release, _ := acquireSlots(ctx, workflow.Step{Name: "a"})
+	defer release()
+
+	// A different handler is unaffected by the tool limit
+	done := make(chan struct{})
+	go func() {
+		releaseOther, _ := acquireSlots(ctx, workflow.Step{Name: "b", Handler: "other"})
+		releaseOther()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Step with a different handler should not be blocked")
+	}
+}
+
+func TestAcquireSlotsCancelled(t *testing.T) {
+	SetLimits(Limits{MaxConcurrency: 1})
+	defer SetLimits(Limits{})
+
+	release, _ := acquireSlots(context.Background(), workflow.Step{Name: "a"})
+	defer release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := acquireSlots(ctx, workflow.Step{Name: "b"}); err == nil {
+		t.Error("Expected acquireSlots to fail once the context is cancelled")
+	}
+}
+
+func TestTickRespectsMaxParallel(t *testing.T) {
+	store := workflow.NewMemoryStore()
+
+	wf := &workflow.Workflow{
+		ID:          "test",
+		MaxParallel: 2,
+		Steps: []workflow.Step{
+			{Name: "a", Content: "a", Output: "out-a"},
+			{Name: "b", Content: "b", Output: "out-b"},
+			{Name: "review", Handler: "human", Content: "r", Output: "out-r"},
+			{Name: "c", Content: "c", Output: "out-c"},
+		},
+	}
+
+	runID := "test-run"
+	CreateRun(store, wf, runID, runID)
+
+	// First tick: only two tool steps start, the human step is not counted
+	if _, err := Tick(store, wf, runID); err != nil {
+		t.Fatalf("First tick failed: %v", err)
+	}
+
+	state, _ := store.LoadRun(runID)
+	if state.StepStates["a"].Status != workflow.StatusSucceeded || state.StepStates["b"].Status != workflow.StatusSucceeded {
+		t.Error("First two tool steps should be succeeded")
+	}
+	if state.StepStates["review"].Status != workflow.StatusReady {
+		t.Errorf("Human step should be ready, got %s", state.StepStates["review"].Status)
+	}
+	if state.StepStates["c"].Status != workflow.StatusPending {
+		t.Errorf("Step over the limit should stay pending, got %s", state.StepStates["c"].Status)
+	}
+
+	// Second tick: the remaining step runs
+	if _, err := Tick(store, wf, runID); err != nil {
+		t.Fatalf("Second tick failed: %v", err)
+	}
+
+	state, _ = store.LoadRun(runID)
+	if state.StepStates["c"].Status != workflow.StatusSucceeded {
+		t.Errorf("Step c should be succeeded after second tick, got %s", state.StepStates["c"].Status)
+	}
+}
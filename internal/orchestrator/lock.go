@@ -0,0 +1,87 @@
+package orchestrator
+
+import (
+	"sync"
+
+	"composer/internal/workflow"
+)
+
+// runLock serializes the changes made to one run
+type runLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+var (
+	runLocksMu sync.Mutex
+	runLocks   = make(map[string]*runLock)
+
+	runningMu sync.Mutex
+	// running holds the tool steps of each run a tick is executing
+	running = make(map[string]map[string]bool)
+)
+
+// lockRun takes the run's lock, held by every load-modify-save of its state
+// in this process so concurrent writers cannot overwrite each other's
+// changes. The returned function releases it.
+func lockRun(runID string) func() {
+	runLocksMu.Lock()
+	lock := runLocks[runID]
+	if lock == nil {
+		lock = &runLock{}
+		runLocks[runID] = lock
+	}
+	lock.refs++
+	runLocksMu.Unlock()
+
+	lock.mu.Lock()
+	return func() {
+		lock.mu.Unlock()
+
+		runLocksMu.Lock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(runLocks, runID)
+		}
+		runLocksMu.Unlock()
+	}
+}
+
+// markRunning records tool steps a tick executes outside the run lock, so
+// other ticks of the run leave them alone. The returned function clears
+// them.
+func markRunning(runID string, steps []workflow.Step) func() {
+	runningMu.Lock()
+	if running[runID] == nil {
+		running[runID] = make(map[string]bool)
+	}
+	for _, step := range steps {
+		running[runID][step.Name] = true
+	}
+	runningMu.Unlock()
+
+	return func() {
+		runningMu.Lock()
+		for _, step := range steps {
+			delete(running[runID], step.Name)
+		}
+		if len(running[runID]) == 0 {
+			delete(running, runID)
+		}
+		runningMu.Unlock()
+	}
+}
+
+// stepRunning reports whether a tick is executing the run's step
+func stepRunning(runID, stepName string) bool {
+	runningMu.Lock()
+	defer runningMu.Unlock()
+	return running[runID][stepName]
+}
+
+// runningCount returns how many of the run's steps ticks are executing
+func runningCount(runID string) int {
+	runningMu.Lock()
+	defer runningMu.Unlock()
+	return len(running[runID])
+}
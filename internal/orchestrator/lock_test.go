@@ -0,0 +1,72 @@
+package orchestrator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"composer/internal/workflow"
+)
+
+func TestCompleteStepDuringTick(t *testing.T) {
+	store := workflow.NewMemoryStore()
+
+	// Hold the only slot so the tick's tool step blocks while executing
+	SetLimits(Limits{MaxConcurrency: 1})
+	defer SetLimits(Limits{})
+	release, _ := acquireSlots(context.Background(), workflow.Step{Name: "holder"})
+
+	wf := &workflow.Workflow{
+		ID: "test",
+		Steps: []workflow.Step{
+			{Name: "review", Handler: "human", Content: "draft", Output: "reviewed"},
+			{Name: "slow", Content: "data", Output: "processed"},
+		},
+	}
+	runID := "test-run"
+	CreateRun(store, wf, runID, runID)
+
+	result := make(chan error)
+	go func() {
+		_, err := Tick(store, wf, runID)
+		result <- err
+	}()
+
+	// Wait for the tick to make the human step ready and start the tool step
+	deadline := time.Now().Add(time.Second)
+	for !stepRunning(runID, "slow") {
+		if time.Now().After(deadline) {
+			t.Fatal("Tick never started the tool step")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if _, err := CompleteStep(store, wf, runID, "review", "alice", Decision{}, workflow.ArtifactMeta{}, nil); err != nil {
+		t.Fatalf("CompleteStep failed: %v", err)
+	}
+
+	// Another tick leaves the running step alone
+	if _, err := Tick(store, wf, runID); err != nil {
+		t.Fatalf("Concurrent tick failed: %v", err)
+	}
+
+	release()
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Fatalf("Tick failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Tick did not finish")
+	}
+
+	state, _ := store.LoadRun(runID)
+	for _, name := range []string{"review", "slow"} {
+		if state.StepStates[name].Status != workflow.StatusSucceeded || state.StepStates[name].Attempt != 1 {
+			t.Errorf("Expected %s to succeed once, got %+v", name, state.StepStates[name])
+		}
+	}
+	if versions, _ := store.Artifacts().Versions(runID, "processed"); len(versions) != 1 {
+		t.Errorf("Expected the tool step to run once, got %d versions", len(versions))
+	}
+}
@@ -0,0 +1,221 @@
+package orchestrator
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"composer/internal/workflow"
+)
+
+// advanceLoops finishes the current iteration of every running loop whose
+// steps have all succeeded or been skipped. The iteration is recorded, then
+// the loop ends if its condition holds or it has no iterations left;
+// otherwise its steps return to pending for the next iteration. Their outputs
+// stay current, so the next iteration writes new versions of them. Reports
+// whether any loop advanced.
+func advanceLoops(wf *workflow.Workflow, artifacts workflow.ArtifactStore, state *workflow.RunState) (bool, error) {
+	advanced := false
+	for _, loop := range wf.Loops {
+		current := state.Loop(loop.Name)
+		if current.Status != workflow.LoopRunning || !iterationFinished(loop, state) {
+			continue
+		}
+
+		iteration, err := recordIteration(wf, artifacts, state, loop, current.Iteration)
+		if err != nil {
+			return false, err
+		}
+		current.Iterations = append(current.Iterations, iteration)
+
+		holds, err := loopConditionHolds(artifacts, state, loop)
+		if err != nil {
+			return false, err
+		}
+
+		switch {
+		case holds:
+			current.Status = workflow.LoopDone
+		case current.Iteration >= loop.Limit():
+			current.Status = workflow.LoopExhausted
+		default:
+			current.Iteration++
+			for _, name := range loop.Steps {
+				reset := state.StepStates[name].Unclaimed()
+				reset.Status = workflow.StatusPending
+				reset.CacheHit = false
+				state.StepStates[name] = reset
+			}
+		}
+
+		state.SetLoop(loop.Name, current)
+		advanced = true
+	}
+	return advanced, nil
+}
+
+// iterationFinished reports whether every step of the loop has succeeded or
+// been skipped
+func iterationFinished(loop workflow.Loop, state *workflow.RunState) bool {
+	for _, name := range loop.Steps {
+		switch state.StepStates[name].Status {
+		case workflow.StatusSucceeded, workflow.StatusSkipped:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// recordIteration captures the loop's step states and the artifact versions
+// its steps produced in the iteration
+func recordIteration(
+	wf *workflow.Workflow,
+	artifacts workflow.ArtifactStore,
+	state *workflow.RunState,
+	loop workflow.Loop,
+	number int,
+) (workflow.LoopIteration, error) {
+	iteration := workflow.LoopIteration{
+		Iteration: number,
+		Steps:     map[string]workflow.StepState{},
+	}
+
+	present, err := workflow.ArtifactNames(artifacts, state.ID)
+	if err != nil {
+		return iteration, fmt.Errorf("failed to list artifacts: %w", err)
+	}
+
+	for _, step := range wf.Steps {
+		if !loop.Has(step.Name) {
+			continue
+		}
+		stepState := state.StepStates[step.Name]
+		iteration.Steps[step.Name] = stepState
+
+		if stepState.Status != workflow.StatusSucceeded || step.Output == "" || !present[step.Output] {
+			continue
+		}
+		info, err := artifacts.Stat(state.ID, step.Output)
+		if err != nil {
+			return iteration, fmt.Errorf("failed to read artifact %s: %w", step.Output, err)
+		}
+		iteration.Artifacts = append(iteration.Artifacts, info.Ref())
+	}
+
+	return iteration, nil
+}
+
+// loopConditionHolds reports whether the loop's until condition is met by
+// the iteration that just finished
+func loopConditionHolds(artifacts workflow.ArtifactStore, state *workflow.RunState, loop workflow.Loop) (bool, error) {
+	until := loop.Until
+	if until.Step != "" {
+		decided := state.StepStates[until.Step]
+		return decided.Status == workflow.StatusSucceeded && decided.Outcome == until.Outcome, nil
+	}
+
+	present, err := workflow.ArtifactNames(artifacts, state.ID)
+	if err != nil {
+		return false, fmt.Errorf("failed to list artifacts: %w", err)
+	}
+	if !present[until.Artifact] {
+		return false, nil
+	}
+
+	data, err := workflow.ReadArtifact(artifacts, state.ID, until.Artifact)
+	if err != nil {
+		return false, err
+	}
+	return strings.Contains(string(data), until.Contains), nil
+}
+
+// waitsOnLoop reports whether a step must wait for a loop before it can run.
+// Steps consuming the output or outcome of a loop's steps wait until the
+// loop ends, and steps inside the loop wait for the loop steps they depend
+// on to finish the current iteration, since their outputs from the previous
+// iteration are still current.
+func waitsOnLoop(wf *workflow.Workflow, state *workflow.RunState, step workflow.Step) bool {
+	if len(wf.Loops) == 0 {
+		return false
+	}
+
+	dependencies := []string{}
+	for _, other := range wf.Steps {
+		if other.Output != "" && other.Name != step.Name && slices.Contains(step.Inputs, other.Output) {
+			dependencies = append(dependencies, other.Name)
+		}
+	}
+	if step.When != nil {
+		dependencies = append(dependencies, step.When.Step)
+	}
+
+	own := wf.LoopOf(step.Name)
+	for _, name := range dependencies {
+		loop := wf.LoopOf(name)
+		if loop == nil {
+			continue
+		}
+
+		if own != nil && own.Name == loop.Name {
+			switch state.StepStates[name].Status {
+			case workflow.StatusPending, workflow.StatusReady:
+				return true
+			}
+			continue
+		}
+		if state.Loop(loop.Name).Status == workflow.LoopRunning {
+			return true
+		}
+	}
+	return false
+}
+
+// reopenLoops returns finished loops that include any of the reset steps to
+// running, dropping their last recorded iteration so it is recorded again
+// when the reset steps finish
+func reopenLoops(wf *workflow.Workflow, state *workflow.RunState, reset []workflow.Step) {
+	for _, loop := range wf.Loops {
+		current := state.Loop(loop.Name)
+		touched := false
+		for _, step := range reset {
+			touched = touched || loop.Has(step.Name)
+		}
+		if !touched || current.Status == workflow.LoopRunning {
+			continue
+		}
+
+		if n := len(current.Iterations); n > 0 && current.Iterations[n-1].Iteration == current.Iteration {
+			current.Iterations = current.Iterations[:n-1]
+		}
+		current.Status = workflow.LoopRunning
+		state.SetLoop(loop.Name, current)
+	}
+}
+
+// previousOutput returns the output a loop step produced in an earlier
+// iteration, if it is still current
+func previousOutput(wf *workflow.Workflow, artifacts workflow.ArtifactStore, runID string, step workflow.Step) (workflow.ArtifactInfo, bool) {
+	if step.Output == "" || wf.LoopOf(step.Name) == nil {
+		return workflow.ArtifactInfo{}, false
+	}
+
+	info, err := artifacts.Stat(runID, step.Output)
+	if err != nil {
+		return workflow.ArtifactInfo{}, false
+	}
+	return info, true
+}
+
+// copyBlob streams the content of an artifact version into w
+func copyBlob(artifacts workflow.ArtifactStore, info workflow.ArtifactInfo, w io.Writer) error {
+	r, err := artifacts.OpenBlob(info.SHA256)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	_, err = io.Copy(w, r)
+	return err
+}
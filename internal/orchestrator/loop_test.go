@@ -0,0 +1,186 @@
+package orchestrator
+
+import (
+	"strings"
+	"testing"
+
+	"composer/internal/workflow"
+)
+
+// revisionWorkflow drafts and reviews a document in a loop of at most three
+// iterations, publishing the approved draft
+func revisionWorkflow() *workflow.Workflow {
+	return &workflow.Workflow{
+		ID: "test",
+		Steps: []workflow.Step{
+			{Name: "brief", Content: "brief", Output: "brief"},
+			{Name: "draft", Handler: "human", Inputs: []string{"brief"}, Output: "draft"},
+			{
+				Name:     "review",
+				Handler:  "human",
+				Inputs:   []string{"draft"},
+				Output:   "notes",
+				Outcomes: []string{"approve", "reject"},
+			},
+			{Name: "publish", Inputs: []string{"draft"}, Output: "published"},
+		},
+		Loops: []workflow.Loop{{
+			Name:          "revision",
+			Steps:         []string{"draft", "review"},
+			Until:         workflow.LoopCondition{Step: "review", Outcome: "approve"},
+			MaxIterations: 3,
+		}},
+	}
+}
+
+// completeWith completes a waiting step with the given content and outcome
+func completeWith(t *testing.T, store workflow.RunStore, wf *workflow.Workflow, runID, step, content, outcome string) *workflow.RunState {
+	t.Helper()
+	state, err := CompleteStep(store, wf, runID, step, "alice", Decision{Outcome: outcome}, workflow.ArtifactMeta{}, strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("CompleteStep %s failed: %v", step, err)
+	}
+	return state
+}
+
+func TestLoopRepeatsUntilApproved(t *testing.T) {
+	store := workflow.NewMemoryStore()
+
+	wf := revisionWorkflow()
+	runID := "test-run"
+	CreateRun(store, wf, runID, runID)
+	tickUntilIdle(store, wf, runID)
+
+	completeWith(t, store, wf, runID, "draft", "first draft", "")
+	tickUntilIdle(store, wf, runID)
+
+	// The draft from the last iteration is still current, but publish waits
+	// for the loop to end
+	state, _ := store.LoadRun(runID)
+	if state.StepStates["publish"].Status != workflow.StatusPending {
+		t.Fatalf("Expected publish to wait for the loop, got %s", state.StepStates["publish"].Status)
+	}
+
+	state = completeWith(t, store, wf, runID, "review", "too short", "reject")
+	loop := state.Loop("revision")
+	if loop.Iteration != 2 || len(loop.Iterations) != 1 {
+		t.Fatalf("Expected the second iteration after a rejection, got %+v", loop)
+	}
+	if state.StepStates["draft"].Status != workflow.StatusPending || state.StepStates["review"].Status != workflow.StatusPending {
+		t.Fatalf("Expected the loop steps to be pending, got %+v", state.StepStates)
+	}
+
+	// The review waits for the new draft even though the old one is current
+	tickUntilIdle(store, wf, runID)
+	tasks, _ := ListWaitingTasks(store, wf, runID)
+	if len(tasks) != 1 || tasks[0].Name != "draft" {
+		t.Fatalf("Expected only the draft to be waiting, got %+v", tasks)
+	}
+	if tasks[0].Prefill != "first draft" || tasks[0].Iteration != 2 || tasks[0].Loop != "revision" {
+		t.Errorf("Expected the redraft to start from the first draft, got %+v", tasks[0])
+	}
+	if len(tasks[0].Feedback) != 1 || tasks[0].Feedback[0].Outcome != "reject" {
+		t.Errorf("Expected the review's rejection as feedback, got %+v", tasks[0].Feedback)
+	}
+
+	completeWith(t, store, wf, runID, "draft", "second draft", "")
+	tickUntilIdle(store, wf, runID)
+	state = completeWith(t, store, wf, runID, "review", "great", "approve")
+
+	loop = state.Loop("revision")
+	if loop.Status != workflow.LoopDone || len(loop.Iterations) != 2 {
+		t.Fatalf("Expected the loop to be done after two iterations, got %+v", loop)
+	}
+	first := loop.Iterations[0]
+	if first.Steps["review"].Outcome != "reject" || len(first.Artifacts) != 2 || first.Artifacts[0].Version != 1 {
+		t.Errorf("Expected the first iteration's decision and versions, got %+v", first)
+	}
+
+	complete, err := Tick(store, wf, runID)
+	if err != nil {
+		t.Fatalf("Tick failed: %v", err)
+	}
+	published, _ := workflow.ReadArtifact(store.Artifacts(), runID, "published")
+	if !complete || string(published) != "second draft" {
+		t.Errorf("Expected the approved draft to be published, got %q", published)
+	}
+}
+
+func TestLoopStopsAtMaxIterations(t *testing.T) {
+	store := workflow.NewMemoryStore()
+
+	wf := revisionWorkflow()
+	runID := "test-run"
+	CreateRun(store, wf, runID, runID)
+
+	var state *workflow.RunState
+	for i := 0; i < 3; i++ {
+		tickUntilIdle(store, wf, runID)
+		completeWith(t, store, wf, runID, "draft", "draft", "")
+		tickUntilIdle(store, wf, runID)
+		state = completeWith(t, store, wf, runID, "review", "", "reject")
+	}
+
+	loop := state.Loop("revision")
+	if loop.Status != workflow.LoopExhausted || loop.Iteration != 3 || len(loop.Iterations) != 3 {
+		t.Fatalf("Expected the loop to stop after three iterations, got %+v", loop)
+	}
+
+	versions, _ := store.Artifacts().Versions(runID, "draft")
+	if len(versions) != 3 {
+		t.Errorf("Expected a draft version per iteration, got %d", len(versions))
+	}
+
+	// The run carries on with the last iteration's results
+	complete, _ := Tick(store, wf, runID)
+	if !complete || !hasArtifact(store, runID, "published") {
+		t.Error("Expected publish to run after the loop")
+	}
+}
+
+func TestLoopUntilArtifactContains(t *testing.T) {
+	store := workflow.NewMemoryStore()
+
+	wf := &workflow.Workflow{
+		ID: "test",
+		Steps: []workflow.Step{
+			{Name: "check", Content: "still failing", Output: "report"},
+		},
+		Loops: []workflow.Loop{{
+			Name:          "retry",
+			Steps:         []string{"check"},
+			Until:         workflow.LoopCondition{Artifact: "report", Contains: "PASS"},
+			MaxIterations: 2,
+		}},
+	}
+	runID := "test-run"
+	CreateRun(store, wf, runID, runID)
+
+	complete, _ := Tick(store, wf, runID)
+	if complete {
+		t.Fatal("Expected a second iteration after the condition failed")
+	}
+	complete, _ = Tick(store, wf, runID)
+
+	state, _ := store.LoadRun(runID)
+	if !complete || state.Loop("retry").Status != workflow.LoopExhausted {
+		t.Errorf("Expected the loop to be exhausted, got %+v", state.Loop("retry"))
+	}
+}
+
+func TestRerunReopensFinishedLoop(t *testing.T) {
+	wf := revisionWorkflow()
+	state := workflow.NewRunState(wf, "test-run", "")
+	state.SetLoop("revision", workflow.LoopState{
+		Status:     workflow.LoopDone,
+		Iteration:  2,
+		Iterations: []workflow.LoopIteration{{Iteration: 1}, {Iteration: 2}},
+	})
+
+	reopenLoops(wf, state, downstreamSteps(wf, "review"))
+
+	loop := state.Loop("revision")
+	if loop.Status != workflow.LoopRunning || loop.Iteration != 2 || len(loop.Iterations) != 1 {
+		t.Errorf("Expected the loop to redo its second iteration, got %+v", loop)
+	}
+}
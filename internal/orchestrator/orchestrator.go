@@ -1,9 +1,16 @@
 package orchestrator
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"strings"
 	"sync"
+	"time"
 
+	"composer/internal/cache"
 	"composer/internal/workflow"
 )
 
@@ -14,71 +21,183 @@ type WaitingTask struct {
 	Prompt      string
 	Inputs      []string
 	Output      string
+	// Prefill is the content the human's output starts from
+	Prefill string
+	// Form lists the fields the task is completed with, if any
+	Form []workflow.FormField
+	// Outcomes lists the decisions the task is completed with, if any
+	Outcomes []string
+	// Feedback holds the task's previous decision and those of reviews that
+	// looped back to it
+	Feedback []StepDecision
+	// Loop names the loop the task belongs to, if any, with its current
+	// iteration and iteration limit
+	Loop          string
+	Iteration     int
+	MaxIterations int
+	// Assignee, Assignees, and Role name who should complete the task, with
+	// run parameters filled in
+	Assignee  string
+	Assignees []string
+	Role      string
+	// ApprovalsRequired is how many assignees must approve the task, and
+	// Approvals the votes so far
+	ApprovalsRequired int
+	Approvals         []workflow.Approval
+	// ClaimedBy holds the task until ClaimExpires; both are empty when the
+	// task is unclaimed or its lease has expired
+	ClaimedBy    string
+	ClaimExpires *time.Time
+	// Due is when the task should be completed, if it has a deadline, and
+	// Overdue flags tasks still waiting past it
+	Due     *time.Time
+	Overdue bool
+	// EscalatedAt is when the overdue task was escalated, if it was
+	EscalatedAt *time.Time
+	// Comments are the comments left on the task, oldest first
+	Comments []workflow.Comment
 }
 
 // CreateRun initializes a new workflow run with the given id and display name
-func CreateRun(wf *workflow.Workflow, runID string, displayName string) error {
+func CreateRun(store workflow.RunStore, wf *workflow.Workflow, runID string, displayName string) error {
+	return CreateRunWithParams(store, wf, runID, displayName, nil)
+}
+
+// CreateRunWithParams initializes a new workflow run with the given
+// parameter values over the workflow's defaults. The run ID must be valid
+// for workflow.ValidateRunID.
+func CreateRunWithParams(
+	store workflow.RunStore,
+	wf *workflow.Workflow,
+	runID string,
+	displayName string,
+	values map[string]string,
+) error {
+	if err := workflow.ValidateRunID(runID); err != nil {
+		return err
+	}
+
+	params, err := wf.ResolveParams(values)
+	if err != nil {
+		return err
+	}
+
 	// Create initial state
 	state := workflow.NewRunState(wf, runID, displayName)
+	if len(params) > 0 {
+		state.Params = params
+	}
 
 	// Save the initial state
-	if err := state.Save(); err != nil {
+	if err := store.SaveRun(state); err != nil {
 		return fmt.Errorf("failed to save initial state: %w", err)
 	}
 
+	wakeRun(runID)
 	return nil
 }
 
-// Tick executes one tick of the workflow, running any steps that are ready
-func Tick(wf *workflow.Workflow, runID string) (bool, error) {
-	// Load current state
-	state, err := workflow.LoadState(runID)
+// Seed is an artifact a triggered run starts with
+type Seed struct {
+	Name        string
+	ContentType string
+	Content     io.Reader
+}
+
+// CreateTriggeredRun initializes a run started by a schedule or other
+// trigger, recording the trigger in its state and writing its seed
+// artifacts. Unlike CreateRun it refuses to replace an existing run.
+func CreateTriggeredRun(
+	store workflow.RunStore,
+	wf *workflow.Workflow,
+	runID string,
+	values map[string]string,
+	trigger workflow.Trigger,
+	seeds []Seed,
+) (*workflow.RunState, error) {
+	unlock := lockRun(runID)
+	defer unlock()
+
+	if _, err := store.LoadRun(runID); err == nil {
+		return nil, fmt.Errorf("run '%s' already exists", runID)
+	}
+
+	params, err := wf.ResolveParams(values)
 	if err != nil {
-		return false, fmt.Errorf("failed to load state: %w", err)
+		return nil, err
 	}
 
-	// Check if workflow is already complete
-	if state.AllStepsCompleted() {
-		return true, nil
+	state := workflow.NewRunState(wf, runID, runID)
+	if len(params) > 0 {
+		state.Params = params
+	}
+	state.Trigger = &trigger
+
+	// Seeds are written before the state so the run only appears, and
+	// ticks, once they are in place
+	for _, seed := range seeds {
+		meta := workflow.ArtifactMeta{
+			ContentType: seed.ContentType,
+			Provenance:  workflow.Provenance{Handler: trigger.Kind},
+		}
+		if _, err := workflow.WriteArtifact(store.Artifacts(), runID, seed.Name, meta, seed.Content); err != nil {
+			return nil, fmt.Errorf("failed to write seed artifact %s: %w", seed.Name, err)
+		}
+	}
+
+	if err := store.SaveRun(state); err != nil {
+		return nil, fmt.Errorf("failed to save initial state: %w", err)
 	}
 
-	// Find all runnable steps
-	runnableSteps := findRunnableSteps(wf, state)
+	wakeRun(runID)
+	return state, nil
+}
 
-	if len(runnableSteps) == 0 {
-		// No steps can run, but workflow isn't complete
-		// This could mean we're waiting for something or there's a deadlock
-		return false, nil
+// Tick executes one tick of the workflow, running any steps that are ready.
+// Paused runs ignore ticks. If the run is cancelled while steps are in
+// flight, their handlers are interrupted and unfinished steps are cancelled.
+// The run is locked while steps are picked and while their results are
+// recorded, but not while handlers execute, so humans can complete steps
+// meanwhile; steps another tick is executing are left alone. A failed step
+// stays pending to be retried until it has failed max_attempts times in a
+// row, when it is marked failed.
+func Tick(store workflow.RunStore, wf *workflow.Workflow, runID string) (bool, error) {
+	unlock := lockRun(runID)
+	state, steps, complete, err := startTick(store, wf, runID)
+	if err != nil || len(steps) == 0 {
+		unlock()
+		return complete, err
 	}
+	done := markRunning(runID, steps)
+	unlock()
+
+	// Register the tick so Cancel can interrupt in-flight handlers
+	ctx, unregister := registerTick(runID)
+	defer unregister()
 
 	// Execute runnable steps in parallel
+	artifacts := store.Artifacts()
 	var wg sync.WaitGroup
 	var mu sync.Mutex
 	errors := []error{}
+	results := map[string]workflow.StepState{}
+	failures := map[string]error{}
 
-	for _, step := range runnableSteps {
-		// Check if this is a human-handled step
-		handler := step.Handler
-		if handler == "" {
-			handler = "tool" // default to tool
-		}
+	for _, step := range steps {
+		// Number this execution after earlier attempts
+		attempt := state.StepStates[step.Name].Attempt + 1
 
-		if handler == "human" {
-			// Don't execute, just mark as ready
-			mu.Lock()
-			state.StepStates[step.Name] = workflow.StepState{
-				Status: workflow.StatusReady,
-			}
-			mu.Unlock()
-			fmt.Printf("Step '%s' is ready for human intervention\n", step.Name)
-			continue
-		}
-
-		// Execute tool steps
 		wg.Add(1)
-		go func(s workflow.Step) {
+		go func(s workflow.Step, attempt int) {
 			defer wg.Done()
 
+			// Wait for a free slot under the process-wide limits
+			release, err := acquireSlots(ctx, s)
+			if err != nil {
+				return
+			}
+			defer release()
+
 			// Print step execution info
 			fmt.Printf("Running step: %s\n", s.Name)
 			fmt.Printf("  Description: %s\n", s.Description)
@@ -88,78 +207,441 @@ func Tick(wf *workflow.Workflow, runID string) (bool, error) {
 			fmt.Printf("  Output: %s\n", s.Output)
 			fmt.Println()
 
-			// Prepare content for the artifact
-			var content string
-			if len(s.Inputs) > 0 {
-				// Load and concatenate input artifacts
-				artifacts, err := state.ReadArtifacts(s.Inputs)
-				if err != nil {
-					mu.Lock()
-					errors = append(errors, fmt.Errorf("failed to read input artifacts for %s: %w", s.Name, err))
-					mu.Unlock()
-					return
-				}
-
-				// Concatenate artifacts in order
-				for _, inputName := range s.Inputs {
-					content += artifacts[inputName]
-				}
-			} else {
-				// Use inline content for steps with no inputs
-				content = s.Content
+			// Stream the output artifact, from the cache when the step opted in
+			// and nothing changed, otherwise from the handler
+			cacheHit, err := writeStepOutput(ctx, wf, artifacts, runID, s, attempt)
+			if ctx.Err() != nil {
+				return
 			}
 
-			// Write output artifact
-			if err := state.WriteArtifact(s.Output, content); err != nil {
-				mu.Lock()
-				errors = append(errors, fmt.Errorf("failed to write artifact for %s: %w", s.Name, err))
-				mu.Unlock()
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				err = fmt.Errorf("failed to write artifact for %s: %w", s.Name, err)
+				errors = append(errors, err)
+				failures[s.Name] = err
 				return
 			}
 
-			// Update state with success
-			mu.Lock()
-			state.StepStates[s.Name] = workflow.StepState{
-				Status: workflow.StatusSucceeded,
+			results[s.Name] = workflow.StepState{
+				Status:   workflow.StatusSucceeded,
+				CacheHit: cacheHit,
+				Attempt:  attempt,
 			}
-			mu.Unlock()
-		}(step)
+		}(step, attempt)
 	}
 
 	// Wait for all steps to complete
 	wg.Wait()
 
+	// Record the results on the current state, which may have changed while
+	// the steps ran
+	unlock = lockRun(runID)
+	defer unlock()
+	defer done()
+
+	current, err := store.LoadRun(runID)
+	if err != nil {
+		return false, fmt.Errorf("failed to load state: %w", err)
+	}
+
+	// Honor a cancel that happened while steps were running
+	if ctx.Err() != nil {
+		current.Status = workflow.RunCancelled
+	}
+	if current.Status == workflow.RunCancelled {
+		current.CancelUnfinishedSteps()
+	} else {
+		// Steps reset or completed meanwhile keep their new state
+		unchanged := func(name string) bool {
+			now := current.StepStates[name]
+			return now.Status == workflow.StatusPending && now.Attempt == state.StepStates[name].Attempt
+		}
+		for name, result := range results {
+			if unchanged(name) {
+				current.StepStates[name] = result
+			}
+		}
+		for _, step := range steps {
+			if err, ok := failures[step.Name]; ok && unchanged(step.Name) {
+				failed := current.StepStates[step.Name]
+				failed.Failures++
+				failed.Error = err.Error()
+				if failed.Failures >= step.AttemptLimit() {
+					failed.Status = workflow.StatusFailed
+				}
+				current.StepStates[step.Name] = failed
+			}
+		}
+
+		// Finish loop iterations the steps just completed
+		if _, err := advanceLoops(wf, artifacts, current); err != nil {
+			return false, fmt.Errorf("failed to advance loops: %w", err)
+		}
+	}
+
+	// Save updated state
+	if err := store.SaveRun(current); err != nil {
+		return false, fmt.Errorf("failed to save state: %w", err)
+	}
+
 	// Check for errors
-	if len(errors) > 0 {
+	if len(errors) > 0 && current.Status != workflow.RunCancelled {
 		// For now, just return the first error
 		// In the future, we might want to handle multiple errors differently
 		return false, errors[0]
 	}
 
-	// Save updated state
-	if err := state.Save(); err != nil {
-		return false, fmt.Errorf("failed to save state: %w", err)
+	// Return whether workflow is complete
+	return current.AllStepsCompleted(), nil
+}
+
+// startTick loads the run, finishes loop iterations, skips unreachable steps
+// and marks runnable human steps ready, saving any change. It returns the
+// loaded state and the tool steps to execute, or, when there are none,
+// whether the run is complete. The caller holds the run lock.
+func startTick(store workflow.RunStore, wf *workflow.Workflow, runID string) (*workflow.RunState, []workflow.Step, bool, error) {
+	// Load current state
+	state, err := store.LoadRun(runID)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("failed to load state: %w", err)
 	}
 
-	// Return whether workflow is complete
-	return state.AllStepsCompleted(), nil
+	// Paused runs wait for resume, cancelled runs are finished
+	switch state.Status {
+	case workflow.RunPaused:
+		fmt.Printf("Run '%s' is paused\n", runID)
+		return state, nil, false, nil
+	case workflow.RunCancelled:
+		return state, nil, true, nil
+	}
+
+	// Finish loop iterations completed since the last tick
+	artifacts := store.Artifacts()
+	changed, err := advanceLoops(wf, artifacts, state)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("failed to advance loops: %w", err)
+	}
+
+	// Check if workflow is already complete
+	if state.AllStepsCompleted() {
+		if changed {
+			if err := store.SaveRun(state); err != nil {
+				return nil, nil, false, fmt.Errorf("failed to save state: %w", err)
+			}
+		}
+		return state, nil, true, nil
+	}
+
+	// Find all runnable steps, capped by the workflow's max_parallel
+	present, err := workflow.ArtifactNames(artifacts, runID)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("failed to list artifacts: %w", err)
+	}
+	if skipUnreachableSteps(wf, state) {
+		changed = true
+	}
+	runnableSteps := limitRunnableSteps(wf, findRunnableSteps(wf, state, present), runningCount(runID))
+
+	tools := []workflow.Step{}
+	for _, step := range runnableSteps {
+		if step.HandlerType() != "human" {
+			tools = append(tools, step)
+			continue
+		}
+
+		// Don't execute human steps, just mark them as ready
+		readyAt := time.Now().UTC()
+		ready := state.StepStates[step.Name]
+		ready.Status = workflow.StatusReady
+		ready.ReadyAt = &readyAt
+		ready.EscalatedAt = nil
+		ready.Role = ""
+		ready.Approvals = nil
+		state.StepStates[step.Name] = ready
+		changed = true
+		fmt.Printf("Step '%s' is ready for human intervention\n", step.Name)
+	}
+
+	// Save any loop iteration, skipped step, or step made ready
+	if changed {
+		if err := store.SaveRun(state); err != nil {
+			return nil, nil, false, fmt.Errorf("failed to save state: %w", err)
+		}
+	}
+
+	// No steps may be able to run, but the workflow may not be complete
+	// This could mean we're waiting for something or there's a deadlock
+	return state, tools, state.AllStepsCompleted(), nil
+}
+
+// statInputs returns the step's current input artifacts in declaration order
+func statInputs(artifacts workflow.ArtifactStore, runID string, step workflow.Step) ([]workflow.ArtifactInfo, error) {
+	inputs := make([]workflow.ArtifactInfo, 0, len(step.Inputs))
+	for _, inputName := range step.Inputs {
+		info, err := artifacts.Stat(runID, inputName)
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, info)
+	}
+
+	return inputs, nil
+}
+
+// stepProvenance records an execution of a step that consumed the given
+// input versions. The actor is empty for automated steps.
+func stepProvenance(step workflow.Step, attempt int, actor string, inputs []workflow.ArtifactInfo) workflow.Provenance {
+	refs := make([]workflow.ArtifactRef, len(inputs))
+	for i, input := range inputs {
+		refs[i] = input.Ref()
+	}
+
+	return workflow.Provenance{
+		Step:           step.Name,
+		Attempt:        attempt,
+		Handler:        step.HandlerType(),
+		HandlerVersion: step.DefinitionDigest(),
+		Actor:          actor,
+		Inputs:         refs,
+	}
+}
+
+// writeStepOutput streams a tool step's output artifact from the step cache
+// or its handler and commits it as a new version. The artifact is discarded
+// if the context is cancelled first. Reports whether the output came from the
+// cache.
+func writeStepOutput(
+	ctx context.Context,
+	wf *workflow.Workflow,
+	artifacts workflow.ArtifactStore,
+	runID string,
+	step workflow.Step,
+	attempt int,
+) (bool, error) {
+	inputs, err := statInputs(artifacts, runID, step)
+	if err != nil {
+		return false, fmt.Errorf("failed to read input artifacts: %w", err)
+	}
+
+	meta := workflow.ArtifactMeta{Provenance: stepProvenance(step, attempt, "", inputs)}
+	w, err := artifacts.Create(runID, step.Output, meta)
+	if err != nil {
+		return false, err
+	}
+
+	key := cacheKey(step, inputs)
+	cached, cacheHit := lookupCache(step, key)
+	if cacheHit {
+		_, err = io.Copy(w, cached)
+		cached.Close()
+	} else {
+		err = runTool(ctx, artifacts, step, inputs, w)
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		w.Abort()
+		return false, err
+	}
+
+	info, err := w.Commit()
+	if err != nil {
+		return false, err
+	}
+
+	if !cacheHit {
+		storeCache(wf, step, key, artifacts, info)
+	}
+	return cacheHit, nil
+}
+
+// runTool executes the built-in tool handler, streaming its output into w:
+// steps with inputs concatenate their input artifacts in order, steps without
+// inputs use their inline content
+func runTool(
+	ctx context.Context,
+	artifacts workflow.ArtifactStore,
+	step workflow.Step,
+	inputs []workflow.ArtifactInfo,
+	w io.Writer,
+) error {
+	if len(step.Inputs) == 0 {
+		_, err := io.WriteString(w, step.Content)
+		return err
+	}
+
+	for _, input := range inputs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		r, err := artifacts.OpenBlob(input.SHA256)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(w, r)
+		r.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// formContent validates submitted form values, read as a JSON object, and
+// returns them normalized as JSON. Without content, the fields' defaults are
+// submitted.
+func formContent(fields []workflow.FormField, content io.Reader) (io.Reader, error) {
+	values := map[string]any{}
+	if content != nil {
+		data, err := io.ReadAll(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read form values: %w", err)
+		}
+		values, err = workflow.DecodeForm(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	values, err := workflow.ValidateForm(fields, values)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(values, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode form values: %w", err)
+	}
+	return bytes.NewReader(data), nil
+}
+
+// writePrefill writes the content a human step's output starts from: the
+// handler's default output for "inputs", the step's inline content for
+// "content", and nothing for "none"
+func writePrefill(
+	ctx context.Context,
+	artifacts workflow.ArtifactStore,
+	step workflow.Step,
+	inputs []workflow.ArtifactInfo,
+	w io.Writer,
+) error {
+	switch step.PrefillMode() {
+	case "inputs":
+		return runTool(ctx, artifacts, step, inputs, w)
+	case "content":
+		_, err := io.WriteString(w, step.Content)
+		return err
+	case "none":
+		return nil
+	default:
+		return fmt.Errorf("unknown prefill '%s'", step.Prefill)
+	}
+}
+
+// writeStepPrefill writes a human step's prefill. A loop step starts from
+// its output of the previous iteration instead, unless its prefill is "none".
+func writeStepPrefill(
+	ctx context.Context,
+	wf *workflow.Workflow,
+	artifacts workflow.ArtifactStore,
+	runID string,
+	step workflow.Step,
+	inputs []workflow.ArtifactInfo,
+	w io.Writer,
+) error {
+	if previous, ok := previousOutput(wf, artifacts, runID, step); ok && step.PrefillMode() != "none" {
+		return copyBlob(artifacts, previous, w)
+	}
+	return writePrefill(ctx, artifacts, step, inputs, w)
+}
+
+// stepPrefill returns a step's prefill from the run's current artifacts
+func stepPrefill(wf *workflow.Workflow, artifacts workflow.ArtifactStore, runID string, step workflow.Step) (string, error) {
+	inputs, err := statInputs(artifacts, runID, step)
+	if err != nil {
+		return "", fmt.Errorf("failed to read input artifacts: %w", err)
+	}
+
+	var b strings.Builder
+	if err := writeStepPrefill(context.Background(), wf, artifacts, runID, step, inputs, &b); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// cacheKey returns the step cache key for a step that opted into caching
+func cacheKey(step workflow.Step, inputs []workflow.ArtifactInfo) string {
+	if !step.Cache {
+		return ""
+	}
+
+	digests := make([]string, len(inputs))
+	for i, input := range inputs {
+		digests[i] = input.SHA256
+	}
+	return cache.Key(step, digests)
+}
+
+// lookupCache opens the cached output for a step that opted into caching.
+// Cache failures are reported and treated as misses.
+func lookupCache(step workflow.Step, key string) (io.ReadCloser, bool) {
+	if !step.Cache {
+		return nil, false
+	}
+
+	cached, ok, err := cache.Lookup(key)
+	if err != nil {
+		fmt.Printf("Warning: step cache lookup failed for %s: %v\n", step.Name, err)
+		return nil, false
+	}
+	if ok {
+		fmt.Printf("Using cached output for step: %s\n", step.Name)
+	}
+	return cached, ok
+}
+
+// storeCache saves a step's committed output when it opted into caching.
+// Cache failures are reported but do not fail the step.
+func storeCache(wf *workflow.Workflow, step workflow.Step, key string, artifacts workflow.ArtifactStore, output workflow.ArtifactInfo) {
+	if !step.Cache {
+		return
+	}
+
+	r, err := artifacts.OpenBlob(output.SHA256)
+	if err == nil {
+		err = cache.Store(key, wf.ID, step.Name, r)
+		r.Close()
+	}
+	if err != nil {
+		fmt.Printf("Warning: failed to cache output for step %s: %v\n", step.Name, err)
+	}
 }
 
 // findRunnableSteps returns all steps that can be run based on current state
-func findRunnableSteps(wf *workflow.Workflow, state *workflow.RunState) []workflow.Step {
+// and the names of the artifacts present in the run
+func findRunnableSteps(wf *workflow.Workflow, state *workflow.RunState, present map[string]bool) []workflow.Step {
 	runnable := []workflow.Step{}
 
 	for _, step := range wf.Steps {
 		// Only consider pending steps (not ready, succeeded, or failed)
+		// that no tick is executing
 		stepState, exists := state.StepStates[step.Name]
-		if !exists || stepState.Status != workflow.StatusPending {
+		if !exists || stepState.Status != workflow.StatusPending || stepRunning(state.ID, step.Name) {
+			continue
+		}
+		if !gateOpen(step, state) || waitsOnLoop(wf, state, step) {
 			continue
 		}
 
 		// Check if all inputs are satisfied
 		canRun := true
 		for _, input := range step.Inputs {
-			if !state.HasArtifact(input) {
+			if !present[input] {
 				canRun = false
 				break
 			}
@@ -174,13 +656,18 @@ func findRunnableSteps(wf *workflow.Workflow, state *workflow.RunState) []workfl
 }
 
 // ListWaitingTasks returns all tasks that are ready for human intervention
-func ListWaitingTasks(wf *workflow.Workflow, runID string) ([]WaitingTask, error) {
+func ListWaitingTasks(store workflow.RunStore, wf *workflow.Workflow, runID string) ([]WaitingTask, error) {
 	// Load current state
-	state, err := workflow.LoadState(runID)
+	state, err := store.LoadRun(runID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load state: %w", err)
 	}
 
+	comments, err := store.Comments(runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load comments: %w", err)
+	}
+
 	tasks := []WaitingTask{}
 
 	// Find all steps with status "ready"
@@ -190,20 +677,64 @@ func ListWaitingTasks(wf *workflow.Workflow, runID string) ([]WaitingTask, error
 			continue
 		}
 
-		tasks = append(tasks, WaitingTask{
+		prefill := ""
+		if len(step.Form) == 0 {
+			prefill, err = stepPrefill(wf, store.Artifacts(), runID, step)
+			if err != nil {
+				return nil, fmt.Errorf("failed to prefill %s: %w", step.Name, err)
+			}
+		}
+
+		task := WaitingTask{
 			Name:        step.Name,
 			Description: step.Description,
 			Prompt:      step.Prompt,
 			Inputs:      step.Inputs,
 			Output:      step.Output,
-		})
+			Prefill:     prefill,
+			Form:        step.Form,
+			Outcomes:    step.Outcomes,
+			Feedback:    taskFeedback(wf, state, step),
+		}
+		task.Assignee, task.Role, err = stepAssignment(step, state)
+		if err != nil {
+			return nil, err
+		}
+		task.Assignees, err = stepAssignees(step, state)
+		if err != nil {
+			return nil, err
+		}
+		task.ApprovalsRequired = step.ApprovalsRequired
+		task.Approvals = stepState.Approvals
+		if claimant := stepState.Claimant(time.Now()); claimant != "" {
+			task.ClaimedBy = claimant
+			task.ClaimExpires = stepState.ClaimExpires
+		}
+		if stepState.ReadyAt != nil {
+			if due, ok := step.Deadline(*stepState.ReadyAt); ok {
+				task.Due = &due
+				task.Overdue = time.Now().After(due)
+			}
+		}
+		task.EscalatedAt = stepState.EscalatedAt
+		for _, c := range comments {
+			if c.Step == step.Name {
+				task.Comments = append(task.Comments, c)
+			}
+		}
+		if loop := wf.LoopOf(step.Name); loop != nil {
+			task.Loop = loop.Name
+			task.Iteration = state.Loop(loop.Name).Iteration
+			task.MaxIterations = loop.Limit()
+		}
+		tasks = append(tasks, task)
 	}
 
 	return tasks, nil
 }
 
 // ListWaitingTasksByRun returns waiting tasks grouped by run name.
-func ListWaitingTasksByRun(runs []workflow.RunState) (map[string][]WaitingTask, error) {
+func ListWaitingTasksByRun(store workflow.RunStore, runs []workflow.RunState) (map[string][]WaitingTask, error) {
 	tasksByRun := make(map[string][]WaitingTask, len(runs))
 	workflowCache := make(map[string]*workflow.Workflow)
 
@@ -222,7 +753,7 @@ func ListWaitingTasksByRun(runs []workflow.RunState) (map[string][]WaitingTask,
 			workflowCache[run.WorkflowName] = wf
 		}
 
-		tasks, err := ListWaitingTasks(wf, run.ID)
+		tasks, err := ListWaitingTasks(store, wf, run.ID)
 		if err != nil {
 			return nil, fmt.Errorf("list waiting tasks for run '%s': %w", run.ID, err)
 		}
@@ -232,72 +763,138 @@ func ListWaitingTasksByRun(runs []workflow.RunState) (map[string][]WaitingTask,
 	return tasksByRun, nil
 }
 
-// CompleteTask marks a ready task as complete and adds its output
-func CompleteTask(wf *workflow.Workflow, runID string, taskIndex int) error {
-	// Load current state
-	state, err := workflow.LoadState(runID)
+// CompleteStep marks a ready step as complete and writes its output,
+// recording the actor who completed it in the output's provenance. The
+// content is the human-supplied output, described by meta's content type;
+// when nil, the output is the step's prefill. For steps with a form, the
+// content is a JSON object of field values, validated against the form and
+// stored as JSON. Steps assigned or claimed by someone else are refused.
+// Steps with outcomes need the decision's outcome; one that loops back
+// resets the run to the loop's target step. On steps collecting approvals
+// the decision is the actor's vote, and the step stays ready until the votes
+// decide it; the deciding vote writes the output. Returns the updated state.
+func CompleteStep(
+	store workflow.RunStore,
+	wf *workflow.Workflow,
+	runID string,
+	stepName string,
+	actor string,
+	decision Decision,
+	meta workflow.ArtifactMeta,
+	content io.Reader,
+) (*workflow.RunState, error) {
+	unlock := lockRun(runID)
+	defer unlock()
+
+	state, step, err := loadWaitingStep(store, wf, runID, stepName)
 	if err != nil {
-		return fmt.Errorf("failed to load state: %w", err)
+		return nil, err
 	}
-
-	// Get list of waiting tasks
-	tasks, err := ListWaitingTasks(wf, runID)
-	if err != nil {
-		return fmt.Errorf("failed to list waiting tasks: %w", err)
+	if err := checkActor(*step, state, actor); err != nil {
+		return nil, err
 	}
 
-	// Validate task index
-	if taskIndex < 0 || taskIndex >= len(tasks) {
-		return fmt.Errorf("invalid task index: %d (must be between 0 and %d)", taskIndex, len(tasks)-1)
+	// Steps collecting approvals wait until the votes reach a decision
+	if step.ApprovalsRequired > 0 {
+		if err := step.CheckOutcome(decision.Outcome); err != nil {
+			return nil, err
+		}
+		outcome, err := recordApproval(state, *step, actor, decision)
+		if err != nil {
+			return nil, err
+		}
+		if outcome == "" {
+			if err := store.SaveRun(state); err != nil {
+				return nil, fmt.Errorf("failed to save state: %w", err)
+			}
+			return state, nil
+		}
+		decision.Outcome = outcome
 	}
 
-	// Get the task to complete
-	task := tasks[taskIndex]
+	return completeLoadedStep(store, wf, state, *step, actor, decision, meta, content)
+}
 
-	// Find the step in the workflow to get its inputs and content
-	var step *workflow.Step
-	for _, s := range wf.Steps {
-		if s.Name == task.Name {
-			step = &s
-			break
-		}
+// completeLoadedStep completes a ready step of a loaded run as CompleteStep
+// does, without checking who the actor is, and saves the run
+func completeLoadedStep(
+	store workflow.RunStore,
+	wf *workflow.Workflow,
+	state *workflow.RunState,
+	step workflow.Step,
+	actor string,
+	decision Decision,
+	meta workflow.ArtifactMeta,
+	content io.Reader,
+) (*workflow.RunState, error) {
+	if err := step.CheckOutcome(decision.Outcome); err != nil {
+		return nil, err
 	}
-	if step == nil {
-		return fmt.Errorf("step %s not found in workflow", task.Name)
+	if err := checkLoopBack(wf, step); err != nil {
+		return nil, err
 	}
 
-	// Prepare content for the artifact
-	var content string
-	if len(step.Inputs) > 0 {
-		// Load and concatenate input artifacts
-		artifacts, err := state.ReadArtifacts(step.Inputs)
+	// Form steps only accept values that satisfy the declared fields
+	if len(step.Form) > 0 {
+		var err error
+		content, err = formContent(step.Form, content)
 		if err != nil {
-			return fmt.Errorf("failed to read input artifacts: %w", err)
+			return nil, err
 		}
+		meta.ContentType = "application/json"
+	}
 
-		// Concatenate artifacts in order
-		for _, inputName := range step.Inputs {
-			content += artifacts[inputName]
-		}
+	// Write the output artifact from the supplied content or the handler
+	artifacts := store.Artifacts()
+	runID := state.ID
+	inputs, err := statInputs(artifacts, runID, step)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input artifacts: %w", err)
+	}
+
+	attempt := state.StepStates[step.Name].Attempt + 1
+	meta.Provenance = stepProvenance(step, attempt, actor, inputs)
+	w, err := artifacts.Create(runID, step.Output, meta)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write artifact: %w", err)
+	}
+	if content != nil {
+		_, err = io.Copy(w, content)
 	} else {
-		// Use inline content for steps with no inputs
-		content = step.Content
+		err = writeStepPrefill(context.Background(), wf, artifacts, runID, step, inputs, w)
+	}
+	if err != nil {
+		w.Abort()
+		return nil, fmt.Errorf("failed to write artifact: %w", err)
+	}
+	if _, err := w.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to write artifact: %w", err)
 	}
 
-	// Write output artifact
-	if err := state.WriteArtifact(step.Output, content); err != nil {
-		return fmt.Errorf("failed to write artifact: %w", err)
+	// Mark step as succeeded with its decision
+	state.StepStates[step.Name] = workflow.StepState{
+		Status:  workflow.StatusSucceeded,
+		Attempt: attempt,
+		Outcome: decision.Outcome,
+		Comment: decision.Comment,
+		Loops:   state.StepStates[step.Name].Loops,
+		// Keep the votes that decided the step
+		Approvals: state.StepStates[step.Name].Approvals,
 	}
 
-	// Mark step as succeeded
-	state.StepStates[task.Name] = workflow.StepState{
-		Status: workflow.StatusSucceeded,
+	// Send the run back for another round when the outcome loops
+	if err := loopBack(wf, artifacts, state, step); err != nil {
+		return nil, err
+	}
+	if _, err := advanceLoops(wf, artifacts, state); err != nil {
+		return nil, fmt.Errorf("failed to advance loops: %w", err)
 	}
 
 	// Save state
-	if err := state.Save(); err != nil {
-		return fmt.Errorf("failed to save state: %w", err)
+	if err := store.SaveRun(state); err != nil {
+		return nil, fmt.Errorf("failed to save state: %w", err)
 	}
 
-	return nil
+	wakeRun(runID)
+	return state, nil
 }
@@ -1,15 +1,30 @@
 package orchestrator
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"composer/internal/workflow"
 )
 
+// readArtifact returns a run's current artifact as a string
+func readArtifact(store workflow.RunStore, runID, name string) (string, error) {
+	data, err := workflow.ReadArtifact(store.Artifacts(), runID, name)
+	return string(data), err
+}
+
+// hasArtifact reports whether a run has a current artifact with the name
+func hasArtifact(store workflow.RunStore, runID, name string) bool {
+	_, err := store.Artifacts().Stat(runID, name)
+	return err == nil
+}
+
 func TestCreateRun(t *testing.T) {
-	tempDir := t.TempDir()
-	os.Chdir(tempDir)
+	store := workflow.NewMemoryStore()
 
 	wf := &workflow.Workflow{
 		ID: "test",
@@ -20,13 +35,13 @@ func TestCreateRun(t *testing.T) {
 	}
 
 	runID := "test-run"
-	err := CreateRun(wf, runID, runID)
+	err := CreateRun(store, wf, runID, runID)
 	if err != nil {
 		t.Fatalf("CreateRun failed: %v", err)
 	}
 
 	// Verify state was saved
-	state, err := workflow.LoadState(runID)
+	state, err := store.LoadRun(runID)
 	if err != nil {
 		t.Fatalf("Failed to load state: %v", err)
 	}
@@ -40,15 +55,28 @@ func TestCreateRun(t *testing.T) {
 	}
 
 	// No artifacts yet
-	artifacts := state.ListArtifacts()
+	artifacts, _ := store.Artifacts().List(runID)
 	if len(artifacts) != 0 {
 		t.Errorf("Expected 0 artifacts, got %d", len(artifacts))
 	}
 }
 
+func TestCreateRunInvalidID(t *testing.T) {
+	store := workflow.NewMemoryStore()
+	wf := &workflow.Workflow{
+		ID:    "test",
+		Steps: []workflow.Step{{Name: "step1", Content: "initial", Output: "out1"}},
+	}
+
+	for _, runID := range []string{"..", "a/b", `a\b`} {
+		if err := CreateRun(store, wf, runID, runID); err == nil {
+			t.Errorf("Expected error for run ID %q", runID)
+		}
+	}
+}
+
 func TestTickWithNoInputs(t *testing.T) {
-	tempDir := t.TempDir()
-	os.Chdir(tempDir)
+	store := workflow.NewMemoryStore()
 
 	wf := &workflow.Workflow{
 		ID: "test",
@@ -58,10 +86,10 @@ func TestTickWithNoInputs(t *testing.T) {
 	}
 
 	runID := "test-run"
-	CreateRun(wf, runID, runID)
+	CreateRun(store, wf, runID, runID)
 
 	// First tick should run the step with no inputs
-	complete, err := Tick(wf, runID)
+	complete, err := Tick(store, wf, runID)
 	if err != nil {
 		t.Fatalf("Tick failed: %v", err)
 	}
@@ -71,21 +99,21 @@ func TestTickWithNoInputs(t *testing.T) {
 	}
 
 	// Verify state
-	state, _ := workflow.LoadState(runID)
+	state, _ := store.LoadRun(runID)
 	if state.StepStates["start"].Status != workflow.StatusSucceeded {
 		t.Error("Start step should be succeeded")
 	}
 
 	// Reload state to access artifacts
-	state, _ = workflow.LoadState(runID)
+	state, _ = store.LoadRun(runID)
 
 	// Verify artifact was created
-	if !state.HasArtifact("started") {
+	if !hasArtifact(store, runID, "started") {
 		t.Error("Should have 'started' artifact")
 	}
 
 	// Verify artifact content
-	content, err := state.ReadArtifact("started")
+	content, err := readArtifact(store, runID, "started")
 	if err != nil {
 		t.Fatalf("Failed to read artifact: %v", err)
 	}
@@ -95,8 +123,7 @@ func TestTickWithNoInputs(t *testing.T) {
 }
 
 func TestTickWithDependencies(t *testing.T) {
-	tempDir := t.TempDir()
-	os.Chdir(tempDir)
+	store := workflow.NewMemoryStore()
 
 	wf := &workflow.Workflow{
 		ID: "test",
@@ -108,10 +135,10 @@ func TestTickWithDependencies(t *testing.T) {
 	}
 
 	runID := "test-run"
-	CreateRun(wf, runID, runID)
+	CreateRun(store, wf, runID, runID)
 
 	// First tick: step1 runs
-	complete, err := Tick(wf, runID)
+	complete, err := Tick(store, wf, runID)
 	if err != nil {
 		t.Fatalf("First tick failed: %v", err)
 	}
@@ -119,7 +146,7 @@ func TestTickWithDependencies(t *testing.T) {
 		t.Error("Workflow should not be complete after first tick")
 	}
 
-	state, _ := workflow.LoadState(runID)
+	state, _ := store.LoadRun(runID)
 	if state.StepStates["step1"].Status != workflow.StatusSucceeded {
 		t.Error("step1 should be succeeded")
 	}
@@ -128,16 +155,16 @@ func TestTickWithDependencies(t *testing.T) {
 	}
 
 	// Reload state to access artifacts
-	state, _ = workflow.LoadState(runID)
+	state, _ = store.LoadRun(runID)
 
 	// Verify artifact content is from step1
-	content, _ := state.ReadArtifact("out1")
+	content, _ := readArtifact(store, runID, "out1")
 	if content != "step1 content" {
 		t.Errorf("out1 should contain 'step1 content', got '%s'", content)
 	}
 
 	// Second tick: step2 runs
-	complete, err = Tick(wf, runID)
+	complete, err = Tick(store, wf, runID)
 	if err != nil {
 		t.Fatalf("Second tick failed: %v", err)
 	}
@@ -145,7 +172,7 @@ func TestTickWithDependencies(t *testing.T) {
 		t.Error("Workflow should not be complete after second tick")
 	}
 
-	state, _ = workflow.LoadState(runID)
+	state, _ = store.LoadRun(runID)
 	if state.StepStates["step2"].Status != workflow.StatusSucceeded {
 		t.Error("step2 should be succeeded")
 	}
@@ -154,16 +181,16 @@ func TestTickWithDependencies(t *testing.T) {
 	}
 
 	// Reload state to access artifacts
-	state, _ = workflow.LoadState(runID)
+	state, _ = store.LoadRun(runID)
 
 	// Verify artifact content is concatenated from step1
-	content, _ = state.ReadArtifact("out2")
+	content, _ = readArtifact(store, runID, "out2")
 	if content != "step1 content" {
 		t.Errorf("out2 should contain 'step1 content', got '%s'", content)
 	}
 
 	// Third tick: step3 runs
-	complete, err = Tick(wf, runID)
+	complete, err = Tick(store, wf, runID)
 	if err != nil {
 		t.Fatalf("Third tick failed: %v", err)
 	}
@@ -171,24 +198,23 @@ func TestTickWithDependencies(t *testing.T) {
 		t.Error("Workflow should be complete after third tick")
 	}
 
-	state, _ = workflow.LoadState(runID)
+	state, _ = store.LoadRun(runID)
 	if state.StepStates["step3"].Status != workflow.StatusSucceeded {
 		t.Error("step3 should be succeeded")
 	}
 
 	// Reload state to access artifacts
-	state, _ = workflow.LoadState(runID)
+	state, _ = store.LoadRun(runID)
 
 	// Verify final artifact content
-	content, _ = state.ReadArtifact("out3")
+	content, _ = readArtifact(store, runID, "out3")
 	if content != "step1 content" {
 		t.Errorf("out3 should contain 'step1 content', got '%s'", content)
 	}
 }
 
 func TestTickWithParallelSteps(t *testing.T) {
-	tempDir := t.TempDir()
-	os.Chdir(tempDir)
+	store := workflow.NewMemoryStore()
 
 	wf := &workflow.Workflow{
 		ID: "test",
@@ -201,10 +227,10 @@ func TestTickWithParallelSteps(t *testing.T) {
 	}
 
 	runID := "test-run"
-	CreateRun(wf, runID, runID)
+	CreateRun(store, wf, runID, runID)
 
 	// First tick: all parallel steps run
-	complete, err := Tick(wf, runID)
+	complete, err := Tick(store, wf, runID)
 	if err != nil {
 		t.Fatalf("First tick failed: %v", err)
 	}
@@ -212,7 +238,7 @@ func TestTickWithParallelSteps(t *testing.T) {
 		t.Error("Workflow should not be complete after first tick")
 	}
 
-	state, _ := workflow.LoadState(runID)
+	state, _ := store.LoadRun(runID)
 	if state.StepStates["parallel1"].Status != workflow.StatusSucceeded {
 		t.Error("parallel1 should be succeeded")
 	}
@@ -224,7 +250,7 @@ func TestTickWithParallelSteps(t *testing.T) {
 	}
 
 	// Second tick: combine step runs
-	complete, err = Tick(wf, runID)
+	complete, err = Tick(store, wf, runID)
 	if err != nil {
 		t.Fatalf("Second tick failed: %v", err)
 	}
@@ -232,15 +258,14 @@ func TestTickWithParallelSteps(t *testing.T) {
 		t.Error("Workflow should be complete after second tick")
 	}
 
-	state, _ = workflow.LoadState(runID)
+	state, _ = store.LoadRun(runID)
 	if state.StepStates["combine"].Status != workflow.StatusSucceeded {
 		t.Error("combine step should be succeeded")
 	}
 }
 
 func TestTickOnCompleteWorkflow(t *testing.T) {
-	tempDir := t.TempDir()
-	os.Chdir(tempDir)
+	store := workflow.NewMemoryStore()
 
 	wf := &workflow.Workflow{
 		ID: "test",
@@ -250,13 +275,13 @@ func TestTickOnCompleteWorkflow(t *testing.T) {
 	}
 
 	runID := "test-run"
-	CreateRun(wf, runID, runID)
+	CreateRun(store, wf, runID, runID)
 
 	// First tick completes the workflow
-	Tick(wf, runID)
+	Tick(store, wf, runID)
 
 	// Second tick on completed workflow should return true
-	complete, err := Tick(wf, runID)
+	complete, err := Tick(store, wf, runID)
 	if err != nil {
 		t.Fatalf("Tick on complete workflow failed: %v", err)
 	}
@@ -266,9 +291,6 @@ func TestTickOnCompleteWorkflow(t *testing.T) {
 }
 
 func TestFindRunnableSteps(t *testing.T) {
-	tempDir := t.TempDir()
-	os.Chdir(tempDir)
-
 	runID := "test-run"
 	wf := &workflow.Workflow{
 		Steps: []workflow.Step{
@@ -281,47 +303,45 @@ func TestFindRunnableSteps(t *testing.T) {
 
 	// Initial state: only step1 should be runnable
 	state := workflow.NewRunState(wf, runID, runID)
-	runnable := findRunnableSteps(wf, state)
+	present := map[string]bool{}
+	runnable := findRunnableSteps(wf, state, present)
 	if len(runnable) != 1 || runnable[0].Name != "step1" {
 		t.Errorf("Expected only step1 to be runnable, got %v", runnable)
 	}
 
 	// After step1 completes
 	state.StepStates["step1"] = workflow.StepState{Status: workflow.StatusSucceeded}
-	state.WriteArtifact("out1", "artifact1 content")
-	runnable = findRunnableSteps(wf, state)
+	present["out1"] = true
+	runnable = findRunnableSteps(wf, state, present)
 	if len(runnable) != 1 || runnable[0].Name != "step2" {
 		t.Errorf("Expected only step2 to be runnable, got %v", runnable)
 	}
 
 	// After step2 completes
 	state.StepStates["step2"] = workflow.StepState{Status: workflow.StatusSucceeded}
-	state.WriteArtifact("out2", "artifact2 content")
-	runnable = findRunnableSteps(wf, state)
+	present["out2"] = true
+	runnable = findRunnableSteps(wf, state, present)
 	if len(runnable) != 1 || runnable[0].Name != "step3" {
 		t.Errorf("Expected only step3 to be runnable, got %v", runnable)
 	}
 
 	// After step3 completes
 	state.StepStates["step3"] = workflow.StepState{Status: workflow.StatusSucceeded}
-	state.WriteArtifact("out3", "artifact3 content")
-	runnable = findRunnableSteps(wf, state)
+	present["out3"] = true
+	runnable = findRunnableSteps(wf, state, present)
 	if len(runnable) != 1 || runnable[0].Name != "step4" {
 		t.Errorf("Expected only step4 to be runnable, got %v", runnable)
 	}
 
 	// After all complete
 	state.StepStates["step4"] = workflow.StepState{Status: workflow.StatusSucceeded}
-	runnable = findRunnableSteps(wf, state)
+	runnable = findRunnableSteps(wf, state, present)
 	if len(runnable) != 0 {
 		t.Errorf("Expected no runnable steps, got %v", runnable)
 	}
 }
 
 func TestFindRunnableStepsWithMultipleInputs(t *testing.T) {
-	tempDir := t.TempDir()
-	os.Chdir(tempDir)
-
 	runID := "test-run"
 	wf := &workflow.Workflow{
 		Steps: []workflow.Step{
@@ -332,33 +352,33 @@ func TestFindRunnableStepsWithMultipleInputs(t *testing.T) {
 	}
 
 	state := workflow.NewRunState(wf, runID, runID)
+	present := map[string]bool{}
 
 	// Both a and b should be runnable
-	runnable := findRunnableSteps(wf, state)
+	runnable := findRunnableSteps(wf, state, present)
 	if len(runnable) != 2 {
 		t.Errorf("Expected 2 runnable steps, got %d", len(runnable))
 	}
 
 	// After only a completes, c should not be runnable
 	state.StepStates["a"] = workflow.StepState{Status: workflow.StatusSucceeded}
-	state.WriteArtifact("out_a", "artifact a")
-	runnable = findRunnableSteps(wf, state)
+	present["out_a"] = true
+	runnable = findRunnableSteps(wf, state, present)
 	if len(runnable) != 1 || runnable[0].Name != "b" {
 		t.Errorf("Expected only b to be runnable, got %v", runnable)
 	}
 
 	// After b completes, c should be runnable
 	state.StepStates["b"] = workflow.StepState{Status: workflow.StatusSucceeded}
-	state.WriteArtifact("out_b", "artifact b")
-	runnable = findRunnableSteps(wf, state)
+	present["out_b"] = true
+	runnable = findRunnableSteps(wf, state, present)
 	if len(runnable) != 1 || runnable[0].Name != "c" {
 		t.Errorf("Expected only c to be runnable, got %v", runnable)
 	}
 }
 
 func TestHumanHandlerStep(t *testing.T) {
-	tempDir := t.TempDir()
-	os.Chdir(tempDir)
+	store := workflow.NewMemoryStore()
 
 	wf := &workflow.Workflow{
 		ID: "test",
@@ -369,10 +389,10 @@ func TestHumanHandlerStep(t *testing.T) {
 	}
 
 	runID := "test-run"
-	CreateRun(wf, runID, runID)
+	CreateRun(store, wf, runID, runID)
 
 	// First tick: automated step runs
-	complete, err := Tick(wf, runID)
+	complete, err := Tick(store, wf, runID)
 	if err != nil {
 		t.Fatalf("First tick failed: %v", err)
 	}
@@ -380,13 +400,13 @@ func TestHumanHandlerStep(t *testing.T) {
 		t.Error("Workflow should not be complete after first tick")
 	}
 
-	state, _ := workflow.LoadState(runID)
+	state, _ := store.LoadRun(runID)
 	if state.StepStates["automated"].Status != workflow.StatusSucceeded {
 		t.Error("Automated step should be succeeded")
 	}
 
 	// Second tick: human step should transition to ready, not succeed
-	complete, err = Tick(wf, runID)
+	complete, err = Tick(store, wf, runID)
 	if err != nil {
 		t.Fatalf("Second tick failed: %v", err)
 	}
@@ -394,20 +414,19 @@ func TestHumanHandlerStep(t *testing.T) {
 		t.Error("Workflow should not be complete with ready task")
 	}
 
-	state, _ = workflow.LoadState(runID)
+	state, _ = store.LoadRun(runID)
 	if state.StepStates["manual"].Status != workflow.StatusReady {
 		t.Errorf("Manual step should be ready, got %s", state.StepStates["manual"].Status)
 	}
 
 	// Manual step artifact should not exist yet
-	if state.HasArtifact("manual-out") {
+	if hasArtifact(store, runID, "manual-out") {
 		t.Error("Manual step artifact should not exist until completed")
 	}
 }
 
 func TestListWaitingTasks(t *testing.T) {
-	tempDir := t.TempDir()
-	os.Chdir(tempDir)
+	store := workflow.NewMemoryStore()
 
 	wf := &workflow.Workflow{
 		ID: "test",
@@ -420,10 +439,10 @@ func TestListWaitingTasks(t *testing.T) {
 	}
 
 	runID := "test-run"
-	CreateRun(wf, runID, runID)
+	CreateRun(store, wf, runID, runID)
 
 	// Initially no tasks waiting
-	tasks, err := ListWaitingTasks(wf, runID)
+	tasks, err := ListWaitingTasks(store, wf, runID)
 	if err != nil {
 		t.Fatalf("ListWaitingTasks failed: %v", err)
 	}
@@ -432,12 +451,12 @@ func TestListWaitingTasks(t *testing.T) {
 	}
 
 	// After first tick, auto1 completes
-	Tick(wf, runID)
+	Tick(store, wf, runID)
 
 	// After second tick, both manual tasks should be ready
-	Tick(wf, runID)
+	Tick(store, wf, runID)
 
-	tasks, err = ListWaitingTasks(wf, runID)
+	tasks, err = ListWaitingTasks(store, wf, runID)
 	if err != nil {
 		t.Fatalf("ListWaitingTasks failed: %v", err)
 	}
@@ -473,9 +492,8 @@ func TestListWaitingTasks(t *testing.T) {
 	}
 }
 
-func TestCompleteTask(t *testing.T) {
-	tempDir := t.TempDir()
-	os.Chdir(tempDir)
+func TestCompleteStepWithPrefill(t *testing.T) {
+	store := workflow.NewMemoryStore()
 
 	wf := &workflow.Workflow{
 		ID: "test",
@@ -488,21 +506,20 @@ func TestCompleteTask(t *testing.T) {
 	}
 
 	runID := "test-run"
-	CreateRun(wf, runID, runID)
+	CreateRun(store, wf, runID, runID)
 
 	// Run until both manual tasks are ready
-	Tick(wf, runID) // auto completes
-	Tick(wf, runID) // manual tasks become ready
+	Tick(store, wf, runID) // auto completes
+	Tick(store, wf, runID) // manual tasks become ready
 
-	// Complete task at index 0
-	err := CompleteTask(wf, runID, 0)
-	if err != nil {
-		t.Fatalf("CompleteTask failed: %v", err)
+	// Complete the first task with its prefill
+	if _, err := CompleteStep(store, wf, runID, "manual1", "tester", Decision{}, workflow.ArtifactMeta{}, nil); err != nil {
+		t.Fatalf("CompleteStep failed: %v", err)
 	}
 
 	// Verify the task is now succeeded
-	state, _ := workflow.LoadState(runID)
-	tasks, _ := ListWaitingTasks(wf, runID)
+	state, _ := store.LoadRun(runID)
+	tasks, _ := ListWaitingTasks(store, wf, runID)
 
 	// One task should still be waiting
 	if len(tasks) != 1 {
@@ -520,11 +537,11 @@ func TestCompleteTask(t *testing.T) {
 			if name == "manual2" {
 				expectedOutput = "out3"
 			}
-			if !state.HasArtifact(expectedOutput) {
+			if !hasArtifact(store, runID, expectedOutput) {
 				t.Errorf("Should have artifact %s after completing %s", expectedOutput, name)
 			}
 			// Verify artifact content is from auto step
-			content, _ := state.ReadArtifact(expectedOutput)
+			content, _ := readArtifact(store, runID, expectedOutput)
 			if content != "auto data" {
 				t.Errorf("Artifact %s should contain 'auto data', got '%s'", expectedOutput, content)
 			}
@@ -536,31 +553,188 @@ func TestCompleteTask(t *testing.T) {
 	}
 }
 
-func TestCompleteTaskInvalidIndex(t *testing.T) {
-	tempDir := t.TempDir()
-	os.Chdir(tempDir)
+func TestStepOutputProvenance(t *testing.T) {
+	store := workflow.NewMemoryStore()
+
+	wf := &workflow.Workflow{
+		ID: "test",
+		Steps: []workflow.Step{
+			{Name: "auto", Content: "auto data", Output: "out1"},
+			{Name: "review", Handler: "human", Inputs: []string{"out1"}, Output: "out2"},
+			{Name: "final", Inputs: []string{"out2"}, Output: "out3"},
+		},
+	}
+
+	runID := "test-run"
+	CreateRun(store, wf, runID, runID)
+	Tick(store, wf, runID)
+	Tick(store, wf, runID)
+	if _, err := CompleteStep(store, wf, runID, "review", "alice", Decision{}, workflow.ArtifactMeta{}, nil); err != nil {
+		t.Fatalf("CompleteStep failed: %v", err)
+	}
+	Tick(store, wf, runID)
+
+	artifacts := store.Artifacts()
+	out1, _ := artifacts.Stat(runID, "out1")
+	reviewed, _ := artifacts.Stat(runID, "out2")
+	if reviewed.Handler != "human" || reviewed.Actor != "alice" || reviewed.Attempt != 1 {
+		t.Errorf("Expected review by alice on attempt 1, got %+v", reviewed.Provenance)
+	}
+	if reviewed.HandlerVersion != wf.Steps[1].DefinitionDigest() {
+		t.Errorf("Expected handler version %s, got %s", wf.Steps[1].DefinitionDigest(), reviewed.HandlerVersion)
+	}
+	if len(reviewed.Inputs) != 1 || reviewed.Inputs[0] != out1.Ref() {
+		t.Errorf("Expected out2 to record input %+v, got %+v", out1.Ref(), reviewed.Inputs)
+	}
+
+	// The lineage of the final output reaches the inline content of auto
+	node, err := workflow.Lineage(artifacts, runID, "out3", 0)
+	if err != nil {
+		t.Fatalf("Lineage failed: %v", err)
+	}
+	chain := []string{}
+	for current := node; ; current = &current.Sources[0] {
+		chain = append(chain, current.Artifact.Step)
+		if len(current.Sources) == 0 {
+			break
+		}
+	}
+	if strings.Join(chain, ",") != "final,review,auto" {
+		t.Errorf("Expected lineage final,review,auto, got %v", chain)
+	}
+}
+
+func TestCompleteStepWithContent(t *testing.T) {
+	store := workflow.NewMemoryStore()
+
+	wf := &workflow.Workflow{
+		ID: "test",
+		Steps: []workflow.Step{
+			{Name: "draft", Content: "draft", Output: "draft"},
+			{Name: "review", Handler: "human", Inputs: []string{"draft"}, Output: "reviewed"},
+		},
+	}
+
+	runID := "test-run"
+	CreateRun(store, wf, runID, runID)
+
+	// Steps that are not waiting for a human cannot be completed
+	if _, err := CompleteStep(store, wf, runID, "review", "alice", Decision{}, workflow.ArtifactMeta{}, nil); err == nil {
+		t.Error("Expected error completing a pending step")
+	}
+
+	Tick(store, wf, runID)
+	Tick(store, wf, runID)
+
+	meta := workflow.ArtifactMeta{ContentType: "text/markdown"}
+	state, err := CompleteStep(store, wf, runID, "review", "alice", Decision{}, meta, strings.NewReader("# Approved"))
+	if err != nil {
+		t.Fatalf("CompleteStep failed: %v", err)
+	}
+	if state.StepStates["review"].Status != workflow.StatusSucceeded {
+		t.Errorf("Expected review to succeed, got %s", state.StepStates["review"].Status)
+	}
+
+	info, _ := store.Artifacts().Stat(runID, "reviewed")
+	if info.ContentType != "text/markdown" || info.Actor != "alice" || len(info.Inputs) != 1 {
+		t.Errorf("Expected markdown from alice recording its input, got %+v", info)
+	}
+	if content, _ := readArtifact(store, runID, "reviewed"); content != "# Approved" {
+		t.Errorf("Expected supplied content, got %q", content)
+	}
+}
+
+func TestWaitingTaskPrefill(t *testing.T) {
+	store := workflow.NewMemoryStore()
 
 	wf := &workflow.Workflow{
 		ID: "test",
 		Steps: []workflow.Step{
-			{Name: "manual", Handler: "human", Prompt: "Task", Content: "data", Output: "out"},
+			{Name: "draft", Content: "draft", Output: "draft"},
+			{Name: "edit", Handler: "human", Inputs: []string{"draft"}, Output: "edited"},
+			{Name: "template", Handler: "human", Inputs: []string{"draft"}, Content: "## Notes", Prefill: "content", Output: "notes"},
+			{Name: "blank", Handler: "human", Inputs: []string{"draft"}, Prefill: "none", Output: "blank"},
 		},
 	}
 
 	runID := "test-run"
-	CreateRun(wf, runID, runID)
-	Tick(wf, runID) // Make manual task ready
+	CreateRun(store, wf, runID, runID)
+	Tick(store, wf, runID)
+	Tick(store, wf, runID)
+
+	tasks, err := ListWaitingTasks(store, wf, runID)
+	if err != nil {
+		t.Fatalf("ListWaitingTasks failed: %v", err)
+	}
+
+	want := map[string]string{"edit": "draft", "template": "## Notes", "blank": ""}
+	if len(tasks) != len(want) {
+		t.Fatalf("Expected %d tasks, got %d", len(want), len(tasks))
+	}
+	for _, task := range tasks {
+		if task.Prefill != want[task.Name] {
+			t.Errorf("Expected %s prefill %q, got %q", task.Name, want[task.Name], task.Prefill)
+		}
+	}
 
-	// Try to complete with invalid index
-	err := CompleteTask(wf, runID, 5)
-	if err == nil {
-		t.Error("Expected error for invalid task index")
+	// Completing without content writes the prefill
+	if _, err := CompleteStep(store, wf, runID, "template", "alice", Decision{}, workflow.ArtifactMeta{}, nil); err != nil {
+		t.Fatalf("CompleteStep failed: %v", err)
+	}
+	if content, _ := readArtifact(store, runID, "notes"); content != "## Notes" {
+		t.Errorf("Expected prefilled content, got %q", content)
+	}
+}
+
+func TestCompleteStepWithForm(t *testing.T) {
+	store := workflow.NewMemoryStore()
+
+	wf := &workflow.Workflow{
+		ID: "test",
+		Steps: []workflow.Step{
+			{Name: "review", Handler: "human", Output: "review", Form: []workflow.FormField{
+				{Name: "verdict", Type: "select", Options: []string{"approve", "reject"}, Required: true},
+				{Name: "notes", Type: "textarea"},
+			}},
+		},
+	}
+
+	runID := "test-run"
+	CreateRun(store, wf, runID, runID)
+	Tick(store, wf, runID)
+
+	tasks, _ := ListWaitingTasks(store, wf, runID)
+	if len(tasks) != 1 || len(tasks[0].Form) != 2 {
+		t.Fatalf("Expected the task to carry its form, got %+v", tasks)
+	}
+
+	// Invalid values are rejected and leave the task waiting
+	_, err := CompleteStep(store, wf, runID, "review", "alice", Decision{}, workflow.ArtifactMeta{}, strings.NewReader(`{"verdict": "maybe"}`))
+	var formErrs workflow.FormErrors
+	if !errors.As(err, &formErrs) {
+		t.Fatalf("Expected form errors, got %v", err)
+	}
+
+	state, err := CompleteStep(store, wf, runID, "review", "alice", Decision{}, workflow.ArtifactMeta{}, strings.NewReader(`{"verdict": "approve"}`))
+	if err != nil {
+		t.Fatalf("CompleteStep failed: %v", err)
+	}
+	if state.StepStates["review"].Status != workflow.StatusSucceeded {
+		t.Errorf("Expected review to succeed, got %s", state.StepStates["review"].Status)
+	}
+
+	info, _ := store.Artifacts().Stat(runID, "review")
+	if info.ContentType != "application/json" {
+		t.Errorf("Expected a JSON artifact, got %s", info.ContentType)
+	}
+	content, _ := readArtifact(store, runID, "review")
+	if content != "{\n  \"verdict\": \"approve\"\n}" {
+		t.Errorf("Expected the validated values, got %q", content)
 	}
 }
 
 func TestMixedHandlerWorkflow(t *testing.T) {
-	tempDir := t.TempDir()
-	os.Chdir(tempDir)
+	store := workflow.NewMemoryStore()
 
 	wf := &workflow.Workflow{
 		ID: "test",
@@ -572,26 +746,26 @@ func TestMixedHandlerWorkflow(t *testing.T) {
 	}
 
 	runID := "test-run"
-	CreateRun(wf, runID, runID)
+	CreateRun(store, wf, runID, runID)
 
 	// First tick: fetch runs and completes
-	complete, _ := Tick(wf, runID)
+	complete, _ := Tick(store, wf, runID)
 	if complete {
 		t.Error("Should not be complete after first tick")
 	}
 
-	state, _ := workflow.LoadState(runID)
+	state, _ := store.LoadRun(runID)
 	if state.StepStates["fetch"].Status != workflow.StatusSucceeded {
 		t.Error("fetch should be succeeded")
 	}
 
 	// Second tick: review becomes ready
-	complete, _ = Tick(wf, runID)
+	complete, _ = Tick(store, wf, runID)
 	if complete {
 		t.Error("Should not be complete with ready task")
 	}
 
-	state, _ = workflow.LoadState(runID)
+	state, _ = store.LoadRun(runID)
 	if state.StepStates["review"].Status != workflow.StatusReady {
 		t.Error("review should be ready")
 	}
@@ -600,23 +774,22 @@ func TestMixedHandlerWorkflow(t *testing.T) {
 	}
 
 	// Complete the review task
-	CompleteTask(wf, runID, 0)
+	CompleteStep(store, wf, runID, "review", "tester", Decision{}, workflow.ArtifactMeta{}, nil)
 
 	// Third tick: process runs
-	complete, _ = Tick(wf, runID)
+	complete, _ = Tick(store, wf, runID)
 	if !complete {
 		t.Error("Should be complete after processing")
 	}
 
-	state, _ = workflow.LoadState(runID)
+	state, _ = store.LoadRun(runID)
 	if state.StepStates["process"].Status != workflow.StatusSucceeded {
 		t.Error("process should be succeeded")
 	}
 }
 
 func TestDefaultHandlerIsTool(t *testing.T) {
-	tempDir := t.TempDir()
-	os.Chdir(tempDir)
+	store := workflow.NewMemoryStore()
 
 	wf := &workflow.Workflow{
 		ID: "test",
@@ -626,16 +799,161 @@ func TestDefaultHandlerIsTool(t *testing.T) {
 	}
 
 	runID := "test-run"
-	CreateRun(wf, runID, runID)
+	CreateRun(store, wf, runID, runID)
 
 	// Should auto-execute like a tool handler
-	complete, _ := Tick(wf, runID)
+	complete, _ := Tick(store, wf, runID)
 	if !complete {
 		t.Error("Should be complete - default handler should be tool")
 	}
 
-	state, _ := workflow.LoadState(runID)
+	state, _ := store.LoadRun(runID)
 	if state.StepStates["step"].Status != workflow.StatusSucceeded {
 		t.Error("Step with no handler should auto-execute (default to tool)")
 	}
 }
+
+func TestTickReusesCachedOutput(t *testing.T) {
+	// The step output cache lives under the working directory
+	os.Chdir(t.TempDir())
+	store := workflow.NewMemoryStore()
+
+	wf := &workflow.Workflow{
+		ID: "test",
+		Steps: []workflow.Step{
+			{Name: "fetch", Content: "data", Output: "raw"},
+			{Name: "process", Inputs: []string{"raw"}, Output: "processed", Cache: true},
+			{Name: "uncached", Inputs: []string{"raw"}, Output: "copy"},
+		},
+	}
+
+	for _, runID := range []string{"first", "second"} {
+		CreateRun(store, wf, runID, runID)
+		Tick(store, wf, runID)
+		Tick(store, wf, runID)
+	}
+
+	first, _ := store.LoadRun("first")
+	if first.StepStates["process"].CacheHit {
+		t.Error("First execution should not be a cache hit")
+	}
+
+	second, _ := store.LoadRun("second")
+	if !second.StepStates["process"].CacheHit {
+		t.Error("Identical second execution should be a cache hit")
+	}
+	if second.StepStates["uncached"].CacheHit {
+		t.Error("Steps without cache = true should never hit the cache")
+	}
+
+	content, _ := readArtifact(store, "second", "processed")
+	if content != "data" {
+		t.Errorf("Cached artifact should contain 'data', got '%s'", content)
+	}
+}
+
+func TestTickWithSQLiteStore(t *testing.T) {
+	store, err := workflow.OpenSQLiteStore(filepath.Join(t.TempDir(), "composer.db"))
+	if err != nil {
+		t.Fatalf("Failed to open SQLite store: %v", err)
+	}
+	defer store.Close()
+
+	wf := &workflow.Workflow{
+		ID: "test",
+		Steps: []workflow.Step{
+			{Name: "parallel1", Content: "a", Output: "out1"},
+			{Name: "parallel2", Content: "b", Output: "out2"},
+			{Name: "combine", Inputs: []string{"out1", "out2"}, Output: "combined"},
+		},
+	}
+
+	runID := "test-run"
+	CreateRun(store, wf, runID, runID)
+	Tick(store, wf, runID)
+	complete, err := Tick(store, wf, runID)
+	if err != nil || !complete {
+		t.Fatalf("Expected run to complete, got %v, %v", complete, err)
+	}
+
+	content, _ := readArtifact(store, runID, "combined")
+	if content != "ab" {
+		t.Errorf("Expected combined artifact 'ab', got '%s'", content)
+	}
+}
+
+// failingStore is a run store whose artifact store cannot write the named
+// artifact, so the step producing it fails
+type failingStore struct {
+	workflow.RunStore
+	artifact string
+}
+
+func (s failingStore) Artifacts() workflow.ArtifactStore {
+	return failingArtifacts{s.RunStore.Artifacts(), s.artifact}
+}
+
+type failingArtifacts struct {
+	workflow.ArtifactStore
+	artifact string
+}
+
+func (a failingArtifacts) Create(runID, name string, meta workflow.ArtifactMeta) (workflow.ArtifactWriter, error) {
+	if name == a.artifact {
+		return nil, fmt.Errorf("disk full")
+	}
+	return a.ArtifactStore.Create(runID, name, meta)
+}
+
+func TestTickFailsStepAfterMaxAttempts(t *testing.T) {
+	store := failingStore{workflow.NewMemoryStore(), "out1"}
+
+	wf := &workflow.Workflow{
+		ID:    "test",
+		Steps: []workflow.Step{{Name: "step1", Content: "data", Output: "out1"}},
+	}
+	runID := "test-run"
+	CreateRun(store, wf, runID, runID)
+
+	if _, err := Tick(store, wf, runID); err == nil {
+		t.Fatal("Expected the failing step to fail the tick")
+	}
+	state, _ := store.LoadRun(runID)
+	if step := state.StepStates["step1"]; step.Status != workflow.StatusPending || step.Failures != 1 || !strings.Contains(step.Error, "disk full") {
+		t.Errorf("Expected the step to stay pending after one failure, got %+v", step)
+	}
+
+	for i := 1; i < workflow.DefaultMaxAttempts; i++ {
+		Tick(store, wf, runID)
+	}
+	state, _ = store.LoadRun(runID)
+	if step := state.StepStates["step1"]; step.Status != workflow.StatusFailed || step.Failures != workflow.DefaultMaxAttempts {
+		t.Errorf("Expected the step to fail after %d failures, got %+v", workflow.DefaultMaxAttempts, step)
+	}
+
+	// A failed step is not retried until it is re-run
+	if _, err := Tick(store, wf, runID); err != nil {
+		t.Errorf("Expected the failed step to be left alone, got %v", err)
+	}
+	state, _, _ = RerunStep(store, wf, runID, "step1")
+	if step := state.StepStates["step1"]; step.Status != workflow.StatusPending || step.Failures != 0 || step.Error != "" {
+		t.Errorf("Expected re-running to clear the failures, got %+v", step)
+	}
+}
+
+func TestTickMaxAttempts(t *testing.T) {
+	store := failingStore{workflow.NewMemoryStore(), "out1"}
+
+	wf := &workflow.Workflow{
+		ID:    "test",
+		Steps: []workflow.Step{{Name: "step1", Content: "data", Output: "out1", MaxAttempts: 1}},
+	}
+	runID := "test-run"
+	CreateRun(store, wf, runID, runID)
+
+	Tick(store, wf, runID)
+	state, _ := store.LoadRun(runID)
+	if step := state.StepStates["step1"]; step.Status != workflow.StatusFailed || step.Failures != 1 {
+		t.Errorf("Expected the step to fail after its only attempt, got %+v", step)
+	}
+}
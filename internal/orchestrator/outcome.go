@@ -0,0 +1,156 @@
+package orchestrator
+
+import (
+	"fmt"
+
+	"composer/internal/workflow"
+)
+
+// Decision is the outcome a human chose when completing a step, with an
+// optional comment
+type Decision struct {
+	Outcome string
+	Comment string
+}
+
+// StepDecision is a decision recorded on a step of the run
+type StepDecision struct {
+	Step    string
+	Outcome string
+	Comment string
+}
+
+// loopBack resets the run to the loop's target step when the step, just
+// completed, chose its loop's outcome and has loops left. The target step
+// and everything after it, including the step itself, return to pending with
+// their outputs archived.
+func loopBack(
+	wf *workflow.Workflow,
+	artifacts workflow.ArtifactStore,
+	state *workflow.RunState,
+	step workflow.Step,
+) error {
+	loop := step.LoopBack
+	current := state.StepStates[step.Name]
+	if loop == nil || current.Outcome != loop.TriggerOutcome() || current.Loops >= loop.Limit() {
+		return nil
+	}
+
+	if err := checkLoopBack(wf, step); err != nil {
+		return err
+	}
+	target := findStep(wf, loop.To)
+
+	present, err := workflow.ArtifactNames(artifacts, state.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list artifacts: %w", err)
+	}
+
+	affected := map[string]bool{target.Name: true, step.Name: true}
+	produced := map[string]bool{}
+	for _, s := range []*workflow.Step{target, &step} {
+		if s.Output != "" {
+			produced[s.Output] = true
+		}
+	}
+	if err := resetSteps(artifacts, state.ID, state, dependentSteps(wf, affected, produced), present); err != nil {
+		return err
+	}
+
+	looped := state.StepStates[step.Name]
+	looped.Loops++
+	state.StepStates[step.Name] = looped
+	return nil
+}
+
+// checkLoopBack returns an error when the step loops back to a step the
+// workflow does not have. Completions check it before writing the step's
+// output, so a bad loop leaves no orphan version behind.
+func checkLoopBack(wf *workflow.Workflow, step workflow.Step) error {
+	if loop := step.LoopBack; loop != nil && findStep(wf, loop.To) == nil {
+		return fmt.Errorf("step %s loops back to unknown step %s", step.Name, loop.To)
+	}
+	return nil
+}
+
+// gateOpen reports whether a step's outcome gate, if any, is met
+func gateOpen(step workflow.Step, state *workflow.RunState) bool {
+	if step.When == nil {
+		return true
+	}
+	gate := state.StepStates[step.When.Step]
+	return gate.Status == workflow.StatusSucceeded && gate.Outcome == step.When.Outcome
+}
+
+// skipUnreachableSteps marks pending steps that can no longer run as
+// skipped: steps gated on an outcome that was decided otherwise, and steps
+// consuming the output of a skipped step. Reports whether any were skipped.
+func skipUnreachableSteps(wf *workflow.Workflow, state *workflow.RunState) bool {
+	producers := map[string]string{}
+	for _, step := range wf.Steps {
+		if step.Output != "" {
+			producers[step.Output] = step.Name
+		}
+	}
+
+	skippedAny := false
+	for changed := true; changed; {
+		changed = false
+		for _, step := range wf.Steps {
+			current := state.StepStates[step.Name]
+			if current.Status != workflow.StatusPending || waitsOnLoop(wf, state, step) || !unreachable(step, state, producers) {
+				continue
+			}
+			current.Status = workflow.StatusSkipped
+			state.StepStates[step.Name] = current
+			changed = true
+			skippedAny = true
+		}
+	}
+	return skippedAny
+}
+
+// unreachable reports whether a pending step's gate or inputs can no longer
+// be satisfied
+func unreachable(step workflow.Step, state *workflow.RunState, producers map[string]string) bool {
+	if step.When != nil {
+		switch state.StepStates[step.When.Step].Status {
+		case workflow.StatusPending, workflow.StatusReady:
+		default:
+			if !gateOpen(step, state) {
+				return true
+			}
+		}
+	}
+
+	for _, input := range step.Inputs {
+		if producer, ok := producers[input]; ok && state.StepStates[producer].Status == workflow.StatusSkipped {
+			return true
+		}
+	}
+	return false
+}
+
+// taskFeedback returns the decisions a waiting step should see: its own
+// previous decision and those of steps that looped back to it or share its
+// loop
+func taskFeedback(wf *workflow.Workflow, state *workflow.RunState, step workflow.Step) []StepDecision {
+	loop := wf.LoopOf(step.Name)
+	feedback := []StepDecision{}
+	for _, other := range wf.Steps {
+		loopedBack := other.LoopBack != nil && other.LoopBack.To == step.Name
+		if other.Name != step.Name && !loopedBack && (loop == nil || !loop.Has(other.Name)) {
+			continue
+		}
+		recorded := state.StepStates[other.Name]
+		if recorded.Outcome == "" {
+			continue
+		}
+		feedback = append(feedback, StepDecision{
+			Step:    other.Name,
+			Outcome: recorded.Outcome,
+			Comment: recorded.Comment,
+		})
+	}
+	return feedback
+}
@@ -0,0 +1,192 @@
+package orchestrator
+
+import (
+	"strings"
+	"testing"
+
+	"composer/internal/workflow"
+)
+
+// reviewWorkflow drafts a document, reviews it, and publishes it only once
+// approved, looping back to the draft on rejection at most twice
+func reviewWorkflow() *workflow.Workflow {
+	return &workflow.Workflow{
+		ID: "test",
+		Steps: []workflow.Step{
+			{Name: "draft", Content: "draft", Output: "draft"},
+			{
+				Name:     "review",
+				Handler:  "human",
+				Inputs:   []string{"draft"},
+				Output:   "reviewed",
+				Outcomes: []string{"approve", "reject"},
+				LoopBack: &workflow.LoopBack{To: "draft", MaxIterations: 2},
+			},
+			{
+				Name:   "publish",
+				Inputs: []string{"reviewed"},
+				Output: "published",
+				When:   &workflow.OutcomeCondition{Step: "review", Outcome: "approve"},
+			},
+		},
+	}
+}
+
+// tickUntilIdle ticks the run until no more steps start
+func tickUntilIdle(store workflow.RunStore, wf *workflow.Workflow, runID string) {
+	for i := 0; i < len(wf.Steps)+1; i++ {
+		Tick(store, wf, runID)
+	}
+}
+
+func TestCompleteStepRequiresOutcome(t *testing.T) {
+	store := workflow.NewMemoryStore()
+
+	wf := reviewWorkflow()
+	runID := "test-run"
+	CreateRun(store, wf, runID, runID)
+	tickUntilIdle(store, wf, runID)
+
+	for _, outcome := range []string{"", "maybe"} {
+		if _, err := CompleteStep(store, wf, runID, "review", "alice", Decision{Outcome: outcome}, workflow.ArtifactMeta{}, nil); err == nil {
+			t.Errorf("Expected outcome %q to be rejected", outcome)
+		}
+	}
+}
+
+func TestCompleteStepChecksLoopTargetFirst(t *testing.T) {
+	store := workflow.NewMemoryStore()
+
+	wf := reviewWorkflow()
+	runID := "test-run"
+	CreateRun(store, wf, runID, runID)
+	tickUntilIdle(store, wf, runID)
+
+	// A workflow edited after the run started may loop back to a removed step
+	wf.Steps[1].LoopBack = &workflow.LoopBack{To: "redraft"}
+	if _, err := CompleteStep(store, wf, runID, "review", "alice", Decision{Outcome: "reject"}, workflow.ArtifactMeta{}, nil); err == nil {
+		t.Fatal("Expected the unknown loop target to be rejected")
+	}
+	if versions, _ := store.Artifacts().Versions(runID, "reviewed"); len(versions) != 0 {
+		t.Errorf("Expected no output to be written, got %d versions", len(versions))
+	}
+}
+
+func TestApproveRunsGatedStep(t *testing.T) {
+	store := workflow.NewMemoryStore()
+
+	wf := reviewWorkflow()
+	runID := "test-run"
+	CreateRun(store, wf, runID, runID)
+	tickUntilIdle(store, wf, runID)
+
+	state, _ := store.LoadRun(runID)
+	if state.StepStates["publish"].Status != workflow.StatusPending {
+		t.Fatalf("Expected publish to wait for the review, got %s", state.StepStates["publish"].Status)
+	}
+
+	decision := Decision{Outcome: "approve", Comment: "Ship it"}
+	state, err := CompleteStep(store, wf, runID, "review", "alice", decision, workflow.ArtifactMeta{}, nil)
+	if err != nil {
+		t.Fatalf("CompleteStep failed: %v", err)
+	}
+	review := state.StepStates["review"]
+	if review.Outcome != "approve" || review.Comment != "Ship it" {
+		t.Errorf("Expected the decision to be recorded, got %+v", review)
+	}
+
+	complete, _ := Tick(store, wf, runID)
+	if !complete || !hasArtifact(store, runID, "published") {
+		t.Error("Expected publish to run and complete the run")
+	}
+}
+
+func TestRejectLoopsBackUntilLimit(t *testing.T) {
+	store := workflow.NewMemoryStore()
+
+	wf := reviewWorkflow()
+	runID := "test-run"
+	CreateRun(store, wf, runID, runID)
+	tickUntilIdle(store, wf, runID)
+
+	// Each rejection within the limit sends the run back to the draft
+	for round := 1; round <= 2; round++ {
+		decision := Decision{Outcome: "reject", Comment: "Needs work"}
+		state, err := CompleteStep(store, wf, runID, "review", "alice", decision, workflow.ArtifactMeta{}, nil)
+		if err != nil {
+			t.Fatalf("CompleteStep failed: %v", err)
+		}
+
+		review := state.StepStates["review"]
+		if review.Status != workflow.StatusPending || review.Loops != round {
+			t.Fatalf("Expected review to be pending after loop %d, got %+v", round, review)
+		}
+		if state.StepStates["draft"].Status != workflow.StatusPending {
+			t.Fatalf("Expected draft to be reset, got %s", state.StepStates["draft"].Status)
+		}
+
+		tickUntilIdle(store, wf, runID)
+
+		// The reviewer sees the previous decision when asked again
+		tasks, _ := ListWaitingTasks(store, wf, runID)
+		if len(tasks) != 1 || len(tasks[0].Feedback) != 1 || tasks[0].Feedback[0].Comment != "Needs work" {
+			t.Fatalf("Expected the review task with its previous decision, got %+v", tasks)
+		}
+	}
+
+	versions, _ := store.Artifacts().Versions(runID, "draft")
+	if len(versions) != 3 {
+		t.Errorf("Expected 3 draft versions, got %d", len(versions))
+	}
+
+	// Past the limit the rejection stands and the gated step is skipped
+	state, err := CompleteStep(store, wf, runID, "review", "alice", Decision{Outcome: "reject"}, workflow.ArtifactMeta{}, nil)
+	if err != nil {
+		t.Fatalf("CompleteStep failed: %v", err)
+	}
+	if state.StepStates["review"].Status != workflow.StatusSucceeded {
+		t.Fatalf("Expected the final rejection to stand, got %s", state.StepStates["review"].Status)
+	}
+
+	complete, err := Tick(store, wf, runID)
+	if err != nil {
+		t.Fatalf("Tick failed: %v", err)
+	}
+	state, _ = store.LoadRun(runID)
+	if !complete || state.StepStates["publish"].Status != workflow.StatusSkipped {
+		t.Errorf("Expected publish to be skipped and the run complete, got %s", state.StepStates["publish"].Status)
+	}
+}
+
+func TestSkipPropagatesToConsumers(t *testing.T) {
+	wf := reviewWorkflow()
+	wf.Steps = append(wf.Steps, workflow.Step{Name: "announce", Inputs: []string{"published"}, Output: "announced"})
+
+	state := workflow.NewRunState(wf, "test-run", "")
+	state.StepStates["draft"] = workflow.StepState{Status: workflow.StatusSucceeded}
+	state.StepStates["review"] = workflow.StepState{Status: workflow.StatusSucceeded, Outcome: "reject"}
+
+	if !skipUnreachableSteps(wf, state) {
+		t.Fatal("Expected steps to be skipped")
+	}
+	for _, name := range []string{"publish", "announce"} {
+		if state.StepStates[name].Status != workflow.StatusSkipped {
+			t.Errorf("Expected %s to be skipped, got %s", name, state.StepStates[name].Status)
+		}
+	}
+}
+
+func TestRerunResetsGatedSteps(t *testing.T) {
+	wf := reviewWorkflow()
+	wf.Steps = append(wf.Steps, workflow.Step{
+		Name:    "notify",
+		Content: "approved",
+		Output:  "notice",
+		When:    &workflow.OutcomeCondition{Step: "review", Outcome: "approve"},
+	})
+
+	names := stepNames(downstreamSteps(wf, "review"))
+	if strings.Join(names, ",") != "review,publish,notify" {
+		t.Errorf("Expected [review publish notify], got %v", names)
+	}
+}
@@ -0,0 +1,68 @@
+package orchestrator
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"composer/internal/workflow"
+)
+
+// ErrNotOverridable reports an artifact whose run does not accept uploads
+var ErrNotOverridable = errors.New("artifact cannot be overridden")
+
+// PutArtifact writes uploaded content as the new current version of a run's
+// artifact, overriding whatever a step produced. The producing step's state
+// is left unchanged. With invalidate, every step that transitively consumes
+// the artifact is reset to pending and its output archived, so the next
+// ticks recompute them from the override. Returns the written version and
+// the names of the reset steps in workflow order.
+func PutArtifact(
+	store workflow.RunStore,
+	wf *workflow.Workflow,
+	runID string,
+	name string,
+	meta workflow.ArtifactMeta,
+	content io.Reader,
+	invalidate bool,
+) (workflow.ArtifactInfo, []string, error) {
+	unlock := lockRun(runID)
+	defer unlock()
+
+	state, err := store.LoadRun(runID)
+	if err != nil {
+		return workflow.ArtifactInfo{}, nil, fmt.Errorf("failed to load state: %w", err)
+	}
+
+	if state.Status == workflow.RunCancelled {
+		return workflow.ArtifactInfo{}, nil, fmt.Errorf("%w: run '%s' is cancelled", ErrNotOverridable, runID)
+	}
+
+	artifacts := store.Artifacts()
+	info, err := workflow.WriteArtifact(artifacts, runID, name, meta, content)
+	if err != nil {
+		return workflow.ArtifactInfo{}, nil, err
+	}
+
+	if !invalidate {
+		wakeRun(runID)
+		return info, []string{}, nil
+	}
+
+	present, err := workflow.ArtifactNames(artifacts, runID)
+	if err != nil {
+		return workflow.ArtifactInfo{}, nil, fmt.Errorf("failed to list artifacts: %w", err)
+	}
+
+	reset := consumerSteps(wf, name)
+	if err := resetSteps(artifacts, runID, state, reset, present); err != nil {
+		return workflow.ArtifactInfo{}, nil, err
+	}
+
+	if err := store.SaveRun(state); err != nil {
+		return workflow.ArtifactInfo{}, nil, fmt.Errorf("failed to save state: %w", err)
+	}
+
+	wakeRun(runID)
+	return info, stepNames(reset), nil
+}
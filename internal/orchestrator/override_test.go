@@ -0,0 +1,67 @@
+package orchestrator
+
+import (
+	"strings"
+	"testing"
+
+	"composer/internal/workflow"
+)
+
+func TestPutArtifactInvalidatesConsumers(t *testing.T) {
+	store := workflow.NewMemoryStore()
+
+	wf := rerunWorkflow()
+	runID := "test-run"
+	CreateRun(store, wf, runID, runID)
+	for i := 0; i < 3; i++ {
+		Tick(store, wf, runID)
+	}
+
+	meta := workflow.ArtifactMeta{Provenance: workflow.Provenance{Handler: "upload", Actor: "admin"}}
+	info, reset, err := PutArtifact(store, wf, runID, "raw", meta, strings.NewReader("fixed"), true)
+	if err != nil {
+		t.Fatalf("PutArtifact failed: %v", err)
+	}
+	if info.Version != 2 || info.Actor != "admin" {
+		t.Errorf("Expected version 2 uploaded by admin, got %+v", info)
+	}
+	if strings.Join(reset, ",") != "process,combine" {
+		t.Errorf("Expected [process combine] to be reset, got %v", reset)
+	}
+
+	// The producing step keeps its state; consumers recompute from the override
+	state, _ := store.LoadRun(runID)
+	if state.StepStates["fetch"].Status != workflow.StatusSucceeded {
+		t.Errorf("Expected fetch to stay succeeded, got %s", state.StepStates["fetch"].Status)
+	}
+	if hasArtifact(store, runID, "processed") {
+		t.Error("Expected processed to be archived")
+	}
+
+	Tick(store, wf, runID)
+	if content, _ := readArtifact(store, runID, "processed"); content != "fixed" {
+		t.Errorf("Expected processed to be recomputed from the override, got %q", content)
+	}
+}
+
+func TestPutArtifactWithoutInvalidation(t *testing.T) {
+	store := workflow.NewMemoryStore()
+
+	wf := rerunWorkflow()
+	runID := "test-run"
+	CreateRun(store, wf, runID, runID)
+	for i := 0; i < 3; i++ {
+		Tick(store, wf, runID)
+	}
+
+	_, reset, err := PutArtifact(store, wf, runID, "raw", workflow.ArtifactMeta{}, strings.NewReader("fixed"), false)
+	if err != nil {
+		t.Fatalf("PutArtifact failed: %v", err)
+	}
+	if len(reset) != 0 {
+		t.Errorf("Expected no steps to be reset, got %v", reset)
+	}
+	if content, _ := readArtifact(store, runID, "processed"); content != "data" {
+		t.Errorf("Expected processed to be untouched, got %q", content)
+	}
+}
@@ -0,0 +1,158 @@
+package orchestrator
+
+import (
+	"errors"
+	"fmt"
+
+	"composer/internal/workflow"
+)
+
+// ErrNotRerunnable reports a step whose run does not allow it to be rerun
+var ErrNotRerunnable = errors.New("step cannot be rerun")
+
+// RerunStep resets a step and every step that transitively depends on its
+// output back to pending. Artifacts produced by the reset steps are moved to
+// the run's history as prior versions, so the next tick recomputes the
+// subgraph. Finished loops with reset steps redo their last iteration.
+// Returns the updated state and the names of the reset steps in workflow
+// order.
+func RerunStep(store workflow.RunStore, wf *workflow.Workflow, runID string, stepName string) (*workflow.RunState, []string, error) {
+	unlock := lockRun(runID)
+	defer unlock()
+
+	state, err := store.LoadRun(runID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load state: %w", err)
+	}
+
+	if state.Status == workflow.RunCancelled {
+		return nil, nil, fmt.Errorf("%w: run '%s' is cancelled", ErrNotRerunnable, runID)
+	}
+
+	if findStep(wf, stepName) == nil {
+		return nil, nil, fmt.Errorf("step %s not found in workflow", stepName)
+	}
+
+	artifacts := store.Artifacts()
+	present, err := workflow.ArtifactNames(artifacts, runID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list artifacts: %w", err)
+	}
+
+	reset := downstreamSteps(wf, stepName)
+	if err := resetSteps(artifacts, runID, state, reset, present); err != nil {
+		return nil, nil, err
+	}
+	reopenLoops(wf, state, reset)
+
+	if err := store.SaveRun(state); err != nil {
+		return nil, nil, fmt.Errorf("failed to save state: %w", err)
+	}
+
+	wakeRun(runID)
+	return state, stepNames(reset), nil
+}
+
+// resetSteps moves the steps back to pending and archives the artifacts they
+// produced, given the names of the run's present artifacts. Attempt counts
+// and decisions are kept; claims are dropped.
+func resetSteps(
+	artifacts workflow.ArtifactStore,
+	runID string,
+	state *workflow.RunState,
+	steps []workflow.Step,
+	present map[string]bool,
+) error {
+	for _, step := range steps {
+		if step.Output != "" && present[step.Output] {
+			if _, err := artifacts.Archive(runID, step.Output); err != nil {
+				return fmt.Errorf("failed to archive artifact for %s: %w", step.Name, err)
+			}
+		}
+
+		reset := state.StepStates[step.Name].Unclaimed()
+		reset.Status = workflow.StatusPending
+		reset.CacheHit = false
+		reset.Failures = 0
+		reset.Error = ""
+		state.StepStates[step.Name] = reset
+	}
+
+	return nil
+}
+
+// stepNames returns the names of the steps in order
+func stepNames(steps []workflow.Step) []string {
+	names := make([]string, len(steps))
+	for i, step := range steps {
+		names[i] = step.Name
+	}
+	return names
+}
+
+// downstreamSteps returns the named step and every step that transitively
+// consumes its output, in workflow order
+func downstreamSteps(wf *workflow.Workflow, stepName string) []workflow.Step {
+	produced := map[string]bool{}
+	if step := findStep(wf, stepName); step != nil && step.Output != "" {
+		produced[step.Output] = true
+	}
+	return dependentSteps(wf, map[string]bool{stepName: true}, produced)
+}
+
+// consumerSteps returns every step that transitively consumes the named
+// artifact, in workflow order
+func consumerSteps(wf *workflow.Workflow, artifactName string) []workflow.Step {
+	return dependentSteps(wf, map[string]bool{}, map[string]bool{artifactName: true})
+}
+
+// dependentSteps extends the affected steps with every step that transitively
+// consumes one of the produced artifacts or is gated on an affected step's
+// outcome, and returns them in workflow order
+func dependentSteps(wf *workflow.Workflow, affected, produced map[string]bool) []workflow.Step {
+	// Keep sweeping until no new consumers are found
+	for changed := true; changed; {
+		changed = false
+		for _, step := range wf.Steps {
+			if affected[step.Name] {
+				continue
+			}
+			if step.When != nil && affected[step.When.Step] {
+				affected[step.Name] = true
+				if step.Output != "" {
+					produced[step.Output] = true
+				}
+				changed = true
+				continue
+			}
+			for _, input := range step.Inputs {
+				if produced[input] {
+					affected[step.Name] = true
+					if step.Output != "" {
+						produced[step.Output] = true
+					}
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	steps := []workflow.Step{}
+	for _, step := range wf.Steps {
+		if affected[step.Name] {
+			steps = append(steps, step)
+		}
+	}
+	return steps
+}
+
+// findStep returns the workflow step with the given name, or nil
+func findStep(wf *workflow.Workflow, name string) *workflow.Step {
+	for i := range wf.Steps {
+		if wf.Steps[i].Name == name {
+			return &wf.Steps[i]
+		}
+	}
+	return nil
+}
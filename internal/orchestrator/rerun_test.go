@@ -0,0 +1,112 @@
+package orchestrator
+
+import (
+	"testing"
+
+	"composer/internal/workflow"
+)
+
+func rerunWorkflow() *workflow.Workflow {
+	return &workflow.Workflow{
+		ID: "test",
+		Steps: []workflow.Step{
+			{Name: "fetch", Content: "data", Output: "raw"},
+			{Name: "other", Content: "side", Output: "side"},
+			{Name: "process", Inputs: []string{"raw"}, Output: "processed"},
+			{Name: "combine", Inputs: []string{"processed", "side"}, Output: "combined"},
+		},
+	}
+}
+
+func TestDownstreamSteps(t *testing.T) {
+	wf := rerunWorkflow()
+
+	tests := map[string][]string{
+		"fetch":   {"fetch", "process", "combine"},
+		"other":   {"other", "combine"},
+		"combine": {"combine"},
+	}
+
+	for name, expected := range tests {
+		steps := downstreamSteps(wf, name)
+		if len(steps) != len(expected) {
+			t.Errorf("downstreamSteps(%s) returned %d steps, want %d", name, len(steps), len(expected))
+			continue
+		}
+		for i, step := range steps {
+			if step.Name != expected[i] {
+				t.Errorf("downstreamSteps(%s)[%d] = %s, want %s", name, i, step.Name, expected[i])
+			}
+		}
+	}
+}
+
+func TestRerunStep(t *testing.T) {
+	store := workflow.NewFSStore(t.TempDir())
+
+	wf := rerunWorkflow()
+	runID := "test-run"
+	CreateRun(store, wf, runID, runID)
+	for i := 0; i < 3; i++ {
+		Tick(store, wf, runID)
+	}
+
+	state, reset, err := RerunStep(store, wf, runID, "process")
+	if err != nil {
+		t.Fatalf("RerunStep failed: %v", err)
+	}
+	if len(reset) != 2 || reset[0] != "process" || reset[1] != "combine" {
+		t.Errorf("Expected [process combine] to be reset, got %v", reset)
+	}
+
+	// Reset steps are pending and their artifacts are gone
+	for _, name := range []string{"process", "combine"} {
+		if state.StepStates[name].Status != workflow.StatusPending {
+			t.Errorf("Step %s should be pending, got %s", name, state.StepStates[name].Status)
+		}
+	}
+	if hasArtifact(store, runID, "processed") || hasArtifact(store, runID, "combined") {
+		t.Error("Reset steps' artifacts should be moved aside")
+	}
+
+	// Upstream and unrelated steps are untouched
+	if state.StepStates["fetch"].Status != workflow.StatusSucceeded || !hasArtifact(store, runID, "raw") {
+		t.Error("Upstream step should keep its state and artifact")
+	}
+
+	// The prior version is kept in the run's history
+	history, err := store.Artifacts().History(runID)
+	if err != nil || len(history["processed"]) != 1 || history["processed"][0].Size != int64(len("data")) {
+		t.Errorf("Expected one prior version of processed, got %+v, %v", history["processed"], err)
+	}
+
+	// Next ticks recompute the subgraph
+	Tick(store, wf, runID)
+	complete, _ := Tick(store, wf, runID)
+	if !complete {
+		t.Error("Run should complete after recomputing the subgraph")
+	}
+
+	// The recomputed output is the step's second attempt
+	versions, err := store.Artifacts().Versions(runID, "processed")
+	if err != nil || len(versions) != 2 {
+		t.Fatalf("Expected two versions of processed, got %+v, %v", versions, err)
+	}
+	for i, version := range versions {
+		if version.Version != i+1 || version.Step != "process" || version.Attempt != i+1 {
+			t.Errorf("Unexpected version %d: %+v", i+1, version)
+		}
+	}
+}
+
+func TestRerunStepUnknownStep(t *testing.T) {
+	store := workflow.NewMemoryStore()
+
+	wf := rerunWorkflow()
+	runID := "test-run"
+	CreateRun(store, wf, runID, runID)
+
+	if _, _, err := RerunStep(store, wf, runID, "missing"); err == nil {
+		t.Error("Expected error for unknown step")
+	}
+}
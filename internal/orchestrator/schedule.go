@@ -0,0 +1,211 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"composer/internal/workflow"
+)
+
+// ScheduleStatus is a schedule with when it last started a run and when it
+// fires next. Schedules whose file fails to load carry only their name and
+// the error.
+type ScheduleStatus struct {
+	workflow.Schedule
+	LastFired *time.Time `json:"last_fired,omitempty"`
+	Next      time.Time  `json:"next"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// ListScheduleStatuses returns every schedule with its last and next fire,
+// including the schedules that fail to load
+func ListScheduleStatuses(store workflow.RunStore, now time.Time) ([]ScheduleStatus, error) {
+	schedules, err := workflow.ListSchedules()
+	invalid, err := splitScheduleErrors(err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schedules: %w", err)
+	}
+	fired, err := lastFires(store)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]ScheduleStatus, 0, len(schedules)+len(invalid))
+	for _, schedule := range schedules {
+		status := ScheduleStatus{Schedule: schedule, Next: schedule.Next(now)}
+		if at, ok := fired[schedule.Name]; ok {
+			status.LastFired = &at
+		}
+		statuses = append(statuses, status)
+	}
+	for _, schedErr := range invalid {
+		statuses = append(statuses, ScheduleStatus{
+			Schedule: workflow.Schedule{Name: schedErr.Name},
+			Error:    schedErr.Error(),
+		})
+	}
+	slices.SortFunc(statuses, func(a, b ScheduleStatus) int { return strings.Compare(a.Name, b.Name) })
+	return statuses, nil
+}
+
+// splitScheduleErrors separates the schedule files ListSchedules failed to
+// load from its other errors
+func splitScheduleErrors(err error) ([]*workflow.ScheduleError, error) {
+	if err == nil {
+		return nil, nil
+	}
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+
+	var invalid []*workflow.ScheduleError
+	var rest []error
+	for _, err := range errs {
+		var schedErr *workflow.ScheduleError
+		if errors.As(err, &schedErr) {
+			invalid = append(invalid, schedErr)
+		} else {
+			rest = append(rest, err)
+		}
+	}
+	return invalid, errors.Join(rest...)
+}
+
+// lastFires returns the latest fire time each schedule started a run for
+func lastFires(store workflow.RunStore) (map[string]time.Time, error) {
+	runs, err := store.ListRuns()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list runs: %w", err)
+	}
+
+	fired := map[string]time.Time{}
+	for _, run := range runs {
+		if run.Trigger == nil || run.Trigger.Kind != workflow.TriggerSchedule {
+			continue
+		}
+		if at, ok := fired[run.Trigger.Name]; !ok || run.Trigger.At.After(at) {
+			fired[run.Trigger.Name] = run.Trigger.At
+		}
+	}
+	return fired, nil
+}
+
+// FireSchedule starts the schedule's run for the fire time and returns its ID
+func FireSchedule(store workflow.RunStore, schedule workflow.Schedule, at time.Time) (string, error) {
+	wf, _, err := workflow.LoadWorkflow(schedule.Workflow)
+	if err != nil {
+		return "", err
+	}
+	runID, err := schedule.ExpandRunID(at)
+	if err != nil {
+		return "", err
+	}
+
+	trigger := workflow.Trigger{Kind: workflow.TriggerSchedule, Name: schedule.Name, At: at.UTC()}
+	if _, err := CreateTriggeredRun(store, wf, runID, schedule.Params, trigger, nil); err != nil {
+		return "", err
+	}
+	return runID, nil
+}
+
+// ScheduleRunner starts runs as schedules fire. Schedules are re-read on
+// every check, so added and edited schedules take effect without a restart.
+type ScheduleRunner struct {
+	store workflow.RunStore
+	// since maps each known schedule to the time it was last checked
+	since map[string]time.Time
+}
+
+// NewScheduleRunner creates a runner starting runs in the store
+func NewScheduleRunner(store workflow.RunStore) *ScheduleRunner {
+	return &ScheduleRunner{store: store}
+}
+
+// Check starts a run for every fire since the previous check, up to now, and
+// returns the IDs of the runs started. On the first check, fires missed since
+// each schedule last started a run follow the schedule's catch-up policy;
+// schedules first seen later start from now. A fire whose run cannot be
+// started is reported and not retried. Schedule files that fail to load are
+// reported while the others keep firing.
+func (r *ScheduleRunner) Check(now time.Time) ([]string, error) {
+	schedules, err := workflow.ListSchedules()
+	var first error
+	if err != nil {
+		first = fmt.Errorf("failed to load schedules: %w", err)
+	}
+
+	var fired map[string]time.Time
+	if r.since == nil {
+		if fired, err = lastFires(r.store); err != nil {
+			return nil, err
+		}
+		r.since = map[string]time.Time{}
+	}
+
+	started := []string{}
+	for _, schedule := range schedules {
+		for _, at := range r.due(schedule, fired, now) {
+			runID, err := FireSchedule(r.store, schedule, at)
+			if err != nil {
+				if first == nil {
+					first = fmt.Errorf("fire schedule '%s': %w", schedule.Name, err)
+				}
+				continue
+			}
+			started = append(started, runID)
+		}
+		r.since[schedule.Name] = now
+	}
+	return started, first
+}
+
+// due returns the schedule's fire times to start runs for, oldest first.
+// fired holds the last fires recorded in runs on the first check.
+func (r *ScheduleRunner) due(schedule workflow.Schedule, fired map[string]time.Time, now time.Time) []time.Time {
+	if since, ok := r.since[schedule.Name]; ok {
+		return schedule.Fires(since, now, workflow.MaxCatchUp)
+	}
+
+	last, ok := fired[schedule.Name]
+	if !ok {
+		return nil
+	}
+	missed := schedule.Fires(last, now, workflow.MaxCatchUp)
+	switch schedule.CatchUpPolicy() {
+	case workflow.CatchUpAll:
+		return missed
+	case workflow.CatchUpOnce:
+		if len(missed) > 0 {
+			// Fires stops at MaxCatchUp, so walk on to the latest fire
+			latest := missed[len(missed)-1]
+			for next := schedule.Next(latest); !next.IsZero() && !next.After(now); next = schedule.Next(next) {
+				latest = next
+			}
+			return []time.Time{latest}
+		}
+	}
+	return nil
+}
+
+// Run checks the schedules at the start of every minute until ctx is
+// cancelled
+func (r *ScheduleRunner) Run(ctx context.Context) {
+	for {
+		if _, err := r.Check(time.Now()); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+
+		now := time.Now()
+		wait := now.Truncate(time.Minute).Add(time.Minute).Sub(now)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
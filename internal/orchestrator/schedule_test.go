@@ -0,0 +1,117 @@
+package orchestrator
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"composer/internal/workflow"
+)
+
+// writeSchedule installs an hourly schedule of the scheduled workflow with
+// the catch-up policy
+func writeSchedule(t *testing.T, name, catchUp string) {
+	t.Helper()
+	dir := filepath.Join(".composer", "schedules")
+	os.MkdirAll(dir, 0755)
+	schedule := "workflow = \"scheduled\"\ncron = \"@hourly\"\ntimezone = \"UTC\"\ncatch_up = \"" + catchUp + "\"\n"
+	if err := os.WriteFile(filepath.Join(dir, name+".toml"), []byte(schedule), 0644); err != nil {
+		t.Fatalf("Failed to write schedule: %v", err)
+	}
+}
+
+func TestScheduleRunnerFiresOnSchedule(t *testing.T) {
+	loadScheduledWorkflow(t)
+	writeSchedule(t, "hourly", "skip")
+	store := workflow.NewMemoryStore()
+	runner := NewScheduleRunner(store)
+
+	// A schedule with no runs yet starts from the first check
+	start := time.Date(2025, 3, 1, 9, 30, 0, 0, time.UTC)
+	if started, err := runner.Check(start); err != nil || len(started) != 0 {
+		t.Fatalf("Expected no runs on the first check, got %v (%v)", started, err)
+	}
+	if started, _ := runner.Check(start.Add(20 * time.Minute)); len(started) != 0 {
+		t.Fatalf("Expected no runs before 10:00, got %v", started)
+	}
+
+	started, err := runner.Check(start.Add(31 * time.Minute))
+	if err != nil || !slices.Equal(started, []string{"hourly-20250301-1000"}) {
+		t.Fatalf("Expected the 10:00 run, got %v (%v)", started, err)
+	}
+	state, _ := store.LoadRun("hourly-20250301-1000")
+	if state.Trigger == nil || state.Trigger.Kind != workflow.TriggerSchedule || state.Trigger.Name != "hourly" {
+		t.Errorf("Expected the run to record its schedule, got %+v", state.Trigger)
+	}
+
+	statuses, err := ListScheduleStatuses(store, start.Add(31*time.Minute))
+	if err != nil || len(statuses) != 1 {
+		t.Fatalf("ListScheduleStatuses failed: %v (%v)", statuses, err)
+	}
+	if !statuses[0].LastFired.Equal(start.Add(30*time.Minute)) || !statuses[0].Next.Equal(start.Add(90*time.Minute)) {
+		t.Errorf("Expected last fire 10:00 and next 11:00, got %v and %v", statuses[0].LastFired, statuses[0].Next)
+	}
+}
+
+func TestScheduleRunnerCatchUp(t *testing.T) {
+	loadScheduledWorkflow(t)
+	wf, _, _ := workflow.LoadWorkflow("scheduled")
+	store := workflow.NewMemoryStore()
+
+	// Each schedule last fired at 10:00, and composerd comes back at 13:30
+	last := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	for name, catchUp := range map[string]string{"skipped": "skip", "once": "once", "all": "all"} {
+		writeSchedule(t, name, catchUp)
+		trigger := workflow.Trigger{Kind: workflow.TriggerSchedule, Name: name, At: last}
+		CreateTriggeredRun(store, wf, name+"-first", nil, trigger, nil)
+	}
+
+	started, err := NewScheduleRunner(store).Check(last.Add(210 * time.Minute))
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	slices.Sort(started)
+	want := []string{"all-20250301-1100", "all-20250301-1200", "all-20250301-1300", "once-20250301-1300"}
+	if !slices.Equal(started, want) {
+		t.Errorf("Expected %v, got %v", want, started)
+	}
+}
+
+func TestCreateTriggeredRunRefusesExistingRun(t *testing.T) {
+	store := workflow.NewMemoryStore()
+	wf := draftWorkflow()
+	CreateRun(store, wf, "run", "run")
+
+	if _, err := CreateTriggeredRun(store, wf, "run", nil, workflow.Trigger{Kind: workflow.TriggerSchedule, Name: "s"}, nil); err == nil {
+		t.Error("Expected a triggered run not to replace an existing run")
+	}
+}
+
+func TestScheduleRunnerSkipsInvalidSchedules(t *testing.T) {
+	loadScheduledWorkflow(t)
+	writeSchedule(t, "hourly", "skip")
+	os.WriteFile(filepath.Join(".composer", "schedules", "broken.toml"), []byte("workflow = \"scheduled\"\ncron = \"every hour\"\n"), 0644)
+	store := workflow.NewMemoryStore()
+	runner := NewScheduleRunner(store)
+
+	start := time.Date(2025, 3, 1, 9, 30, 0, 0, time.UTC)
+	runner.Check(start)
+	started, err := runner.Check(start.Add(31 * time.Minute))
+	if err == nil || !strings.Contains(err.Error(), "broken.toml") {
+		t.Errorf("Expected the broken schedule to be reported, got %v", err)
+	}
+	if !slices.Equal(started, []string{"hourly-20250301-1000"}) {
+		t.Errorf("Expected the valid schedule to fire, got %v", started)
+	}
+
+	statuses, err := ListScheduleStatuses(store, start)
+	if err != nil || len(statuses) != 2 {
+		t.Fatalf("ListScheduleStatuses failed: %v (%v)", statuses, err)
+	}
+	if statuses[0].Name != "broken" || statuses[0].Error == "" || statuses[1].Name != "hourly" || statuses[1].Error != "" {
+		t.Errorf("Expected broken to carry its error, got %+v", statuses)
+	}
+}
@@ -0,0 +1,273 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"composer/internal/workflow"
+)
+
+// DefaultTickInterval is how often the scheduler looks for runs with work
+// when COMPOSER_TICK_INTERVAL is unset
+const DefaultTickInterval = 10 * time.Second
+
+// DefaultTickConcurrency is how many runs the scheduler ticks at once when
+// COMPOSER_TICK_CONCURRENCY is unset
+const DefaultTickConcurrency = 4
+
+// SchedulerConfig configures the background scheduler
+type SchedulerConfig struct {
+	// Interval is how often every run is checked for work (0 = only on wake)
+	Interval time.Duration
+	// Concurrency caps how many runs tick at once
+	Concurrency int
+}
+
+// SchedulerConfigFromEnv reads the scheduler's interval from
+// COMPOSER_TICK_INTERVAL, a duration such as "5s", and its concurrency from
+// COMPOSER_TICK_CONCURRENCY. An interval of 0 leaves runs to be woken by
+// task completions and uploads; a concurrency of 0 disables the scheduler.
+func SchedulerConfigFromEnv() (SchedulerConfig, error) {
+	config := SchedulerConfig{
+		Interval:    DefaultTickInterval,
+		Concurrency: DefaultTickConcurrency,
+	}
+
+	if value := strings.TrimSpace(os.Getenv("COMPOSER_TICK_INTERVAL")); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval < 0 {
+			return SchedulerConfig{}, fmt.Errorf("invalid COMPOSER_TICK_INTERVAL '%s'", value)
+		}
+		config.Interval = interval
+	}
+
+	if value := strings.TrimSpace(os.Getenv("COMPOSER_TICK_CONCURRENCY")); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return SchedulerConfig{}, fmt.Errorf("invalid COMPOSER_TICK_CONCURRENCY '%s'", value)
+		}
+		config.Concurrency = n
+	}
+
+	return config, nil
+}
+
+// Scheduler ticks runs in the background. Runs are queued when woken and on
+// every interval if they have work, and ticked in queue order, each at most
+// once at a time. A run that still has work after its tick goes to the back
+// of the queue so busy runs cannot starve the others.
+type Scheduler struct {
+	store  workflow.RunStore
+	config SchedulerConfig
+
+	mu      sync.Mutex
+	queue   []string
+	queued  map[string]bool
+	ticking map[string]bool
+	signal  chan struct{}
+}
+
+// NewScheduler creates a scheduler ticking the store's runs
+func NewScheduler(store workflow.RunStore, config SchedulerConfig) *Scheduler {
+	if config.Concurrency < 1 {
+		config.Concurrency = 1
+	}
+	return &Scheduler{
+		store:   store,
+		config:  config,
+		queued:  make(map[string]bool),
+		ticking: make(map[string]bool),
+		signal:  make(chan struct{}, 1),
+	}
+}
+
+var (
+	schedulerMu sync.Mutex
+	scheduler   *Scheduler
+)
+
+// wakeRun asks the scheduler running in this process, if any, to tick the
+// run as soon as it can
+func wakeRun(runID string) {
+	schedulerMu.Lock()
+	s := scheduler
+	schedulerMu.Unlock()
+
+	if s != nil {
+		s.Wake(runID)
+	}
+}
+
+// Wake queues the run to tick as soon as a slot is free. A run woken while
+// it ticks is ticked again afterwards.
+func (s *Scheduler) Wake(runID string) {
+	s.mu.Lock()
+	if !s.queued[runID] {
+		s.queued[runID] = true
+		s.queue = append(s.queue, runID)
+	}
+	s.mu.Unlock()
+
+	s.notify()
+}
+
+// notify prompts the dispatch loop without blocking
+func (s *Scheduler) notify() {
+	select {
+	case s.signal <- struct{}{}:
+	default:
+	}
+}
+
+// Run ticks runs until ctx is cancelled, then waits for the ticks in flight
+// to finish so their steps are not cut short. While it runs, task
+// completions, artifact uploads, and other changes in this process wake the
+// affected run.
+func (s *Scheduler) Run(ctx context.Context) {
+	schedulerMu.Lock()
+	scheduler = s
+	schedulerMu.Unlock()
+	defer func() {
+		schedulerMu.Lock()
+		if scheduler == s {
+			scheduler = nil
+		}
+		schedulerMu.Unlock()
+	}()
+
+	var interval <-chan time.Time
+	if s.config.Interval > 0 {
+		ticker := time.NewTicker(s.config.Interval)
+		defer ticker.Stop()
+		interval = ticker.C
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	s.queueRunsWithWork()
+	for {
+		s.dispatch(&wg)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-interval:
+			s.queueRunsWithWork()
+		case <-s.signal:
+		}
+	}
+}
+
+// queueRunsWithWork wakes every active run that a tick would advance
+func (s *Scheduler) queueRunsWithWork() {
+	runs, err := s.store.QueryRuns(workflow.RunQuery{Status: workflow.RunActive})
+	if err != nil {
+		fmt.Printf("Warning: scheduler failed to list runs: %v\n", err)
+		return
+	}
+
+	workflows := map[string]*workflow.Workflow{}
+	for i := range runs {
+		run := &runs[i]
+		if run.AllStepsCompleted() {
+			continue
+		}
+
+		wf, ok := workflows[run.WorkflowName]
+		if !ok {
+			wf, _, err = workflow.LoadWorkflow(run.WorkflowName)
+			if err != nil {
+				continue
+			}
+			workflows[run.WorkflowName] = wf
+		}
+
+		if needsTick(s.store, wf, run) {
+			s.Wake(run.ID)
+		}
+	}
+}
+
+// dispatch starts ticks for queued runs that are not already ticking, in
+// queue order, while slots are free
+func (s *Scheduler) dispatch(wg *sync.WaitGroup) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	waiting := s.queue[:0]
+	for _, runID := range s.queue {
+		if s.ticking[runID] || len(s.ticking) >= s.config.Concurrency {
+			waiting = append(waiting, runID)
+			continue
+		}
+
+		delete(s.queued, runID)
+		s.ticking[runID] = true
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			again := s.tick(runID)
+
+			s.mu.Lock()
+			delete(s.ticking, runID)
+			s.mu.Unlock()
+
+			if again {
+				s.Wake(runID)
+			} else {
+				s.notify()
+			}
+		}()
+	}
+	s.queue = waiting
+}
+
+// tick advances the run once and reports whether another tick would
+// advance it further
+func (s *Scheduler) tick(runID string) bool {
+	state, err := s.store.LoadRun(runID)
+	if err != nil {
+		fmt.Printf("Warning: scheduler failed to load run '%s': %v\n", runID, err)
+		return false
+	}
+	wf, _, err := workflow.LoadWorkflow(state.WorkflowName)
+	if err != nil {
+		fmt.Printf("Warning: scheduler failed to load workflow '%s' for run '%s': %v\n", state.WorkflowName, runID, err)
+		return false
+	}
+
+	// Failed steps are retried on the next interval rather than right away
+	if _, err := Tick(s.store, wf, runID); err != nil {
+		fmt.Printf("Warning: scheduled tick of run '%s' failed: %v\n", runID, err)
+		return false
+	}
+
+	state, err = s.store.LoadRun(runID)
+	if err != nil {
+		return false
+	}
+	return state.Status == workflow.RunActive && !state.AllStepsCompleted() && needsTick(s.store, wf, state)
+}
+
+// needsTick reports whether a tick would advance the run: steps can start
+// or become ready, or a loop iteration has finished. Runs that only wait
+// on humans do not need one.
+func needsTick(store workflow.RunStore, wf *workflow.Workflow, state *workflow.RunState) bool {
+	for _, loop := range wf.Loops {
+		if state.Loop(loop.Name).Status == workflow.LoopRunning && iterationFinished(loop, state) {
+			return true
+		}
+	}
+
+	present, err := workflow.ArtifactNames(store.Artifacts(), state.ID)
+	if err != nil {
+		return false
+	}
+	return len(findRunnableSteps(wf, state, present)) > 0
+}
@@ -0,0 +1,187 @@
+package orchestrator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"composer/internal/workflow"
+)
+
+// scheduledWorkflow chains two tool steps into a human review and a final
+// tool step
+const scheduledWorkflow = `
+[[steps]]
+name = "fetch"
+content = "data"
+output = "raw"
+
+[[steps]]
+name = "process"
+inputs = ["raw"]
+output = "processed"
+
+[[steps]]
+name = "review"
+handler = "human"
+inputs = ["processed"]
+output = "reviewed"
+
+[[steps]]
+name = "publish"
+inputs = ["reviewed"]
+output = "published"
+`
+
+// loadScheduledWorkflow installs the workflow where the scheduler finds it
+func loadScheduledWorkflow(t *testing.T) *workflow.Workflow {
+	t.Helper()
+	os.Chdir(t.TempDir())
+	dir := filepath.Join(".composer", "workflows")
+	os.MkdirAll(dir, 0755)
+	if err := os.WriteFile(filepath.Join(dir, "scheduled.toml"), []byte(scheduledWorkflow), 0644); err != nil {
+		t.Fatalf("Failed to write workflow: %v", err)
+	}
+
+	wf, _, err := workflow.LoadWorkflow("scheduled")
+	if err != nil {
+		t.Fatalf("Failed to load workflow: %v", err)
+	}
+	return wf
+}
+
+// waitForStep polls until the step reaches the status or the test times out
+func waitForStep(t *testing.T, store workflow.RunStore, runID, step string, status workflow.StepStatus) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if state, err := store.LoadRun(runID); err == nil && state.StepStates[step].Status == status {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Timed out waiting for %s of run %s to be %s", step, runID, status)
+}
+
+func TestSchedulerAdvancesWokenRuns(t *testing.T) {
+	wf := loadScheduledWorkflow(t)
+	store := workflow.NewMemoryStore()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	s := NewScheduler(store, SchedulerConfig{Concurrency: 1})
+	go func() {
+		s.Run(ctx)
+		close(stopped)
+	}()
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+
+	// Wait for Run to register the scheduler so creating runs wakes it
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		schedulerMu.Lock()
+		registered := scheduler == s
+		schedulerMu.Unlock()
+		if registered || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// New runs tick until they wait on a human, without an interval
+	for _, runID := range []string{"first", "second"} {
+		if err := CreateRun(store, wf, runID, runID); err != nil {
+			t.Fatalf("CreateRun failed: %v", err)
+		}
+	}
+	waitForStep(t, store, "first", "review", workflow.StatusReady)
+	waitForStep(t, store, "second", "review", workflow.StatusReady)
+
+	// Completing the task wakes the run again
+	if _, err := CompleteStep(store, wf, "first", "review", "alice", Decision{}, workflow.ArtifactMeta{}, nil); err != nil {
+		t.Fatalf("CompleteStep failed: %v", err)
+	}
+	waitForStep(t, store, "first", "publish", workflow.StatusSucceeded)
+
+	second, _ := store.LoadRun("second")
+	if second.StepStates["publish"].Status != workflow.StatusPending {
+		t.Errorf("Expected the second run to keep waiting on its review, got %+v", second.StepStates["publish"])
+	}
+}
+
+func TestSchedulerTicksRunsWithWorkOnInterval(t *testing.T) {
+	wf := loadScheduledWorkflow(t)
+	store := workflow.NewMemoryStore()
+
+	// Runs created before the scheduler starts are found by its scan
+	CreateRun(store, wf, "existing", "existing")
+	PauseRun(store, "existing")
+	CreateRun(store, wf, "active", "active")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		NewScheduler(store, SchedulerConfig{Interval: 10 * time.Millisecond, Concurrency: 2}).Run(ctx)
+		close(stopped)
+	}()
+
+	waitForStep(t, store, "active", "review", workflow.StatusReady)
+	cancel()
+	<-stopped
+
+	paused, _ := store.LoadRun("existing")
+	if paused.StepStates["fetch"].Status != workflow.StatusPending {
+		t.Errorf("Expected the paused run not to be ticked, got %+v", paused.StepStates["fetch"])
+	}
+
+	schedulerMu.Lock()
+	defer schedulerMu.Unlock()
+	if scheduler != nil {
+		t.Error("Expected the scheduler to unregister once stopped")
+	}
+}
+
+func TestNeedsTick(t *testing.T) {
+	wf := loadScheduledWorkflow(t)
+	store := workflow.NewMemoryStore()
+	CreateRun(store, wf, "run", "run")
+
+	state, _ := store.LoadRun("run")
+	if !needsTick(store, wf, state) {
+		t.Error("Expected a new run to need a tick")
+	}
+
+	Tick(store, wf, "run")
+	Tick(store, wf, "run")
+	Tick(store, wf, "run")
+	state, _ = store.LoadRun("run")
+	if state.StepStates["review"].Status != workflow.StatusReady || needsTick(store, wf, state) {
+		t.Errorf("Expected a run waiting on its review not to need a tick, got %+v", state.StepStates)
+	}
+}
+
+func TestSchedulerConfigFromEnv(t *testing.T) {
+	t.Setenv("COMPOSER_TICK_INTERVAL", "")
+	t.Setenv("COMPOSER_TICK_CONCURRENCY", "")
+	config, err := SchedulerConfigFromEnv()
+	if err != nil || config.Interval != DefaultTickInterval || config.Concurrency != DefaultTickConcurrency {
+		t.Errorf("Expected the defaults, got %+v (%v)", config, err)
+	}
+
+	t.Setenv("COMPOSER_TICK_INTERVAL", "2s")
+	t.Setenv("COMPOSER_TICK_CONCURRENCY", "8")
+	config, err = SchedulerConfigFromEnv()
+	if err != nil || config.Interval != 2*time.Second || config.Concurrency != 8 {
+		t.Errorf("Expected 2s and 8, got %+v (%v)", config, err)
+	}
+
+	t.Setenv("COMPOSER_TICK_INTERVAL", "soon")
+	if _, err := SchedulerConfigFromEnv(); err == nil {
+		t.Error("Expected an invalid interval to fail")
+	}
+}
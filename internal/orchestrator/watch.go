@@ -0,0 +1,323 @@
+package orchestrator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"composer/internal/workflow"
+)
+
+// DefaultWatchInterval is how often composerd scans watched directories
+const DefaultWatchInterval = 5 * time.Second
+
+// WatchIntervalFromEnv reads the watch interval from COMPOSER_WATCH_INTERVAL,
+// a duration such as "30s". Zero disables watch triggers.
+func WatchIntervalFromEnv() (time.Duration, error) {
+	value := strings.TrimSpace(os.Getenv("COMPOSER_WATCH_INTERVAL"))
+	if value == "" {
+		return DefaultWatchInterval, nil
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval < 0 {
+		return 0, fmt.Errorf("invalid COMPOSER_WATCH_INTERVAL '%s'", value)
+	}
+	return interval, nil
+}
+
+// errRejectedFile reports a watched file no run can be started for, such as
+// one the trigger cannot map; the file goes straight to the failed directory
+var errRejectedFile = errors.New("rejected file")
+
+// Watcher starts runs for files dropped into the directories of watch
+// triggers. A new file is claimed by moving it into the trigger's processing
+// directory under its run ID, then its run is started; once the run succeeds
+// or fails the file moves on to the processed or failed directory. All of
+// this is kept on disk and in the runs, so a restarted watcher carries on
+// where it stopped. Triggers are re-read on every check.
+type Watcher struct {
+	store workflow.RunStore
+}
+
+// NewWatcher creates a watcher starting runs in the store
+func NewWatcher(store workflow.RunStore) *Watcher {
+	return &Watcher{store: store}
+}
+
+// Check claims the settled files in every watch trigger's directory, starts
+// the runs of claimed files, and moves on the files of finished runs. It
+// returns the IDs of the runs started. A file that fails is reported and
+// retried on the next check, as are trigger files that fail to load.
+func (w *Watcher) Check(now time.Time) ([]string, error) {
+	triggers, err := workflow.ListTriggers()
+	var first error
+	if err != nil {
+		first = fmt.Errorf("failed to load triggers: %w", err)
+	}
+
+	started := []string{}
+	report := func(trigger *workflow.TriggerSpec, err error) {
+		if err != nil && first == nil {
+			first = fmt.Errorf("watch trigger '%s': %w", trigger.Name, err)
+		}
+	}
+	for i := range triggers {
+		trigger := &triggers[i]
+		if trigger.Kind() != workflow.TriggerWatch {
+			continue
+		}
+		report(trigger, w.claim(trigger, now))
+		runs, err := w.process(trigger, now)
+		started = append(started, runs...)
+		report(trigger, err)
+	}
+	return started, first
+}
+
+// claim moves the settled files in the trigger's directory into its
+// processing directory, each under the ID of the run to start for it
+func (w *Watcher) claim(trigger *workflow.TriggerSpec, now time.Time) error {
+	entries, err := os.ReadDir(trigger.Dir)
+	if err != nil {
+		return fmt.Errorf("failed to read watch directory: %w", err)
+	}
+
+	var first error
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !trigger.Watches(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !trigger.Settled(info.ModTime(), now) {
+			continue
+		}
+		if err := w.claimFile(trigger, entry.Name(), now); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// claimFile moves one file into the processing directory. Files the trigger
+// cannot map, or whose run ID is taken, go to the failed directory instead.
+func (w *Watcher) claimFile(trigger *workflow.TriggerSpec, name string, now time.Time) error {
+	path := filepath.Join(trigger.Dir, name)
+	runID, err := w.watchedRunID(trigger, path, now)
+	if errors.Is(err, errRejectedFile) {
+		fmt.Printf("Warning: %v\n", err)
+		return moveWatchedFile(path, trigger.DoneDir(true), "")
+	}
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(trigger.ProcessingDir(), runID)
+	if err := os.MkdirAll(trigger.ProcessingDir(), 0755); err != nil {
+		return fmt.Errorf("failed to create processing directory: %w", err)
+	}
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return fmt.Errorf("failed to claim %s: %w", name, err)
+	}
+	if err := os.Rename(path, filepath.Join(dir, name)); err != nil {
+		os.Remove(dir)
+		return fmt.Errorf("failed to claim %s: %w", name, err)
+	}
+	return nil
+}
+
+// watchedRunID maps the file and expands the run ID for it, rejecting files
+// the trigger cannot map and run IDs already in use
+func (w *Watcher) watchedRunID(trigger *workflow.TriggerSpec, path string, now time.Time) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	params, _, err := trigger.MapPayload(content)
+	if err != nil {
+		return "", fmt.Errorf("%w %s: %v", errRejectedFile, path, err)
+	}
+
+	// The file name is only available to the run ID template
+	values := make(map[string]string, len(params)+1)
+	for name, value := range params {
+		values[name] = value
+	}
+	values["file"] = filepath.Base(path)
+
+	runID, err := trigger.ExpandRunID(values, now.UTC())
+	if err != nil {
+		return "", fmt.Errorf("%w %s: %v", errRejectedFile, path, err)
+	}
+	if _, err := w.store.LoadRun(runID); err == nil {
+		return "", fmt.Errorf("%w %s: run '%s' already exists", errRejectedFile, path, runID)
+	}
+	if _, err := os.Stat(filepath.Join(trigger.ProcessingDir(), runID)); err == nil {
+		return "", fmt.Errorf("%w %s: run '%s' is already processing", errRejectedFile, path, runID)
+	}
+	return runID, nil
+}
+
+// process starts the runs of claimed files that have none yet and moves the
+// files of finished runs out of the processing directory
+func (w *Watcher) process(trigger *workflow.TriggerSpec, now time.Time) ([]string, error) {
+	entries, err := os.ReadDir(trigger.ProcessingDir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read processing directory: %w", err)
+	}
+
+	started := []string{}
+	var first error
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		runID := entry.Name()
+		dir := filepath.Join(trigger.ProcessingDir(), runID)
+		files, err := os.ReadDir(dir)
+		if err != nil || len(files) == 0 {
+			continue
+		}
+		path := filepath.Join(dir, files[0].Name())
+
+		state, err := w.store.LoadRun(runID)
+		if err != nil {
+			err = w.startWatchedRun(trigger, runID, path, now)
+			if err == nil {
+				started = append(started, runID)
+				continue
+			}
+			if !errors.Is(err, errRejectedFile) {
+				if first == nil {
+					first = err
+				}
+				continue
+			}
+			fmt.Printf("Warning: %v\n", err)
+			err = w.finish(trigger, dir, path, runID, true)
+		} else if failed, done := watchedRunFinished(state); done {
+			err = w.finish(trigger, dir, path, runID, failed)
+		}
+		if err != nil && first == nil {
+			first = err
+		}
+	}
+	return started, first
+}
+
+// startWatchedRun starts the run for a claimed file, seeding the artifacts
+// the trigger maps from it
+func (w *Watcher) startWatchedRun(trigger *workflow.TriggerSpec, runID, path string, now time.Time) error {
+	wf, _, err := workflow.LoadWorkflow(trigger.Workflow)
+	if err != nil {
+		return err
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	params, contents, err := trigger.MapPayload(content)
+	if err != nil {
+		return fmt.Errorf("%w %s: %v", errRejectedFile, path, err)
+	}
+	if _, err := wf.ResolveParams(params); err != nil {
+		return fmt.Errorf("%w %s: %v", errRejectedFile, path, err)
+	}
+
+	// The whole file keeps the content type of its extension; extracted
+	// values are detected from their content
+	name := filepath.Base(path)
+	seeds := make([]Seed, 0, len(contents))
+	for artifact, value := range contents {
+		seed := Seed{Name: artifact, Content: bytes.NewReader(value)}
+		if trigger.Artifacts[artifact] == "." {
+			seed.ContentType = mime.TypeByExtension(filepath.Ext(name))
+		}
+		seeds = append(seeds, seed)
+	}
+	sort.Slice(seeds, func(i, j int) bool { return seeds[i].Name < seeds[j].Name })
+
+	source := workflow.Trigger{Kind: workflow.TriggerWatch, Name: trigger.Name, At: now.UTC(), File: name}
+	if _, err := CreateTriggeredRun(w.store, wf, runID, params, source, seeds); err != nil {
+		return fmt.Errorf("failed to start run for %s: %w", path, err)
+	}
+	return nil
+}
+
+// finish moves a claimed file to the processed or failed directory and
+// removes its processing directory
+func (w *Watcher) finish(trigger *workflow.TriggerSpec, dir, path, runID string, failed bool) error {
+	if err := moveWatchedFile(path, trigger.DoneDir(failed), runID); err != nil {
+		return err
+	}
+	if err := os.Remove(dir); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", dir, err)
+	}
+	return nil
+}
+
+// watchedRunFinished reports whether a watched file's run is finished and
+// whether it failed. Cancelled runs have failed, as have runs with a step
+// that failed max_attempts times.
+func watchedRunFinished(state *workflow.RunState) (failed bool, done bool) {
+	if state.Status == workflow.RunCancelled {
+		return true, true
+	}
+	for _, step := range state.StepStates {
+		if step.Status == workflow.StatusFailed {
+			return true, true
+		}
+	}
+	return false, state.AllStepsCompleted()
+}
+
+// moveWatchedFile moves a file into dir. A file of the same name already
+// there is kept, prefixing the moved file with the run ID or, without one,
+// the time.
+func moveWatchedFile(path, dir, runID string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	name := filepath.Base(path)
+	dest := filepath.Join(dir, name)
+	if _, err := os.Stat(dest); err == nil {
+		prefix := runID
+		if prefix == "" {
+			prefix = time.Now().UTC().Format("20060102-150405")
+		}
+		dest = filepath.Join(dir, prefix+"-"+name)
+	}
+	if err := os.Rename(path, dest); err != nil {
+		return fmt.Errorf("failed to move %s: %w", path, err)
+	}
+	return nil
+}
+
+// Run checks the watch triggers every interval until ctx is cancelled
+func (w *Watcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := w.Check(time.Now()); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package orchestrator
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"composer/internal/workflow"
+)
+
+// intakeWorkflow reviews a seeded document for a customer
+const intakeWorkflow = `
+[params]
+customer = ""
+
+[[steps]]
+name = "review"
+handler = "human"
+inputs = ["document"]
+output = "reviewed"
+`
+
+// setupWatchTrigger installs the intake workflow and a watch trigger on the
+// inbox directory naming runs after the customer in each file
+func setupWatchTrigger(t *testing.T) {
+	t.Helper()
+	os.Chdir(t.TempDir())
+	files := map[string]string{
+		".composer/workflows/intake.toml": intakeWorkflow,
+		".composer/triggers/intake.toml": `
+type = "watch"
+workflow = "intake"
+dir = "inbox"
+pattern = "*.json"
+run_id = "intake-{{.customer}}"
+
+[params]
+customer = "customer"
+
+[artifacts]
+document = "."
+`,
+	}
+	for path, content := range files {
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+	os.MkdirAll("inbox", 0755)
+}
+
+// dropFile writes a file into the inbox
+func dropFile(t *testing.T, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join("inbox", name), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to drop %s: %v", name, err)
+	}
+}
+
+// expectFile fails the test unless the file exists
+func expectFile(t *testing.T, path string) {
+	t.Helper()
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Expected %s to exist: %v", path, err)
+	}
+}
+
+func TestWatcherStartsRunsForDroppedFiles(t *testing.T) {
+	setupWatchTrigger(t)
+	store := workflow.NewMemoryStore()
+	watcher := NewWatcher(store)
+
+	dropFile(t, "acme.json", `{"customer": "acme"}`)
+	dropFile(t, "broken.json", `not json`)
+	dropFile(t, "notes.txt", `ignored`)
+	dropFile(t, ".acme.json.swp", `ignored`)
+
+	// Files still being written are left alone until they settle
+	if started, err := watcher.Check(time.Now()); err != nil || len(started) != 0 {
+		t.Fatalf("Expected unsettled files to be left alone, got %v (%v)", started, err)
+	}
+
+	later := time.Now().Add(time.Minute)
+	started, err := watcher.Check(later)
+	if err != nil || !slices.Equal(started, []string{"intake-acme"}) {
+		t.Fatalf("Expected the acme run, got %v (%v)", started, err)
+	}
+	expectFile(t, "inbox/processing/intake-acme/acme.json")
+	expectFile(t, "inbox/failed/broken.json")
+	expectFile(t, "inbox/notes.txt")
+	expectFile(t, "inbox/.acme.json.swp")
+
+	state, err := store.LoadRun("intake-acme")
+	if err != nil {
+		t.Fatalf("Failed to load run: %v", err)
+	}
+	if state.Params["customer"] != "acme" {
+		t.Errorf("Expected the customer param, got %v", state.Params)
+	}
+	if state.Trigger == nil || state.Trigger.Kind != workflow.TriggerWatch || state.Trigger.File != "acme.json" {
+		t.Errorf("Expected the run to record its file, got %+v", state.Trigger)
+	}
+	content, err := workflow.ReadArtifact(store.Artifacts(), "intake-acme", "document")
+	if err != nil || string(content) != `{"customer": "acme"}` {
+		t.Errorf("Expected the file as the document, got %q (%v)", content, err)
+	}
+
+	// The file stays in processing until the run finishes
+	if started, _ := watcher.Check(later); len(started) != 0 {
+		t.Errorf("Expected no new runs, got %v", started)
+	}
+	expectFile(t, "inbox/processing/intake-acme/acme.json")
+
+	review := state.StepStates["review"]
+	review.Status = workflow.StatusSucceeded
+	state.StepStates["review"] = review
+	store.SaveRun(state)
+
+	if _, err := watcher.Check(later); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	expectFile(t, "inbox/processed/acme.json")
+	if _, err := os.Stat("inbox/processing/intake-acme"); !os.IsNotExist(err) {
+		t.Errorf("Expected the processing directory to be removed, got %v", err)
+	}
+
+	// A file for a run that already exists is rejected
+	dropFile(t, "again.json", `{"customer": "acme"}`)
+	if started, _ := watcher.Check(later); len(started) != 0 {
+		t.Errorf("Expected the duplicate to be rejected, got %v", started)
+	}
+	expectFile(t, "inbox/failed/again.json")
+}
+
+func TestWatcherFailsCancelledRuns(t *testing.T) {
+	setupWatchTrigger(t)
+	store := workflow.NewMemoryStore()
+	watcher := NewWatcher(store)
+	later := time.Now().Add(time.Minute)
+
+	// A file claimed before a restart gets its run on the next check
+	os.MkdirAll("inbox/processing/intake-globex", 0755)
+	os.WriteFile("inbox/processing/intake-globex/globex.json", []byte(`{"customer": "globex"}`), 0644)
+	started, err := watcher.Check(later)
+	if err != nil || !slices.Equal(started, []string{"intake-globex"}) {
+		t.Fatalf("Expected the claimed file's run, got %v (%v)", started, err)
+	}
+
+	// A failed file of the same name is kept
+	os.MkdirAll("inbox/failed", 0755)
+	os.WriteFile("inbox/failed/globex.json", []byte("earlier"), 0644)
+
+	if _, err := CancelRun(store, "intake-globex"); err != nil {
+		t.Fatalf("Cancel failed: %v", err)
+	}
+	if _, err := watcher.Check(later); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	expectFile(t, "inbox/failed/globex.json")
+	expectFile(t, "inbox/failed/intake-globex-globex.json")
+}
+
+func TestWatcherFailsRunsWithFailedSteps(t *testing.T) {
+	setupWatchTrigger(t)
+	os.WriteFile(".composer/workflows/intake.toml", []byte(`
+[params]
+customer = ""
+
+[[steps]]
+name = "extract"
+inputs = ["document"]
+output = "extracted"
+max_attempts = 1
+`), 0644)
+	// A broken trigger file does not stop the others
+	os.WriteFile(".composer/triggers/broken.toml", []byte("workflow = "), 0644)
+
+	store := workflow.NewMemoryStore()
+	watcher := NewWatcher(store)
+	later := time.Now().Add(time.Minute)
+
+	dropFile(t, "acme.json", `{"customer": "acme"}`)
+	started, err := watcher.Check(later)
+	if err == nil || !strings.Contains(err.Error(), "broken.toml") {
+		t.Errorf("Expected the broken trigger to be reported, got %v", err)
+	}
+	if !slices.Equal(started, []string{"intake-acme"}) {
+		t.Fatalf("Expected the acme run, got %v", started)
+	}
+
+	wf, _, _ := workflow.LoadWorkflow("intake")
+	if _, err := Tick(failingStore{store, "extracted"}, wf, "intake-acme"); err == nil {
+		t.Fatal("Expected the extract step to fail")
+	}
+
+	watcher.Check(later)
+	expectFile(t, "inbox/failed/acme.json")
+}
@@ -0,0 +1,184 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"composer/internal/ui/pages"
+	"composer/internal/ui/views"
+	"composer/internal/workflow"
+	"composer/pkg/ui/components"
+)
+
+// maxDiffBytes is the largest artifact version the dashboard will diff.
+const maxDiffBytes = 1 << 20
+
+func artifactHref(runID, name string) string {
+	return fmt.Sprintf("/run/%s/artifact/%s", url.PathEscape(runID), url.PathEscape(name))
+}
+
+// downloadHref links to an artifact version's content through the API
+func downloadHref(runID, name string, version int) string {
+	return fmt.Sprintf("/api/run/%s/artifact/%s?version=%d&download=1", url.PathEscape(runID), url.PathEscape(name), version)
+}
+
+// parseDiffRange reads the versions to compare from the from and to query
+// parameters. By default the latest version is compared with the one before
+// it; from 0 compares against empty content.
+func parseDiffRange(r *http.Request, latest int) (int, int, error) {
+	params := r.URL.Query()
+	to := latest
+	if value := params.Get("to"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > latest {
+			return 0, 0, fmt.Errorf("invalid to version '%s'", value)
+		}
+		to = n
+	}
+
+	from := to - 1
+	if value := params.Get("from"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 || n > latest {
+			return 0, 0, fmt.Errorf("invalid from version '%s'", value)
+		}
+		from = n
+	}
+
+	return from, to, nil
+}
+
+func buildArtifactModel(
+	artifacts workflow.ArtifactStore,
+	state *workflow.RunState,
+	name string,
+	versions []workflow.ArtifactInfo,
+	from, to int,
+) (pages.ArtifactProps, error) {
+	current := 0
+	if info, err := artifacts.Stat(state.ID, name); err == nil {
+		current = info.Version
+	}
+
+	byVersion := make(map[int]workflow.ArtifactInfo, len(versions))
+	versionVMs := make([]views.ArtifactVersion, 0, len(versions))
+	for i := len(versions) - 1; i >= 0; i-- {
+		info := versions[i]
+		byVersion[info.Version] = info
+
+		producer := ""
+		if info.Step != "" {
+			producer = fmt.Sprintf("%s attempt %d", info.Step, info.Attempt)
+		}
+
+		diffHref := ""
+		if i > 0 {
+			diffHref = fmt.Sprintf("%s?from=%d&to=%d", artifactHref(state.ID, name), versions[i-1].Version, info.Version)
+		}
+
+		versionVMs = append(versionVMs, views.ArtifactVersion{
+			Version:      info.Version,
+			Created:      info.CreatedAt.Local().Format(time.DateTime),
+			Producer:     producer,
+			Size:         info.Size,
+			Current:      info.Version == current,
+			DiffHref:     diffHref,
+			DownloadHref: downloadHref(state.ID, name, info.Version),
+		})
+	}
+
+	artifact := views.ArtifactView{
+		RunID:    state.ID,
+		Name:     name,
+		Versions: versionVMs,
+		From:     from,
+		To:       to,
+	}
+
+	fromText, message, err := diffableContent(artifacts, byVersion, from)
+	if err != nil {
+		return pages.ArtifactProps{}, err
+	}
+	toText, toMessage, err := diffableContent(artifacts, byVersion, to)
+	if err != nil {
+		return pages.ArtifactProps{}, err
+	}
+	if message == "" {
+		message = toMessage
+	}
+	if message != "" {
+		artifact.DiffMessage = message
+	} else {
+		artifact.Diff = diffLines(fromText, toText)
+	}
+
+	displayName := strings.TrimSpace(state.Name)
+	if displayName == "" {
+		displayName = state.ID
+	}
+
+	return pages.ArtifactProps{
+		Sidebar: components.SidebarProps{
+			Title: "Composer",
+			Links: []components.SidebarLink{
+				{
+					Label: "Dashboard",
+					Href:  "/",
+				},
+			},
+		},
+		Title:    fmt.Sprintf("%s: %s", displayName, name),
+		Artifact: artifact,
+	}, nil
+}
+
+// diffableContent reads an artifact version as text. Version 0 is empty. When
+// the version cannot be diffed, returns a message explaining why instead.
+func diffableContent(
+	artifacts workflow.ArtifactStore,
+	versions map[int]workflow.ArtifactInfo,
+	version int,
+) (string, string, error) {
+	if version == 0 {
+		return "", "", nil
+	}
+
+	info, ok := versions[version]
+	if !ok {
+		return "", fmt.Sprintf("Version %d does not exist.", version), nil
+	}
+	if !isTextContent(info.ContentType) {
+		return "", fmt.Sprintf("Version %d is %s and cannot be diffed.", version, info.ContentType), nil
+	}
+	if info.Size > maxDiffBytes {
+		return "", fmt.Sprintf("Version %d is too large to diff (%d bytes).", version, info.Size), nil
+	}
+
+	r, err := artifacts.OpenBlob(info.SHA256)
+	if err != nil {
+		return "", "", err
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read version %d: %w", version, err)
+	}
+
+	return string(data), "", nil
+}
+
+func isTextContent(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.TrimSpace(mediaType)
+	return strings.HasPrefix(mediaType, "text/") ||
+		mediaType == "application/json" ||
+		mediaType == "application/xml" ||
+		strings.HasSuffix(mediaType, "+json") ||
+		strings.HasSuffix(mediaType, "+xml")
+}
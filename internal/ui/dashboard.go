@@ -1,7 +1,9 @@
 package ui
 
 import (
+	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 
 	"composer/internal/orchestrator"
@@ -15,6 +17,8 @@ func buildDashboardModel(
 	workflows []workflow.Workflow,
 	runs []workflow.RunState,
 	waitingTasks map[string][]orchestrator.WaitingTask,
+	artifacts map[string][]workflow.ArtifactInfo,
+	filter orchestrator.TaskFilter,
 ) pages.DashboardProps {
 	workflowVMs := make([]views.WorkflowView, 0, len(workflows))
 	for _, wf := range workflows {
@@ -48,13 +52,23 @@ func buildDashboardModel(
 			})
 		}
 
+		runArtifacts := make([]views.RunArtifact, 0, len(artifacts[runState.ID]))
+		for _, info := range artifacts[runState.ID] {
+			runArtifacts = append(runArtifacts, views.RunArtifact{
+				Name: info.Name,
+				Href: artifactHref(runState.ID, info.Name),
+			})
+		}
+
 		runVMs = append(runVMs, views.RunView{
 			DisplayName:  displayName,
 			ID:           runID,
 			StateLabel:   status.Label,
 			StateClass:   status.Class,
 			WorkflowName: strings.TrimSpace(runState.WorkflowName),
+			ParentRunID:  strings.TrimSpace(runState.ParentRunID),
 			Steps:        steps,
+			Artifacts:    runArtifacts,
 		})
 	}
 	sort.Slice(runVMs, func(i, j int) bool {
@@ -80,6 +94,16 @@ func buildDashboardModel(
 				Name:        strings.TrimSpace(task.Name),
 				Description: strings.TrimSpace(task.Description),
 				Prompt:      strings.TrimSpace(task.Prompt),
+				Prefill:     task.Prefill,
+				Form:        formFields(task.Form),
+				Outcomes:    task.Outcomes,
+				Feedback:    append(taskFeedback(task.Feedback), taskVotes(task.Approvals)...),
+				Iteration:   taskIteration(task),
+				Assignment:  taskAssignment(task),
+				Claim:       taskClaim(task, filter.Actor),
+				Due:         taskDue(task),
+				Overdue:     task.Overdue,
+				Comments:    taskComments(task.Comments),
 			})
 		}
 
@@ -129,11 +153,8 @@ func buildDashboardModel(
 			Title: "Runs",
 			Runs:  runVMs,
 		},
-		RunModal: pages.DefaultRunModal(),
-		TaskColumn: views.WaitingColumnProps{
-			Title:  "Tasks",
-			Groups: taskGroupVMs,
-		},
+		RunModal:   pages.DefaultRunModal(),
+		TaskColumn: taskColumn(filter, taskGroupVMs),
 	}
 }
 
@@ -166,6 +187,10 @@ func stateClassForStatus(status workflow.StepStatus) string {
 		return "status-badge--ready"
 	case workflow.StatusPending:
 		return "status-badge--pending"
+	case workflow.StatusCancelled:
+		return "status-badge--cancelled"
+	case workflow.StatusSkipped:
+		return "status-badge--skipped"
 	default:
 		return "status-badge--unknown"
 	}
@@ -177,6 +202,13 @@ type runStatus struct {
 }
 
 func summarizeRunState(rs workflow.RunState) runStatus {
+	switch rs.Status {
+	case workflow.RunCancelled:
+		return runStatus{Label: "cancelled", Class: "status-badge--cancelled"}
+	case workflow.RunPaused:
+		return runStatus{Label: "paused", Class: "status-badge--paused"}
+	}
+
 	if len(rs.StepStates) == 0 {
 		return runStatus{Label: "pending", Class: "status-badge--pending"}
 	}
@@ -189,7 +221,7 @@ func summarizeRunState(rs workflow.RunState) runStatus {
 		switch step.Status {
 		case workflow.StatusFailed:
 			return runStatus{Label: "failed", Class: "status-badge--failed"}
-		case workflow.StatusSucceeded:
+		case workflow.StatusSucceeded, workflow.StatusSkipped:
 			// still successful unless other statuses contradict
 		case workflow.StatusReady:
 			hasReady = true
@@ -213,3 +245,188 @@ func summarizeRunState(rs workflow.RunState) runStatus {
 	}
 	return runStatus{Label: "unknown", Class: "status-badge--unknown"}
 }
+
+// formFields maps a step's declared form to dashboard form fields, starting
+// each control from the field's default
+func formFields(fields []workflow.FormField) []views.FormFieldProps {
+	props := make([]views.FormFieldProps, 0, len(fields))
+	for _, field := range fields {
+		input := views.FormInputProps{
+			Name:     field.Name,
+			Type:     field.FieldType(),
+			Required: field.Required,
+			Options:  field.Options,
+		}
+		if field.Default != nil {
+			input.Value = fmt.Sprint(field.Default)
+		}
+
+		switch field.FieldType() {
+		case "boolean":
+			input.Type = "checkbox"
+			input.Checked = field.Default == true
+			input.Value = ""
+		case "number":
+			if field.Min != nil {
+				input.Min = strconv.FormatFloat(*field.Min, 'f', -1, 64)
+			}
+			if field.Max != nil {
+				input.Max = strconv.FormatFloat(*field.Max, 'f', -1, 64)
+			}
+		}
+
+		props = append(props, views.FormFieldProps{
+			Label:          field.DisplayLabel(),
+			Hint:           field.Hint,
+			FormInputProps: input,
+		})
+	}
+	return props
+}
+
+// taskColumn titles the waiting tasks, offering to narrow them to the
+// viewer's own tasks or, when already narrowed, to show them all
+func taskColumn(filter orchestrator.TaskFilter, groups []views.WaitingGroup) views.WaitingColumnProps {
+	column := views.WaitingColumnProps{
+		Title: "Tasks",
+		Actions: []components.ButtonProps{
+			{
+				ID:       "toggle-my-tasks",
+				Class:    "button--outline button--sm",
+				Label:    "Mine",
+				Title:    "Show only my tasks",
+				HideIcon: true,
+			},
+		},
+		Groups: groups,
+	}
+
+	if filter.Actor != "" || len(filter.Roles) > 0 {
+		column.Title = "My tasks"
+		if filter.Actor != "" {
+			column.Title += " (" + filter.Actor + ")"
+		}
+		column.Actions[0].Label = "All"
+		column.Actions[0].Title = "Show every task"
+		column.Actions[0].Data = map[string]string{"filtered": "true"}
+	}
+	return column
+}
+
+// taskAssignment describes who should complete a waiting task and who holds
+// it
+func taskAssignment(task orchestrator.WaitingTask) string {
+	parts := []string{}
+	if task.Assignee != "" {
+		parts = append(parts, "Assigned to "+task.Assignee)
+	}
+	if len(task.Assignees) > 0 {
+		parts = append(parts, "Assigned to "+strings.Join(task.Assignees, ", "))
+	}
+	if task.ApprovalsRequired > 0 {
+		approved := 0
+		for _, vote := range task.Approvals {
+			if vote.Outcome == workflow.OutcomeApprove {
+				approved++
+			}
+		}
+		parts = append(parts, fmt.Sprintf("%d of %d approvals", approved, task.ApprovalsRequired))
+	}
+	if task.Role != "" {
+		parts = append(parts, "Role "+task.Role)
+	}
+	if task.ClaimedBy != "" {
+		parts = append(parts, fmt.Sprintf("Claimed by %s until %s", task.ClaimedBy, task.ClaimExpires.Format("15:04 MST")))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// taskClaim returns the claim action the viewer can take on a waiting task:
+// "release" for their own claim, "claim" for unclaimed tasks they may take,
+// and "" otherwise, including for tasks collecting approvals
+func taskClaim(task orchestrator.WaitingTask, actor string) string {
+	switch {
+	case task.ApprovalsRequired > 0:
+		return ""
+	case task.ClaimedBy != "":
+		if task.ClaimedBy == actor {
+			return "release"
+		}
+		return ""
+	case task.Assignee != "" && actor != "" && task.Assignee != actor:
+		return ""
+	default:
+		return "claim"
+	}
+}
+
+// taskDue describes a waiting task's deadline, if any
+func taskDue(task orchestrator.WaitingTask) string {
+	switch {
+	case task.Due == nil:
+		return ""
+	case task.EscalatedAt != nil:
+		return "Overdue since " + task.Due.Format("Jan 2 15:04 MST") + " · Escalated"
+	case task.Overdue:
+		return "Overdue since " + task.Due.Format("Jan 2 15:04 MST")
+	default:
+		return "Due " + task.Due.Format("Jan 2 15:04 MST")
+	}
+}
+
+// taskIteration describes a waiting task's place in its loop, if any
+func taskIteration(task orchestrator.WaitingTask) string {
+	if task.Loop == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s: iteration %d of %d", task.Loop, task.Iteration, task.MaxIterations)
+}
+
+// taskComments maps the comments shown on a waiting task
+func taskComments(comments []workflow.Comment) []views.TaskComment {
+	mapped := make([]views.TaskComment, len(comments))
+	for i, c := range comments {
+		context := ""
+		if c.Artifact != nil {
+			context = fmt.Sprintf("%s v%d", c.Artifact.Name, c.Artifact.Version)
+			if c.Lines != nil {
+				context += " " + c.Lines.Describe()
+			}
+		}
+		mapped[i] = views.TaskComment{
+			Author:  c.Author,
+			At:      c.At.Format("Jan 2 15:04 MST"),
+			Body:    c.Body,
+			Context: context,
+			Reply:   c.ReplyTo != 0,
+		}
+	}
+	return mapped
+}
+
+// taskVotes maps the votes cast on a task collecting approvals, labeled by
+// voter
+func taskVotes(votes []workflow.Approval) []views.TaskFeedback {
+	feedback := make([]views.TaskFeedback, len(votes))
+	for i, vote := range votes {
+		feedback[i] = views.TaskFeedback{
+			Step:    vote.Actor,
+			Outcome: vote.Outcome,
+			Comment: vote.Comment,
+		}
+	}
+	return feedback
+}
+
+// taskFeedback maps the decisions shown on a waiting task
+func taskFeedback(decisions []orchestrator.StepDecision) []views.TaskFeedback {
+	feedback := make([]views.TaskFeedback, len(decisions))
+	for i, decision := range decisions {
+		feedback[i] = views.TaskFeedback{
+			Step:    decision.Step,
+			Outcome: decision.Outcome,
+			Comment: decision.Comment,
+		}
+	}
+	return feedback
+}
@@ -2,6 +2,7 @@ package ui
 
 import (
 	"testing"
+	"time"
 
 	"composer/internal/orchestrator"
 	"composer/internal/workflow"
@@ -64,7 +65,7 @@ func TestBuildDashboardModel(t *testing.T) {
 		},
 	}
 
-	model := buildDashboardModel(workflows, runs, waiting)
+	model := buildDashboardModel(workflows, runs, waiting, nil, orchestrator.TaskFilter{})
 
 	if model.Sidebar.Title != "Composer" {
 		t.Fatalf("Sidebar title = %q, want %q", model.Sidebar.Title, "Composer")
@@ -125,6 +126,16 @@ func TestSummarizeRunState(t *testing.T) {
 			},
 			expected: runStatus{Label: "succeeded", Class: "status-badge--succeeded"},
 		},
+		{
+			name: "skipped steps still succeed",
+			state: workflow.RunState{
+				StepStates: map[string]workflow.StepState{
+					"a": {Status: workflow.StatusSucceeded, Outcome: "reject"},
+					"b": {Status: workflow.StatusSkipped},
+				},
+			},
+			expected: runStatus{Label: "succeeded", Class: "status-badge--succeeded"},
+		},
 		{
 			name: "ready takes precedence over pending",
 			state: workflow.RunState{
@@ -135,6 +146,27 @@ func TestSummarizeRunState(t *testing.T) {
 			},
 			expected: runStatus{Label: "ready", Class: "status-badge--ready"},
 		},
+		{
+			name: "paused run",
+			state: workflow.RunState{
+				Status: workflow.RunPaused,
+				StepStates: map[string]workflow.StepState{
+					"a": {Status: workflow.StatusPending},
+				},
+			},
+			expected: runStatus{Label: "paused", Class: "status-badge--paused"},
+		},
+		{
+			name: "cancelled run",
+			state: workflow.RunState{
+				Status: workflow.RunCancelled,
+				StepStates: map[string]workflow.StepState{
+					"a": {Status: workflow.StatusSucceeded},
+					"b": {Status: workflow.StatusCancelled},
+				},
+			},
+			expected: runStatus{Label: "cancelled", Class: "status-badge--cancelled"},
+		},
 		{
 			name: "pending fallback",
 			state: workflow.RunState{
@@ -161,6 +193,8 @@ func TestStateClassForStatus(t *testing.T) {
 		workflow.StatusSucceeded: "status-badge--succeeded",
 		workflow.StatusReady:     "status-badge--ready",
 		workflow.StatusPending:   "status-badge--pending",
+		workflow.StatusCancelled: "status-badge--cancelled",
+		workflow.StatusSkipped:   "status-badge--skipped",
 		workflow.StepStatus("x"): "status-badge--unknown",
 	}
 
@@ -170,3 +204,92 @@ func TestStateClassForStatus(t *testing.T) {
 		}
 	}
 }
+
+func TestFormFields(t *testing.T) {
+	max := 5.0
+	fields := formFields([]workflow.FormField{
+		{Name: "title", Label: "Title", Default: "Draft"},
+		{Name: "final", Type: "boolean", Default: true},
+		{Name: "score", Type: "number", Max: &max, Default: int64(3)},
+	})
+
+	if len(fields) != 3 {
+		t.Fatalf("expected 3 fields, got %d", len(fields))
+	}
+	if fields[0].Label != "Title" || fields[0].Type != "text" || fields[0].Value != "Draft" {
+		t.Fatalf("unexpected text field: %+v", fields[0])
+	}
+	if fields[1].Label != "final" || fields[1].Type != "checkbox" || !fields[1].Checked {
+		t.Fatalf("unexpected boolean field: %+v", fields[1])
+	}
+	if fields[2].Max != "5" || fields[2].Value != "3" {
+		t.Fatalf("unexpected number field: %+v", fields[2])
+	}
+}
+
+func TestTaskClaim(t *testing.T) {
+	cases := []struct {
+		task  orchestrator.WaitingTask
+		actor string
+		want  string
+	}{
+		{orchestrator.WaitingTask{}, "", "claim"},
+		{orchestrator.WaitingTask{ClaimedBy: "alice"}, "alice", "release"},
+		{orchestrator.WaitingTask{ClaimedBy: "alice"}, "bob", ""},
+		{orchestrator.WaitingTask{Assignee: "alice"}, "bob", ""},
+		{orchestrator.WaitingTask{Assignee: "alice"}, "alice", "claim"},
+		{orchestrator.WaitingTask{Assignees: []string{"alice", "bob"}, ApprovalsRequired: 2}, "alice", ""},
+	}
+
+	for _, tc := range cases {
+		if got := taskClaim(tc.task, tc.actor); got != tc.want {
+			t.Fatalf("taskClaim(%+v, %q) = %q, want %q", tc.task, tc.actor, got, tc.want)
+		}
+	}
+}
+
+func TestTaskDue(t *testing.T) {
+	due := time.Date(2025, 3, 2, 9, 0, 0, 0, time.UTC)
+	cases := []struct {
+		task orchestrator.WaitingTask
+		want string
+	}{
+		{orchestrator.WaitingTask{}, ""},
+		{orchestrator.WaitingTask{Due: &due}, "Due Mar 2 09:00 UTC"},
+		{orchestrator.WaitingTask{Due: &due, Overdue: true}, "Overdue since Mar 2 09:00 UTC"},
+		{orchestrator.WaitingTask{Due: &due, Overdue: true, EscalatedAt: &due}, "Overdue since Mar 2 09:00 UTC · Escalated"},
+	}
+
+	for _, tc := range cases {
+		if got := taskDue(tc.task); got != tc.want {
+			t.Fatalf("taskDue(%+v) = %q, want %q", tc.task, got, tc.want)
+		}
+	}
+}
+
+func TestTaskComments(t *testing.T) {
+	at := time.Date(2025, 3, 2, 9, 0, 0, 0, time.UTC)
+	comments := taskComments([]workflow.Comment{
+		{ID: 1, Author: "bob", Body: "Too long", At: at, Artifact: &workflow.ArtifactRef{Name: "draft", Version: 2}, Lines: &workflow.LineRange{Start: 4, End: 6}},
+		{ID: 2, Author: "alice", Body: "Trimmed", At: at, ReplyTo: 1},
+	})
+
+	if comments[0].Context != "draft v2 lines 4-6" || comments[0].At != "Mar 2 09:00 UTC" || comments[0].Reply {
+		t.Fatalf("unexpected comment: %+v", comments[0])
+	}
+	if comments[1].Context != "" || !comments[1].Reply {
+		t.Fatalf("unexpected reply: %+v", comments[1])
+	}
+}
+
+func TestTaskColumnFilter(t *testing.T) {
+	column := taskColumn(orchestrator.TaskFilter{}, nil)
+	if column.Title != "Tasks" || column.Actions[0].Label != "Mine" {
+		t.Fatalf("unexpected unfiltered column: %+v", column)
+	}
+
+	column = taskColumn(orchestrator.TaskFilter{Actor: "alice"}, nil)
+	if column.Title != "My tasks (alice)" || column.Actions[0].Label != "All" {
+		t.Fatalf("unexpected filtered column: %+v", column)
+	}
+}
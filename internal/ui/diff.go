@@ -0,0 +1,78 @@
+package ui
+
+import (
+	"strings"
+
+	"composer/internal/ui/views"
+)
+
+// maxDiffLines bounds the line-by-line comparison, which costs time and memory
+// proportional to the product of both line counts. Larger inputs are shown as
+// a wholesale replacement.
+const maxDiffLines = 4000
+
+// diffLines compares two texts line by line and returns the lines of the
+// second annotated against the first using a longest common subsequence.
+func diffLines(from, to string) []views.DiffLine {
+	a := splitLines(from)
+	b := splitLines(to)
+
+	if len(a) > maxDiffLines || len(b) > maxDiffLines {
+		lines := make([]views.DiffLine, 0, len(a)+len(b))
+		for _, text := range a {
+			lines = append(lines, views.DiffLine{Kind: views.DiffRemoved, Text: text})
+		}
+		for _, text := range b {
+			lines = append(lines, views.DiffLine{Kind: views.DiffAdded, Text: text})
+		}
+		return lines
+	}
+
+	// lcs[i][j] is the common subsequence length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	lines := make([]views.DiffLine, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, views.DiffLine{Kind: views.DiffContext, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, views.DiffLine{Kind: views.DiffRemoved, Text: a[i]})
+			i++
+		default:
+			lines = append(lines, views.DiffLine{Kind: views.DiffAdded, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, views.DiffLine{Kind: views.DiffRemoved, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, views.DiffLine{Kind: views.DiffAdded, Text: b[j]})
+	}
+
+	return lines
+}
+
+// splitLines splits text into lines, ignoring a trailing newline
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
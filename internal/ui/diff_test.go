@@ -0,0 +1,36 @@
+package ui
+
+import (
+	"testing"
+
+	"composer/internal/ui/views"
+)
+
+func TestDiffLines(t *testing.T) {
+	from := "title\nold line\nshared\n"
+	to := "title\nshared\nnew line\n"
+
+	got := diffLines(from, to)
+	want := []views.DiffLine{
+		{Kind: views.DiffContext, Text: "title"},
+		{Kind: views.DiffRemoved, Text: "old line"},
+		{Kind: views.DiffContext, Text: "shared"},
+		{Kind: views.DiffAdded, Text: "new line"},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d lines, got %+v", len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Line %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestDiffLinesFromEmpty(t *testing.T) {
+	got := diffLines("", "a\nb")
+	if len(got) != 2 || got[0].Kind != views.DiffAdded || got[1].Kind != views.DiffAdded {
+		t.Errorf("Expected every line added, got %+v", got)
+	}
+}
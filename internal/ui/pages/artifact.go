@@ -0,0 +1,33 @@
+package pages
+
+import (
+	"composer/internal/ui/views"
+	"composer/pkg/ui/components"
+
+	g "maragu.dev/gomponents"
+	"maragu.dev/gomponents/html"
+)
+
+// ArtifactProps aggregates the components required to render an artifact's
+// version history page.
+type ArtifactProps struct {
+	Sidebar  components.SidebarProps
+	Title    string
+	Artifact views.ArtifactView
+}
+
+// Artifact returns the root gomponent for an artifact's version history.
+func Artifact(p ArtifactProps) g.Node {
+	return html.Doctype(
+		html.HTML(
+			html.Lang("en"),
+			head(),
+			html.Body(
+				shell(p.Sidebar,
+					html.H1(g.Text(p.Title)),
+					p.Artifact.Render(),
+				),
+			),
+		),
+	)
+}
@@ -59,27 +59,16 @@ func head() g.Node {
 
 func body(p DashboardProps) g.Node {
 	return html.Body(
-		html.Div(
-			html.Class("ui-shell"),
-			html.Aside(
-				html.Class("ui-shell__sidebar"),
-				components.Sidebar(p.Sidebar),
-			),
-			html.Main(
-				html.Class("ui-shell__main"),
-				html.Div(
-					html.Class("ui-shell__content"),
-					html.H1(g.Text("Workflow Dashboard")),
-					html.Div(
-						html.Class("panel-grid"),
-						views.WorkflowColumn(p.WorkflowColumn),
-						p.RunColumn.Render(),
-						views.WaitingColumn(p.TaskColumn),
-					),
-					p.RunModal.Render(),
-					views.WorkflowModal(p.WorkflowModal),
-				),
+		shell(p.Sidebar,
+			html.H1(g.Text("Workflow Dashboard")),
+			html.Div(
+				html.Class("panel-grid"),
+				views.WorkflowColumn(p.WorkflowColumn),
+				p.RunColumn.Render(),
+				views.WaitingColumn(p.TaskColumn),
 			),
+			p.RunModal.Render(),
+			views.WorkflowModal(p.WorkflowModal),
 		),
 		html.Script(
 			html.Src("/static/dashboard.js"),
@@ -88,6 +77,24 @@ func body(p DashboardProps) g.Node {
 	)
 }
 
+// shell wraps page content in the sidebar layout shared by all pages.
+func shell(sidebar components.SidebarProps, content ...g.Node) g.Node {
+	return html.Div(
+		html.Class("ui-shell"),
+		html.Aside(
+			html.Class("ui-shell__sidebar"),
+			components.Sidebar(sidebar),
+		),
+		html.Main(
+			html.Class("ui-shell__main"),
+			html.Div(
+				html.Class("ui-shell__content"),
+				g.Group(content),
+			),
+		),
+	)
+}
+
 func buttonProps() components.ButtonProps {
 	return components.ButtonProps{
 		ID:       "add-workflow-step",
@@ -9,15 +9,17 @@ import (
 	"composer/internal/workflow"
 )
 
-// BuildRouter creates and configures the UI router.
-func (s *Server) BuildRouter() *http.ServeMux {
+// BuildRouter creates and configures the UI router, showing runs from the
+// given store.
+func (s *Server) BuildRouter(store workflow.RunStore) *http.ServeMux {
 	mux := http.NewServeMux()
-	mux.Handle("GET /", handleDashboard())
+	mux.Handle("GET /", handleDashboard(store))
+	mux.Handle("GET /run/{id}/artifact/{name}", handleArtifact(store))
 	mux.Handle("GET /static/", http.StripPrefix("/static/", http.FileServer(http.FS(s.static))))
 	return mux
 }
 
-func handleDashboard() http.HandlerFunc {
+func handleDashboard(store workflow.RunStore) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		workflows, err := workflow.ListWorkflows()
 		if err != nil {
@@ -25,19 +27,33 @@ func handleDashboard() http.HandlerFunc {
 			return
 		}
 
-		runs, err := workflow.ListRuns()
+		runs, err := store.ListRuns()
 		if err != nil {
 			http.Error(w, fmt.Sprintf("failed to load runs: %v", err), http.StatusInternalServerError)
 			return
 		}
 
-		tasks, err := orchestrator.ListWaitingTasksByRun(runs)
+		tasks, err := orchestrator.ListWaitingTasksByRun(store, runs)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("failed to load waiting tasks: %v", err), http.StatusInternalServerError)
 			return
 		}
 
-		props := buildDashboardModel(workflows, runs, tasks)
+		// Narrow the tasks to one actor's when asked
+		filter := orchestrator.NewTaskFilter(r.URL.Query().Get("actor"), r.URL.Query().Get("roles"))
+		tasks = orchestrator.FilterTasks(tasks, filter)
+
+		artifacts := make(map[string][]workflow.ArtifactInfo, len(runs))
+		for _, run := range runs {
+			infos, err := store.Artifacts().List(run.ID)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("failed to load artifacts: %v", err), http.StatusInternalServerError)
+				return
+			}
+			artifacts[run.ID] = infos
+		}
+
+		props := buildDashboardModel(workflows, runs, tasks, artifacts, filter)
 		page := pages.Dashboard(props)
 
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -46,3 +62,40 @@ func handleDashboard() http.HandlerFunc {
 		}
 	}
 }
+
+func handleArtifact(store workflow.RunStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		runID := r.PathValue("id")
+		name := r.PathValue("name")
+
+		state, err := store.LoadRun(runID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("run not found: %v", err), http.StatusNotFound)
+			return
+		}
+
+		versions, err := store.Artifacts().Versions(runID, name)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("artifact not found: %v", err), http.StatusNotFound)
+			return
+		}
+
+		from, to, err := parseDiffRange(r, len(versions))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		props, err := buildArtifactModel(store.Artifacts(), state, name, versions, from, to)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to load artifact versions: %v", err), http.StatusInternalServerError)
+			return
+		}
+		page := pages.Artifact(props)
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := page.Render(w); err != nil {
+			http.Error(w, fmt.Sprintf("failed to render artifact: %v", err), http.StatusInternalServerError)
+		}
+	}
+}
@@ -2,6 +2,11 @@
   initWorkflowModal();
   initRunModal();
   initRunTickButtons();
+  initTaskCompleteForms();
+  initMyTasksToggle();
+
+  const ACTOR_KEY = "composer.actor";
+  const ROLES_KEY = "composer.roles";
 
   function initWorkflowModal() {
     const modal = document.getElementById("workflow-modal");
@@ -441,4 +446,218 @@
       });
     });
   }
+
+  function initTaskCompleteForms() {
+    const forms = document.querySelectorAll(".waiting-task__form");
+    if (forms.length === 0) {
+      return;
+    }
+
+    forms.forEach((form) => {
+      const claimButton = form.querySelector("[data-claim]");
+      if (claimButton) {
+        claimButton.addEventListener("click", () => claimTask(form, claimButton));
+      }
+
+      form.addEventListener("submit", async (event) => {
+        event.preventDefault();
+
+        const runId = form.getAttribute("data-run-id");
+        const step = form.getAttribute("data-step");
+        const button = event.submitter || form.querySelector("button[type='submit']");
+        if (!runId || !step || !button) {
+          return;
+        }
+
+        const payload = { tick: true };
+        const outcome = button.getAttribute("data-outcome");
+        if (outcome) {
+          payload.outcome = outcome;
+          const comment = form.querySelector("[name='decision-comment']");
+          if (comment && comment.value.trim()) {
+            payload.comment = comment.value.trim();
+          }
+        }
+        if (form.getAttribute("data-form") === "values") {
+          payload.values = formValues(form);
+        } else {
+          payload.content = form.querySelector("textarea[name='content']").value;
+        }
+
+        const errorBanner = document.getElementById(form.getAttribute("data-error-id") || "");
+        if (errorBanner) {
+          errorBanner.textContent = "";
+          errorBanner.classList.remove("is-visible");
+        }
+
+        const originalLabel = button.textContent;
+        button.classList.remove("has-error");
+        button.removeAttribute("title");
+        button.disabled = true;
+        button.textContent = "Completing...";
+
+        try {
+          const response = await fetch(
+            "/api/run/" + encodeURIComponent(runId) + "/task/" + encodeURIComponent(step) + "/complete",
+            {
+              method: "POST",
+              headers: actorHeaders(currentActor(false), { "Content-Type": "application/json" }),
+              body: JSON.stringify(payload),
+            },
+          );
+
+          if (!response.ok) {
+            let message = "Failed to complete " + step + ".";
+            try {
+              const data = await response.json();
+              if (data && data.error && data.error.message) {
+                message = data.error.message;
+              }
+            } catch (_ignore) {
+              // Ignore JSON parsing errors on failure
+            }
+            throw new Error(message);
+          }
+
+          window.location.reload();
+        } catch (error) {
+          const message = error instanceof Error && error.message ? error.message : "Failed to complete " + step + ".";
+          button.disabled = false;
+          button.textContent = originalLabel;
+          button.classList.add("has-error");
+          button.setAttribute("title", message);
+          if (errorBanner) {
+            errorBanner.textContent = message;
+            errorBanner.classList.add("is-visible");
+          }
+          console.error(message);
+        }
+      });
+    });
+  }
+
+  // claimTask claims or releases a task for the current actor, asking for
+  // their name the first time
+  async function claimTask(form, button) {
+    const runId = form.getAttribute("data-run-id");
+    const step = form.getAttribute("data-step");
+    const action = button.getAttribute("data-claim");
+    const actor = currentActor(true);
+    if (!runId || !step || !action || !actor) {
+      return;
+    }
+
+    const originalLabel = button.textContent;
+    button.classList.remove("has-error");
+    button.removeAttribute("title");
+    button.disabled = true;
+    button.textContent = action === "claim" ? "Claiming..." : "Releasing...";
+
+    try {
+      const response = await fetch(
+        "/api/run/" + encodeURIComponent(runId) + "/task/" + encodeURIComponent(step) + "/" + action,
+        { method: "POST", headers: actorHeaders(actor, {}) },
+      );
+
+      if (!response.ok) {
+        let message = "Failed to " + action + " " + step + ".";
+        try {
+          const data = await response.json();
+          if (data && data.error && data.error.message) {
+            message = data.error.message;
+          }
+        } catch (_ignore) {
+          // Ignore JSON parsing errors on failure
+        }
+        throw new Error(message);
+      }
+
+      window.location.reload();
+    } catch (error) {
+      const message = error instanceof Error && error.message ? error.message : "Failed to " + action + " " + step + ".";
+      button.disabled = false;
+      button.textContent = originalLabel;
+      button.classList.add("has-error");
+      button.setAttribute("title", message);
+      console.error(message);
+    }
+  }
+
+  // initMyTasksToggle narrows the task list to the current actor's tasks,
+  // or shows every task again when it is already narrowed
+  function initMyTasksToggle() {
+    const button = document.getElementById("toggle-my-tasks");
+    if (!button) {
+      return;
+    }
+
+    button.addEventListener("click", () => {
+      if (button.getAttribute("data-filtered") === "true") {
+        window.location.href = window.location.pathname;
+        return;
+      }
+
+      const actor = currentActor(true);
+      if (!actor) {
+        return;
+      }
+      const roles = (window.prompt("Your roles, comma-separated (optional)", window.localStorage.getItem(ROLES_KEY) || "") || "").trim();
+      window.localStorage.setItem(ROLES_KEY, roles);
+
+      const params = new URLSearchParams({ actor: actor });
+      if (roles) {
+        params.set("roles", roles);
+      }
+      window.location.search = params.toString();
+    });
+  }
+
+  // currentActor names who is using the dashboard: the actor the task list
+  // is narrowed to, otherwise the name remembered from an earlier prompt.
+  // With ask, the name is prompted for when unknown.
+  function currentActor(ask) {
+    const narrowed = new URLSearchParams(window.location.search).get("actor");
+    if (narrowed) {
+      return narrowed;
+    }
+
+    let actor = window.localStorage.getItem(ACTOR_KEY) || "";
+    if (!actor && ask) {
+      actor = (window.prompt("Your name") || "").trim();
+      if (actor) {
+        window.localStorage.setItem(ACTOR_KEY, actor);
+      }
+    }
+    return actor;
+  }
+
+  // actorHeaders adds the actor header to the request headers when the actor
+  // is known
+  function actorHeaders(actor, headers) {
+    if (actor) {
+      headers["X-Composer-Actor"] = actor;
+    }
+    return headers;
+  }
+
+  // formValues collects a task form's fields as typed values, leaving out
+  // empty numbers so the step's defaults and rules apply
+  function formValues(form) {
+    const values = {};
+    form.querySelectorAll("input[name], select[name], textarea[name]").forEach((field) => {
+      if (field.name === "decision-comment") {
+        return;
+      }
+      if (field.type === "checkbox") {
+        values[field.name] = field.checked;
+      } else if (field.type === "number") {
+        if (field.value !== "") {
+          values[field.name] = Number(field.value);
+        }
+      } else if (field.value !== "") {
+        values[field.name] = field.value;
+      }
+    });
+    return values;
+  }
 })();
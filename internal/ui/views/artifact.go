@@ -0,0 +1,132 @@
+package views
+
+import (
+	"fmt"
+
+	g "maragu.dev/gomponents"
+	"maragu.dev/gomponents/html"
+
+	"composer/pkg/ui/components"
+)
+
+// DiffKind classifies a line in an artifact diff.
+type DiffKind string
+
+const (
+	DiffContext DiffKind = "context"
+	DiffAdded   DiffKind = "added"
+	DiffRemoved DiffKind = "removed"
+)
+
+// DiffLine is a single line of an artifact diff.
+type DiffLine struct {
+	Kind DiffKind
+	Text string
+}
+
+// ArtifactVersion summarizes one stored version of an artifact.
+type ArtifactVersion struct {
+	Version      int
+	Created      string
+	Producer     string
+	Size         int64
+	Current      bool
+	DiffHref     string
+	DownloadHref string
+}
+
+// ArtifactView shows an artifact's versions and the diff between two of them.
+type ArtifactView struct {
+	RunID       string
+	Name        string
+	Versions    []ArtifactVersion
+	From        int
+	To          int
+	Diff        []DiffLine
+	DiffMessage string
+}
+
+// Render returns the version list and diff panels.
+func (a ArtifactView) Render() g.Node {
+
+	// transform versions into data list items
+	items := make([]components.DataListItem, len(a.Versions))
+	for i, version := range a.Versions {
+		primary := fmt.Sprintf("v%d · %s · %d bytes", version.Version, version.Created, version.Size)
+		if version.Producer != "" {
+			primary += " · " + version.Producer
+		}
+
+		secondary := []g.Node{}
+		if version.Current {
+			secondary = append(secondary, components.StatusBadge(components.StatusBadgeProps{
+				Label:   "current",
+				Variant: "status-badge--succeeded",
+			}))
+		}
+		if version.DownloadHref != "" {
+			secondary = append(secondary, html.A(
+				html.Class("button button--text button--sm"),
+				html.Href(version.DownloadHref),
+				g.Text("Download"),
+			))
+		}
+		if version.DiffHref != "" {
+			secondary = append(secondary, html.A(
+				html.Class("button button--text button--sm"),
+				html.Href(version.DiffHref),
+				g.Text("Diff"),
+			))
+		}
+
+		items[i] = components.DataListItem{
+			Primary:   primary,
+			Secondary: g.Group(secondary),
+		}
+	}
+
+	versions := html.Section(
+		html.Class("panel"),
+		components.ColumnHeader("Versions", nil),
+		components.DataList(components.DataListProps{Items: items}),
+	)
+
+	// render the diff, or the reason there is none
+	var body g.Node
+	if a.DiffMessage != "" {
+		body = html.P(g.Text(a.DiffMessage))
+	} else {
+		body = html.Pre(
+			html.Class("artifact-diff"),
+			g.Map(a.Diff, func(line DiffLine) g.Node {
+				return html.Span(
+					html.Class("artifact-diff__line artifact-diff__line--"+string(line.Kind)),
+					g.Text(diffPrefix(line.Kind)+line.Text),
+				)
+			}),
+		)
+	}
+
+	title := fmt.Sprintf("Changes in v%d", a.To)
+	if a.From > 0 {
+		title = fmt.Sprintf("Changes from v%d to v%d", a.From, a.To)
+	}
+	diff := html.Section(
+		html.Class("panel"),
+		components.ColumnHeader(title, nil),
+		body,
+	)
+
+	return g.Group{versions, diff}
+}
+
+func diffPrefix(kind DiffKind) string {
+	switch kind {
+	case DiffAdded:
+		return "+ "
+	case DiffRemoved:
+		return "- "
+	default:
+		return "  "
+	}
+}
@@ -0,0 +1,43 @@
+package views_test
+
+import (
+	"testing"
+
+	"composer/internal/ui/views"
+	"composer/pkg/ui/testutil"
+	"gotest.tools/v3/golden"
+)
+
+func TestRenderArtifactView(t *testing.T) {
+	artifact := views.ArtifactView{
+		RunID: "run-a",
+		Name:  "draft",
+		Versions: []views.ArtifactVersion{
+			{
+				Version:      2,
+				Created:      "2026-01-02 10:00:00",
+				Producer:     "write attempt 2",
+				Size:         12,
+				Current:      true,
+				DiffHref:     "/run/run-a/artifact/draft?from=1&to=2",
+				DownloadHref: "/api/run/run-a/artifact/draft?version=2&download=1",
+			},
+			{
+				Version:  1,
+				Created:  "2026-01-01 10:00:00",
+				Producer: "write attempt 1",
+				Size:     10,
+			},
+		},
+		From: 1,
+		To:   2,
+		Diff: []views.DiffLine{
+			{Kind: views.DiffContext, Text: "title"},
+			{Kind: views.DiffRemoved, Text: "old"},
+			{Kind: views.DiffAdded, Text: "new"},
+		},
+	}
+
+	html := testutil.Render(t, artifact.Render())
+	golden.Assert(t, html, "artifact_diff.golden")
+}
@@ -2,6 +2,7 @@ package views
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	g "maragu.dev/gomponents"
@@ -24,7 +25,15 @@ type RunView struct {
 	StateLabel   string
 	StateClass   string
 	WorkflowName string
+	ParentRunID  string
 	Steps        []RunStep
+	Artifacts    []RunArtifact
+}
+
+// RunArtifact links to the version history of one of a run's artifacts.
+type RunArtifact struct {
+	Name string
+	Href string
 }
 
 // RunColumnProps describes the runs column rendered on the dashboard.
@@ -92,6 +101,9 @@ func (run RunView) Render() g.Node {
 		components.ColumnInfoRow("Run ID:", run.ID),
 		components.ColumnInfoRow("Workflow:", run.WorkflowName),
 	}
+	if parent := strings.TrimSpace(run.ParentRunID); parent != "" {
+		bodyNodes = append(bodyNodes, components.ColumnInfoRow("Forked From:", parent))
+	}
 
 	// render steps in the body
 	numSteps := len(run.Steps)
@@ -124,6 +136,24 @@ func (run RunView) Render() g.Node {
 		bodyNodes = append(bodyNodes, list)
 	}
 
+	// render artifact history links in the body
+	if len(run.Artifacts) > 0 {
+		bodyNodes = append(bodyNodes, html.H3(g.Text("Artifacts")))
+
+		items := make([]components.DataListItem, len(run.Artifacts))
+		for i, artifact := range run.Artifacts {
+			items[i] = components.DataListItem{
+				Primary: artifact.Name,
+				Secondary: html.A(
+					html.Class("button button--text button--sm"),
+					html.Href(artifact.Href),
+					g.Text("History"),
+				),
+			}
+		}
+		bodyNodes = append(bodyNodes, components.DataList(components.DataListProps{Items: items}))
+	}
+
 	// build and render card
 	card := components.CardProps{
 		Title:  run.DisplayName,
@@ -236,6 +266,8 @@ func (RunModalProps) Render() g.Node {
 type FormProps struct {
 	ID      string
 	ErrorID string
+	Class   string
+	Data    map[string]string
 	Fields  []FormFieldProps
 	Hidden  []FormInputProps
 	Actions []components.ButtonProps
@@ -249,6 +281,7 @@ type FormFieldProps struct {
 }
 
 // FormInputProps provides the data necessary to render a single input element.
+// The "textarea" and "select" types render those elements instead of an input.
 type FormInputProps struct {
 	ID          string
 	Name        string
@@ -256,6 +289,11 @@ type FormInputProps struct {
 	Placeholder string
 	ReadOnly    bool
 	Required    bool
+	Value       string
+	Checked     bool
+	Options     []string
+	Min         string
+	Max         string
 }
 
 func (form FormProps) Render() g.Node {
@@ -269,6 +307,8 @@ func (form FormProps) Render() g.Node {
 		// form
 		html.Form(
 			html.ID(form.ID),
+			g.If(form.Class != "", html.Class(form.Class)),
+			dataAttrs(form.Data),
 			g.Map(form.Fields, func(f FormFieldProps) g.Node { return f.Render() }),
 			g.Map(form.Hidden, func(i FormInputProps) g.Node { return i.Render() }),
 			html.Div(
@@ -281,6 +321,19 @@ func (form FormProps) Render() g.Node {
 	}
 }
 
+// dataAttrs renders data-* attributes in a stable order
+func dataAttrs(data map[string]string) g.Node {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return g.Map(keys, func(key string) g.Node {
+		return html.Data(key, data[key])
+	})
+}
+
 func (field FormFieldProps) Render() g.Node {
 	return html.Div(
 		html.Class("form__field"),
@@ -303,13 +356,43 @@ func (input FormInputProps) Render() g.Node {
 		inputType = "text"
 	}
 
-	return html.Input(
+	attrs := g.Group{
 		g.If(input.ID != "", html.ID(input.ID)),
 		g.If(input.Name != "", html.Name(input.Name)),
-		html.Type(inputType),
+	}
+	common := g.Group{
 		g.If(input.Placeholder != "", html.Placeholder(input.Placeholder)),
 		g.If(input.ReadOnly, html.ReadOnly()),
 		g.If(input.ReadOnly, html.Aria("readonly", "true")),
 		g.If(input.Required, html.Required()),
+	}
+
+	switch inputType {
+	case "textarea":
+		return html.Textarea(attrs, common, g.Text(input.Value))
+	case "select":
+		return html.Select(
+			attrs,
+			g.If(input.Required, html.Required()),
+			html.Option(html.Value("")),
+			g.Map(input.Options, func(option string) g.Node {
+				return html.Option(
+					html.Value(option),
+					g.If(option == input.Value, html.Selected()),
+					g.Text(option),
+				)
+			}),
+		)
+	}
+
+	return html.Input(
+		attrs,
+		html.Type(inputType),
+		g.If(input.Value != "", html.Value(input.Value)),
+		g.If(input.Checked, html.Checked()),
+		g.If(input.Min != "", html.Min(input.Min)),
+		g.If(input.Max != "", html.Max(input.Max)),
+		g.If(inputType == "number", html.Step("any")),
+		common,
 	)
 }
@@ -33,6 +33,20 @@ func TestRenderRunColumn(t *testing.T) {
 	golden.Assert(t, html, "run_column.golden")
 }
 
+func TestRenderForkedRun(t *testing.T) {
+	run := views.RunView{
+		DisplayName:  "Run B",
+		ID:           "run-b",
+		StateLabel:   "pending",
+		StateClass:   "status-badge--pending",
+		WorkflowName: "Alpha",
+		ParentRunID:  "run-a",
+	}
+
+	html := testutil.Render(t, run.Render())
+	golden.Assert(t, html, "run_forked.golden")
+}
+
 func TestRenderRunModal(t *testing.T) {
 	html := testutil.Render(t, views.RunModalProps{}.Render())
 	golden.Assert(t, html, "run_modal.golden")
@@ -12,6 +12,47 @@ type WaitingTask struct {
 	Name        string
 	Description string
 	Prompt      string
+	Prefill     string
+	// Form holds the step's declared fields; tasks without one are completed
+	// with free text
+	Form []FormFieldProps
+	// Outcomes are the decisions the task is completed with, one button each
+	Outcomes []string
+	// Feedback lists earlier decisions the task should see
+	Feedback []TaskFeedback
+	// Iteration describes the task's place in a loop, e.g. "revision:
+	// iteration 2 of 3"; empty outside loops
+	Iteration string
+	// Assignment describes who should complete the task and who holds it
+	Assignment string
+	// Claim is the claim action offered on the task: "claim", "release", or
+	// empty for none
+	Claim string
+	// Due describes the task's deadline, if any; Overdue highlights the card
+	Due     string
+	Overdue bool
+	// Comments are the discussion on the task, oldest first
+	Comments []TaskComment
+}
+
+// TaskComment is a comment shown on a task card.
+type TaskComment struct {
+	Author string
+	At     string
+	Body   string
+	// Context says what the comment refers to, e.g. "draft v2 lines 4-6"
+	Context string
+	// Reply marks answers to an earlier comment, indented under the thread
+	Reply bool
+}
+
+// TaskFeedback is a decision recorded on a step, shown on the task it
+// concerns. Votes on a task collecting approvals are labeled by voter.
+type TaskFeedback struct {
+	// Step labels the decision: the step that made it, or the voter
+	Step    string
+	Outcome string
+	Comment string
 }
 
 // WaitingGroup aggregates pending human tasks for a specific run.
@@ -63,7 +104,7 @@ func waitingItems(groups []WaitingGroup) []components.ColumnItem {
 				),
 				html.Ul(
 					html.Class("waiting-group__tasks"),
-					g.Group(waitingTasks(group.Tasks)),
+					g.Group(waitingTasks(group.RunID, group.Tasks)),
 				),
 			),
 		})
@@ -72,20 +113,182 @@ func waitingItems(groups []WaitingGroup) []components.ColumnItem {
 	return items
 }
 
-func waitingTasks(tasks []WaitingTask) []g.Node {
+func waitingTasks(runID string, tasks []WaitingTask) []g.Node {
 	nodes := make([]g.Node, 0, len(tasks))
 	for _, task := range tasks {
+		class := "card card--compact waiting-task"
+		if task.Overdue {
+			class += " waiting-task--overdue"
+		}
 		nodes = append(nodes, html.Li(
-			html.Class("card card--compact waiting-task"),
+			html.Class(class),
 			html.Div(
 				html.Class("waiting-task__name"),
 				g.Text(task.Name),
 			),
+			g.If(task.Iteration != "", html.Div(
+				html.Class("waiting-task__iteration"),
+				g.Text(task.Iteration),
+			)),
+			g.If(task.Assignment != "", html.Div(
+				html.Class("waiting-task__assignment"),
+				g.Text(task.Assignment),
+			)),
+			g.If(task.Due != "", html.Div(
+				html.Class("waiting-task__due"),
+				g.Text(task.Due),
+			)),
 			g.If(task.Description != "", html.Div(
 				html.Class("waiting-task__description"),
 				g.Text(task.Description),
 			)),
+			g.If(len(task.Feedback) > 0, taskFeedback(task.Feedback)),
+			g.If(len(task.Comments) > 0, taskComments(task.Comments)),
+			g.If(len(task.Form) == 0, completeForm(runID, task)),
+			g.If(len(task.Form) > 0, taskForm(runID, task)),
 		))
 	}
 	return nodes
 }
+
+// completeForm lets a human write the task's output, starting from the
+// step's prefill.
+func completeForm(runID string, task WaitingTask) g.Node {
+	return html.Form(
+		html.Class("waiting-task__form"),
+		html.Data("run-id", runID),
+		html.Data("step", task.Name),
+		html.Textarea(
+			html.Name("content"),
+			html.Rows("3"),
+			html.Placeholder("Output"),
+			html.Aria("label", "Output for "+task.Name),
+			g.Text(task.Prefill),
+		),
+		g.If(len(task.Outcomes) > 0, html.Input(
+			html.Name("decision-comment"),
+			html.Type("text"),
+			html.Placeholder("Comment"),
+			html.Aria("label", "Comment for "+task.Name),
+		)),
+		html.Div(
+			html.Class("waiting-task__actions"),
+			g.Map(completeButtons(task), components.Button),
+		),
+	)
+}
+
+// completeButtons returns a submit button per outcome, or a single Complete
+// button for tasks without outcomes, after the task's claim action if any
+func completeButtons(task WaitingTask) []components.ButtonProps {
+	buttons := []components.ButtonProps{}
+	if task.Claim != "" {
+		label := "Claim"
+		if task.Claim == "release" {
+			label = "Release"
+		}
+		buttons = append(buttons, components.ButtonProps{
+			Label:    label,
+			Class:    "button--text button--sm",
+			HideIcon: true,
+			Data:     map[string]string{"claim": task.Claim},
+		})
+	}
+
+	if len(task.Outcomes) == 0 {
+		return append(buttons, components.ButtonProps{
+			Label:    "Complete",
+			Class:    "button--primary button--sm",
+			Type:     "submit",
+			HideIcon: true,
+		})
+	}
+
+	for i, outcome := range task.Outcomes {
+		class := "button--outline button--sm"
+		if i == 0 {
+			class = "button--primary button--sm"
+		}
+		buttons = append(buttons, components.ButtonProps{
+			Label:    outcome,
+			Class:    class,
+			Type:     "submit",
+			HideIcon: true,
+			Data:     map[string]string{"outcome": outcome},
+		})
+	}
+	return buttons
+}
+
+// taskFeedback lists earlier decisions, such as the rejection that sent a
+// draft back for revision
+func taskFeedback(feedback []TaskFeedback) g.Node {
+	return html.Ul(
+		html.Class("waiting-task__feedback"),
+		g.Map(feedback, func(f TaskFeedback) g.Node {
+			return html.Li(
+				html.Strong(g.Text(f.Step+": "+f.Outcome)),
+				g.If(f.Comment != "", g.Text(" — "+f.Comment)),
+			)
+		}),
+	)
+}
+
+// taskComments lists the discussion on a task, indenting replies
+func taskComments(comments []TaskComment) g.Node {
+	return html.Ul(
+		html.Class("waiting-task__comments"),
+		g.Map(comments, func(c TaskComment) g.Node {
+			class := "waiting-task__comment"
+			if c.Reply {
+				class += " waiting-task__comment--reply"
+			}
+			return html.Li(
+				html.Class(class),
+				html.Div(
+					html.Class("waiting-task__comment-meta"),
+					html.Strong(g.Text(c.Author)),
+					g.Text(" · "+c.At),
+					g.If(c.Context != "", g.Text(" · "+c.Context)),
+				),
+				html.Div(g.Text(c.Body)),
+			)
+		}),
+	)
+}
+
+// taskForm renders a step's declared fields. Field ids are scoped to the run
+// and step so several task forms can share the dashboard.
+func taskForm(runID string, task WaitingTask) g.Node {
+	id := "task-" + runID + "-" + task.Name
+	fields := make([]FormFieldProps, len(task.Form))
+	for i, field := range task.Form {
+		field.ID = id + "-" + field.Name
+		fields[i] = field
+	}
+	if len(task.Outcomes) > 0 {
+		fields = append(fields, FormFieldProps{
+			Label: "Comment",
+			FormInputProps: FormInputProps{
+				ID:   id + "-decision-comment",
+				Name: "decision-comment",
+				Type: "text",
+			},
+		})
+	}
+
+	form := FormProps{
+		ID:      id,
+		ErrorID: id + "-error",
+		Class:   "waiting-task__form",
+		Data: map[string]string{
+			"run-id":   runID,
+			"step":     task.Name,
+			"error-id": id + "-error",
+			"form":     "values",
+		},
+		Fields:  fields,
+		Actions: completeButtons(task),
+	}
+	return form.Render()
+}
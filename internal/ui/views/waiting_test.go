@@ -22,6 +22,7 @@ func TestRenderWaitingColumn(t *testing.T) {
 					{
 						Name:        "Review",
 						Description: "Check",
+						Prefill:     "Draft <v1>",
 					},
 				},
 			},
@@ -31,3 +32,95 @@ func TestRenderWaitingColumn(t *testing.T) {
 	html := testutil.Render(t, views.WaitingColumn(props))
 	golden.Assert(t, html, "waiting_column.golden")
 }
+
+func TestRenderWaitingColumnForm(t *testing.T) {
+	props := views.WaitingColumnProps{
+		Title: "Tasks",
+		Groups: []views.WaitingGroup{
+			{
+				RunID:          "run-a",
+				RunDisplayName: "Run A",
+				TaskCount:      1,
+				Tasks: []views.WaitingTask{
+					{
+						Name: "Review",
+						Form: []views.FormFieldProps{
+							{
+								Label: "Verdict",
+								FormInputProps: views.FormInputProps{
+									Name:     "verdict",
+									Type:     "select",
+									Required: true,
+									Options:  []string{"approve", "reject"},
+								},
+							},
+							{
+								Label: "Score",
+								Hint:  "From 1 to 5",
+								FormInputProps: views.FormInputProps{
+									Name: "score",
+									Type: "number",
+									Min:  "1",
+									Max:  "5",
+								},
+							},
+							{
+								Label: "Notes",
+								FormInputProps: views.FormInputProps{
+									Name: "notes",
+									Type: "textarea",
+								},
+							},
+							{
+								Label: "Final",
+								FormInputProps: views.FormInputProps{
+									Name:    "final",
+									Type:    "checkbox",
+									Checked: true,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	html := testutil.Render(t, views.WaitingColumn(props))
+	golden.Assert(t, html, "waiting_form.golden")
+}
+
+func TestRenderWaitingColumnOutcomes(t *testing.T) {
+	props := views.WaitingColumnProps{
+		Title: "Tasks",
+		Groups: []views.WaitingGroup{
+			{
+				RunID:          "run-a",
+				RunDisplayName: "Run A",
+				TaskCount:      1,
+				Tasks: []views.WaitingTask{
+					{
+						Name:       "Review",
+						Prefill:    "Draft v2",
+						Outcomes:   []string{"approve", "reject"},
+						Iteration:  "revision: iteration 2 of 3",
+						Assignment: "Role editor · Claimed by alice until 10:30 UTC",
+						Claim:      "release",
+						Due:        "Overdue since Mar 2 09:00 UTC",
+						Overdue:    true,
+						Feedback: []views.TaskFeedback{
+							{Step: "Review", Outcome: "reject", Comment: "Tighten the intro"},
+						},
+						Comments: []views.TaskComment{
+							{Author: "bob", At: "Mar 2 08:00 UTC", Body: "Intro is long", Context: "draft v2 lines 1-4"},
+							{Author: "alice", At: "Mar 2 08:30 UTC", Body: "Cut it down", Reply: true},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	html := testutil.Render(t, views.WaitingColumn(props))
+	golden.Assert(t, html, "waiting_outcomes.golden")
+}
@@ -0,0 +1,78 @@
+package workflow
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Approval outcomes a step collecting approvals is completed with
+const (
+	OutcomeApprove = "approve"
+	OutcomeReject  = "reject"
+)
+
+// Approval is one person's vote on a step that collects approvals
+type Approval struct {
+	Actor   string    `json:"actor"`
+	Outcome string    `json:"outcome"`
+	Comment string    `json:"comment,omitempty"`
+	At      time.Time `json:"at"`
+}
+
+// Quorum reports the outcome the votes decide for a step needing required
+// approvals from voters people: approve once enough approve, reject once
+// too many reject for the rest to reach quorum, or "" while undecided
+func Quorum(votes []Approval, required, voters int) string {
+	approved, rejected := 0, 0
+	for _, vote := range votes {
+		if vote.Outcome == OutcomeApprove {
+			approved++
+		} else {
+			rejected++
+		}
+	}
+
+	switch {
+	case approved >= required:
+		return OutcomeApprove
+	case rejected > voters-required:
+		return OutcomeReject
+	default:
+		return ""
+	}
+}
+
+// ValidateApprovals checks that steps collecting approvals are human steps
+// with approve and reject outcomes and enough distinct assignees to reach
+// quorum
+func (w *Workflow) ValidateApprovals() error {
+	for _, step := range w.Steps {
+		if step.Assignee != "" && len(step.Assignees) > 0 {
+			return fmt.Errorf("step %s has both assignee and assignees", step.Name)
+		}
+		if step.ApprovalsRequired == 0 {
+			continue
+		}
+
+		if step.ApprovalsRequired < 0 {
+			return fmt.Errorf("step %s has an invalid approvals_required %d", step.Name, step.ApprovalsRequired)
+		}
+		if step.HandlerType() != "human" {
+			return fmt.Errorf("step %s collects approvals but is not a human step", step.Name)
+		}
+		if len(step.Outcomes) != 2 || !slices.Contains(step.Outcomes, OutcomeApprove) || !slices.Contains(step.Outcomes, OutcomeReject) {
+			return fmt.Errorf("step %s collects approvals and needs outcomes %s and %s", step.Name, OutcomeApprove, OutcomeReject)
+		}
+
+		assignees := slices.Clone(step.Assignees)
+		slices.Sort(assignees)
+		if len(slices.Compact(assignees)) != len(step.Assignees) {
+			return fmt.Errorf("step %s lists an assignee twice", step.Name)
+		}
+		if len(step.Assignees) < step.ApprovalsRequired {
+			return fmt.Errorf("step %s needs %d approvals but lists %d assignees", step.Name, step.ApprovalsRequired, len(step.Assignees))
+		}
+	}
+	return nil
+}
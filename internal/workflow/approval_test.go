@@ -0,0 +1,72 @@
+package workflow
+
+import "testing"
+
+func TestQuorum(t *testing.T) {
+	approve := Approval{Outcome: OutcomeApprove}
+	reject := Approval{Outcome: OutcomeReject}
+
+	tests := []struct {
+		name  string
+		votes []Approval
+		want  string
+	}{
+		{"no votes", nil, ""},
+		{"one approval", []Approval{approve}, ""},
+		{"quorum", []Approval{approve, reject, approve}, OutcomeApprove},
+		{"one rejection", []Approval{reject}, ""},
+		{"quorum unreachable", []Approval{reject, approve, reject}, OutcomeReject},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Quorum(tt.votes, 2, 3); got != tt.want {
+				t.Errorf("Quorum() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateApprovals(t *testing.T) {
+	signOff := func(required int, assignees ...string) Step {
+		return Step{
+			Name:              "sign-off",
+			Handler:           "human",
+			Outcomes:          []string{OutcomeApprove, OutcomeReject},
+			Assignees:         assignees,
+			ApprovalsRequired: required,
+		}
+	}
+
+	valid := Workflow{Steps: []Step{signOff(2, "alice", "bob", "carol")}}
+	if err := valid.ValidateApprovals(); err != nil {
+		t.Fatalf("ValidateApprovals failed: %v", err)
+	}
+
+	toolStep := signOff(1, "alice")
+	toolStep.Handler = ""
+	noOutcomes := signOff(1, "alice")
+	noOutcomes.Outcomes = nil
+	bothAssignees := signOff(1, "alice")
+	bothAssignees.Assignee = "bob"
+
+	tests := []struct {
+		name string
+		step Step
+	}{
+		{"tool step", toolStep},
+		{"no outcomes", noOutcomes},
+		{"too few assignees", signOff(3, "alice", "bob")},
+		{"duplicate assignee", signOff(2, "alice", "alice")},
+		{"assignee and assignees", bothAssignees},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wf := Workflow{Steps: []Step{tt.step}}
+			if err := wf.ValidateApprovals(); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,309 @@
+package workflow
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// ArtifactInfo describes one stored version of a run artifact
+type ArtifactInfo struct {
+	// Name is the artifact name steps use as inputs and outputs
+	Name string `json:"name"`
+	// Version numbers every write of the artifact from 1
+	Version int `json:"version"`
+	// ContentType is the MIME type given when written, or detected from content
+	ContentType string `json:"content_type"`
+	// Size is the content length in bytes
+	Size int64 `json:"size"`
+	// SHA256 is the hex digest the content is stored under
+	SHA256 string `json:"sha256"`
+	// CreatedAt is when the content was written
+	CreatedAt time.Time `json:"created_at"`
+	// Provenance records what produced this version
+	Provenance
+}
+
+// Provenance records what produced an artifact version. Versions written
+// outside a step, such as by earlier releases, have no provenance.
+type Provenance struct {
+	// Step is the step that produced this version
+	Step string `json:"step,omitempty"`
+	// Attempt is the producing step's execution attempt
+	Attempt int `json:"attempt,omitempty"`
+	// Handler is the handler that ran the step, "tool" or "human",
+	// "upload" for content uploaded through the API, or the kind of trigger
+	// that seeded the content
+	Handler string `json:"handler,omitempty"`
+	// HandlerVersion is the digest of the step definition the handler ran
+	HandlerVersion string `json:"handler_version,omitempty"`
+	// Actor is who completed a human step or uploaded the content
+	Actor string `json:"actor,omitempty"`
+	// Inputs are the exact input versions the step consumed, in order
+	Inputs []ArtifactRef `json:"inputs,omitempty"`
+}
+
+// ArtifactRef identifies one version of a run artifact
+type ArtifactRef struct {
+	Name    string `json:"name"`
+	Version int    `json:"version"`
+	SHA256  string `json:"sha256"`
+}
+
+// Ref returns the reference to this artifact version
+func (info ArtifactInfo) Ref() ArtifactRef {
+	return ArtifactRef{Name: info.Name, Version: info.Version, SHA256: info.SHA256}
+}
+
+// ArtifactMeta describes a new artifact version before it is written
+type ArtifactMeta struct {
+	// ContentType is detected from the content when empty
+	ContentType string
+	// Provenance records what produced the version
+	Provenance
+}
+
+// ArtifactStore stores run artifacts as content-addressed blobs. Each run maps
+// artifact names to blobs, so identical content is stored once no matter how
+// many runs or versions reference it.
+type ArtifactStore interface {
+	// List returns a run's current artifacts ordered by name
+	List(runID string) ([]ArtifactInfo, error)
+	// Stat returns a run's current artifact
+	Stat(runID, name string) (ArtifactInfo, error)
+	// Open streams a run's current artifact
+	Open(runID, name string) (io.ReadCloser, ArtifactInfo, error)
+	// OpenBlob streams the content stored under a SHA-256 digest
+	OpenBlob(sha256 string) (io.ReadCloser, error)
+	// Create starts writing a new version of a run's artifact. When the
+	// writer is committed, the version becomes current and the previous
+	// current version moves to the history.
+	Create(runID, name string, meta ArtifactMeta) (ArtifactWriter, error)
+	// Archive moves a run's current artifact to its history, leaving the
+	// name without a current version. Returns the archived version number.
+	Archive(runID, name string) (int, error)
+	// History returns the prior versions of a run's artifacts, keyed by
+	// artifact name, oldest first
+	History(runID string) (map[string][]ArtifactInfo, error)
+	// Versions returns every version of a run's artifact, including the
+	// current one, oldest first
+	Versions(runID, name string) ([]ArtifactInfo, error)
+}
+
+// ArtifactWriter streams the content of a new artifact
+type ArtifactWriter interface {
+	io.Writer
+	// Commit stores the written content and makes it the current artifact
+	Commit() (ArtifactInfo, error)
+	// Abort discards the written content
+	Abort() error
+}
+
+// ReadArtifact reads a run's whole current artifact into memory
+func ReadArtifact(artifacts ArtifactStore, runID, name string) ([]byte, error) {
+	r, _, err := artifacts.Open(runID, name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artifact %s: %w", name, err)
+	}
+
+	return data, nil
+}
+
+// WriteArtifact streams content into a new version of a run's artifact and
+// commits it
+func WriteArtifact(artifacts ArtifactStore, runID, name string, meta ArtifactMeta, content io.Reader) (ArtifactInfo, error) {
+	w, err := artifacts.Create(runID, name, meta)
+	if err != nil {
+		return ArtifactInfo{}, err
+	}
+
+	if _, err := io.Copy(w, content); err != nil {
+		w.Abort()
+		return ArtifactInfo{}, fmt.Errorf("failed to write artifact %s: %w", name, err)
+	}
+
+	return w.Commit()
+}
+
+// ArtifactNames returns the set of a run's current artifact names
+func ArtifactNames(artifacts ArtifactStore, runID string) (map[string]bool, error) {
+	infos, err := artifacts.List(runID)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool, len(infos))
+	for _, info := range infos {
+		names[info.Name] = true
+	}
+	return names, nil
+}
+
+// contentDigest tracks the hash, size, and leading bytes of streamed content
+type contentDigest struct {
+	hash hash.Hash
+	size int64
+	head []byte
+}
+
+// sniffLen is how many leading bytes content type detection considers
+const sniffLen = 512
+
+func newContentDigest() *contentDigest {
+	return &contentDigest{hash: sha256.New()}
+}
+
+func (d *contentDigest) Write(p []byte) (int, error) {
+	if len(d.head) < sniffLen {
+		d.head = append(d.head, p[:min(len(p), sniffLen-len(d.head))]...)
+	}
+	d.hash.Write(p)
+	d.size += int64(len(p))
+	return len(p), nil
+}
+
+// sum returns the hex SHA-256 of the content written so far
+func (d *contentDigest) sum() string {
+	return hex.EncodeToString(d.hash.Sum(nil))
+}
+
+// info describes the written content as a new, not yet numbered, version
+func (d *contentDigest) info(name string, meta ArtifactMeta) ArtifactInfo {
+	contentType := meta.ContentType
+	if contentType == "" {
+		contentType = http.DetectContentType(d.head)
+	}
+	return ArtifactInfo{
+		Name:        name,
+		ContentType: contentType,
+		Size:        d.size,
+		SHA256:      d.sum(),
+		CreatedAt:   time.Now().UTC(),
+		Provenance:  meta.Provenance,
+	}
+}
+
+// artifactRecord is the current version and prior versions for one name
+type artifactRecord struct {
+	Current *ArtifactInfo  `json:"current,omitempty"`
+	History []ArtifactInfo `json:"history,omitempty"`
+}
+
+// artifactManifest maps a run's artifact names to their records. Stores that
+// keep a manifest per run share its bookkeeping.
+type artifactManifest map[string]*artifactRecord
+
+func (m artifactManifest) list() []ArtifactInfo {
+	infos := []ArtifactInfo{}
+	for _, record := range m {
+		if record.Current != nil {
+			infos = append(infos, *record.Current)
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+func (m artifactManifest) stat(name string) (ArtifactInfo, error) {
+	record, exists := m[name]
+	if !exists || record.Current == nil {
+		return ArtifactInfo{}, fmt.Errorf("artifact %s not found", name)
+	}
+	return *record.Current, nil
+}
+
+// commit numbers a new version and makes it current
+func (m artifactManifest) commit(info ArtifactInfo) ArtifactInfo {
+	record, exists := m[info.Name]
+	if !exists {
+		record = &artifactRecord{}
+		m[info.Name] = record
+	}
+
+	if record.Current != nil {
+		record.History = append(record.History, *record.Current)
+	}
+	info.Version = len(record.History) + 1
+	record.Current = &info
+
+	return info
+}
+
+func (m artifactManifest) archive(name string) (int, error) {
+	record, exists := m[name]
+	if !exists || record.Current == nil {
+		return 0, fmt.Errorf("artifact %s not found", name)
+	}
+
+	archived := *record.Current
+	record.History = append(record.History, archived)
+	record.Current = nil
+
+	return archived.Version, nil
+}
+
+func (m artifactManifest) versions(name string) ([]ArtifactInfo, error) {
+	record, exists := m[name]
+	if !exists {
+		return nil, fmt.Errorf("artifact %s not found", name)
+	}
+
+	versions := append([]ArtifactInfo(nil), record.History...)
+	if record.Current != nil {
+		versions = append(versions, *record.Current)
+	}
+	return versions, nil
+}
+
+func (m artifactManifest) history() map[string][]ArtifactInfo {
+	history := make(map[string][]ArtifactInfo)
+	for name, record := range m {
+		if len(record.History) > 0 {
+			history[name] = append([]ArtifactInfo(nil), record.History...)
+		}
+	}
+	return history
+}
+
+// bufferedArtifactWriter collects content in memory and hands it to a commit
+// function, for stores that cannot stream into their backing storage
+type bufferedArtifactWriter struct {
+	buf    bytes.Buffer
+	digest *contentDigest
+	commit func(data []byte, digest *contentDigest) (ArtifactInfo, error)
+	done   bool
+}
+
+func (w *bufferedArtifactWriter) Write(p []byte) (int, error) {
+	if w.done {
+		return 0, fmt.Errorf("artifact writer is closed")
+	}
+	w.digest.Write(p)
+	return w.buf.Write(p)
+}
+
+func (w *bufferedArtifactWriter) Commit() (ArtifactInfo, error) {
+	if w.done {
+		return ArtifactInfo{}, fmt.Errorf("artifact writer is closed")
+	}
+	w.done = true
+	return w.commit(w.buf.Bytes(), w.digest)
+}
+
+func (w *bufferedArtifactWriter) Abort() error {
+	w.done = true
+	w.buf.Reset()
+	return nil
+}
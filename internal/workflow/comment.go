@@ -0,0 +1,77 @@
+package workflow
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Comment is a remark left on a run, or on one of its tasks, optionally
+// pointing at lines of an artifact version. Replies name the comment they
+// answer, forming threads.
+type Comment struct {
+	// ID numbers the run's comments from 1 in the order they were left
+	ID     int       `json:"id"`
+	Author string    `json:"author"`
+	Body   string    `json:"body"`
+	At     time.Time `json:"at"`
+	// Step is the task the comment is on; empty for comments on the run
+	Step string `json:"step,omitempty"`
+	// ReplyTo is the ID of the comment this one answers, or 0
+	ReplyTo int `json:"reply_to,omitempty"`
+	// Artifact is the artifact version discussed, and Lines the part of it
+	Artifact *ArtifactRef `json:"artifact,omitempty"`
+	Lines    *LineRange   `json:"lines,omitempty"`
+}
+
+// LineRange is an inclusive range of lines, numbered from 1
+type LineRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// ParseLineRange parses a line or range of lines such as "12" or "12-20"
+func ParseLineRange(s string) (LineRange, error) {
+	start, end, isRange := strings.Cut(strings.TrimSpace(s), "-")
+	if !isRange {
+		end = start
+	}
+
+	var r LineRange
+	var err1, err2 error
+	r.Start, err1 = strconv.Atoi(strings.TrimSpace(start))
+	r.End, err2 = strconv.Atoi(strings.TrimSpace(end))
+	if err1 != nil || err2 != nil {
+		return LineRange{}, fmt.Errorf("invalid line range '%s'", s)
+	}
+	if err := r.Validate(); err != nil {
+		return LineRange{}, err
+	}
+	return r, nil
+}
+
+// Validate checks that the range starts at line 1 or later and does not end
+// before it starts
+func (r LineRange) Validate() error {
+	if r.Start < 1 || r.End < r.Start {
+		return fmt.Errorf("invalid line range %s", r)
+	}
+	return nil
+}
+
+// String formats the range as "12" or "12-20"
+func (r LineRange) String() string {
+	if r.Start == r.End {
+		return strconv.Itoa(r.Start)
+	}
+	return fmt.Sprintf("%d-%d", r.Start, r.End)
+}
+
+// Describe names the range in prose, as "line 12" or "lines 12-20"
+func (r LineRange) Describe() string {
+	if r.Start == r.End {
+		return "line " + r.String()
+	}
+	return "lines " + r.String()
+}
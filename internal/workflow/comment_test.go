@@ -0,0 +1,42 @@
+package workflow
+
+import "testing"
+
+func TestParseLineRange(t *testing.T) {
+	tests := []struct {
+		input string
+		want  LineRange
+		err   bool
+	}{
+		{input: "12", want: LineRange{Start: 12, End: 12}},
+		{input: "12-20", want: LineRange{Start: 12, End: 20}},
+		{input: " 3 - 4 ", want: LineRange{Start: 3, End: 4}},
+		{input: "0", err: true},
+		{input: "20-12", err: true},
+		{input: "a-b", err: true},
+		{input: "", err: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseLineRange(tt.input)
+		if tt.err {
+			if err == nil {
+				t.Errorf("ParseLineRange(%q) expected an error, got %v", tt.input, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseLineRange(%q) = %v, %v; want %v", tt.input, got, err, tt.want)
+		}
+	}
+
+	if s := (LineRange{Start: 12, End: 12}).String(); s != "12" {
+		t.Errorf("Expected a single line to format as 12, got %s", s)
+	}
+	if s := (LineRange{Start: 12, End: 20}).String(); s != "12-20" {
+		t.Errorf("Expected a range to format as 12-20, got %s", s)
+	}
+	if s := (LineRange{Start: 12, End: 12}).Describe(); s != "line 12" {
+		t.Errorf("Expected a single line to read as line 12, got %s", s)
+	}
+}
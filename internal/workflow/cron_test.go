@@ -0,0 +1,54 @@
+package workflow
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronRejectsInvalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "5-1 * * * *", "*/0 * * * *", "@sometimes"} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q) should fail", expr)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// Saturday, March 1 2025
+	from := time.Date(2025, 3, 1, 10, 17, 30, 0, time.UTC)
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2025, 3, 1, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2025, 3, 1, 10, 30, 0, 0, time.UTC)},
+		{"0 9 * * *", time.Date(2025, 3, 2, 9, 0, 0, 0, time.UTC)},
+		{"30 9 * * 1-5", time.Date(2025, 3, 3, 9, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC)},
+		{"0 8 1,15 * *", time.Date(2025, 3, 15, 8, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// With both day fields restricted, either one matches
+		{"0 8 10 * 1", time.Date(2025, 3, 3, 8, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		cron, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseCron(%q) failed: %v", tt.expr, err)
+		}
+		if got := cron.Next(from); !got.Equal(tt.want) {
+			t.Errorf("Next(%q) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestCronNextInLocation(t *testing.T) {
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	cron, _ := ParseCron("0 9 * * *")
+
+	got := cron.Next(time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC).In(loc))
+	if want := time.Date(2025, 3, 2, 7, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Expected 09:00 in UTC+2 to be %v, got %v", want, got)
+	}
+}
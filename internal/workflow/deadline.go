@@ -0,0 +1,82 @@
+package workflow
+
+import (
+	"fmt"
+	"time"
+)
+
+// Escalation says what happens to a human step that is still waiting a grace
+// period after its deadline: it can be reassigned to another role, announced
+// to a URL, and completed with a default outcome
+type Escalation struct {
+	// After is the grace period past the deadline, e.g. "1h" (default none)
+	After string `toml:"after,omitempty" json:"after,omitempty"`
+	// Role reassigns the step to everyone holding this role
+	Role string `toml:"role,omitempty" json:"role,omitempty"`
+	// Notify is a URL the overdue task is posted to as JSON
+	Notify string `toml:"notify,omitempty" json:"notify,omitempty"`
+	// Outcome completes the step with this outcome and its prefill
+	Outcome string `toml:"outcome,omitempty" json:"outcome,omitempty"`
+}
+
+// Deadline returns when a step that became ready at the given time is due,
+// and false for steps without a due_in
+func (s Step) Deadline(readyAt time.Time) (time.Time, bool) {
+	if s.DueIn == "" {
+		return time.Time{}, false
+	}
+	dueIn, err := time.ParseDuration(s.DueIn)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return readyAt.Add(dueIn), true
+}
+
+// EscalatesAt returns when a step that became ready at the given time is
+// escalated, and false for steps without a deadline or an escalation
+func (s Step) EscalatesAt(readyAt time.Time) (time.Time, bool) {
+	due, ok := s.Deadline(readyAt)
+	if !ok || s.Escalation == nil {
+		return time.Time{}, false
+	}
+	after, _ := time.ParseDuration(s.Escalation.After)
+	return due.Add(after), true
+}
+
+// ValidateDeadlines checks that deadlines and grace periods are durations,
+// that only human steps with a deadline escalate, and that escalations do
+// something, completing only with one of the step's outcomes
+func (w *Workflow) ValidateDeadlines() error {
+	for _, step := range w.Steps {
+		if step.DueIn != "" {
+			if step.HandlerType() != "human" {
+				return fmt.Errorf("step %s has a deadline but is not a human step", step.Name)
+			}
+			if dueIn, err := time.ParseDuration(step.DueIn); err != nil || dueIn <= 0 {
+				return fmt.Errorf("step %s has an invalid due_in '%s'", step.Name, step.DueIn)
+			}
+		}
+
+		esc := step.Escalation
+		if esc == nil {
+			continue
+		}
+		if step.DueIn == "" {
+			return fmt.Errorf("step %s escalates but has no due_in", step.Name)
+		}
+		if esc.After != "" {
+			if after, err := time.ParseDuration(esc.After); err != nil || after < 0 {
+				return fmt.Errorf("step %s has an invalid escalation after '%s'", step.Name, esc.After)
+			}
+		}
+		if esc.Role == "" && esc.Notify == "" && esc.Outcome == "" {
+			return fmt.Errorf("step %s escalation needs a role, notify, or outcome", step.Name)
+		}
+		if esc.Outcome != "" {
+			if err := step.CheckOutcome(esc.Outcome); err != nil {
+				return fmt.Errorf("invalid escalation: %w", err)
+			}
+		}
+	}
+	return nil
+}
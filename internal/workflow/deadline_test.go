@@ -0,0 +1,56 @@
+package workflow
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStepDeadline(t *testing.T) {
+	readyAt := time.Date(2025, 3, 2, 9, 0, 0, 0, time.UTC)
+	step := Step{DueIn: "2h", Escalation: &Escalation{After: "30m", Role: "leads"}}
+
+	due, ok := step.Deadline(readyAt)
+	if !ok || !due.Equal(readyAt.Add(2*time.Hour)) {
+		t.Errorf("Expected the step due two hours after ready, got %v", due)
+	}
+	at, ok := step.EscalatesAt(readyAt)
+	if !ok || !at.Equal(readyAt.Add(150*time.Minute)) {
+		t.Errorf("Expected escalation half an hour after the deadline, got %v", at)
+	}
+
+	if _, ok := (Step{}).Deadline(readyAt); ok {
+		t.Error("Expected no deadline without due_in")
+	}
+}
+
+func TestValidateDeadlines(t *testing.T) {
+	review := func(dueIn string, esc *Escalation) Step {
+		return Step{Name: "review", Handler: "human", Outcomes: []string{"approve"}, DueIn: dueIn, Escalation: esc}
+	}
+
+	valid := Workflow{Steps: []Step{review("4h", &Escalation{After: "1h", Outcome: "approve"})}}
+	if err := valid.ValidateDeadlines(); err != nil {
+		t.Fatalf("ValidateDeadlines failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		step Step
+	}{
+		{"tool step", Step{Name: "build", DueIn: "1h"}},
+		{"bad due_in", review("soon", nil)},
+		{"escalation without due_in", review("", &Escalation{Role: "leads"})},
+		{"bad grace period", review("1h", &Escalation{After: "later", Role: "leads"})},
+		{"no action", review("1h", &Escalation{After: "1h"})},
+		{"unknown outcome", review("1h", &Escalation{Outcome: "reject"})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wf := Workflow{Steps: []Step{tt.step}}
+			if err := wf.ValidateDeadlines(); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,301 @@
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// FormField is a typed value a human step asks for. Submitted forms are
+// stored as a JSON object keyed by field name.
+type FormField struct {
+	Name  string `toml:"name" json:"name"`
+	Label string `toml:"label,omitempty" json:"label,omitempty"`
+	// Type is "text" (default), "textarea", "select", "boolean", or "number"
+	Type     string `toml:"type,omitempty" json:"type,omitempty"`
+	Hint     string `toml:"hint,omitempty" json:"hint,omitempty"`
+	Required bool   `toml:"required,omitempty" json:"required,omitempty"`
+	// Default is used when the field is not submitted
+	Default any `toml:"default,omitempty" json:"default,omitempty"`
+	// Options lists the allowed values of a select
+	Options []string `toml:"options,omitempty" json:"options,omitempty"`
+	// MinLength, MaxLength, and Pattern constrain text and textarea values
+	MinLength int    `toml:"min_length,omitempty" json:"min_length,omitempty"`
+	MaxLength int    `toml:"max_length,omitempty" json:"max_length,omitempty"`
+	Pattern   string `toml:"pattern,omitempty" json:"pattern,omitempty"`
+	// Min and Max bound number values
+	Min *float64 `toml:"min,omitempty" json:"min,omitempty"`
+	Max *float64 `toml:"max,omitempty" json:"max,omitempty"`
+}
+
+// FieldType returns the field's type, defaulting to "text" when unset
+func (f FormField) FieldType() string {
+	if f.Type == "" {
+		return "text"
+	}
+	return f.Type
+}
+
+// DisplayLabel returns the field's label, defaulting to its name
+func (f FormField) DisplayLabel() string {
+	if f.Label == "" {
+		return f.Name
+	}
+	return f.Label
+}
+
+// FormError is a submitted value that failed its field's validation
+type FormError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// FormErrors collects every invalid value of a form submission
+type FormErrors []FormError
+
+func (e FormErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, fe := range e {
+		messages[i] = fe.Field + ": " + fe.Message
+	}
+	return "invalid form submission: " + strings.Join(messages, "; ")
+}
+
+// ValidateFormFields checks that a form's declaration is usable: field names
+// are set and unique, types are known, selects have options, and patterns
+// compile
+func ValidateFormFields(fields []FormField) error {
+	seen := map[string]bool{}
+	for _, f := range fields {
+		if f.Name == "" {
+			return fmt.Errorf("form field name cannot be empty")
+		}
+		if seen[f.Name] {
+			return fmt.Errorf("duplicate form field '%s'", f.Name)
+		}
+		seen[f.Name] = true
+
+		switch f.FieldType() {
+		case "text", "textarea", "boolean", "number":
+		case "select":
+			if len(f.Options) == 0 {
+				return fmt.Errorf("select field '%s' has no options", f.Name)
+			}
+		default:
+			return fmt.Errorf("form field '%s' has unknown type '%s'", f.Name, f.Type)
+		}
+
+		if f.Pattern != "" {
+			if _, err := regexp.Compile(f.Pattern); err != nil {
+				return fmt.Errorf("form field '%s' has invalid pattern: %w", f.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateForms checks the form declared by every step
+func (w *Workflow) ValidateForms() error {
+	for _, step := range w.Steps {
+		if err := ValidateFormFields(step.Form); err != nil {
+			return fmt.Errorf("step %s: %w", step.Name, err)
+		}
+	}
+	return nil
+}
+
+// ValidateForm checks submitted values against the form's fields, filling in
+// defaults. Returns the values normalized to strings, booleans, and numbers,
+// or FormErrors listing every invalid value.
+func ValidateForm(fields []FormField, values map[string]any) (map[string]any, error) {
+	if err := ValidateFormFields(fields); err != nil {
+		return nil, err
+	}
+
+	var errs FormErrors
+	declared := map[string]bool{}
+	result := make(map[string]any, len(fields))
+	for _, f := range fields {
+		declared[f.Name] = true
+
+		value, ok := values[f.Name]
+		if !ok || value == nil {
+			value = f.Default
+		}
+
+		normalized, err := f.Validate(value)
+		if err != nil {
+			errs = append(errs, FormError{Field: f.Name, Message: err.Error()})
+			continue
+		}
+		if normalized != nil {
+			result[f.Name] = normalized
+		}
+	}
+
+	// Reject values the form does not ask for, in a stable order
+	unknown := []string{}
+	for name := range values {
+		if !declared[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	slices.Sort(unknown)
+	for _, name := range unknown {
+		errs = append(errs, FormError{Field: name, Message: "unknown field"})
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return result, nil
+}
+
+// Validate checks a single value against the field's type and rules. A nil
+// value means the field was left empty; the normalized result is then nil
+// unless the field has a zero value of its own.
+func (f FormField) Validate(value any) (any, error) {
+	switch f.FieldType() {
+	case "boolean":
+		checked := false
+		if value != nil {
+			b, ok := value.(bool)
+			if !ok {
+				return nil, fmt.Errorf("must be true or false")
+			}
+			checked = b
+		}
+		if f.Required && !checked {
+			return nil, fmt.Errorf("must be checked")
+		}
+		return checked, nil
+
+	case "number":
+		if value == nil {
+			if f.Required {
+				return nil, fmt.Errorf("is required")
+			}
+			return nil, nil
+		}
+		n, ok := toFloat(value)
+		if !ok {
+			return nil, fmt.Errorf("must be a number")
+		}
+		if f.Min != nil && n < *f.Min {
+			return nil, fmt.Errorf("must be at least %s", formatNumber(*f.Min))
+		}
+		if f.Max != nil && n > *f.Max {
+			return nil, fmt.Errorf("must be at most %s", formatNumber(*f.Max))
+		}
+		return n, nil
+
+	default:
+		s := ""
+		if value != nil {
+			str, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("must be a string")
+			}
+			s = str
+		}
+		if strings.TrimSpace(s) == "" {
+			if f.Required {
+				return nil, fmt.Errorf("is required")
+			}
+			if value == nil {
+				return nil, nil
+			}
+			return s, nil
+		}
+
+		if f.FieldType() == "select" {
+			if !slices.Contains(f.Options, s) {
+				return nil, fmt.Errorf("must be one of %s", strings.Join(f.Options, ", "))
+			}
+			return s, nil
+		}
+
+		length := utf8.RuneCountInString(s)
+		if f.MinLength > 0 && length < f.MinLength {
+			return nil, fmt.Errorf("must be at least %d characters", f.MinLength)
+		}
+		if f.MaxLength > 0 && length > f.MaxLength {
+			return nil, fmt.Errorf("must be at most %d characters", f.MaxLength)
+		}
+		if f.Pattern != "" {
+			re, err := regexp.Compile("^(?:" + f.Pattern + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("has an invalid pattern: %w", err)
+			}
+			if !re.MatchString(s) {
+				return nil, fmt.Errorf("must match %s", f.Pattern)
+			}
+		}
+		return s, nil
+	}
+}
+
+// Parse converts text typed by a human into a value of the field's type,
+// such as the answer to a prompt. Empty text is a nil value.
+func (f FormField) Parse(raw string) (any, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+
+	switch f.FieldType() {
+	case "boolean":
+		switch strings.ToLower(raw) {
+		case "y", "yes", "true":
+			return true, nil
+		case "n", "no", "false":
+			return false, nil
+		}
+		return nil, fmt.Errorf("must be yes or no")
+	case "number":
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+			return nil, fmt.Errorf("must be a number")
+		}
+		return n, nil
+	default:
+		return raw, nil
+	}
+}
+
+// DecodeForm reads a JSON object of submitted form values
+func DecodeForm(data []byte) (map[string]any, error) {
+	var values map[string]any
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("form values must be a JSON object: %w", err)
+	}
+	if values == nil {
+		values = map[string]any{}
+	}
+	return values, nil
+}
+
+// toFloat converts the numeric types JSON and TOML decode into
+func toFloat(value any) (float64, bool) {
+	switch n := value.(type) {
+	case float64:
+		return n, true
+	case int64:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
@@ -0,0 +1,140 @@
+package workflow
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+func TestFormUnmarshaling(t *testing.T) {
+	var step Step
+	err := toml.Unmarshal([]byte(`
+name = "review"
+handler = "human"
+output = "review"
+
+[[form]]
+name = "verdict"
+type = "select"
+options = ["approve", "reject"]
+required = true
+
+[[form]]
+name = "score"
+type = "number"
+min = 1
+max = 5
+default = 3
+`), &step)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+
+	if len(step.Form) != 2 {
+		t.Fatalf("Expected 2 form fields, got %d", len(step.Form))
+	}
+	score := step.Form[1]
+	if score.FieldType() != "number" || *score.Min != 1 || *score.Max != 5 {
+		t.Errorf("Unexpected score field: %+v", score)
+	}
+}
+
+func TestValidateForm(t *testing.T) {
+	one, five := 1.0, 5.0
+	fields := []FormField{
+		{Name: "title", Required: true, MaxLength: 10},
+		{Name: "notes", Type: "textarea"},
+		{Name: "verdict", Type: "select", Options: []string{"approve", "reject"}, Default: "approve"},
+		{Name: "agree", Type: "boolean", Required: true},
+		{Name: "score", Type: "number", Min: &one, Max: &five, Default: int64(3)},
+		{Name: "code", Pattern: "[A-Z]{3}"},
+	}
+
+	values, err := ValidateForm(fields, map[string]any{
+		"title": "Looks good",
+		"agree": true,
+	})
+	if err != nil {
+		t.Fatalf("ValidateForm failed: %v", err)
+	}
+
+	want := map[string]any{"title": "Looks good", "verdict": "approve", "agree": true, "score": 3.0}
+	if len(values) != len(want) {
+		t.Errorf("Expected %v, got %v", want, values)
+	}
+	for name, value := range want {
+		if values[name] != value {
+			t.Errorf("Expected %s = %v, got %v", name, value, values[name])
+		}
+	}
+
+	_, err = ValidateForm(fields, map[string]any{
+		"title":   "",
+		"verdict": "maybe",
+		"agree":   false,
+		"score":   9.0,
+		"code":    "abcd",
+		"extra":   "x",
+	})
+	var formErrs FormErrors
+	if !errors.As(err, &formErrs) {
+		t.Fatalf("Expected FormErrors, got %v", err)
+	}
+
+	got := map[string]bool{}
+	for _, fe := range formErrs {
+		got[fe.Field] = true
+	}
+	for _, name := range []string{"title", "verdict", "agree", "score", "code", "extra"} {
+		if !got[name] {
+			t.Errorf("Expected an error for %s, got %v", name, formErrs)
+		}
+	}
+}
+
+func TestValidateFormFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields []FormField
+	}{
+		{"empty name", []FormField{{Type: "text"}}},
+		{"duplicate", []FormField{{Name: "a"}, {Name: "a"}}},
+		{"unknown type", []FormField{{Name: "a", Type: "date"}}},
+		{"select without options", []FormField{{Name: "a", Type: "select"}}},
+		{"bad pattern", []FormField{{Name: "a", Pattern: "("}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateFormFields(tt.fields); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestFormFieldParse(t *testing.T) {
+	tests := []struct {
+		field FormField
+		raw   string
+		want  any
+	}{
+		{FormField{Name: "a"}, " hello ", "hello"},
+		{FormField{Name: "a", Type: "boolean"}, "yes", true},
+		{FormField{Name: "a", Type: "boolean"}, "N", false},
+		{FormField{Name: "a", Type: "number"}, "2.5", 2.5},
+		{FormField{Name: "a", Type: "number"}, "", nil},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.field.Parse(tt.raw)
+		if err != nil || got != tt.want {
+			t.Errorf("Parse(%q) = %v, %v, want %v", tt.raw, got, err, tt.want)
+		}
+	}
+
+	if _, err := (FormField{Name: "a", Type: "number"}).Parse("many"); err == nil {
+		t.Error("Expected an error parsing a non-number")
+	}
+}
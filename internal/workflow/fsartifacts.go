@@ -0,0 +1,357 @@
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// fsArtifactStore keeps artifact content in {root}/blobs/ named by SHA-256
+// digest, and each run's name to blob mapping in runs/{id}/artifacts.json
+type fsArtifactStore struct {
+	root  string
+	blobs blobDir
+	// mu serializes manifest updates from concurrently committing steps
+	mu sync.Mutex
+}
+
+// newFSArtifactStore creates an artifact store rooted at the directory
+func newFSArtifactStore(root string) *fsArtifactStore {
+	return &fsArtifactStore{root: root, blobs: blobDir(filepath.Join(root, "blobs"))}
+}
+
+// blobDir is a directory of content-addressed blob files, stored as
+// {dir}/{sha[:2]}/{sha}. Stores keeping content on disk share its layout.
+type blobDir string
+
+// path returns where the blob with the given digest is stored
+func (d blobDir) path(sha256 string) string {
+	return filepath.Join(string(d), sha256[:2], sha256)
+}
+
+// open opens the blob file for a digest
+func (d blobDir) open(sha256 string) (io.ReadCloser, error) {
+	if len(sha256) < 2 {
+		return nil, fmt.Errorf("invalid blob digest '%s'", sha256)
+	}
+
+	f, err := os.Open(d.path(sha256))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open blob %s: %w", sha256, err)
+	}
+	return f, nil
+}
+
+// create starts a blob streamed into a temporary file in the directory
+func (d blobDir) create() (*blobWriter, error) {
+	if err := os.MkdirAll(string(d), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create blobs directory: %w", err)
+	}
+
+	f, err := os.CreateTemp(string(d), "upload-*")
+	if err != nil {
+		return nil, err
+	}
+	return &blobWriter{dir: d, file: f, digest: newContentDigest()}, nil
+}
+
+// blobWriter hashes content as it streams into a temporary blob file
+type blobWriter struct {
+	dir    blobDir
+	file   *os.File
+	digest *contentDigest
+}
+
+func (w *blobWriter) Write(p []byte) (int, error) {
+	w.digest.Write(p)
+	return w.file.Write(p)
+}
+
+// commit moves the temporary file into place under its digest, or discards
+// it when identical content is already stored
+func (w *blobWriter) commit() error {
+	tempPath := w.file.Name()
+	if err := w.file.Close(); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+
+	blobPath := w.dir.path(w.digest.sum())
+	if _, err := os.Stat(blobPath); err == nil {
+		os.Remove(tempPath)
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(blobPath), 0755); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to create blob directory: %w", err)
+	}
+	if err := os.Rename(tempPath, blobPath); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+	return nil
+}
+
+// abort discards the temporary file
+func (w *blobWriter) abort() error {
+	w.file.Close()
+	return os.Remove(w.file.Name())
+}
+
+// runDir returns the directory for a specific run
+func (a *fsArtifactStore) runDir(runID string) string {
+	return filepath.Join(a.root, "runs", runID)
+}
+
+// manifestPath returns the path of a run's artifact manifest
+func (a *fsArtifactStore) manifestPath(runID string) string {
+	return filepath.Join(a.runDir(runID), "artifacts.json")
+}
+
+// loadManifest reads a run's artifact manifest, importing artifacts written
+// by earlier versions as plain files under artifacts/
+func (a *fsArtifactStore) loadManifest(runID string) (artifactManifest, error) {
+	data, err := os.ReadFile(a.manifestPath(runID))
+	if os.IsNotExist(err) {
+		return a.importLegacyArtifacts(runID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artifact manifest: %w", err)
+	}
+
+	manifest := artifactManifest{}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal artifact manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// saveManifest writes a run's artifact manifest
+func (a *fsArtifactStore) saveManifest(runID string, manifest artifactManifest) error {
+	if err := os.MkdirAll(a.runDir(runID), 0755); err != nil {
+		return fmt.Errorf("failed to create run directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal artifact manifest: %w", err)
+	}
+
+	if err := os.WriteFile(a.manifestPath(runID), data, 0644); err != nil {
+		return fmt.Errorf("failed to write artifact manifest: %w", err)
+	}
+	return nil
+}
+
+// importLegacyArtifacts moves plain artifact files into blobs and records
+// them in a new manifest. Runs without legacy files get an empty manifest.
+func (a *fsArtifactStore) importLegacyArtifacts(runID string) (artifactManifest, error) {
+	manifest := artifactManifest{}
+	artifactsDir := filepath.Join(a.runDir(runID), "artifacts")
+
+	importFile := func(path, name string) (ArtifactInfo, error) {
+		f, err := os.Open(path)
+		if err != nil {
+			return ArtifactInfo{}, err
+		}
+		defer f.Close()
+
+		stat, err := f.Stat()
+		if err != nil {
+			return ArtifactInfo{}, err
+		}
+
+		w, err := a.newWriter(runID, name, ArtifactMeta{})
+		if err != nil {
+			return ArtifactInfo{}, err
+		}
+		if _, err := io.Copy(w, f); err != nil {
+			w.Abort()
+			return ArtifactInfo{}, err
+		}
+		info, err := w.storeBlob()
+		if err != nil {
+			return ArtifactInfo{}, err
+		}
+		info.CreatedAt = stat.ModTime().UTC()
+		return info, nil
+	}
+
+	artifactEntries, _ := os.ReadDir(artifactsDir)
+	for _, entry := range artifactEntries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := importFile(filepath.Join(artifactsDir, entry.Name()), entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to import artifact %s: %w", entry.Name(), err)
+		}
+		manifest.commit(info)
+	}
+
+	if len(manifest) == 0 {
+		return manifest, nil
+	}
+
+	if err := a.saveManifest(runID, manifest); err != nil {
+		return nil, err
+	}
+	os.RemoveAll(artifactsDir)
+
+	return manifest, nil
+}
+
+// List returns a run's current artifacts ordered by name
+func (a *fsArtifactStore) List(runID string) ([]ArtifactInfo, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	manifest, err := a.loadManifest(runID)
+	if err != nil {
+		return nil, err
+	}
+	return manifest.list(), nil
+}
+
+// Stat returns a run's current artifact
+func (a *fsArtifactStore) Stat(runID, name string) (ArtifactInfo, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	manifest, err := a.loadManifest(runID)
+	if err != nil {
+		return ArtifactInfo{}, err
+	}
+	return manifest.stat(name)
+}
+
+// Open streams a run's current artifact from its blob file
+func (a *fsArtifactStore) Open(runID, name string) (io.ReadCloser, ArtifactInfo, error) {
+	info, err := a.Stat(runID, name)
+	if err != nil {
+		return nil, ArtifactInfo{}, err
+	}
+
+	r, err := a.OpenBlob(info.SHA256)
+	if err != nil {
+		return nil, ArtifactInfo{}, err
+	}
+	return r, info, nil
+}
+
+// OpenBlob opens the blob file for a digest
+func (a *fsArtifactStore) OpenBlob(sha256 string) (io.ReadCloser, error) {
+	return a.blobs.open(sha256)
+}
+
+// Create streams content into a temporary file in the blobs directory
+func (a *fsArtifactStore) Create(runID, name string, meta ArtifactMeta) (ArtifactWriter, error) {
+	return a.newWriter(runID, name, meta)
+}
+
+func (a *fsArtifactStore) newWriter(runID, name string, meta ArtifactMeta) (*fsArtifactWriter, error) {
+	blob, err := a.blobs.create()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create artifact %s: %w", name, err)
+	}
+
+	return &fsArtifactWriter{
+		blobWriter: blob,
+		store:      a,
+		runID:      runID,
+		name:       name,
+		meta:       meta,
+	}, nil
+}
+
+// Archive moves a run's current artifact into its history
+func (a *fsArtifactStore) Archive(runID, name string) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	manifest, err := a.loadManifest(runID)
+	if err != nil {
+		return 0, err
+	}
+
+	version, err := manifest.archive(name)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := a.saveManifest(runID, manifest); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// History returns the prior versions of a run's artifacts
+func (a *fsArtifactStore) History(runID string) (map[string][]ArtifactInfo, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	manifest, err := a.loadManifest(runID)
+	if err != nil {
+		return nil, err
+	}
+	return manifest.history(), nil
+}
+
+// Versions returns every version of a run's artifact
+func (a *fsArtifactStore) Versions(runID, name string) ([]ArtifactInfo, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	manifest, err := a.loadManifest(runID)
+	if err != nil {
+		return nil, err
+	}
+	return manifest.versions(name)
+}
+
+// fsArtifactWriter streams content into a blob and records it in the run's
+// manifest on commit
+type fsArtifactWriter struct {
+	*blobWriter
+	store *fsArtifactStore
+	runID string
+	name  string
+	meta  ArtifactMeta
+}
+
+// storeBlob moves the content into place under its digest and describes it
+func (w *fsArtifactWriter) storeBlob() (ArtifactInfo, error) {
+	if err := w.commit(); err != nil {
+		return ArtifactInfo{}, fmt.Errorf("failed to store artifact %s: %w", w.name, err)
+	}
+	return w.digest.info(w.name, w.meta), nil
+}
+
+func (w *fsArtifactWriter) Commit() (ArtifactInfo, error) {
+	info, err := w.storeBlob()
+	if err != nil {
+		return ArtifactInfo{}, err
+	}
+
+	w.store.mu.Lock()
+	defer w.store.mu.Unlock()
+
+	manifest, err := w.store.loadManifest(w.runID)
+	if err != nil {
+		return ArtifactInfo{}, err
+	}
+	info = manifest.commit(info)
+	if err := w.store.saveManifest(w.runID, manifest); err != nil {
+		return ArtifactInfo{}, err
+	}
+
+	return info, nil
+}
+
+func (w *fsArtifactWriter) Abort() error {
+	return w.abort()
+}
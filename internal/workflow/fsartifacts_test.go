@@ -0,0 +1,61 @@
+package workflow
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFSArtifactStoreLayout(t *testing.T) {
+	store := NewFSStore(t.TempDir())
+
+	info, err := WriteArtifact(store.Artifacts(), "test-run", "doc", ArtifactMeta{}, strings.NewReader("content"))
+	if err != nil {
+		t.Fatalf("WriteArtifact failed: %v", err)
+	}
+
+	blobPath := filepath.Join(store.Root(), "blobs", info.SHA256[:2], info.SHA256)
+	if data, err := os.ReadFile(blobPath); err != nil || string(data) != "content" {
+		t.Errorf("Expected blob at %s, got %q, %v", blobPath, data, err)
+	}
+	if _, err := os.Stat(filepath.Join(store.Root(), "runs", "test-run", "artifacts.json")); err != nil {
+		t.Errorf("Expected artifact manifest: %v", err)
+	}
+
+	// Temporary upload files do not linger
+	uploads, _ := filepath.Glob(filepath.Join(store.Root(), "blobs", "upload-*"))
+	if len(uploads) != 0 {
+		t.Errorf("Expected no leftover uploads, got %v", uploads)
+	}
+}
+
+func TestFSArtifactStoreImportsLegacyFiles(t *testing.T) {
+	store := NewFSStore(t.TempDir())
+	runDir := filepath.Join(store.Root(), "runs", "legacy-run")
+
+	// Earlier versions kept artifacts as plain files
+	files := map[string]string{
+		"artifacts/doc":   "current",
+		"artifacts/other": "other",
+	}
+	for name, content := range files {
+		path := filepath.Join(runDir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write legacy file: %v", err)
+		}
+	}
+
+	artifacts := store.Artifacts()
+	if content, _ := ReadArtifact(artifacts, "legacy-run", "doc"); string(content) != "current" {
+		t.Errorf("Expected imported artifact 'current', got %q", content)
+	}
+	if names, _ := ArtifactNames(artifacts, "legacy-run"); len(names) != 2 {
+		t.Errorf("Expected 2 imported artifacts, got %v", names)
+	}
+
+	if _, err := os.Stat(filepath.Join(runDir, "artifacts")); !os.IsNotExist(err) {
+		t.Errorf("Expected legacy artifacts directory to be removed")
+	}
+}
@@ -0,0 +1,182 @@
+package workflow
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// FSStore is a RunStore that keeps runs as directories under {root}/runs/.
+// Each run directory holds state.json, comments.jsonl with one comment per
+// line, and artifacts.json, the manifest mapping artifact names to content
+// stored under {root}/blobs/.
+type FSStore struct {
+	root      string
+	artifacts *fsArtifactStore
+}
+
+// NewFSStore creates a filesystem store rooted at the given data directory
+func NewFSStore(root string) *FSStore {
+	return &FSStore{
+		root:      root,
+		artifacts: newFSArtifactStore(root),
+	}
+}
+
+// Root returns the data directory the store is rooted at
+func (s *FSStore) Root() string {
+	return s.root
+}
+
+// runsDir returns the directory containing every run
+func (s *FSStore) runsDir() string {
+	return filepath.Join(s.root, "runs")
+}
+
+// runDir returns the directory for a specific run
+func (s *FSStore) runDir(runID string) string {
+	return filepath.Join(s.runsDir(), runID)
+}
+
+// Artifacts returns the store for run artifacts
+func (s *FSStore) Artifacts() ArtifactStore {
+	return s.artifacts
+}
+
+// SaveRun saves the run state to a JSON file in the run directory
+func (s *FSStore) SaveRun(rs *RunState) error {
+	if rs.ID == "" {
+		return fmt.Errorf("run ID is required to save state")
+	}
+
+	runDir := s.runDir(rs.ID)
+
+	// Create the run directory if it doesn't exist
+	if err := os.MkdirAll(runDir, 0755); err != nil {
+		return fmt.Errorf("failed to create run directory: %w", err)
+	}
+
+	statePath := filepath.Join(runDir, "state.json")
+	data, err := json.MarshalIndent(rs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	if err := os.WriteFile(statePath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+
+	return nil
+}
+
+// LoadRun loads the run state from a JSON file in the run directory
+func (s *FSStore) LoadRun(runID string) (*RunState, error) {
+	statePath := filepath.Join(s.runDir(runID), "state.json")
+
+	data, err := os.ReadFile(statePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	return unmarshalRunState(data)
+}
+
+// ListRuns returns all runs found in the runs directory
+func (s *FSStore) ListRuns() ([]RunState, error) {
+	runsDir := s.runsDir()
+
+	// Check if runs directory exists
+	if _, err := os.Stat(runsDir); os.IsNotExist(err) {
+		return []RunState{}, nil
+	}
+
+	// Read directory entries
+	entries, err := os.ReadDir(runsDir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading runs directory: %w", err)
+	}
+
+	runs := []RunState{}
+	for _, entry := range entries {
+		// Skip non-directories
+		if !entry.IsDir() {
+			continue
+		}
+
+		// Try to load the run state
+		state, err := s.LoadRun(entry.Name())
+		if err != nil {
+			// Skip runs that can't be loaded (might be incomplete or corrupted)
+			continue
+		}
+
+		runs = append(runs, *state)
+	}
+
+	return runs, nil
+}
+
+// QueryRuns loads every run and filters it against the query
+func (s *FSStore) QueryRuns(q RunQuery) ([]RunState, error) {
+	runs, err := s.ListRuns()
+	if err != nil {
+		return nil, err
+	}
+	return filterRuns(runs, q), nil
+}
+
+// AddComment appends the comment as a line of the run's comments.jsonl
+func (s *FSStore) AddComment(runID string, c Comment) error {
+	runDir := s.runDir(runID)
+	if err := os.MkdirAll(runDir, 0755); err != nil {
+		return fmt.Errorf("failed to create run directory: %w", err)
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to marshal comment: %w", err)
+	}
+
+	f, err := os.OpenFile(filepath.Join(runDir, "comments.jsonl"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open comments file: %w", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write comment: %w", err)
+	}
+	return f.Close()
+}
+
+// Comments reads the run's comments.jsonl; runs without one have none
+func (s *FSStore) Comments(runID string) ([]Comment, error) {
+	f, err := os.Open(filepath.Join(s.runDir(runID), "comments.jsonl"))
+	if errors.Is(err, os.ErrNotExist) {
+		return []Comment{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open comments file: %w", err)
+	}
+	defer f.Close()
+
+	comments := []Comment{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var c Comment
+		if err := json.Unmarshal(scanner.Bytes(), &c); err != nil {
+			return nil, fmt.Errorf("failed to parse comment: %w", err)
+		}
+		comments = append(comments, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read comments file: %w", err)
+	}
+	return comments, nil
+}
@@ -0,0 +1,109 @@
+package workflow
+
+import "fmt"
+
+// LineageNode is an artifact version and the input versions it was produced
+// from. Nodes without sources are root content, such as inline step content.
+type LineageNode struct {
+	Artifact ArtifactInfo  `json:"artifact"`
+	Sources  []LineageNode `json:"sources,omitempty"`
+	// Missing marks an input version that is no longer stored in the run;
+	// only its reference is known
+	Missing bool `json:"missing,omitempty"`
+}
+
+// Lineage walks the provenance of a run's artifact version back to its root
+// content. Version 0 selects the current version.
+func Lineage(artifacts ArtifactStore, runID, name string, version int) (*LineageNode, error) {
+	versions, err := artifacts.Versions(runID, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var start ArtifactInfo
+	if version == 0 {
+		start, err = artifacts.Stat(runID, name)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		found := false
+		for _, info := range versions {
+			if info.Version == version {
+				start, found = info, true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("artifact %s has no version %d", name, version)
+		}
+	}
+
+	walker := lineageWalker{
+		artifacts: artifacts,
+		runID:     runID,
+		versions:  map[string][]ArtifactInfo{name: versions},
+	}
+	node := walker.walk(start, map[ArtifactRef]bool{})
+	return &node, nil
+}
+
+// lineageWalker resolves input references against a run's artifact versions,
+// loading each name's versions once
+type lineageWalker struct {
+	artifacts ArtifactStore
+	runID     string
+	versions  map[string][]ArtifactInfo
+}
+
+// walk builds the lineage of info. Visiting tracks the versions on the current
+// path so malformed provenance cannot recurse forever.
+func (w *lineageWalker) walk(info ArtifactInfo, visiting map[ArtifactRef]bool) LineageNode {
+	node := LineageNode{Artifact: info}
+
+	ref := info.Ref()
+	if visiting[ref] {
+		return node
+	}
+	visiting[ref] = true
+	defer delete(visiting, ref)
+
+	for _, input := range info.Inputs {
+		source, ok := w.resolve(input)
+		if !ok {
+			node.Sources = append(node.Sources, LineageNode{
+				Artifact: ArtifactInfo{Name: input.Name, Version: input.Version, SHA256: input.SHA256},
+				Missing:  true,
+			})
+			continue
+		}
+
+		node.Sources = append(node.Sources, w.walk(source, visiting))
+	}
+
+	return node
+}
+
+// resolve finds the stored version a reference names. Artifacts copied into
+// a forked run keep their provenance but are renumbered, so a reference whose
+// version does not match falls back to the latest version with its content.
+func (w *lineageWalker) resolve(ref ArtifactRef) (ArtifactInfo, bool) {
+	versions, ok := w.versions[ref.Name]
+	if !ok {
+		// A name with no stored versions leaves the reference unresolved
+		versions, _ = w.artifacts.Versions(w.runID, ref.Name)
+		w.versions[ref.Name] = versions
+	}
+
+	var match ArtifactInfo
+	found := false
+	for _, info := range versions {
+		if info.SHA256 != ref.SHA256 {
+			continue
+		}
+		if info.Version == ref.Version {
+			return info, true
+		}
+		match, found = info, true
+	}
+	return match, found
+}
@@ -0,0 +1,67 @@
+package workflow
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLineage(t *testing.T) {
+	artifacts := NewMemoryStore().Artifacts()
+
+	write := func(name, content string, inputs ...ArtifactInfo) ArtifactInfo {
+		refs := []ArtifactRef{}
+		for _, input := range inputs {
+			refs = append(refs, input.Ref())
+		}
+		meta := ArtifactMeta{Provenance: Provenance{Step: "make-" + name, Attempt: 1, Inputs: refs}}
+		info, err := WriteArtifact(artifacts, "test-run", name, meta, strings.NewReader(content))
+		if err != nil {
+			t.Fatalf("WriteArtifact failed: %v", err)
+		}
+		return info
+	}
+
+	draft := write("draft", "draft v1")
+	notes := write("notes", "notes")
+	write("final", "final", draft, notes)
+
+	// Later versions of an input do not change what the output came from
+	write("draft", "draft v2")
+
+	node, err := Lineage(artifacts, "test-run", "final", 0)
+	if err != nil {
+		t.Fatalf("Lineage failed: %v", err)
+	}
+	if node.Artifact.Name != "final" || len(node.Sources) != 2 {
+		t.Fatalf("Expected final with two sources, got %+v", node)
+	}
+	source := node.Sources[0]
+	if source.Artifact.Name != "draft" || source.Artifact.Version != 1 || source.Artifact.Step != "make-draft" {
+		t.Errorf("Expected draft v1 as the first source, got %+v", source.Artifact)
+	}
+	if len(source.Sources) != 0 {
+		t.Errorf("Expected draft to be root content, got %+v", source.Sources)
+	}
+
+	if _, err := Lineage(artifacts, "test-run", "final", 2); err == nil {
+		t.Error("Expected error tracing a version that does not exist")
+	}
+}
+
+func TestLineageMissingInput(t *testing.T) {
+	artifacts := NewMemoryStore().Artifacts()
+
+	meta := ArtifactMeta{Provenance: Provenance{
+		Step:   "summarize",
+		Inputs: []ArtifactRef{{Name: "gone", Version: 1, SHA256: sha256Hex("gone")}},
+	}}
+	WriteArtifact(artifacts, "test-run", "summary", meta, strings.NewReader("summary"))
+
+	node, err := Lineage(artifacts, "test-run", "summary", 1)
+	if err != nil {
+		t.Fatalf("Lineage failed: %v", err)
+	}
+	if len(node.Sources) != 1 || !node.Sources[0].Missing || node.Sources[0].Artifact.Name != "gone" {
+		t.Errorf("Expected a missing source 'gone', got %+v", node.Sources)
+	}
+}
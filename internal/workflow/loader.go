@@ -40,6 +40,25 @@ func LoadWorkflow(id string) (*Workflow, string, error) {
 			return nil, "", fmt.Errorf("error parsing workflow file %s: %w", workflowPath, err)
 		}
 
+		if err := workflow.ValidateLoops(); err != nil {
+			return nil, "", fmt.Errorf("error in workflow file %s: %w", workflowPath, err)
+		}
+		if err := workflow.ValidatePrefills(); err != nil {
+			return nil, "", fmt.Errorf("error in workflow file %s: %w", workflowPath, err)
+		}
+		if err := workflow.ValidateForms(); err != nil {
+			return nil, "", fmt.Errorf("error in workflow file %s: %w", workflowPath, err)
+		}
+		if err := workflow.ValidateOutcomes(); err != nil {
+			return nil, "", fmt.Errorf("error in workflow file %s: %w", workflowPath, err)
+		}
+		if err := workflow.ValidateDeadlines(); err != nil {
+			return nil, "", fmt.Errorf("error in workflow file %s: %w", workflowPath, err)
+		}
+		if err := workflow.ValidateApprovals(); err != nil {
+			return nil, "", fmt.Errorf("error in workflow file %s: %w", workflowPath, err)
+		}
+
 		// Set the workflow ID from the filename (without .toml extension)
 		workflow.ID = id
 
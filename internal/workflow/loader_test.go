@@ -122,6 +122,43 @@ this is not valid TOML
 	}
 }
 
+func TestLoadWorkflow_InvalidPrefill(t *testing.T) {
+	os.Chdir(t.TempDir())
+	os.MkdirAll(filepath.Join(".composer", "workflows"), 0755)
+	os.WriteFile(filepath.Join(".composer", "workflows", "review.toml"), []byte(`
+[[steps]]
+name = "review"
+handler = "human"
+prefill = "input"
+output = "reviewed"
+`), 0644)
+
+	_, _, err := LoadWorkflow("review")
+	if err == nil || !strings.Contains(err.Error(), "unknown prefill 'input'") {
+		t.Errorf("Expected the prefill to be rejected, got %v", err)
+	}
+}
+
+func TestLoadWorkflow_InvalidForm(t *testing.T) {
+	os.Chdir(t.TempDir())
+	os.MkdirAll(filepath.Join(".composer", "workflows"), 0755)
+	os.WriteFile(filepath.Join(".composer", "workflows", "intake.toml"), []byte(`
+[[steps]]
+name = "intake"
+handler = "human"
+output = "details"
+
+[[steps.form]]
+name = "priority"
+type = "select"
+`), 0644)
+
+	_, _, err := LoadWorkflow("intake")
+	if err == nil || !strings.Contains(err.Error(), "select field 'priority' has no options") {
+		t.Errorf("Expected the form to be rejected, got %v", err)
+	}
+}
+
 func TestLoadWorkflow_EmptyID(t *testing.T) {
 	workflow, _, err := LoadWorkflow("")
 	if err == nil {
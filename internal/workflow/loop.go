@@ -0,0 +1,108 @@
+package workflow
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Loop is a group of steps that repeats, one iteration after another, until
+// its condition holds or it has run MaxIterations times
+type Loop struct {
+	Name  string   `toml:"name" json:"name"`
+	Steps []string `toml:"steps" json:"steps"`
+	// Until ends the loop after an iteration that satisfies it
+	Until         LoopCondition `toml:"until" json:"until"`
+	MaxIterations int           `toml:"max_iterations,omitempty" json:"max_iterations,omitempty"`
+}
+
+// LoopCondition ends a loop when a step chose an outcome, such as a human
+// approving, or when an artifact contains some text
+type LoopCondition struct {
+	Step     string `toml:"step,omitempty" json:"step,omitempty"`
+	Outcome  string `toml:"outcome,omitempty" json:"outcome,omitempty"`
+	Artifact string `toml:"artifact,omitempty" json:"artifact,omitempty"`
+	Contains string `toml:"contains,omitempty" json:"contains,omitempty"`
+}
+
+// Limit returns the loop's max_iterations, or DefaultMaxIterations when unset
+func (l Loop) Limit() int {
+	if l.MaxIterations <= 0 {
+		return DefaultMaxIterations
+	}
+	return l.MaxIterations
+}
+
+// Has reports whether the named step belongs to the loop
+func (l Loop) Has(step string) bool {
+	return slices.Contains(l.Steps, step)
+}
+
+// LoopOf returns the loop the named step belongs to, or nil
+func (w *Workflow) LoopOf(step string) *Loop {
+	for i := range w.Loops {
+		if w.Loops[i].Has(step) {
+			return &w.Loops[i]
+		}
+	}
+	return nil
+}
+
+// ValidateLoops checks that every loop is named, groups existing steps that
+// belong to no other loop, and has a condition on one of its own steps or
+// their outputs
+func (w *Workflow) ValidateLoops() error {
+	steps := map[string]bool{}
+	outputs := map[string]bool{}
+	for _, step := range w.Steps {
+		steps[step.Name] = true
+	}
+
+	names := map[string]bool{}
+	grouped := map[string]string{}
+	for _, loop := range w.Loops {
+		if loop.Name == "" {
+			return fmt.Errorf("loop has no name")
+		}
+		if names[loop.Name] {
+			return fmt.Errorf("duplicate loop %s", loop.Name)
+		}
+		names[loop.Name] = true
+
+		if len(loop.Steps) == 0 {
+			return fmt.Errorf("loop %s has no steps", loop.Name)
+		}
+		clear(outputs)
+		for _, name := range loop.Steps {
+			if !steps[name] {
+				return fmt.Errorf("loop %s has unknown step %s", loop.Name, name)
+			}
+			if other, ok := grouped[name]; ok {
+				return fmt.Errorf("step %s is in loops %s and %s", name, other, loop.Name)
+			}
+			grouped[name] = loop.Name
+		}
+		for _, step := range w.Steps {
+			if loop.Has(step.Name) && step.Output != "" {
+				outputs[step.Output] = true
+			}
+		}
+
+		until := loop.Until
+		switch {
+		case until.Step != "" && until.Artifact != "":
+			return fmt.Errorf("loop %s ends on a step outcome or an artifact, not both", loop.Name)
+		case until.Step != "":
+			if !loop.Has(until.Step) || until.Outcome == "" {
+				return fmt.Errorf("loop %s must end on an outcome of one of its steps", loop.Name)
+			}
+		case until.Artifact != "":
+			if !outputs[until.Artifact] || until.Contains == "" {
+				return fmt.Errorf("loop %s must end on text in an output of one of its steps", loop.Name)
+			}
+		default:
+			return fmt.Errorf("loop %s has no until condition", loop.Name)
+		}
+	}
+
+	return nil
+}
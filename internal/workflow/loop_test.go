@@ -0,0 +1,77 @@
+package workflow
+
+import (
+	"testing"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+func TestLoopUnmarshaling(t *testing.T) {
+	var wf Workflow
+	err := toml.Unmarshal([]byte(`
+[[steps]]
+name = "draft"
+handler = "human"
+output = "draft"
+
+[[steps]]
+name = "review"
+handler = "human"
+inputs = ["draft"]
+output = "notes"
+outcomes = ["approve", "reject"]
+
+[[loops]]
+name = "revision"
+steps = ["draft", "review"]
+until = { step = "review", outcome = "approve" }
+max_iterations = 5
+`), &wf)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+
+	if err := wf.ValidateLoops(); err != nil {
+		t.Fatalf("ValidateLoops failed: %v", err)
+	}
+	loop := wf.LoopOf("review")
+	if loop == nil || loop.Name != "revision" || loop.Limit() != 5 || loop.Until.Outcome != "approve" {
+		t.Errorf("Unexpected loop: %+v", loop)
+	}
+	if wf.LoopOf("publish") != nil {
+		t.Error("Expected no loop for a step outside it")
+	}
+}
+
+func TestValidateLoops(t *testing.T) {
+	steps := []Step{
+		{Name: "draft", Output: "draft"},
+		{Name: "review", Output: "notes"},
+	}
+	until := LoopCondition{Step: "review", Outcome: "approve"}
+
+	tests := []struct {
+		name  string
+		loops []Loop
+	}{
+		{"no name", []Loop{{Steps: []string{"draft"}, Until: until}}},
+		{"no steps", []Loop{{Name: "a", Until: until}}},
+		{"unknown step", []Loop{{Name: "a", Steps: []string{"edit"}, Until: until}}},
+		{"step in two loops", []Loop{
+			{Name: "a", Steps: []string{"review"}, Until: until},
+			{Name: "b", Steps: []string{"review"}, Until: until},
+		}},
+		{"no condition", []Loop{{Name: "a", Steps: []string{"review"}}}},
+		{"condition outside loop", []Loop{{Name: "a", Steps: []string{"draft"}, Until: until}}},
+		{"artifact without text", []Loop{{Name: "a", Steps: []string{"draft"}, Until: LoopCondition{Artifact: "draft"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wf := Workflow{Steps: steps, Loops: tt.loops}
+			if err := wf.ValidateLoops(); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package workflow
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// MemoryStore is a RunStore that keeps everything in process memory. It is
+// safe for concurrent use and suited to tests and ephemeral servers.
+type MemoryStore struct {
+	mu        sync.RWMutex
+	runs      map[string][]byte
+	comments  map[string][]Comment
+	artifacts *memoryArtifactStore
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		runs:     make(map[string][]byte),
+		comments: make(map[string][]Comment),
+		artifacts: &memoryArtifactStore{
+			blobs:     make(map[string][]byte),
+			manifests: make(map[string]artifactManifest),
+		},
+	}
+}
+
+// Artifacts returns the store for run artifacts
+func (s *MemoryStore) Artifacts() ArtifactStore {
+	return s.artifacts
+}
+
+// SaveRun stores a serialized copy of the run state so later changes to rs
+// are not visible until it is saved again
+func (s *MemoryStore) SaveRun(rs *RunState) error {
+	if rs.ID == "" {
+		return fmt.Errorf("run ID is required to save state")
+	}
+
+	data, err := json.Marshal(rs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	s.mu.Lock()
+	s.runs[rs.ID] = data
+	s.mu.Unlock()
+
+	return nil
+}
+
+// LoadRun returns a fresh copy of a stored run state
+func (s *MemoryStore) LoadRun(runID string) (*RunState, error) {
+	s.mu.RLock()
+	data, exists := s.runs[runID]
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("run %s not found", runID)
+	}
+
+	return unmarshalRunState(data)
+}
+
+// ListRuns returns every stored run ordered by ID
+func (s *MemoryStore) ListRuns() ([]RunState, error) {
+	s.mu.RLock()
+	ids := make([]string, 0, len(s.runs))
+	for id := range s.runs {
+		ids = append(ids, id)
+	}
+	s.mu.RUnlock()
+	sort.Strings(ids)
+
+	runs := []RunState{}
+	for _, id := range ids {
+		state, err := s.LoadRun(id)
+		if err != nil {
+			continue
+		}
+		runs = append(runs, *state)
+	}
+
+	return runs, nil
+}
+
+// QueryRuns filters every stored run against the query
+func (s *MemoryStore) QueryRuns(q RunQuery) ([]RunState, error) {
+	runs, err := s.ListRuns()
+	if err != nil {
+		return nil, err
+	}
+	return filterRuns(runs, q), nil
+}
+
+// AddComment appends a comment to a run's discussion
+func (s *MemoryStore) AddComment(runID string, c Comment) error {
+	s.mu.Lock()
+	s.comments[runID] = append(s.comments[runID], c)
+	s.mu.Unlock()
+	return nil
+}
+
+// Comments returns a copy of a run's comments
+func (s *MemoryStore) Comments(runID string) ([]Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Comment{}, s.comments[runID]...), nil
+}
+
+// memoryArtifactStore keeps blobs and per-run manifests in maps
+type memoryArtifactStore struct {
+	mu        sync.RWMutex
+	blobs     map[string][]byte
+	manifests map[string]artifactManifest
+}
+
+// manifest returns a run's manifest, creating it if needed. Callers hold mu.
+func (a *memoryArtifactStore) manifest(runID string) artifactManifest {
+	if a.manifests[runID] == nil {
+		a.manifests[runID] = artifactManifest{}
+	}
+	return a.manifests[runID]
+}
+
+// List returns a run's current artifacts ordered by name
+func (a *memoryArtifactStore) List(runID string) ([]ArtifactInfo, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.manifests[runID].list(), nil
+}
+
+// Stat returns a run's current artifact
+func (a *memoryArtifactStore) Stat(runID, name string) (ArtifactInfo, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.manifests[runID].stat(name)
+}
+
+// Open returns a reader over a run's current artifact
+func (a *memoryArtifactStore) Open(runID, name string) (io.ReadCloser, ArtifactInfo, error) {
+	info, err := a.Stat(runID, name)
+	if err != nil {
+		return nil, ArtifactInfo{}, err
+	}
+
+	r, err := a.OpenBlob(info.SHA256)
+	if err != nil {
+		return nil, ArtifactInfo{}, err
+	}
+	return r, info, nil
+}
+
+// OpenBlob returns a reader over the blob stored under a digest
+func (a *memoryArtifactStore) OpenBlob(sha256 string) (io.ReadCloser, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	data, exists := a.blobs[sha256]
+	if !exists {
+		return nil, fmt.Errorf("blob %s not found", sha256)
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// Create buffers content until it is committed
+func (a *memoryArtifactStore) Create(runID, name string, meta ArtifactMeta) (ArtifactWriter, error) {
+	return &bufferedArtifactWriter{
+		digest: newContentDigest(),
+		commit: func(data []byte, digest *contentDigest) (ArtifactInfo, error) {
+			info := digest.info(name, meta)
+
+			a.mu.Lock()
+			defer a.mu.Unlock()
+
+			if _, exists := a.blobs[info.SHA256]; !exists {
+				a.blobs[info.SHA256] = bytes.Clone(data)
+			}
+			return a.manifest(runID).commit(info), nil
+		},
+	}, nil
+}
+
+// Archive moves a run's current artifact into its history
+func (a *memoryArtifactStore) Archive(runID, name string) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.manifest(runID).archive(name)
+}
+
+// History returns the prior versions of a run's artifacts
+func (a *memoryArtifactStore) History(runID string) (map[string][]ArtifactInfo, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.manifests[runID].history(), nil
+}
+
+// Versions returns every version of a run's artifact
+func (a *memoryArtifactStore) Versions(runID, name string) ([]ArtifactInfo, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.manifests[runID].versions(name)
+}
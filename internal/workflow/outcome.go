@@ -0,0 +1,37 @@
+package workflow
+
+import (
+	"fmt"
+	"slices"
+)
+
+// ValidateOutcomes checks that outcome gates and loop backs name existing
+// steps and outcomes those steps declare
+func (w *Workflow) ValidateOutcomes() error {
+	steps := map[string]Step{}
+	for _, step := range w.Steps {
+		steps[step.Name] = step
+	}
+
+	for _, step := range w.Steps {
+		if when := step.When; when != nil {
+			gate, ok := steps[when.Step]
+			if !ok || when.Step == step.Name {
+				return fmt.Errorf("step %s is gated on unknown step %s", step.Name, when.Step)
+			}
+			if !slices.Contains(gate.Outcomes, when.Outcome) {
+				return fmt.Errorf("step %s is gated on outcome '%s', which step %s does not declare", step.Name, when.Outcome, gate.Name)
+			}
+		}
+
+		if loop := step.LoopBack; loop != nil {
+			if _, ok := steps[loop.To]; !ok {
+				return fmt.Errorf("step %s loops back to unknown step %s", step.Name, loop.To)
+			}
+			if !slices.Contains(step.Outcomes, loop.TriggerOutcome()) {
+				return fmt.Errorf("step %s loops back on outcome '%s', which it does not declare", step.Name, loop.TriggerOutcome())
+			}
+		}
+	}
+	return nil
+}
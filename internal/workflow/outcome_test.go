@@ -0,0 +1,53 @@
+package workflow
+
+import "testing"
+
+func TestValidateOutcomes(t *testing.T) {
+	review := func() Step {
+		return Step{
+			Name:     "review",
+			Handler:  "human",
+			Outcomes: []string{"approve", "reject"},
+			LoopBack: &LoopBack{To: "draft"},
+		}
+	}
+	publish := func() Step {
+		return Step{Name: "publish", When: &OutcomeCondition{Step: "review", Outcome: "approve"}}
+	}
+
+	valid := Workflow{Steps: []Step{{Name: "draft"}, review(), publish()}}
+	if err := valid.ValidateOutcomes(); err != nil {
+		t.Fatalf("ValidateOutcomes failed: %v", err)
+	}
+
+	unknownGate := publish()
+	unknownGate.When.Step = "sign-off"
+	unknownOutcome := publish()
+	unknownOutcome.When.Outcome = "approved"
+	unknownTarget := review()
+	unknownTarget.LoopBack.To = "redraft"
+	undeclaredLoop := review()
+	undeclaredLoop.LoopBack.Outcome = "revise"
+	noOutcomes := review()
+	noOutcomes.Outcomes = nil
+
+	tests := []struct {
+		name  string
+		steps []Step
+	}{
+		{"unknown gate step", []Step{{Name: "draft"}, review(), unknownGate}},
+		{"undeclared gate outcome", []Step{{Name: "draft"}, review(), unknownOutcome}},
+		{"unknown loop target", []Step{{Name: "draft"}, unknownTarget}},
+		{"undeclared loop outcome", []Step{{Name: "draft"}, undeclaredLoop}},
+		{"loop without outcomes", []Step{{Name: "draft"}, noOutcomes}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wf := Workflow{Steps: tt.steps}
+			if err := wf.ValidateOutcomes(); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// ErrUnknownParams reports run parameter values the workflow does not declare
+var ErrUnknownParams = errors.New("unknown parameters")
+
+// ResolveParams returns the run parameters for the given values, filling in
+// the workflow's defaults. Every value must name a declared parameter.
+func (w *Workflow) ResolveParams(values map[string]string) (map[string]string, error) {
+	unknown := []string{}
+	for name := range values {
+		if _, ok := w.Params[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("%w: %s", ErrUnknownParams, strings.Join(unknown, ", "))
+	}
+
+	params := make(map[string]string, len(w.Params))
+	for name, value := range w.Params {
+		params[name] = value
+	}
+	for name, value := range values {
+		params[name] = value
+	}
+	return params, nil
+}
+
+// ExpandParams fills the {{.name}} references in s with run parameters.
+// Referencing a parameter the run does not have is an error.
+func ExpandParams(s string, params map[string]string) (string, error) {
+	if !strings.Contains(s, "{{") {
+		return s, nil
+	}
+
+	tmpl, err := template.New("").Option("missingkey=error").Parse(s)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse '%s': %w", s, err)
+	}
+
+	if params == nil {
+		params = map[string]string{}
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, params); err != nil {
+		return "", fmt.Errorf("failed to expand '%s': %w", s, err)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,34 @@
+package workflow
+
+import "testing"
+
+func TestResolveParams(t *testing.T) {
+	wf := Workflow{Params: map[string]string{"reviewer": "alice", "team": "docs"}}
+
+	params, err := wf.ResolveParams(map[string]string{"reviewer": "bob"})
+	if err != nil {
+		t.Fatalf("ResolveParams failed: %v", err)
+	}
+	if params["reviewer"] != "bob" || params["team"] != "docs" {
+		t.Errorf("Expected the value over the defaults, got %v", params)
+	}
+
+	if _, err := wf.ResolveParams(map[string]string{"owner": "carol"}); err == nil {
+		t.Error("Expected an undeclared parameter to be rejected")
+	}
+}
+
+func TestExpandParams(t *testing.T) {
+	params := map[string]string{"team": "docs"}
+
+	got, err := ExpandParams("{{.team}}-lead", params)
+	if err != nil || got != "docs-lead" {
+		t.Errorf("ExpandParams = %q, %v, want docs-lead", got, err)
+	}
+	if got, _ := ExpandParams("alice", nil); got != "alice" {
+		t.Errorf("Expected plain text unchanged, got %q", got)
+	}
+	if _, err := ExpandParams("{{.owner}}", params); err == nil {
+		t.Error("Expected a missing parameter to fail")
+	}
+}
@@ -52,15 +52,58 @@ func GetWorkflowPaths() []string {
 	return workflowPaths
 }
 
-// GetRunsDir returns the path to the runs directory in the current working directory
-// (./.composer/runs/)
-func GetRunsDir() string {
+// GetSchedulePaths returns the ordered list of directories to search for
+// schedule files: the "schedules" subdirectory of each search path
+func GetSchedulePaths() []string {
+	basePaths := GetSearchPaths()
+	schedulePaths := make([]string, len(basePaths))
+
+	for i, basePath := range basePaths {
+		schedulePaths[i] = filepath.Join(basePath, "schedules")
+	}
+
+	return schedulePaths
+}
+
+// GetTriggerPaths returns the ordered list of directories to search for
+// trigger files: the "triggers" subdirectory of each search path
+func GetTriggerPaths() []string {
+	basePaths := GetSearchPaths()
+	triggerPaths := make([]string, len(basePaths))
+
+	for i, basePath := range basePaths {
+		triggerPaths[i] = filepath.Join(basePath, "triggers")
+	}
+
+	return triggerPaths
+}
+
+// GetDataDir returns the data root where runs and the step output cache are
+// kept: $COMPOSER_DATA_DIR if set, otherwise ./.composer/ in the current
+// working directory
+func GetDataDir() string {
+	if dataDir := os.Getenv("COMPOSER_DATA_DIR"); dataDir != "" {
+		return dataDir
+	}
+
 	cwd, err := os.Getwd()
 	if err != nil {
 		// Fallback to relative path if we can't get cwd
-		return filepath.Join(".composer", "runs")
+		return ".composer"
 	}
-	return filepath.Join(cwd, ".composer", "runs")
+	return filepath.Join(cwd, ".composer")
+}
+
+// GetRunsDir returns the path to the runs directory in the current working directory
+// (./.composer/runs/)
+func GetRunsDir() string {
+	return filepath.Join(GetDataDir(), "runs")
+}
+
+// GetCacheDir returns the path to the shared step output cache in the current
+// working directory (./.composer/cache/)
+func GetCacheDir() string {
+	return filepath.Join(GetDataDir(), "cache")
 }
 
 // GetRunDir returns the path to a specific run's directory
@@ -68,9 +111,3 @@ func GetRunsDir() string {
 func GetRunDir(runID string) string {
 	return filepath.Join(GetRunsDir(), runID)
 }
-
-// GetArtifactsDir returns the path to a specific run's artifacts directory
-// (./.composer/runs/{runID}/artifacts/)
-func GetArtifactsDir(runID string) string {
-	return filepath.Join(GetRunDir(runID), "artifacts")
-}
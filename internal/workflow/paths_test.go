@@ -159,3 +159,33 @@ func TestGetRunDirWithDifferentNames(t *testing.T) {
 		})
 	}
 }
+
+func TestGetCacheDir(t *testing.T) {
+	cacheDir := GetCacheDir()
+
+	// Should be ./.composer/cache in current working directory
+	cwd, _ := os.Getwd()
+	expected := filepath.Join(cwd, ".composer", "cache")
+	if cacheDir != expected {
+		t.Errorf("Expected cache dir to be %s, got %s", expected, cacheDir)
+	}
+}
+
+func TestGetDataDir(t *testing.T) {
+	// Defaults to ./.composer in current working directory
+	t.Setenv("COMPOSER_DATA_DIR", "")
+	cwd, _ := os.Getwd()
+	expected := filepath.Join(cwd, ".composer")
+	if dataDir := GetDataDir(); dataDir != expected {
+		t.Errorf("Expected data dir to be %s, got %s", expected, dataDir)
+	}
+
+	// COMPOSER_DATA_DIR overrides the default
+	t.Setenv("COMPOSER_DATA_DIR", "/srv/composer")
+	if dataDir := GetDataDir(); dataDir != "/srv/composer" {
+		t.Errorf("Expected data dir to be /srv/composer, got %s", dataDir)
+	}
+	if cacheDir := GetCacheDir(); cacheDir != "/srv/composer/cache" {
+		t.Errorf("Expected cache dir to follow the data dir, got %s", cacheDir)
+	}
+}
@@ -0,0 +1,207 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// Catch-up policies for fires a schedule missed while composerd was down
+const (
+	// CatchUpSkip drops missed fires
+	CatchUpSkip = "skip"
+	// CatchUpOnce starts one run for the latest missed fire
+	CatchUpOnce = "once"
+	// CatchUpAll starts a run for every missed fire, up to MaxCatchUp
+	CatchUpAll = "all"
+)
+
+// MaxCatchUp bounds how many missed fires a schedule catches up at once
+const MaxCatchUp = 100
+
+// DefaultScheduleRunID names scheduled runs after the schedule and the time
+// they fired for
+const DefaultScheduleRunID = "{{.schedule}}-{{.date}}-{{.time}}"
+
+// Schedule starts runs of a workflow on a cron expression. Schedules are
+// TOML files in the schedules/ directory of the search paths, named by their
+// filename like workflows.
+type Schedule struct {
+	Name     string `toml:"-" json:"name"`
+	Workflow string `toml:"workflow" json:"workflow"`
+	// Cron is a five-field cron expression or a macro such as "@daily"
+	Cron string `toml:"cron" json:"cron"`
+	// Timezone is the IANA zone Cron is evaluated in; local time when empty
+	Timezone string `toml:"timezone,omitempty" json:"timezone,omitempty"`
+	// Params are the run parameters the schedule starts runs with
+	Params map[string]string `toml:"params,omitempty" json:"params,omitempty"`
+	// RunID is a template for the run ID (see DefaultScheduleRunID)
+	RunID string `toml:"run_id,omitempty" json:"run_id,omitempty"`
+	// CatchUp is "skip" (default), "once", or "all"
+	CatchUp string `toml:"catch_up,omitempty" json:"catch_up,omitempty"`
+
+	cron     Cron
+	location *time.Location
+}
+
+// Validate parses the schedule's cron expression and time zone and checks
+// its catch-up policy
+func (s *Schedule) Validate() error {
+	if s.Workflow == "" {
+		return fmt.Errorf("schedule needs a workflow")
+	}
+
+	cron, err := ParseCron(s.Cron)
+	if err != nil {
+		return err
+	}
+	s.cron = cron
+
+	s.location = time.Local
+	if s.Timezone != "" {
+		if s.location, err = time.LoadLocation(s.Timezone); err != nil {
+			return fmt.Errorf("invalid timezone '%s': %w", s.Timezone, err)
+		}
+	}
+
+	switch s.CatchUp {
+	case "", CatchUpSkip, CatchUpOnce, CatchUpAll:
+	default:
+		return fmt.Errorf("invalid catch_up '%s': expected skip, once, or all", s.CatchUp)
+	}
+	return nil
+}
+
+// CatchUpPolicy returns the schedule's catch-up policy, defaulting to skip
+func (s *Schedule) CatchUpPolicy() string {
+	if s.CatchUp == "" {
+		return CatchUpSkip
+	}
+	return s.CatchUp
+}
+
+// Next returns the first fire time after t
+func (s *Schedule) Next(t time.Time) time.Time {
+	return s.cron.Next(t.In(s.location))
+}
+
+// Fires returns the fire times after from up to and including to, oldest
+// first, stopping after limit fires
+func (s *Schedule) Fires(from, to time.Time, limit int) []time.Time {
+	fires := []time.Time{}
+	for next := s.Next(from); !next.IsZero() && !next.After(to) && len(fires) < limit; next = s.Next(next) {
+		fires = append(fires, next)
+	}
+	return fires
+}
+
+// ExpandRunID fills the run ID template for a fire. Besides the schedule's
+// params it may reference {{.schedule}}, {{.date}} (20060102), {{.time}}
+// (1504), and {{.unix}}, all in the schedule's time zone.
+func (s *Schedule) ExpandRunID(at time.Time) (string, error) {
+	template := s.RunID
+	if template == "" {
+		template = DefaultScheduleRunID
+	}
+
+	at = at.In(s.location)
+	values := make(map[string]string, len(s.Params)+4)
+	for name, value := range s.Params {
+		values[name] = value
+	}
+	values["schedule"] = s.Name
+	values["date"] = at.Format("20060102")
+	values["time"] = at.Format("1504")
+	values["unix"] = strconv.FormatInt(at.Unix(), 10)
+
+	id, err := ExpandParams(template, values)
+	if err != nil {
+		return "", err
+	}
+	if err := ValidateRunID(id); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// ScheduleError reports a schedule file that failed to load
+type ScheduleError struct {
+	Name string
+	Err  error
+}
+
+func (e *ScheduleError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ScheduleError) Unwrap() error {
+	return e.Err
+}
+
+// ListSchedules returns every schedule found in the search paths, ordered by
+// name. Schedules in earlier paths take precedence. Schedule files that fail
+// to load are left out and reported together in the error as
+// *ScheduleError, so one bad file does not stop the others from firing.
+func ListSchedules() ([]Schedule, error) {
+	schedules := []Schedule{}
+	seen := make(map[string]bool)
+	var errs []error
+
+	for _, dir := range GetSchedulePaths() {
+		entries, err := os.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error reading schedule directory %s: %w", dir, err))
+			continue
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() || filepath.Ext(entry.Name()) != ".toml" {
+				continue
+			}
+
+			name := strings.TrimSuffix(entry.Name(), ".toml")
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			schedule, err := loadSchedule(filepath.Join(dir, entry.Name()), name)
+			if err != nil {
+				errs = append(errs, &ScheduleError{Name: name, Err: err})
+				continue
+			}
+			schedules = append(schedules, *schedule)
+		}
+	}
+
+	slices.SortFunc(schedules, func(a, b Schedule) int { return strings.Compare(a.Name, b.Name) })
+	return schedules, errors.Join(errs...)
+}
+
+// loadSchedule reads and validates a schedule file
+func loadSchedule(path, name string) (*Schedule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading schedule file %s: %w", path, err)
+	}
+
+	var schedule Schedule
+	if err := toml.Unmarshal(data, &schedule); err != nil {
+		return nil, fmt.Errorf("error parsing schedule file %s: %w", path, err)
+	}
+	schedule.Name = name
+	if err := schedule.Validate(); err != nil {
+		return nil, fmt.Errorf("error in schedule file %s: %w", path, err)
+	}
+	return &schedule, nil
+}
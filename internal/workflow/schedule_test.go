@@ -0,0 +1,103 @@
+package workflow
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestListSchedules(t *testing.T) {
+	os.Chdir(t.TempDir())
+	dir := filepath.Join(".composer", "schedules")
+	os.MkdirAll(dir, 0755)
+	os.WriteFile(filepath.Join(dir, "nightly.toml"), []byte(`
+workflow = "report"
+cron = "0 2 * * *"
+timezone = "UTC"
+run_id = "report-{{.region}}-{{.date}}"
+catch_up = "once"
+
+[params]
+region = "eu"
+`), 0644)
+	os.WriteFile(filepath.Join(dir, "hourly.toml"), []byte(`
+workflow = "sync"
+cron = "@hourly"
+`), 0644)
+
+	schedules, err := ListSchedules()
+	if err != nil {
+		t.Fatalf("ListSchedules failed: %v", err)
+	}
+	if len(schedules) != 2 || schedules[0].Name != "hourly" || schedules[1].Name != "nightly" {
+		t.Fatalf("Expected hourly and nightly, got %+v", schedules)
+	}
+
+	nightly := schedules[1]
+	if nightly.CatchUpPolicy() != CatchUpOnce || schedules[0].CatchUpPolicy() != CatchUpSkip {
+		t.Errorf("Expected once and the default skip, got %s and %s", nightly.CatchUpPolicy(), schedules[0].CatchUpPolicy())
+	}
+	at := time.Date(2025, 3, 1, 2, 0, 0, 0, time.UTC)
+	if next := nightly.Next(at.Add(-time.Hour)); !next.Equal(at) {
+		t.Errorf("Expected the next fire at %v, got %v", at, next)
+	}
+	if id, err := nightly.ExpandRunID(at); err != nil || id != "report-eu-20250301" {
+		t.Errorf("Expected run ID report-eu-20250301, got %q (%v)", id, err)
+	}
+	nightly.RunID = "{{.region}}"
+	for _, region := range []string{"..", "eu/west"} {
+		nightly.Params["region"] = region
+		if id, err := nightly.ExpandRunID(at); err == nil {
+			t.Errorf("Expected run ID %q to be rejected", id)
+		}
+	}
+
+	os.WriteFile(filepath.Join(dir, "broken.toml"), []byte(`
+workflow = "report"
+cron = "every day"
+`), 0644)
+	schedules, err = ListSchedules()
+	var scheduleErr *ScheduleError
+	if !errors.As(err, &scheduleErr) || scheduleErr.Name != "broken" {
+		t.Errorf("Expected the invalid cron expression to be reported, got %v", err)
+	}
+	if len(schedules) != 2 {
+		t.Errorf("Expected the valid schedules to load, got %d", len(schedules))
+	}
+}
+
+func TestScheduleValidate(t *testing.T) {
+	tests := []Schedule{
+		{Cron: "@daily"},
+		{Workflow: "report", Cron: "@daily", Timezone: "Nowhere/Special"},
+		{Workflow: "report", Cron: "@daily", CatchUp: "sometimes"},
+	}
+	for _, schedule := range tests {
+		if err := schedule.Validate(); err == nil {
+			t.Errorf("Expected %+v to be invalid", schedule)
+		}
+	}
+}
+
+func TestScheduleFires(t *testing.T) {
+	schedule := Schedule{Name: "hourly", Workflow: "sync", Cron: "@hourly", Timezone: "UTC"}
+	if err := schedule.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+
+	from := time.Date(2025, 3, 1, 9, 30, 0, 0, time.UTC)
+	fires := schedule.Fires(from, from.Add(3*time.Hour), MaxCatchUp)
+	if len(fires) != 3 || fires[0].Hour() != 10 || fires[2].Hour() != 12 {
+		t.Errorf("Expected fires at 10, 11, and 12, got %v", fires)
+	}
+	if fires := schedule.Fires(from, from.Add(3*time.Hour), 2); len(fires) != 2 {
+		t.Errorf("Expected the limit to stop after 2 fires, got %v", fires)
+	}
+
+	id, _ := schedule.ExpandRunID(fires[0])
+	if id != "hourly-20250301-1000" {
+		t.Errorf("Expected the default run ID hourly-20250301-1000, got %s", id)
+	}
+}
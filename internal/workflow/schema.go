@@ -1,5 +1,15 @@
 package workflow
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
 // Step represents a single step in a workflow
 type Step struct {
 	Name        string   `toml:"name" json:"name"`
@@ -9,6 +19,157 @@ type Step struct {
 	Content     string   `toml:"content" json:"content"` // Inline content for steps with no inputs
 	Inputs      []string `toml:"inputs" json:"inputs"`
 	Output      string   `toml:"output" json:"output"`
+	// Cache reuses a stored output when the step and its inputs are unchanged
+	Cache bool `toml:"cache,omitempty" json:"cache,omitempty"`
+	// MaxAttempts is how many times in a row a tool step may fail before it
+	// is marked failed (see DefaultMaxAttempts)
+	MaxAttempts int `toml:"max_attempts,omitempty" json:"max_attempts,omitempty"`
+	// Prefill seeds the output a human edits: "inputs" (default), "content",
+	// or "none"
+	Prefill string `toml:"prefill,omitempty" json:"prefill,omitempty"`
+	// Form declares typed fields a human step is completed with; the
+	// submitted values are stored as a JSON artifact
+	Form []FormField `toml:"form,omitempty" json:"form,omitempty"`
+	// Outcomes lists the decisions a human step is completed with, such as
+	// "approve" and "reject"
+	Outcomes []string `toml:"outcomes,omitempty" json:"outcomes,omitempty"`
+	// When gates the step on another step's outcome
+	When *OutcomeCondition `toml:"when,omitempty" json:"when,omitempty"`
+	// LoopBack returns the run to an earlier step on one of the outcomes
+	LoopBack *LoopBack `toml:"loop_back,omitempty" json:"loop_back,omitempty"`
+	// Assignee and Role name who should complete a human step; both may
+	// reference run parameters as {{.name}}
+	Assignee string `toml:"assignee,omitempty" json:"assignee,omitempty"`
+	Role     string `toml:"role,omitempty" json:"role,omitempty"`
+	// Assignees lists the people who may complete a human step; with
+	// ApprovalsRequired, the step succeeds once that many of them approve
+	Assignees         []string `toml:"assignees,omitempty" json:"assignees,omitempty"`
+	ApprovalsRequired int      `toml:"approvals_required,omitempty" json:"approvals_required,omitempty"`
+	// DueIn is how long a human step may wait once ready, e.g. "4h"
+	DueIn string `toml:"due_in,omitempty" json:"due_in,omitempty"`
+	// Escalation acts on the step once it is overdue
+	Escalation *Escalation `toml:"escalation,omitempty" json:"escalation,omitempty"`
+}
+
+// OutcomeCondition matches a step that succeeded with the given outcome
+type OutcomeCondition struct {
+	Step    string `toml:"step" json:"step"`
+	Outcome string `toml:"outcome" json:"outcome"`
+}
+
+// LoopBack resets the run to an earlier step, and everything after it, when
+// a human step is completed with the outcome. After MaxIterations loops the
+// outcome stands.
+type LoopBack struct {
+	// Outcome triggers the loop, defaulting to "reject"
+	Outcome       string `toml:"outcome,omitempty" json:"outcome,omitempty"`
+	To            string `toml:"to" json:"to"`
+	MaxIterations int    `toml:"max_iterations,omitempty" json:"max_iterations,omitempty"`
+}
+
+// DefaultMaxIterations bounds loops that do not set max_iterations
+const DefaultMaxIterations = 3
+
+// TriggerOutcome returns the outcome that loops back, defaulting to "reject"
+func (l LoopBack) TriggerOutcome() string {
+	if l.Outcome == "" {
+		return "reject"
+	}
+	return l.Outcome
+}
+
+// Limit returns the loop's max_iterations, or DefaultMaxIterations when unset
+func (l LoopBack) Limit() int {
+	if l.MaxIterations <= 0 {
+		return DefaultMaxIterations
+	}
+	return l.MaxIterations
+}
+
+// DefaultMaxAttempts bounds the failed executions of steps that do not set
+// max_attempts
+const DefaultMaxAttempts = 3
+
+// AttemptLimit returns the step's max_attempts, or DefaultMaxAttempts when
+// unset
+func (s Step) AttemptLimit() int {
+	if s.MaxAttempts <= 0 {
+		return DefaultMaxAttempts
+	}
+	return s.MaxAttempts
+}
+
+// HandlerType returns the step's handler, defaulting to "tool" when unset
+func (s Step) HandlerType() string {
+	if s.Handler == "" {
+		return "tool"
+	}
+	return s.Handler
+}
+
+// PrefillMode returns the step's prefill, defaulting to "inputs" when unset
+func (s Step) PrefillMode() string {
+	if s.Prefill == "" {
+		return "inputs"
+	}
+	return s.Prefill
+}
+
+// ValidatePrefills checks that every step's prefill is "inputs", "content",
+// or "none"
+func (w *Workflow) ValidatePrefills() error {
+	for _, step := range w.Steps {
+		switch step.PrefillMode() {
+		case "inputs", "content", "none":
+		default:
+			return fmt.Errorf("step %s has unknown prefill '%s': expected inputs, content, or none", step.Name, step.Prefill)
+		}
+	}
+	return nil
+}
+
+// ErrInvalidOutcome reports a decision outcome the step does not offer
+var ErrInvalidOutcome = errors.New("invalid outcome")
+
+// CheckOutcome returns an error unless the outcome is one the step declares.
+// Steps without outcomes take none.
+func (s Step) CheckOutcome(outcome string) error {
+	if len(s.Outcomes) == 0 {
+		if outcome != "" {
+			return fmt.Errorf("%w: step %s has no outcomes", ErrInvalidOutcome, s.Name)
+		}
+		return nil
+	}
+	if !slices.Contains(s.Outcomes, outcome) {
+		return fmt.Errorf("%w: step %s needs an outcome of %s", ErrInvalidOutcome, s.Name, strings.Join(s.Outcomes, ", "))
+	}
+	return nil
+}
+
+// DefinitionDigest returns the SHA-256 of the parts of the step definition
+// that determine what its handler produces, identifying the version of the
+// step a handler ran
+func (s Step) DefinitionDigest() string {
+	definition, _ := json.Marshal(struct {
+		Handler string      `json:"handler"`
+		Prompt  string      `json:"prompt"`
+		Content string      `json:"content"`
+		Inputs  []string    `json:"inputs"`
+		Output  string      `json:"output"`
+		Prefill string      `json:"prefill,omitempty"`
+		Form    []FormField `json:"form,omitempty"`
+	}{
+		Handler: s.HandlerType(),
+		Prompt:  s.Prompt,
+		Content: s.Content,
+		Inputs:  s.Inputs,
+		Output:  s.Output,
+		Prefill: s.Prefill,
+		Form:    s.Form,
+	})
+
+	sum := sha256.Sum256(definition)
+	return hex.EncodeToString(sum[:])
 }
 
 // Workflow represents a workflow definition
@@ -18,5 +179,11 @@ type Workflow struct {
 	DisplayName string `toml:"display_name" json:"display_name"`
 	Description string `toml:"description" json:"description"`
 	Message     string `toml:"message" json:"message"`
+	// MaxParallel caps how many steps a single tick starts (0 = unlimited)
+	MaxParallel int    `toml:"max_parallel,omitempty" json:"max_parallel,omitempty"`
 	Steps       []Step `toml:"steps" json:"steps"`
+	// Loops are groups of steps that repeat until a condition holds
+	Loops []Loop `toml:"loops,omitempty" json:"loops,omitempty"`
+	// Params declares the parameters a run can set, with their defaults
+	Params map[string]string `toml:"params,omitempty" json:"params,omitempty"`
 }
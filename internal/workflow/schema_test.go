@@ -172,3 +172,89 @@ output = "finished"
 		t.Errorf("Steps[2].Inputs = %v, want [processed]", workflow.Steps[2].Inputs)
 	}
 }
+
+func TestWorkflowMaxParallel(t *testing.T) {
+	tomlData := `
+display_name = "Limited"
+max_parallel = 3
+
+[[steps]]
+name = "start"
+output = "started"
+`
+
+	var workflow Workflow
+	if err := toml.Unmarshal([]byte(tomlData), &workflow); err != nil {
+		t.Fatalf("failed to unmarshal workflow: %v", err)
+	}
+
+	if workflow.MaxParallel != 3 {
+		t.Errorf("MaxParallel = %v, want 3", workflow.MaxParallel)
+	}
+}
+
+func TestStepMaxAttempts(t *testing.T) {
+	var step Step
+	if err := toml.Unmarshal([]byte(`
+name = "fetch"
+output = "fetched"
+max_attempts = 5
+`), &step); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+
+	if got := step.AttemptLimit(); got != 5 {
+		t.Errorf("AttemptLimit() = %v, want 5", got)
+	}
+	if got := (Step{}).AttemptLimit(); got != DefaultMaxAttempts {
+		t.Errorf("AttemptLimit() = %v, want %v", got, DefaultMaxAttempts)
+	}
+}
+
+func TestStepHandlerType(t *testing.T) {
+	if got := (Step{}).HandlerType(); got != "tool" {
+		t.Errorf("HandlerType() = %v, want tool", got)
+	}
+	if got := (Step{Handler: "human"}).HandlerType(); got != "human" {
+		t.Errorf("HandlerType() = %v, want human", got)
+	}
+}
+
+func TestStepPrefillMode(t *testing.T) {
+	if got := (Step{}).PrefillMode(); got != "inputs" {
+		t.Errorf("PrefillMode() = %v, want inputs", got)
+	}
+	if got := (Step{Prefill: "none"}).PrefillMode(); got != "none" {
+		t.Errorf("PrefillMode() = %v, want none", got)
+	}
+}
+
+func TestStepOutcomes(t *testing.T) {
+	var step Step
+	err := toml.Unmarshal([]byte(`
+name = "review"
+handler = "human"
+outcomes = ["approve", "reject"]
+loop_back = { to = "draft" }
+`), &step)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+
+	if step.LoopBack == nil || step.LoopBack.To != "draft" {
+		t.Fatalf("Expected a loop back to draft, got %+v", step.LoopBack)
+	}
+	if step.LoopBack.TriggerOutcome() != "reject" || step.LoopBack.Limit() != DefaultMaxIterations {
+		t.Errorf("Expected reject and the default limit, got %s and %d", step.LoopBack.TriggerOutcome(), step.LoopBack.Limit())
+	}
+
+	if err := step.CheckOutcome("approve"); err != nil {
+		t.Errorf("Expected approve to be accepted: %v", err)
+	}
+	if err := step.CheckOutcome(""); err == nil {
+		t.Error("Expected a missing outcome to be rejected")
+	}
+	if err := (Step{Name: "plain"}).CheckOutcome("approve"); err == nil {
+		t.Error("Expected an outcome on a step without outcomes to be rejected")
+	}
+}
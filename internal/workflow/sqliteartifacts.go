@@ -0,0 +1,294 @@
+package workflow
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// sqliteArtifactStore keeps the artifacts of a SQLite store in its tables
+// and their content in blob files next to the database, in the same layout
+// as the filesystem store. The current version of each artifact is in
+// artifacts and prior versions are in artifact_history; blobs lists the
+// stored digests.
+type sqliteArtifactStore struct {
+	db    *sql.DB
+	blobs blobDir
+}
+
+// artifactColumns are the columns scanArtifactInfo reads, shared by the
+// artifacts and artifact_history tables
+const artifactColumns = `name, version, sha256, content_type, size, created_at,
+	step, attempt, handler, handler_version, actor, inputs`
+
+// scanner is the row scanning method shared by sql.Row and sql.Rows
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanArtifactInfo reads a row selected with artifactColumns. Provenance
+// inputs are stored as JSON.
+func scanArtifactInfo(row scanner) (ArtifactInfo, error) {
+	var info ArtifactInfo
+	var createdAt int64
+	var inputs string
+	err := row.Scan(
+		&info.Name, &info.Version, &info.SHA256, &info.ContentType, &info.Size,
+		&createdAt, &info.Step, &info.Attempt, &info.Handler, &info.HandlerVersion,
+		&info.Actor, &inputs,
+	)
+	if err != nil {
+		return ArtifactInfo{}, err
+	}
+	info.CreatedAt = time.UnixMilli(createdAt).UTC()
+	if inputs != "" {
+		if err := json.Unmarshal([]byte(inputs), &info.Inputs); err != nil {
+			return ArtifactInfo{}, fmt.Errorf("failed to decode inputs of artifact %s: %w", info.Name, err)
+		}
+	}
+	return info, nil
+}
+
+// queryArtifactInfos runs a query selecting artifactColumns
+func (a *sqliteArtifactStore) queryArtifactInfos(query string, args ...any) ([]ArtifactInfo, error) {
+	rows, err := a.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	infos := []ArtifactInfo{}
+	for rows.Next() {
+		info, err := scanArtifactInfo(rows)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+
+	return infos, rows.Err()
+}
+
+// List returns a run's current artifacts ordered by name
+func (a *sqliteArtifactStore) List(runID string) ([]ArtifactInfo, error) {
+	infos, err := a.queryArtifactInfos(
+		`SELECT `+artifactColumns+` FROM artifacts WHERE run_id = ? ORDER BY name`,
+		runID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list artifacts: %w", err)
+	}
+	return infos, nil
+}
+
+// Stat returns a run's current artifact
+func (a *sqliteArtifactStore) Stat(runID, name string) (ArtifactInfo, error) {
+	info, err := scanArtifactInfo(a.db.QueryRow(
+		`SELECT `+artifactColumns+` FROM artifacts WHERE run_id = ? AND name = ?`,
+		runID, name,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ArtifactInfo{}, fmt.Errorf("artifact %s not found", name)
+	}
+	if err != nil {
+		return ArtifactInfo{}, fmt.Errorf("failed to read artifact %s: %w", name, err)
+	}
+
+	return info, nil
+}
+
+// Open returns a reader over a run's current artifact
+func (a *sqliteArtifactStore) Open(runID, name string) (io.ReadCloser, ArtifactInfo, error) {
+	info, err := a.Stat(runID, name)
+	if err != nil {
+		return nil, ArtifactInfo{}, err
+	}
+
+	r, err := a.OpenBlob(info.SHA256)
+	if err != nil {
+		return nil, ArtifactInfo{}, err
+	}
+	return r, info, nil
+}
+
+// OpenBlob opens the blob file for a digest
+func (a *sqliteArtifactStore) OpenBlob(sha256 string) (io.ReadCloser, error) {
+	return a.blobs.open(sha256)
+}
+
+// Create streams content into a temporary blob file. Committing moves it
+// into place, then records the blob, moves the current version to
+// artifact_history, and inserts the new version in one transaction.
+func (a *sqliteArtifactStore) Create(runID, name string, meta ArtifactMeta) (ArtifactWriter, error) {
+	blob, err := a.blobs.create()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create artifact %s: %w", name, err)
+	}
+	return &sqliteArtifactWriter{blobWriter: blob, store: a, runID: runID, name: name, meta: meta}, nil
+}
+
+// sqliteArtifactWriter streams content into a blob file and records the new
+// version on commit
+type sqliteArtifactWriter struct {
+	*blobWriter
+	store *sqliteArtifactStore
+	runID string
+	name  string
+	meta  ArtifactMeta
+}
+
+func (w *sqliteArtifactWriter) Commit() (ArtifactInfo, error) {
+	if err := w.commit(); err != nil {
+		return ArtifactInfo{}, fmt.Errorf("failed to store artifact %s: %w", w.name, err)
+	}
+	info := w.digest.info(w.name, w.meta)
+	runID, name := w.runID, w.name
+
+	tx, err := w.store.db.Begin()
+	if err != nil {
+		return ArtifactInfo{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := insertBlob(tx, info.SHA256, info.Size); err != nil {
+		return ArtifactInfo{}, err
+	}
+
+	if err := archiveCurrent(tx, runID, name); err != nil {
+		return ArtifactInfo{}, fmt.Errorf("failed to write artifact %s: %w", name, err)
+	}
+
+	err = tx.QueryRow(
+		`SELECT COALESCE(MAX(version), 0) + 1 FROM artifact_history WHERE run_id = ? AND name = ?`,
+		runID, name,
+	).Scan(&info.Version)
+	if err != nil {
+		return ArtifactInfo{}, fmt.Errorf("failed to read artifact history: %w", err)
+	}
+
+	inputs := ""
+	if len(info.Inputs) > 0 {
+		data, err := json.Marshal(info.Inputs)
+		if err != nil {
+			return ArtifactInfo{}, fmt.Errorf("failed to encode inputs of artifact %s: %w", name, err)
+		}
+		inputs = string(data)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO artifacts (run_id, `+artifactColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		runID, name, info.Version, info.SHA256, info.ContentType, info.Size,
+		info.CreatedAt.UnixMilli(), info.Step, info.Attempt, info.Handler,
+		info.HandlerVersion, info.Actor, inputs,
+	)
+	if err != nil {
+		return ArtifactInfo{}, fmt.Errorf("failed to write artifact %s: %w", name, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ArtifactInfo{}, fmt.Errorf("failed to write artifact %s: %w", name, err)
+	}
+	return info, nil
+}
+
+func (w *sqliteArtifactWriter) Abort() error {
+	return w.abort()
+}
+
+// insertBlob records a stored blob unless it is already recorded
+func insertBlob(tx *sql.Tx, sha256 string, size int64) error {
+	_, err := tx.Exec(
+		`INSERT INTO blobs (sha256, size) VALUES (?, ?) ON CONFLICT (sha256) DO NOTHING`,
+		sha256, size,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to write blob %s: %w", sha256, err)
+	}
+	return nil
+}
+
+// archiveCurrent moves an artifact's current version, if any, to
+// artifact_history
+func archiveCurrent(tx *sql.Tx, runID, name string) error {
+	_, err := tx.Exec(`
+		INSERT INTO artifact_history (run_id, `+artifactColumns+`)
+		SELECT run_id, `+artifactColumns+` FROM artifacts WHERE run_id = ? AND name = ?`,
+		runID, name,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`DELETE FROM artifacts WHERE run_id = ? AND name = ?`, runID, name)
+	return err
+}
+
+// Archive moves a run's current artifact into artifact_history
+func (a *sqliteArtifactStore) Archive(runID, name string) (int, error) {
+	tx, err := a.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var version int
+	err = tx.QueryRow(
+		`SELECT version FROM artifacts WHERE run_id = ? AND name = ?`,
+		runID, name,
+	).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("artifact %s not found", name)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read artifact %s: %w", name, err)
+	}
+
+	if err := archiveCurrent(tx, runID, name); err != nil {
+		return 0, fmt.Errorf("failed to archive artifact %s: %w", name, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to archive artifact %s: %w", name, err)
+	}
+
+	return version, nil
+}
+
+// History returns the prior versions of a run's artifacts
+func (a *sqliteArtifactStore) History(runID string) (map[string][]ArtifactInfo, error) {
+	infos, err := a.queryArtifactInfos(
+		`SELECT `+artifactColumns+` FROM artifact_history WHERE run_id = ? ORDER BY name, version`,
+		runID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artifact history: %w", err)
+	}
+
+	history := make(map[string][]ArtifactInfo)
+	for _, info := range infos {
+		history[info.Name] = append(history[info.Name], info)
+	}
+	return history, nil
+}
+
+// Versions returns every version of a run's artifact
+func (a *sqliteArtifactStore) Versions(runID, name string) ([]ArtifactInfo, error) {
+	versions, err := a.queryArtifactInfos(`
+		SELECT `+artifactColumns+` FROM artifact_history WHERE run_id = ? AND name = ?
+		UNION ALL
+		SELECT `+artifactColumns+` FROM artifacts WHERE run_id = ? AND name = ?
+		ORDER BY version`,
+		runID, name, runID, name,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artifact versions: %w", err)
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("artifact %s not found", name)
+	}
+	return versions, nil
+}
@@ -0,0 +1,400 @@
+package workflow
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteSchema creates the SQLite store tables. The full run state is kept as
+// JSON in runs.data; the other runs columns and step_states are indexed
+// projections of it for queries. Artifact content is kept in blob files, and
+// blobs only lists their digests.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS runs (
+	id            TEXT PRIMARY KEY,
+	workflow_name TEXT NOT NULL,
+	status        TEXT NOT NULL,
+	created_at    INTEGER NOT NULL,
+	updated_at    INTEGER NOT NULL,
+	data          TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS runs_by_workflow ON runs (workflow_name, created_at);
+CREATE INDEX IF NOT EXISTS runs_by_status ON runs (status, created_at);
+CREATE INDEX IF NOT EXISTS runs_by_created ON runs (created_at);
+
+CREATE TABLE IF NOT EXISTS step_states (
+	run_id    TEXT NOT NULL REFERENCES runs (id) ON DELETE CASCADE,
+	step_name TEXT NOT NULL,
+	status    TEXT NOT NULL,
+	PRIMARY KEY (run_id, step_name)
+);
+CREATE INDEX IF NOT EXISTS step_states_by_status ON step_states (status);
+
+CREATE TABLE IF NOT EXISTS events (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	run_id     TEXT NOT NULL,
+	step_name  TEXT NOT NULL,
+	status     TEXT NOT NULL,
+	created_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS events_by_run ON events (run_id, id);
+
+CREATE TABLE IF NOT EXISTS comments (
+	run_id TEXT NOT NULL,
+	id     INTEGER NOT NULL,
+	data   TEXT NOT NULL,
+	PRIMARY KEY (run_id, id)
+);
+
+CREATE TABLE IF NOT EXISTS blobs (
+	sha256 TEXT PRIMARY KEY,
+	size   INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS artifacts (
+	run_id          TEXT NOT NULL,
+	name            TEXT NOT NULL,
+	sha256          TEXT NOT NULL REFERENCES blobs (sha256),
+	content_type    TEXT NOT NULL,
+	size            INTEGER NOT NULL,
+	created_at      INTEGER NOT NULL,
+	version         INTEGER NOT NULL DEFAULT 1,
+	step            TEXT NOT NULL DEFAULT '',
+	attempt         INTEGER NOT NULL DEFAULT 0,
+	handler         TEXT NOT NULL DEFAULT '',
+	handler_version TEXT NOT NULL DEFAULT '',
+	actor           TEXT NOT NULL DEFAULT '',
+	inputs          TEXT NOT NULL DEFAULT '',
+	PRIMARY KEY (run_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS artifact_history (
+	run_id          TEXT NOT NULL,
+	name            TEXT NOT NULL,
+	version         INTEGER NOT NULL,
+	sha256          TEXT NOT NULL REFERENCES blobs (sha256),
+	content_type    TEXT NOT NULL,
+	size            INTEGER NOT NULL,
+	created_at      INTEGER NOT NULL,
+	step            TEXT NOT NULL DEFAULT '',
+	attempt         INTEGER NOT NULL DEFAULT 0,
+	handler         TEXT NOT NULL DEFAULT '',
+	handler_version TEXT NOT NULL DEFAULT '',
+	actor           TEXT NOT NULL DEFAULT '',
+	inputs          TEXT NOT NULL DEFAULT '',
+	PRIMARY KEY (run_id, name, version)
+);
+`
+
+// RunEvent records a run or step status change in a SQLite store
+type RunEvent struct {
+	ID    int64
+	RunID string
+	// Step is the step that changed status, or empty for run-level changes
+	Step   string
+	Status string
+	At     time.Time
+}
+
+// SQLiteStore is a RunStore backed by a single SQLite database. Each SaveRun
+// updates the run, its step states, and its status change events in one
+// transaction.
+type SQLiteStore struct {
+	db    *sql.DB
+	blobs blobDir
+}
+
+// OpenSQLiteStore opens or creates a SQLite store at the given database path.
+// Artifact content is stored in the blobs/ directory next to the database.
+func OpenSQLiteStore(path string) (*SQLiteStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %w", err)
+	}
+
+	dsn := "file:" + path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// SQLite allows a single writer; serialize access instead of retrying
+	db.SetMaxOpenConns(1)
+
+	if err := createSQLiteSchema(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &SQLiteStore{db: db, blobs: blobDir(filepath.Join(filepath.Dir(path), "blobs"))}, nil
+}
+
+// Artifacts returns the store for run artifacts, recorded in the same
+// database
+func (s *SQLiteStore) Artifacts() ArtifactStore {
+	return &sqliteArtifactStore{db: s.db, blobs: s.blobs}
+}
+
+// createSQLiteSchema creates any tables and indexes the database lacks
+func createSQLiteSchema(db *sql.DB) error {
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		return fmt.Errorf("failed to create database schema: %w", err)
+	}
+	return nil
+}
+
+// Close closes the underlying database
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// SaveRun writes the run, its step states, and any status change events in
+// a single transaction
+func (s *SQLiteStore) SaveRun(rs *RunState) error {
+	if rs.ID == "" {
+		return fmt.Errorf("run ID is required to save state")
+	}
+
+	data, err := json.Marshal(rs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Read the previous statuses to record what changed
+	var prevStatus string
+	err = tx.QueryRow(`SELECT status FROM runs WHERE id = ?`, rs.ID).Scan(&prevStatus)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to read run status: %w", err)
+	}
+
+	prevSteps, err := queryStepStatuses(tx, rs.ID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	_, err = tx.Exec(`
+		INSERT INTO runs (id, workflow_name, status, created_at, updated_at, data)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			workflow_name = excluded.workflow_name,
+			status = excluded.status,
+			created_at = excluded.created_at,
+			updated_at = excluded.updated_at,
+			data = excluded.data`,
+		rs.ID, rs.WorkflowName, string(rs.Status), rs.CreatedAt.UnixMilli(), now.UnixMilli(), string(data),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to write run: %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM step_states WHERE run_id = ?`, rs.ID); err != nil {
+		return fmt.Errorf("failed to clear step states: %w", err)
+	}
+
+	addEvent := func(step, status string) error {
+		_, err := tx.Exec(
+			`INSERT INTO events (run_id, step_name, status, created_at) VALUES (?, ?, ?, ?)`,
+			rs.ID, step, status, now.UnixMilli(),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to record event: %w", err)
+		}
+		return nil
+	}
+
+	if string(rs.Status) != prevStatus {
+		if err := addEvent("", string(rs.Status)); err != nil {
+			return err
+		}
+	}
+
+	for name, state := range rs.StepStates {
+		_, err := tx.Exec(
+			`INSERT INTO step_states (run_id, step_name, status) VALUES (?, ?, ?)`,
+			rs.ID, name, string(state.Status),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to write step state: %w", err)
+		}
+
+		if prevSteps[name] != string(state.Status) {
+			if err := addEvent(name, string(state.Status)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit run: %w", err)
+	}
+
+	return nil
+}
+
+// AddComment stores a comment as JSON under its run and ID
+func (s *SQLiteStore) AddComment(runID string, c Comment) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to marshal comment: %w", err)
+	}
+
+	_, err = s.db.Exec(`INSERT INTO comments (run_id, id, data) VALUES (?, ?, ?)`, runID, c.ID, string(data))
+	if err != nil {
+		return fmt.Errorf("failed to write comment: %w", err)
+	}
+	return nil
+}
+
+// Comments returns a run's comments ordered by ID
+func (s *SQLiteStore) Comments(runID string) ([]Comment, error) {
+	rows, err := s.db.Query(`SELECT data FROM comments WHERE run_id = ? ORDER BY id`, runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read comments: %w", err)
+	}
+	defer rows.Close()
+
+	comments := []Comment{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to read comment: %w", err)
+		}
+		var c Comment
+		if err := json.Unmarshal([]byte(data), &c); err != nil {
+			return nil, fmt.Errorf("failed to parse comment: %w", err)
+		}
+		comments = append(comments, c)
+	}
+	return comments, rows.Err()
+}
+
+// queryStepStatuses returns the stored status of each of a run's steps
+func queryStepStatuses(tx *sql.Tx, runID string) (map[string]string, error) {
+	rows, err := tx.Query(`SELECT step_name, status FROM step_states WHERE run_id = ?`, runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read step states: %w", err)
+	}
+	defer rows.Close()
+
+	statuses := make(map[string]string)
+	for rows.Next() {
+		var name, status string
+		if err := rows.Scan(&name, &status); err != nil {
+			return nil, fmt.Errorf("failed to read step state: %w", err)
+		}
+		statuses[name] = status
+	}
+
+	return statuses, rows.Err()
+}
+
+// LoadRun reads a run
+func (s *SQLiteStore) LoadRun(runID string) (*RunState, error) {
+	var data string
+	err := s.db.QueryRow(`SELECT data FROM runs WHERE id = ?`, runID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("run %s not found", runID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read run: %w", err)
+	}
+
+	return unmarshalRunState([]byte(data))
+}
+
+// ListRuns returns every run ordered by ID
+func (s *SQLiteStore) ListRuns() ([]RunState, error) {
+	return s.QueryRuns(RunQuery{})
+}
+
+// QueryRuns selects matching runs using the runs indexes
+func (s *SQLiteStore) QueryRuns(q RunQuery) ([]RunState, error) {
+	conditions := []string{}
+	args := []any{}
+	if q.Workflow != "" {
+		conditions = append(conditions, "r.workflow_name = ?")
+		args = append(args, q.Workflow)
+	}
+	if q.Status != "" {
+		conditions = append(conditions, "r.status = ?")
+		args = append(args, string(q.Status))
+	}
+	if !q.Since.IsZero() {
+		conditions = append(conditions, "r.created_at >= ?")
+		args = append(args, q.Since.UnixMilli())
+	}
+	if !q.Until.IsZero() {
+		conditions = append(conditions, "r.created_at < ?")
+		args = append(args, q.Until.UnixMilli())
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	rows, err := s.db.Query(`SELECT r.data FROM runs r `+where+` ORDER BY r.id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []RunState{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to read run: %w", err)
+		}
+
+		state, err := unmarshalRunState([]byte(data))
+		if err != nil {
+			// Skip runs that can't be loaded (might be corrupted)
+			continue
+		}
+		runs = append(runs, *state)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query runs: %w", err)
+	}
+
+	return runs, nil
+}
+
+// Events returns a run's status change events, oldest first
+func (s *SQLiteStore) Events(runID string) ([]RunEvent, error) {
+	rows, err := s.db.Query(
+		`SELECT id, run_id, step_name, status, created_at FROM events WHERE run_id = ? ORDER BY id`,
+		runID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []RunEvent{}
+	for rows.Next() {
+		var event RunEvent
+		var at int64
+		if err := rows.Scan(&event.ID, &event.RunID, &event.Step, &event.Status, &at); err != nil {
+			return nil, fmt.Errorf("failed to read event: %w", err)
+		}
+		event.At = time.UnixMilli(at).UTC()
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
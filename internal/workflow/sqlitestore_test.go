@@ -0,0 +1,79 @@
+package workflow
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSQLiteStoreRecordsEvents(t *testing.T) {
+	store := openTestSQLiteStore(t)
+
+	wf := &Workflow{ID: "wf", Steps: []Step{{Name: "step1"}, {Name: "step2"}}}
+	state := NewRunState(wf, "test-run", "")
+	if err := store.SaveRun(state); err != nil {
+		t.Fatalf("SaveRun failed: %v", err)
+	}
+
+	// Only changed statuses produce new events
+	state.StepStates["step1"] = StepState{Status: StatusSucceeded}
+	store.SaveRun(state)
+	store.SaveRun(state)
+	state.Status = RunPaused
+	store.SaveRun(state)
+
+	events, err := store.Events("test-run")
+	if err != nil {
+		t.Fatalf("Events failed: %v", err)
+	}
+
+	type change struct{ step, status string }
+	got := []change{}
+	for _, event := range events {
+		got = append(got, change{event.Step, event.Status})
+	}
+
+	// Initial statuses are recorded in arbitrary step order
+	if len(got) != 5 {
+		t.Fatalf("Expected 5 events, got %v", got)
+	}
+	if got[0] != (change{"", "active"}) {
+		t.Errorf("Expected run creation event first, got %v", got[0])
+	}
+	if got[3] != (change{"step1", "succeeded"}) || got[4] != (change{"", "paused"}) {
+		t.Errorf("Expected step1 succeeded then run paused, got %v", got[3:])
+	}
+}
+
+func TestSQLiteStorePersistsAcrossOpens(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "composer.db")
+
+	store, err := OpenSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("Failed to open SQLite store: %v", err)
+	}
+	state := NewRunState(&Workflow{ID: "wf"}, "test-run", "")
+	store.SaveRun(state)
+	WriteArtifact(store.Artifacts(), "test-run", "doc", ArtifactMeta{}, strings.NewReader("content"))
+	store.Close()
+
+	reopened, err := OpenSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen SQLite store: %v", err)
+	}
+	defer reopened.Close()
+
+	if _, err := reopened.LoadRun("test-run"); err != nil {
+		t.Fatalf("LoadRun failed: %v", err)
+	}
+	if content, _ := ReadArtifact(reopened.Artifacts(), "test-run", "doc"); string(content) != "content" {
+		t.Errorf("Expected artifact to persist, got %q", content)
+	}
+
+	// Content is kept on disk, not in the database
+	blobPath := filepath.Join(filepath.Dir(path), "blobs", sha256Hex("content")[:2], sha256Hex("content"))
+	if data, err := os.ReadFile(blobPath); err != nil || string(data) != "content" {
+		t.Errorf("Expected blob at %s, got %q, %v", blobPath, data, err)
+	}
+}
@@ -3,8 +3,8 @@ package workflow
 import (
 	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
+	"strings"
+	"time"
 )
 
 // StepStatus represents the status of a step in a workflow
@@ -15,11 +15,96 @@ const (
 	StatusReady     StepStatus = "ready"
 	StatusFailed    StepStatus = "failed"
 	StatusSucceeded StepStatus = "succeeded"
+	StatusCancelled StepStatus = "cancelled"
+	// StatusSkipped marks a step whose outcome gate can no longer be met
+	StatusSkipped StepStatus = "skipped"
+)
+
+// RunStatus represents the run-level execution status
+type RunStatus string
+
+const (
+	RunActive    RunStatus = "active"
+	RunPaused    RunStatus = "paused"
+	RunCancelled RunStatus = "cancelled"
+)
+
+// LoopStatus represents the progress of a loop in a run
+type LoopStatus string
+
+const (
+	LoopRunning LoopStatus = "running"
+	// LoopDone marks a loop whose condition held
+	LoopDone LoopStatus = "done"
+	// LoopExhausted marks a loop that ran out of iterations first
+	LoopExhausted LoopStatus = "exhausted"
 )
 
 // StepState represents the state of a single step
 type StepState struct {
 	Status StepStatus `json:"status"`
+	// CacheHit is set when the output was reused from the step output cache
+	CacheHit bool `json:"cache_hit,omitempty"`
+	// Attempt counts the step's executions; resets keep it so the next
+	// execution is numbered after the last
+	Attempt int `json:"attempt,omitempty"`
+	// Failures counts the step's failed executions since it last succeeded
+	// or was reset, and Error holds the last failure
+	Failures int    `json:"failures,omitempty"`
+	Error    string `json:"error,omitempty"`
+	// Outcome and Comment record a human decision; resets keep them so the
+	// next round can see the previous one
+	Outcome string `json:"outcome,omitempty"`
+	Comment string `json:"comment,omitempty"`
+	// Loops counts the times the step's outcome looped the run back
+	Loops int `json:"loops,omitempty"`
+	// ClaimedBy holds a ready step for one actor until ClaimExpires
+	ClaimedBy    string     `json:"claimed_by,omitempty"`
+	ClaimExpires *time.Time `json:"claim_expires,omitempty"`
+	// ReadyAt is when a human step last became ready; its deadline counts
+	// from here
+	ReadyAt *time.Time `json:"ready_at,omitempty"`
+	// EscalatedAt is when an overdue step was escalated, and Role the role
+	// it was reassigned to, if any
+	EscalatedAt *time.Time `json:"escalated_at,omitempty"`
+	Role        string     `json:"role,omitempty"`
+	// Approvals records the votes on a step collecting approvals, kept once
+	// the step is decided
+	Approvals []Approval `json:"approvals,omitempty"`
+}
+
+// Claimant returns who holds the step's claim at the given time, or "" when
+// the step is unclaimed or the lease has expired
+func (s StepState) Claimant(at time.Time) string {
+	if s.ClaimedBy == "" || s.ClaimExpires == nil || !at.Before(*s.ClaimExpires) {
+		return ""
+	}
+	return s.ClaimedBy
+}
+
+// Unclaimed returns the step state with any claim removed
+func (s StepState) Unclaimed() StepState {
+	s.ClaimedBy = ""
+	s.ClaimExpires = nil
+	return s
+}
+
+// LoopState tracks a loop's iterations in a run
+type LoopState struct {
+	Status LoopStatus `json:"status"`
+	// Iteration is the current iteration, numbered from 1
+	Iteration int `json:"iteration"`
+	// Iterations records each finished iteration, oldest first
+	Iterations []LoopIteration `json:"iterations,omitempty"`
+}
+
+// LoopIteration records the outcome of one iteration of a loop
+type LoopIteration struct {
+	Iteration int `json:"iteration"`
+	// Steps holds the loop's step states as the iteration finished
+	Steps map[string]StepState `json:"steps"`
+	// Artifacts are the versions the iteration's steps produced
+	Artifacts []ArtifactRef `json:"artifacts,omitempty"`
 }
 
 // RunState represents the complete state of a workflow run
@@ -30,10 +115,35 @@ type RunState struct {
 	Name string `json:"name"`
 	// WorkflowName is the name of the workflow this run belongs to
 	WorkflowName string `json:"workflow_name"`
+	// Status is the run-level status; runs saved before it existed load as active
+	Status RunStatus `json:"status"`
+	// ParentRunID is the run this run was forked from, if any
+	ParentRunID string `json:"parent_run_id,omitempty"`
+	// ForkedFromStep is the step the fork was reset from, if any
+	ForkedFromStep string `json:"forked_from_step,omitempty"`
+	// CreatedAt is when the run was created; zero for runs saved before it existed
+	CreatedAt time.Time `json:"created_at"`
 	// StepStates maps step names to their current state
 	StepStates map[string]StepState `json:"step_states"`
-	// artifactPaths maps artifact names to their filesystem paths (not persisted to JSON)
-	artifactPaths map[string]string `json:"-"`
+	// Loops maps loop names to their iterations
+	Loops map[string]LoopState `json:"loops,omitempty"`
+	// Params holds the run's parameters, defaults included
+	Params map[string]string `json:"params,omitempty"`
+	// Trigger records what started the run when it was not started by hand
+	Trigger *Trigger `json:"trigger,omitempty"`
+}
+
+// TriggerSchedule marks runs started by a schedule
+const TriggerSchedule = "schedule"
+
+// Trigger identifies the schedule or other source that started a run, and
+// the time it fired for
+type Trigger struct {
+	Kind string    `json:"kind"`
+	Name string    `json:"name"`
+	At   time.Time `json:"at"`
+	// File is the watched file the run was started for
+	File string `json:"file,omitempty"`
 }
 
 // NewRunState creates a new run state initialized with pending steps
@@ -43,11 +153,12 @@ func NewRunState(workflow *Workflow, runID string, displayName string) *RunState
 	}
 
 	state := &RunState{
-		WorkflowName:  workflow.ID,
-		StepStates:    make(map[string]StepState),
-		ID:            runID,
-		Name:          displayName,
-		artifactPaths: make(map[string]string),
+		WorkflowName: workflow.ID,
+		Status:       RunActive,
+		CreatedAt:    time.Now().UTC(),
+		StepStates:   make(map[string]StepState),
+		ID:           runID,
+		Name:         displayName,
 	}
 
 	// Initialize all steps as pending
@@ -57,73 +168,57 @@ func NewRunState(workflow *Workflow, runID string, displayName string) *RunState
 		}
 	}
 
-	return state
-}
-
-// Save saves the run state to a JSON file in the run directory
-func (rs *RunState) Save() error {
-	if rs.ID == "" {
-		return fmt.Errorf("run ID is required to save state")
-	}
-
-	runDir := GetRunDir(rs.ID)
-
-	// Create the run directory if it doesn't exist
-	if err := os.MkdirAll(runDir, 0755); err != nil {
-		return fmt.Errorf("failed to create run directory: %w", err)
+	// Every loop starts in its first iteration
+	for _, loop := range workflow.Loops {
+		state.SetLoop(loop.Name, LoopState{Status: LoopRunning, Iteration: 1})
 	}
 
-	statePath := filepath.Join(runDir, "state.json")
-	data, err := json.MarshalIndent(rs, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal state: %w", err)
-	}
+	return state
+}
 
-	if err := os.WriteFile(statePath, data, 0644); err != nil {
-		return fmt.Errorf("failed to write state file: %w", err)
+// ValidateRunID rejects run IDs that cannot name a run's files: empty IDs,
+// "." and "..", and IDs containing path separators
+func ValidateRunID(id string) error {
+	if id == "" || id == "." || id == ".." || strings.ContainsAny(id, "/\\") {
+		return fmt.Errorf("invalid run ID '%s'", id)
 	}
-
 	return nil
 }
 
-// LoadState loads the run state from a JSON file in the run directory
-func LoadState(runID string) (*RunState, error) {
-	runDir := GetRunDir(runID)
-	statePath := filepath.Join(runDir, "state.json")
+// Loop returns the state of the named loop. Loops missing from runs created
+// before the loop was declared are in their first iteration.
+func (rs *RunState) Loop(name string) LoopState {
+	if loop, ok := rs.Loops[name]; ok {
+		return loop
+	}
+	return LoopState{Status: LoopRunning, Iteration: 1}
+}
 
-	data, err := os.ReadFile(statePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read state file: %w", err)
+// SetLoop stores the state of the named loop
+func (rs *RunState) SetLoop(name string, loop LoopState) {
+	if rs.Loops == nil {
+		rs.Loops = make(map[string]LoopState)
 	}
+	rs.Loops[name] = loop
+}
 
+// unmarshalRunState decodes a serialized run state, defaulting fields that
+// older state files may not have
+func unmarshalRunState(data []byte) (*RunState, error) {
 	var state RunState
 	if err := json.Unmarshal(data, &state); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal state: %w", err)
 	}
 
-	state.artifactPaths = make(map[string]string)
-
-	// Scan artifacts directory and populate the map
-	artifactsDir := GetArtifactsDir(runID)
-	if _, err := os.Stat(artifactsDir); err == nil {
-		entries, err := os.ReadDir(artifactsDir)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read artifacts directory: %w", err)
-		}
-
-		for _, entry := range entries {
-			if !entry.IsDir() {
-				artifactName := entry.Name()
-				artifactPath := filepath.Join(artifactsDir, artifactName)
-				state.artifactPaths[artifactName] = artifactPath
-			}
-		}
+	if state.Status == "" {
+		state.Status = RunActive
 	}
 
 	return &state, nil
 }
 
-// AllStepsCompleted checks if all steps are either succeeded or failed
+// AllStepsCompleted checks if all steps are succeeded, failed, skipped, or
+// cancelled
 func (rs *RunState) AllStepsCompleted() bool {
 	for _, state := range rs.StepStates {
 		if state.Status == StatusPending || state.Status == StatusReady {
@@ -133,108 +228,12 @@ func (rs *RunState) AllStepsCompleted() bool {
 	return true
 }
 
-// HasArtifact checks if an artifact with the given name exists
-func (rs *RunState) HasArtifact(name string) bool {
-	_, exists := rs.artifactPaths[name]
-	return exists
-}
-
-// ListArtifacts returns a list of all artifact names
-func (rs *RunState) ListArtifacts() []string {
-	artifacts := make([]string, 0, len(rs.artifactPaths))
-	for name := range rs.artifactPaths {
-		artifacts = append(artifacts, name)
-	}
-	return artifacts
-}
-
-// ReadArtifact reads the content of a single artifact
-func (rs *RunState) ReadArtifact(name string) (string, error) {
-	path, exists := rs.artifactPaths[name]
-	if !exists {
-		return "", fmt.Errorf("artifact %s not found", name)
-	}
-
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return "", fmt.Errorf("failed to read artifact %s: %w", name, err)
-	}
-
-	return string(data), nil
-}
-
-// ReadArtifacts reads multiple artifacts and returns a map of name to content
-func (rs *RunState) ReadArtifacts(names []string) (map[string]string, error) {
-	artifacts := make(map[string]string)
-
-	for _, name := range names {
-		content, err := rs.ReadArtifact(name)
-		if err != nil {
-			return nil, err
-		}
-		artifacts[name] = content
-	}
-
-	return artifacts, nil
-}
-
-// WriteArtifact writes content to an artifact file and updates the artifact registry
-func (rs *RunState) WriteArtifact(name, content string) error {
-	if rs.ID == "" {
-		return fmt.Errorf("run ID is required to write artifacts")
-	}
-
-	artifactsDir := GetArtifactsDir(rs.ID)
-
-	// Create artifacts directory if it doesn't exist
-	if err := os.MkdirAll(artifactsDir, 0755); err != nil {
-		return fmt.Errorf("failed to create artifacts directory: %w", err)
-	}
-
-	artifactPath := filepath.Join(artifactsDir, name)
-	if err := os.WriteFile(artifactPath, []byte(content), 0644); err != nil {
-		return fmt.Errorf("failed to write artifact %s: %w", name, err)
-	}
-
-	// Update the artifact registry
-	rs.artifactPaths[name] = artifactPath
-
-	return nil
-}
-
-// ListRuns returns all runs found in the runs directory
-func ListRuns() ([]RunState, error) {
-	runsDir := GetRunsDir()
-
-	// Check if runs directory exists
-	if _, err := os.Stat(runsDir); os.IsNotExist(err) {
-		return []RunState{}, nil
-	}
-
-	// Read directory entries
-	entries, err := os.ReadDir(runsDir)
-	if err != nil {
-		return nil, fmt.Errorf("error reading runs directory: %w", err)
-	}
-
-	runs := []RunState{}
-	for _, entry := range entries {
-		// Skip non-directories
-		if !entry.IsDir() {
-			continue
-		}
-
-		runID := entry.Name()
-
-		// Try to load the run state
-		state, err := LoadState(runID)
-		if err != nil {
-			// Skip runs that can't be loaded (might be incomplete or corrupted)
-			continue
+// CancelUnfinishedSteps marks every pending or ready step as cancelled
+func (rs *RunState) CancelUnfinishedSteps() {
+	for name, state := range rs.StepStates {
+		if state.Status == StatusPending || state.Status == StatusReady {
+			state.Status = StatusCancelled
+			rs.StepStates[name] = state
 		}
-
-		runs = append(runs, *state)
 	}
-
-	return runs, nil
 }
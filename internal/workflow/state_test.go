@@ -47,25 +47,16 @@ func TestNewRunState(t *testing.T) {
 }
 
 func TestSaveAndLoadState(t *testing.T) {
-	// Create a temporary run directory for testing
 	tempDir := t.TempDir()
+	store := NewFSStore(tempDir)
 	runID := "test-run"
 	displayName := "Test Run"
 
-	// Override GetRunDir for this test
-	originalGetwd := os.Getwd
-	os.Chdir(tempDir)
-	defer os.Chdir(tempDir) // Won't restore but that's ok for tests
-	if cwd, err := originalGetwd(); err == nil {
-		defer os.Chdir(cwd)
-	}
-
 	// Create a state
 	state := &RunState{
-		ID:            runID,
-		Name:          displayName,
-		WorkflowName:  "test-workflow",
-		artifactPaths: make(map[string]string),
+		ID:           runID,
+		Name:         displayName,
+		WorkflowName: "test-workflow",
 		StepStates: map[string]StepState{
 			"step1": {Status: StatusSucceeded},
 			"step2": {Status: StatusPending},
@@ -74,19 +65,19 @@ func TestSaveAndLoadState(t *testing.T) {
 	}
 
 	// Save the state
-	err := state.Save()
+	err := store.SaveRun(state)
 	if err != nil {
 		t.Fatalf("Failed to save state: %v", err)
 	}
 
 	// Verify the file exists
-	statePath := filepath.Join(tempDir, ".composer", "runs", runID, "state.json")
+	statePath := filepath.Join(tempDir, "runs", runID, "state.json")
 	if _, err := os.Stat(statePath); os.IsNotExist(err) {
 		t.Fatalf("State file was not created at %s", statePath)
 	}
 
 	// Load the state back
-	loadedState, err := LoadState(runID)
+	loadedState, err := store.LoadRun(runID)
 	if err != nil {
 		t.Fatalf("Failed to load state: %v", err)
 	}
@@ -147,6 +138,14 @@ func TestAllStepsCompleted(t *testing.T) {
 			},
 			expected: true,
 		},
+		{
+			name: "succeeded and cancelled",
+			stepStates: map[string]StepState{
+				"step1": {Status: StatusSucceeded},
+				"step2": {Status: StatusCancelled},
+			},
+			expected: true,
+		},
 		{
 			name: "one pending",
 			stepStates: map[string]StepState{
@@ -176,299 +175,50 @@ func TestAllStepsCompleted(t *testing.T) {
 	}
 }
 
-// Artifact tests
-
-func TestWriteAndReadArtifact(t *testing.T) {
-	tempDir := t.TempDir()
-	os.Chdir(tempDir)
-
-	runID := "test-run"
-	artifactName := "test-artifact"
-	content := "This is test content\nWith multiple lines"
-
+func TestCancelUnfinishedSteps(t *testing.T) {
 	state := &RunState{
-		ID:            runID,
-		artifactPaths: make(map[string]string),
-	}
-
-	// Write artifact
-	err := state.WriteArtifact(artifactName, content)
-	if err != nil {
-		t.Fatalf("WriteArtifact failed: %v", err)
-	}
-
-	// Verify file was created
-	artifactPath := filepath.Join(GetArtifactsDir(runID), artifactName)
-	if _, err := os.Stat(artifactPath); os.IsNotExist(err) {
-		t.Fatalf("Artifact file was not created at %s", artifactPath)
-	}
-
-	// Read artifact
-	readContent, err := state.ReadArtifact(artifactName)
-	if err != nil {
-		t.Fatalf("ReadArtifact failed: %v", err)
-	}
-
-	// Verify content matches
-	if readContent != content {
-		t.Errorf("Content mismatch.\nExpected: %s\nGot: %s", content, readContent)
-	}
-}
-
-func TestHasArtifact(t *testing.T) {
-	tempDir := t.TempDir()
-	os.Chdir(tempDir)
-
-	runID := "test-run"
-
-	state := &RunState{
-		ID:            runID,
-		artifactPaths: make(map[string]string),
-	}
-
-	// Should return false for non-existent artifact
-	if state.HasArtifact("nonexistent") {
-		t.Error("HasArtifact should return false for non-existent artifact")
-	}
-
-	// Write an artifact
-	state.WriteArtifact("existing", "content")
-
-	// Should return true for existing artifact
-	if !state.HasArtifact("existing") {
-		t.Error("HasArtifact should return true for existing artifact")
-	}
-
-	// Should still return false for different artifact
-	if state.HasArtifact("other") {
-		t.Error("HasArtifact should return false for different artifact")
-	}
-}
-
-func TestListArtifacts(t *testing.T) {
-	tempDir := t.TempDir()
-	os.Chdir(tempDir)
-
-	runID := "test-run"
-
-	state := &RunState{
-		ID:            runID,
-		artifactPaths: make(map[string]string),
-	}
-
-	// Should return empty list when no artifacts exist
-	artifacts := state.ListArtifacts()
-	if len(artifacts) != 0 {
-		t.Errorf("Expected 0 artifacts, got %d", len(artifacts))
-	}
-
-	// Write some artifacts
-	state.WriteArtifact("artifact1", "content1")
-	state.WriteArtifact("artifact2", "content2")
-	state.WriteArtifact("artifact3", "content3")
-
-	// List should return all artifacts
-	artifacts = state.ListArtifacts()
-
-	if len(artifacts) != 3 {
-		t.Errorf("Expected 3 artifacts, got %d", len(artifacts))
+		StepStates: map[string]StepState{
+			"done":    {Status: StatusSucceeded},
+			"broken":  {Status: StatusFailed},
+			"waiting": {Status: StatusReady},
+			"queued":  {Status: StatusPending},
+		},
 	}
 
-	// Verify all artifact names are present
-	expectedNames := map[string]bool{
-		"artifact1": false,
-		"artifact2": false,
-		"artifact3": false,
-	}
+	state.CancelUnfinishedSteps()
 
-	for _, name := range artifacts {
-		if _, exists := expectedNames[name]; exists {
-			expectedNames[name] = true
-		} else {
-			t.Errorf("Unexpected artifact name: %s", name)
-		}
+	expected := map[string]StepStatus{
+		"done":    StatusSucceeded,
+		"broken":  StatusFailed,
+		"waiting": StatusCancelled,
+		"queued":  StatusCancelled,
 	}
-
-	for name, found := range expectedNames {
-		if !found {
-			t.Errorf("Expected artifact %s not found in list", name)
+	for name, status := range expected {
+		if state.StepStates[name].Status != status {
+			t.Errorf("Step %s status is %s, expected %s", name, state.StepStates[name].Status, status)
 		}
 	}
 }
 
-func TestReadArtifacts(t *testing.T) {
+func TestLoadRunDefaultsRunStatus(t *testing.T) {
 	tempDir := t.TempDir()
-	os.Chdir(tempDir)
+	store := NewFSStore(tempDir)
 
-	runID := "test-run"
-
-	state := &RunState{
-		ID:            runID,
-		artifactPaths: make(map[string]string),
+	// State files written before run status existed have no status field
+	runDir := filepath.Join(tempDir, "runs", "legacy-run")
+	if err := os.MkdirAll(runDir, 0755); err != nil {
+		t.Fatalf("Failed to create run directory: %v", err)
 	}
-
-	// Write multiple artifacts
-	state.WriteArtifact("doc1", "Content of document 1")
-	state.WriteArtifact("doc2", "Content of document 2")
-	state.WriteArtifact("doc3", "Content of document 3")
-
-	// Read multiple artifacts
-	names := []string{"doc1", "doc3"}
-	artifacts, err := state.ReadArtifacts(names)
-	if err != nil {
-		t.Fatalf("ReadArtifacts failed: %v", err)
-	}
-
-	if len(artifacts) != 2 {
-		t.Errorf("Expected 2 artifacts, got %d", len(artifacts))
+	legacy := `{"id": "legacy-run", "name": "legacy-run", "workflow_name": "wf", "step_states": {}}`
+	if err := os.WriteFile(filepath.Join(runDir, "state.json"), []byte(legacy), 0644); err != nil {
+		t.Fatalf("Failed to write state file: %v", err)
 	}
 
-	// Verify contents
-	if artifacts["doc1"] != "Content of document 1" {
-		t.Errorf("doc1 content mismatch: got %s", artifacts["doc1"])
-	}
-	if artifacts["doc3"] != "Content of document 3" {
-		t.Errorf("doc3 content mismatch: got %s", artifacts["doc3"])
-	}
-
-	// doc2 should not be in the map
-	if _, exists := artifacts["doc2"]; exists {
-		t.Error("doc2 should not be in the returned artifacts")
-	}
-}
-
-func TestReadArtifactsNonExistent(t *testing.T) {
-	tempDir := t.TempDir()
-	os.Chdir(tempDir)
-
-	runID := "test-run"
-
-	state := &RunState{
-		ID:            runID,
-		artifactPaths: make(map[string]string),
-	}
-
-	// Try to read a non-existent artifact
-	names := []string{"nonexistent"}
-	_, err := state.ReadArtifacts(names)
-	if err == nil {
-		t.Error("ReadArtifacts should return error for non-existent artifact")
-	}
-}
-
-func TestWriteArtifactCreatesDirectory(t *testing.T) {
-	tempDir := t.TempDir()
-	os.Chdir(tempDir)
-
-	runID := "new-run"
-
-	state := &RunState{
-		ID:            runID,
-		artifactPaths: make(map[string]string),
-	}
-
-	// Verify artifacts directory doesn't exist yet
-	artifactsDir := GetArtifactsDir(runID)
-	if _, err := os.Stat(artifactsDir); !os.IsNotExist(err) {
-		t.Fatalf("Artifacts directory should not exist yet")
-	}
-
-	// Write artifact should create the directory
-	err := state.WriteArtifact("first", "content")
-	if err != nil {
-		t.Fatalf("WriteArtifact failed: %v", err)
-	}
-
-	// Verify directory was created
-	if _, err := os.Stat(artifactsDir); os.IsNotExist(err) {
-		t.Error("WriteArtifact should have created artifacts directory")
-	}
-}
-
-func TestReadArtifactEmpty(t *testing.T) {
-	tempDir := t.TempDir()
-	os.Chdir(tempDir)
-
-	runID := "test-run"
-
-	state := &RunState{
-		ID:            runID,
-		artifactPaths: make(map[string]string),
-	}
-
-	// Write empty artifact
-	err := state.WriteArtifact("empty", "")
-	if err != nil {
-		t.Fatalf("WriteArtifact failed: %v", err)
-	}
-
-	// Read empty artifact
-	content, err := state.ReadArtifact("empty")
-	if err != nil {
-		t.Fatalf("ReadArtifact failed: %v", err)
-	}
-
-	if content != "" {
-		t.Errorf("Expected empty content, got: %s", content)
-	}
-}
-
-func TestLoadStatePopulatesArtifactRegistry(t *testing.T) {
-	tempDir := t.TempDir()
-	os.Chdir(tempDir)
-
-	runID := "test-run"
-
-	// Create a state with some artifacts
-	state := &RunState{
-		WorkflowName:  "test-workflow",
-		ID:            runID,
-		artifactPaths: make(map[string]string),
-		StepStates: map[string]StepState{
-			"step1": {Status: StatusSucceeded},
-		},
-	}
-
-	// Write some artifacts
-	state.WriteArtifact("artifact1", "content1")
-	state.WriteArtifact("artifact2", "content2")
-	state.WriteArtifact("artifact3", "content3")
-
-	// Save the state
-	err := state.Save()
-	if err != nil {
-		t.Fatalf("Failed to save state: %v", err)
-	}
-
-	// Load the state back
-	loadedState, err := LoadState(runID)
+	state, err := store.LoadRun("legacy-run")
 	if err != nil {
 		t.Fatalf("Failed to load state: %v", err)
 	}
-
-	// Verify artifact registry was populated
-	if !loadedState.HasArtifact("artifact1") {
-		t.Error("LoadState should populate artifact registry with artifact1")
-	}
-	if !loadedState.HasArtifact("artifact2") {
-		t.Error("LoadState should populate artifact registry with artifact2")
-	}
-	if !loadedState.HasArtifact("artifact3") {
-		t.Error("LoadState should populate artifact registry with artifact3")
-	}
-
-	// Verify we can read artifacts through the loaded state
-	content, err := loadedState.ReadArtifact("artifact1")
-	if err != nil {
-		t.Fatalf("Failed to read artifact from loaded state: %v", err)
-	}
-	if content != "content1" {
-		t.Errorf("Expected 'content1', got '%s'", content)
-	}
-
-	// Verify ListArtifacts works
-	artifacts := loadedState.ListArtifacts()
-	if len(artifacts) != 3 {
-		t.Errorf("Expected 3 artifacts in loaded state, got %d", len(artifacts))
+	if state.Status != RunActive {
+		t.Errorf("Expected legacy run to load as active, got %s", state.Status)
 	}
 }
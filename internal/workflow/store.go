@@ -0,0 +1,192 @@
+package workflow
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// RunStore persists run state and the artifacts each run produces
+type RunStore interface {
+	// SaveRun writes the run state
+	SaveRun(rs *RunState) error
+	// LoadRun reads a run state
+	LoadRun(runID string) (*RunState, error)
+	// ListRuns returns every run that can be loaded, ordered by ID
+	ListRuns() ([]RunState, error)
+	// QueryRuns returns the runs matching the query, ordered by ID
+	QueryRuns(q RunQuery) ([]RunState, error)
+
+	// AddComment appends a comment to a run's discussion
+	AddComment(runID string, c Comment) error
+	// Comments returns a run's comments in the order they were added
+	Comments(runID string) ([]Comment, error)
+
+	// Artifacts returns the store for the artifacts runs produce
+	Artifacts() ArtifactStore
+}
+
+// Store kinds accepted by OpenStore
+const (
+	StoreFS     = "fs"
+	StoreSQLite = "sqlite"
+)
+
+// OpenStore opens the named kind of store under a data directory. An empty
+// kind selects the filesystem store. The SQLite database is kept at
+// {dataDir}/composer.db.
+func OpenStore(kind, dataDir string) (RunStore, error) {
+	switch kind {
+	case "", StoreFS:
+		return NewFSStore(dataDir), nil
+	case StoreSQLite:
+		return OpenSQLiteStore(filepath.Join(dataDir, "composer.db"))
+	default:
+		return nil, fmt.Errorf("unknown store '%s' (expected %s or %s)", kind, StoreFS, StoreSQLite)
+	}
+}
+
+// RunQuery selects runs by workflow, run status, and creation time. Zero
+// fields match every run.
+type RunQuery struct {
+	// Workflow matches runs of the workflow with this ID
+	Workflow string
+	// Status matches runs with this run-level status
+	Status RunStatus
+	// Since matches runs created at or after this time
+	Since time.Time
+	// Until matches runs created before this time
+	Until time.Time
+}
+
+// Matches reports whether a run satisfies the query
+func (q RunQuery) Matches(rs *RunState) bool {
+	if q.Workflow != "" && rs.WorkflowName != q.Workflow {
+		return false
+	}
+	if q.Status != "" && rs.Status != q.Status {
+		return false
+	}
+	if !q.Since.IsZero() && rs.CreatedAt.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !rs.CreatedAt.Before(q.Until) {
+		return false
+	}
+	return true
+}
+
+// filterRuns returns the runs matching the query, for stores without indexes
+func filterRuns(runs []RunState, q RunQuery) []RunState {
+	matched := []RunState{}
+	for i := range runs {
+		if q.Matches(&runs[i]) {
+			matched = append(matched, runs[i])
+		}
+	}
+	return matched
+}
+
+// MigrateRuns copies every run, its current artifacts, and its archived
+// artifact versions from one store to another. Runs that already exist in the
+// destination are skipped. Returns the IDs of the migrated and skipped runs.
+func MigrateRuns(from, to RunStore) ([]string, []string, error) {
+	runs, err := from.ListRuns()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list runs: %w", err)
+	}
+
+	migrated := []string{}
+	skipped := []string{}
+	for i := range runs {
+		run := &runs[i]
+
+		if _, err := to.LoadRun(run.ID); err == nil {
+			skipped = append(skipped, run.ID)
+			continue
+		}
+
+		if err := migrateArtifacts(from.Artifacts(), to.Artifacts(), run.ID); err != nil {
+			return migrated, skipped, fmt.Errorf("failed to migrate run '%s': %w", run.ID, err)
+		}
+
+		comments, err := from.Comments(run.ID)
+		if err != nil {
+			return migrated, skipped, fmt.Errorf("failed to migrate run '%s': %w", run.ID, err)
+		}
+		for _, c := range comments {
+			if err := to.AddComment(run.ID, c); err != nil {
+				return migrated, skipped, fmt.Errorf("failed to migrate run '%s': %w", run.ID, err)
+			}
+		}
+
+		// Save the state last so a partially copied run is not visible
+		if err := to.SaveRun(run); err != nil {
+			return migrated, skipped, fmt.Errorf("failed to migrate run '%s': %w", run.ID, err)
+		}
+
+		migrated = append(migrated, run.ID)
+	}
+
+	return migrated, skipped, nil
+}
+
+// migrateArtifacts replays every version of a run's artifacts in order, so
+// version numbers and the producing steps carry over, then archives the
+// artifacts that have no current version
+func migrateArtifacts(from, to ArtifactStore, runID string) error {
+	history, err := from.History(runID)
+	if err != nil {
+		return err
+	}
+	current, err := from.List(runID)
+	if err != nil {
+		return err
+	}
+
+	hasCurrent := make(map[string]bool, len(current))
+	for _, info := range current {
+		hasCurrent[info.Name] = true
+	}
+	names := make(map[string]bool, len(history)+len(current))
+	for name := range history {
+		names[name] = true
+	}
+	for name := range hasCurrent {
+		names[name] = true
+	}
+
+	for name := range names {
+		versions, err := from.Versions(runID, name)
+		if err != nil {
+			return err
+		}
+		for _, version := range versions {
+			if err := copyBlob(from, to, runID, version); err != nil {
+				return err
+			}
+		}
+
+		if !hasCurrent[name] {
+			if _, err := to.Archive(runID, name); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// copyBlob writes the content of an artifact version from one store as a new
+// version of the same artifact in another
+func copyBlob(from, to ArtifactStore, runID string, info ArtifactInfo) error {
+	r, err := from.OpenBlob(info.SHA256)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	meta := ArtifactMeta{ContentType: info.ContentType, Provenance: info.Provenance}
+	_, err = WriteArtifact(to, runID, info.Name, meta, r)
+	return err
+}
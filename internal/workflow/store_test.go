@@ -0,0 +1,445 @@
+package workflow
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// storeFactories builds a fresh instance of every RunStore implementation
+func storeFactories(t *testing.T) map[string]func() RunStore {
+	return map[string]func() RunStore{
+		"fs":     func() RunStore { return NewFSStore(t.TempDir()) },
+		"memory": func() RunStore { return NewMemoryStore() },
+		"sqlite": func() RunStore { return openTestSQLiteStore(t) },
+	}
+}
+
+// openTestSQLiteStore opens a SQLite store in a temporary directory
+func openTestSQLiteStore(t *testing.T) *SQLiteStore {
+	t.Helper()
+
+	store, err := OpenSQLiteStore(filepath.Join(t.TempDir(), "composer.db"))
+	if err != nil {
+		t.Fatalf("Failed to open SQLite store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	return store
+}
+
+func TestRunStoreSaveLoadAndList(t *testing.T) {
+	for name, newStore := range storeFactories(t) {
+		t.Run(name, func(t *testing.T) {
+			store := newStore()
+
+			if _, err := store.LoadRun("missing"); err == nil {
+				t.Error("Expected error loading a missing run")
+			}
+
+			runs, err := store.ListRuns()
+			if err != nil || len(runs) != 0 {
+				t.Fatalf("Expected no runs, got %v, %v", runs, err)
+			}
+
+			wf := &Workflow{ID: "wf", Steps: []Step{{Name: "step1", Output: "out1"}}}
+			for _, id := range []string{"run-b", "run-a"} {
+				if err := store.SaveRun(NewRunState(wf, id, "")); err != nil {
+					t.Fatalf("SaveRun failed: %v", err)
+				}
+			}
+
+			state, err := store.LoadRun("run-a")
+			if err != nil {
+				t.Fatalf("LoadRun failed: %v", err)
+			}
+			if state.WorkflowName != "wf" || state.Status != RunActive {
+				t.Errorf("Unexpected loaded state: %+v", state)
+			}
+			if state.StepStates["step1"].Status != StatusPending {
+				t.Errorf("Expected step1 pending, got %s", state.StepStates["step1"].Status)
+			}
+
+			// Changes are only visible once saved
+			state.StepStates["step1"] = StepState{Status: StatusSucceeded}
+			reloaded, _ := store.LoadRun("run-a")
+			if reloaded.StepStates["step1"].Status != StatusPending {
+				t.Error("Unsaved changes should not be visible in the store")
+			}
+			store.SaveRun(state)
+			reloaded, _ = store.LoadRun("run-a")
+			if reloaded.StepStates["step1"].Status != StatusSucceeded {
+				t.Error("Saved changes should be visible in the store")
+			}
+
+			runs, err = store.ListRuns()
+			if err != nil {
+				t.Fatalf("ListRuns failed: %v", err)
+			}
+			ids := []string{}
+			for _, run := range runs {
+				ids = append(ids, run.ID)
+			}
+			slices.Sort(ids)
+			if !slices.Equal(ids, []string{"run-a", "run-b"}) {
+				t.Errorf("Expected runs [run-a run-b], got %v", ids)
+			}
+		})
+	}
+}
+
+func TestRunStoreArtifacts(t *testing.T) {
+	for name, newStore := range storeFactories(t) {
+		t.Run(name, func(t *testing.T) {
+			artifacts := newStore().Artifacts()
+
+			if _, err := artifacts.Stat("test-run", "doc"); err == nil {
+				t.Error("Expected error statting a missing artifact")
+			}
+
+			info, err := WriteArtifact(artifacts, "test-run", "doc", ArtifactMeta{}, strings.NewReader("first"))
+			if err != nil {
+				t.Fatalf("WriteArtifact failed: %v", err)
+			}
+			if info.Size != 5 || info.SHA256 != sha256Hex("first") || info.ContentType != "text/plain; charset=utf-8" {
+				t.Errorf("Unexpected artifact info: %+v", info)
+			}
+
+			content, err := ReadArtifact(artifacts, "test-run", "doc")
+			if err != nil || string(content) != "first" {
+				t.Errorf("Expected 'first', got %q, %v", content, err)
+			}
+			if _, err := ReadArtifact(artifacts, "test-run", "missing"); err == nil {
+				t.Error("Expected error reading a missing artifact")
+			}
+
+			// Explicit content types are kept and binary content round trips
+			binary := []byte{0x00, 0xff, 0x10, 0x80}
+			WriteArtifact(artifacts, "test-run", "image", ArtifactMeta{ContentType: "image/png"}, bytes.NewReader(binary))
+			stat, err := artifacts.Stat("test-run", "image")
+			if err != nil || stat.ContentType != "image/png" || stat.Size != int64(len(binary)) {
+				t.Errorf("Unexpected artifact info: %+v, %v", stat, err)
+			}
+			if content, _ := ReadArtifact(artifacts, "test-run", "image"); !bytes.Equal(content, binary) {
+				t.Errorf("Binary content did not round trip, got %v", content)
+			}
+
+			// Aborted writes leave the current artifact alone
+			w, _ := artifacts.Create("test-run", "doc", ArtifactMeta{})
+			io.WriteString(w, "discarded")
+			w.Abort()
+			if content, _ := ReadArtifact(artifacts, "test-run", "doc"); string(content) != "first" {
+				t.Errorf("Aborted write replaced the artifact with %q", content)
+			}
+
+			for i, content := range []string{"first", "second"} {
+				if i > 0 {
+					WriteArtifact(artifacts, "test-run", "doc", ArtifactMeta{}, strings.NewReader(content))
+				}
+				version, err := artifacts.Archive("test-run", "doc")
+				if err != nil {
+					t.Fatalf("Archive failed: %v", err)
+				}
+				if version != i+1 {
+					t.Errorf("Expected version %d, got %d", i+1, version)
+				}
+			}
+
+			names, err := ArtifactNames(artifacts, "test-run")
+			if err != nil || names["doc"] || !names["image"] {
+				t.Errorf("Expected only image to remain current, got %v, %v", names, err)
+			}
+			if _, err := artifacts.Archive("test-run", "doc"); err == nil {
+				t.Error("Expected error archiving a missing artifact")
+			}
+
+			history, err := artifacts.History("test-run")
+			if err != nil {
+				t.Fatalf("History failed: %v", err)
+			}
+			if len(history["doc"]) != 2 || history["doc"][0].Version != 1 || history["doc"][1].SHA256 != sha256Hex("second") {
+				t.Errorf("Unexpected history: %+v", history["doc"])
+			}
+		})
+	}
+}
+
+func TestRunStoreArtifactVersions(t *testing.T) {
+	for name, newStore := range storeFactories(t) {
+		t.Run(name, func(t *testing.T) {
+			artifacts := newStore().Artifacts()
+
+			if _, err := artifacts.Versions("test-run", "doc"); err == nil {
+				t.Error("Expected error listing versions of a missing artifact")
+			}
+
+			// Every write is kept as a numbered version
+			for attempt, content := range []string{"first", "second"} {
+				meta := ArtifactMeta{Provenance: Provenance{Step: "draft", Attempt: attempt + 1}}
+				info, err := WriteArtifact(artifacts, "test-run", "doc", meta, strings.NewReader(content))
+				if err != nil {
+					t.Fatalf("WriteArtifact failed: %v", err)
+				}
+				if info.Version != attempt+1 {
+					t.Errorf("Expected version %d, got %d", attempt+1, info.Version)
+				}
+			}
+
+			current, _ := artifacts.Stat("test-run", "doc")
+			if current.Version != 2 || current.Step != "draft" || current.Attempt != 2 {
+				t.Errorf("Unexpected current version: %+v", current)
+			}
+			history, _ := artifacts.History("test-run")
+			if len(history["doc"]) != 1 || history["doc"][0].SHA256 != sha256Hex("first") {
+				t.Errorf("Expected the superseded version in history, got %+v", history["doc"])
+			}
+
+			// Archiving keeps the version, and the next write continues the numbering
+			artifacts.Archive("test-run", "doc")
+			info, _ := WriteArtifact(artifacts, "test-run", "doc", ArtifactMeta{}, strings.NewReader("third"))
+			if info.Version != 3 {
+				t.Errorf("Expected version 3 after archiving, got %d", info.Version)
+			}
+
+			versions, err := artifacts.Versions("test-run", "doc")
+			if err != nil {
+				t.Fatalf("Versions failed: %v", err)
+			}
+			got := []int{}
+			for _, version := range versions {
+				got = append(got, version.Version)
+			}
+			if !slices.Equal(got, []int{1, 2, 3}) || versions[0].Attempt != 1 {
+				t.Errorf("Expected versions [1 2 3] oldest first, got %+v", versions)
+			}
+		})
+	}
+}
+
+func TestRunStoreArtifactProvenance(t *testing.T) {
+	for name, newStore := range storeFactories(t) {
+		t.Run(name, func(t *testing.T) {
+			artifacts := newStore().Artifacts()
+
+			input, _ := WriteArtifact(artifacts, "test-run", "notes", ArtifactMeta{}, strings.NewReader("notes"))
+			provenance := Provenance{
+				Step:           "review",
+				Attempt:        1,
+				Handler:        "human",
+				HandlerVersion: "abc123",
+				Actor:          "alice",
+				Inputs:         []ArtifactRef{input.Ref()},
+			}
+			WriteArtifact(artifacts, "test-run", "doc", ArtifactMeta{Provenance: provenance}, strings.NewReader("v1"))
+
+			// Provenance stays with the version after it is superseded
+			WriteArtifact(artifacts, "test-run", "doc", ArtifactMeta{}, strings.NewReader("v2"))
+			versions, err := artifacts.Versions("test-run", "doc")
+			if err != nil {
+				t.Fatalf("Versions failed: %v", err)
+			}
+
+			got := versions[0].Provenance
+			if got.Actor != "alice" || got.Handler != "human" || got.HandlerVersion != "abc123" ||
+				!slices.Equal(got.Inputs, provenance.Inputs) {
+				t.Errorf("Expected provenance %+v, got %+v", provenance, got)
+			}
+			if len(versions[1].Inputs) != 0 || versions[1].Actor != "" {
+				t.Errorf("Expected no provenance on version 2, got %+v", versions[1].Provenance)
+			}
+		})
+	}
+}
+
+func TestRunStoreComments(t *testing.T) {
+	for name, newStore := range storeFactories(t) {
+		t.Run(name, func(t *testing.T) {
+			store := newStore()
+
+			comments, err := store.Comments("test-run")
+			if err != nil || len(comments) != 0 {
+				t.Fatalf("Expected no comments, got %v (%v)", comments, err)
+			}
+
+			at := time.Date(2024, 3, 2, 9, 0, 0, 0, time.UTC)
+			first := Comment{ID: 1, Author: "alice", Body: "Looks good", At: at}
+			reply := Comment{
+				ID:       2,
+				Author:   "bob",
+				Body:     "Line 3 is wrong",
+				At:       at,
+				Step:     "review",
+				ReplyTo:  1,
+				Artifact: &ArtifactRef{Name: "draft", Version: 2},
+				Lines:    &LineRange{Start: 3, End: 3},
+			}
+			for _, c := range []Comment{first, reply} {
+				if err := store.AddComment("test-run", c); err != nil {
+					t.Fatalf("AddComment failed: %v", err)
+				}
+			}
+
+			comments, err = store.Comments("test-run")
+			if err != nil {
+				t.Fatalf("Comments failed: %v", err)
+			}
+			if len(comments) != 2 || comments[0].Body != first.Body || !comments[0].At.Equal(at) {
+				t.Fatalf("Expected both comments in order, got %+v", comments)
+			}
+			got := comments[1]
+			if got.Step != "review" || got.ReplyTo != 1 || *got.Artifact != *reply.Artifact || *got.Lines != *reply.Lines {
+				t.Errorf("Expected %+v, got %+v", reply, got)
+			}
+		})
+	}
+}
+
+func TestRunStoreArtifactsDeduplicate(t *testing.T) {
+	for name, newStore := range storeFactories(t) {
+		t.Run(name, func(t *testing.T) {
+			artifacts := newStore().Artifacts()
+
+			first, _ := WriteArtifact(artifacts, "run-a", "doc", ArtifactMeta{}, strings.NewReader("shared"))
+			second, _ := WriteArtifact(artifacts, "run-b", "copy", ArtifactMeta{}, strings.NewReader("shared"))
+			if first.SHA256 != second.SHA256 {
+				t.Errorf("Identical content should share a digest, got %s and %s", first.SHA256, second.SHA256)
+			}
+
+			r, err := artifacts.OpenBlob(first.SHA256)
+			if err != nil {
+				t.Fatalf("OpenBlob failed: %v", err)
+			}
+			defer r.Close()
+			if content, _ := io.ReadAll(r); string(content) != "shared" {
+				t.Errorf("Expected 'shared', got %q", content)
+			}
+
+			// Each run still sees only its own artifacts
+			if names, _ := ArtifactNames(artifacts, "run-a"); len(names) != 1 || !names["doc"] {
+				t.Errorf("Expected only doc in run-a, got %v", names)
+			}
+		})
+	}
+}
+
+// sha256Hex returns the hex SHA-256 digest of a string
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestRunStoreQueryRuns(t *testing.T) {
+	for name, newStore := range storeFactories(t) {
+		t.Run(name, func(t *testing.T) {
+			store := newStore()
+
+			base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+			fixtures := []struct {
+				id       string
+				workflow string
+				status   RunStatus
+				created  time.Time
+			}{
+				{"a-1", "alpha", RunActive, base},
+				{"a-2", "alpha", RunPaused, base.Add(24 * time.Hour)},
+				{"b-1", "beta", RunActive, base.Add(48 * time.Hour)},
+			}
+			for _, f := range fixtures {
+				state := NewRunState(&Workflow{ID: f.workflow}, f.id, "")
+				state.Status = f.status
+				state.CreatedAt = f.created
+				if err := store.SaveRun(state); err != nil {
+					t.Fatalf("SaveRun failed: %v", err)
+				}
+			}
+
+			tests := []struct {
+				name     string
+				query    RunQuery
+				expected []string
+			}{
+				{"everything", RunQuery{}, []string{"a-1", "a-2", "b-1"}},
+				{"by workflow", RunQuery{Workflow: "alpha"}, []string{"a-1", "a-2"}},
+				{"by status", RunQuery{Status: RunActive}, []string{"a-1", "b-1"}},
+				{"since", RunQuery{Since: base.Add(24 * time.Hour)}, []string{"a-2", "b-1"}},
+				{"until", RunQuery{Until: base.Add(24 * time.Hour)}, []string{"a-1"}},
+				{"combined", RunQuery{Workflow: "alpha", Status: RunActive}, []string{"a-1"}},
+			}
+			for _, tt := range tests {
+				runs, err := store.QueryRuns(tt.query)
+				if err != nil {
+					t.Fatalf("%s: QueryRuns failed: %v", tt.name, err)
+				}
+				ids := []string{}
+				for _, run := range runs {
+					ids = append(ids, run.ID)
+				}
+				if !slices.Equal(ids, tt.expected) {
+					t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, ids)
+				}
+			}
+		})
+	}
+}
+
+func TestMigrateRuns(t *testing.T) {
+	from := NewFSStore(t.TempDir())
+	to := openTestSQLiteStore(t)
+
+	wf := &Workflow{ID: "wf", Steps: []Step{{Name: "step1", Output: "doc"}}}
+	state := NewRunState(wf, "run-1", "Run One")
+	state.StepStates["step1"] = StepState{Status: StatusSucceeded}
+	from.SaveRun(state)
+	WriteArtifact(from.Artifacts(), "run-1", "doc", ArtifactMeta{Provenance: Provenance{Step: "step1", Attempt: 1}}, strings.NewReader("v1"))
+	WriteArtifact(from.Artifacts(), "run-1", "doc", ArtifactMeta{Provenance: Provenance{Step: "step1", Attempt: 2}}, strings.NewReader("v2"))
+	WriteArtifact(from.Artifacts(), "run-1", "old", ArtifactMeta{}, strings.NewReader("gone"))
+	from.Artifacts().Archive("run-1", "old")
+	from.AddComment("run-1", Comment{ID: 1, Author: "alice", Body: "Ship it"})
+	from.SaveRun(NewRunState(wf, "run-2", ""))
+
+	// Runs already in the destination are left alone
+	to.SaveRun(NewRunState(wf, "run-2", "Existing"))
+
+	migrated, skipped, err := MigrateRuns(from, to)
+	if err != nil {
+		t.Fatalf("MigrateRuns failed: %v", err)
+	}
+	if !slices.Equal(migrated, []string{"run-1"}) || !slices.Equal(skipped, []string{"run-2"}) {
+		t.Errorf("Expected run-1 migrated and run-2 skipped, got %v and %v", migrated, skipped)
+	}
+
+	loaded, err := to.LoadRun("run-1")
+	if err != nil {
+		t.Fatalf("Failed to load migrated run: %v", err)
+	}
+	if loaded.Name != "Run One" || loaded.StepStates["step1"].Status != StatusSucceeded {
+		t.Errorf("Migrated state does not match: %+v", loaded)
+	}
+	if !loaded.CreatedAt.Equal(state.CreatedAt) {
+		t.Errorf("Expected created_at %v, got %v", state.CreatedAt, loaded.CreatedAt)
+	}
+	if content, _ := ReadArtifact(to.Artifacts(), "run-1", "doc"); string(content) != "v2" {
+		t.Errorf("Expected current artifact 'v2', got %q", content)
+	}
+	versions, _ := to.Artifacts().Versions("run-1", "doc")
+	if len(versions) != 2 || versions[0].SHA256 != sha256Hex("v1") || versions[0].Attempt != 1 || versions[1].Version != 2 {
+		t.Errorf("Expected versions [v1 v2] with their attempts, got %+v", versions)
+	}
+	if _, err := to.Artifacts().Stat("run-1", "old"); err == nil {
+		t.Error("Archived artifact should have no current version after migration")
+	}
+	if comments, _ := to.Comments("run-1"); len(comments) != 1 || comments[0].Body != "Ship it" {
+		t.Errorf("Expected the comment to be migrated, got %+v", comments)
+	}
+
+	existing, _ := to.LoadRun("run-2")
+	if existing.Name != "Existing" {
+		t.Error("Skipped run should not be overwritten")
+	}
+}
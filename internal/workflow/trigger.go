@@ -0,0 +1,376 @@
+package workflow
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// TriggerWebhook marks runs started by a webhook trigger
+const TriggerWebhook = "webhook"
+
+// TriggerWatch marks runs started by a file appearing in a watched directory
+const TriggerWatch = "watch"
+
+// DefaultWatchSettle is how long a watched file must go unmodified before it
+// is picked up, so files still being written are left alone
+const DefaultWatchSettle = 5 * time.Second
+
+// DefaultTriggerRunID names triggered runs after the trigger, the time, and
+// a random suffix
+const DefaultTriggerRunID = "{{.trigger}}-{{.date}}-{{.time}}-{{.nonce}}"
+
+// DefaultSignatureHeader carries a webhook's HMAC signature
+const DefaultSignatureHeader = "X-Composer-Signature"
+
+// ErrInvalidSignature reports a payload whose signature is missing or wrong
+var ErrInvalidSignature = errors.New("invalid payload signature")
+
+// TriggerSpec configures a trigger that starts runs of a workflow. Triggers
+// are TOML files in the triggers/ directory of the search paths, named by
+// their filename like workflows.
+type TriggerSpec struct {
+	Name string `toml:"-" json:"name"`
+	// Type is the kind of trigger, "webhook" (default) or "watch"
+	Type     string `toml:"type,omitempty" json:"type,omitempty"`
+	Workflow string `toml:"workflow" json:"workflow"`
+	// Params maps run parameters to dotted paths in the JSON payload, or
+	// the watched file, such as "issue.key" or "commits.0.id"
+	Params map[string]string `toml:"params,omitempty" json:"params,omitempty"`
+	// Artifacts maps seed artifacts to payload paths; "." seeds the whole
+	// payload
+	Artifacts map[string]string `toml:"artifacts,omitempty" json:"artifacts,omitempty"`
+	// RunID is a template for the run ID (see DefaultTriggerRunID)
+	RunID string `toml:"run_id,omitempty" json:"run_id,omitempty"`
+	// SecretEnv names the environment variable holding the HMAC shared
+	// secret; payloads are not checked when it is empty
+	SecretEnv string `toml:"secret_env,omitempty" json:"secret_env,omitempty"`
+	// SignatureHeader carries the payload's signature (see
+	// DefaultSignatureHeader)
+	SignatureHeader string `toml:"signature_header,omitempty" json:"signature_header,omitempty"`
+
+	// Dir is the directory a watch trigger picks up new files from
+	Dir string `toml:"dir,omitempty" json:"dir,omitempty"`
+	// Pattern limits a watch trigger to file names matching the glob
+	Pattern string `toml:"pattern,omitempty" json:"pattern,omitempty"`
+	// Settle is how long a file must go unmodified before it is picked up
+	// (see DefaultWatchSettle)
+	Settle string `toml:"settle,omitempty" json:"settle,omitempty"`
+	// ProcessedDir and FailedDir receive files once their run succeeds or
+	// fails; processed/ and failed/ in Dir when empty
+	ProcessedDir string `toml:"processed_dir,omitempty" json:"processed_dir,omitempty"`
+	FailedDir    string `toml:"failed_dir,omitempty" json:"failed_dir,omitempty"`
+
+	settle time.Duration
+}
+
+// Validate checks the trigger's type and that it names a workflow
+func (t *TriggerSpec) Validate() error {
+	if t.Workflow == "" {
+		return fmt.Errorf("trigger needs a workflow")
+	}
+	switch t.Kind() {
+	case TriggerWebhook:
+	case TriggerWatch:
+		if t.Dir == "" {
+			return fmt.Errorf("watch trigger needs a dir")
+		}
+		if len(t.Artifacts) == 0 {
+			return fmt.Errorf("watch trigger needs an artifact to seed the file into")
+		}
+		if _, err := filepath.Match(t.Pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern '%s': %w", t.Pattern, err)
+		}
+		t.settle = DefaultWatchSettle
+		if t.Settle != "" {
+			settle, err := time.ParseDuration(t.Settle)
+			if err != nil || settle < 0 {
+				return fmt.Errorf("invalid settle '%s'", t.Settle)
+			}
+			t.settle = settle
+		}
+	default:
+		return fmt.Errorf("invalid trigger type '%s'", t.Type)
+	}
+	return nil
+}
+
+// Kind returns the trigger's type, defaulting to webhook
+func (t *TriggerSpec) Kind() string {
+	if t.Type == "" {
+		return TriggerWebhook
+	}
+	return t.Type
+}
+
+// Header returns the header carrying the payload's signature
+func (t *TriggerSpec) Header() string {
+	if t.SignatureHeader == "" {
+		return DefaultSignatureHeader
+	}
+	return t.SignatureHeader
+}
+
+// Watches reports whether a watch trigger picks up the named file: files
+// matching its pattern, if any, that are not hidden
+func (t *TriggerSpec) Watches(name string) bool {
+	if strings.HasPrefix(name, ".") {
+		return false
+	}
+	if t.Pattern == "" {
+		return true
+	}
+	ok, _ := filepath.Match(t.Pattern, name)
+	return ok
+}
+
+// Settled reports whether a file last modified at modified has gone
+// unmodified long enough to be picked up at now
+func (t *TriggerSpec) Settled(modified, now time.Time) bool {
+	return !modified.Add(t.settle).After(now)
+}
+
+// ProcessingDir holds a watch trigger's files while their runs are going,
+// each in a directory named after its run
+func (t *TriggerSpec) ProcessingDir() string {
+	return filepath.Join(t.Dir, "processing")
+}
+
+// DoneDir returns the directory files go to once their run succeeded or
+// failed
+func (t *TriggerSpec) DoneDir(failed bool) string {
+	switch {
+	case failed && t.FailedDir != "":
+		return t.FailedDir
+	case failed:
+		return filepath.Join(t.Dir, "failed")
+	case t.ProcessedDir != "":
+		return t.ProcessedDir
+	default:
+		return filepath.Join(t.Dir, "processed")
+	}
+}
+
+// VerifySignature checks the signature, the hex HMAC-SHA256 of the payload
+// under the trigger's secret, optionally prefixed with "sha256=". Triggers
+// without a secret accept any payload.
+func (t *TriggerSpec) VerifySignature(payload []byte, signature string) error {
+	if t.SecretEnv == "" {
+		return nil
+	}
+	secret := os.Getenv(t.SecretEnv)
+	if secret == "" {
+		return fmt.Errorf("trigger secret %s is not set", t.SecretEnv)
+	}
+
+	got, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(signature), "sha256="))
+	if err != nil || len(got) == 0 {
+		return ErrInvalidSignature
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// MapPayload extracts the run parameters and seed artifact contents the
+// trigger maps from a JSON payload. String values are used as they are;
+// other values as their JSON text.
+func (t *TriggerSpec) MapPayload(payload []byte) (map[string]string, map[string][]byte, error) {
+	params := map[string]string{}
+	artifacts := map[string][]byte{}
+	if len(t.Params) == 0 && len(t.Artifacts) == 0 {
+		return params, artifacts, nil
+	}
+
+	var body any
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	wholeOnly := len(t.Params) == 0
+	for _, path := range t.Artifacts {
+		wholeOnly = wholeOnly && path == "."
+	}
+	if !wholeOnly {
+		if err := decoder.Decode(&body); err != nil {
+			return nil, nil, fmt.Errorf("payload is not valid JSON: %w", err)
+		}
+	}
+
+	for name, path := range t.Params {
+		value, err := payloadValue(body, path)
+		if err != nil {
+			return nil, nil, err
+		}
+		params[name] = string(value)
+	}
+	for name, path := range t.Artifacts {
+		if path == "." {
+			artifacts[name] = payload
+			continue
+		}
+		value, err := payloadValue(body, path)
+		if err != nil {
+			return nil, nil, err
+		}
+		artifacts[name] = value
+	}
+	return params, artifacts, nil
+}
+
+// payloadValue looks up a dotted path in a decoded JSON payload
+func payloadValue(body any, path string) ([]byte, error) {
+	value := body
+	for _, key := range strings.Split(path, ".") {
+		switch node := value.(type) {
+		case map[string]any:
+			next, ok := node[key]
+			if !ok {
+				return nil, fmt.Errorf("payload has no %s", path)
+			}
+			value = next
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, fmt.Errorf("payload has no %s", path)
+			}
+			value = node[i]
+		default:
+			return nil, fmt.Errorf("payload has no %s", path)
+		}
+	}
+
+	if s, ok := value.(string); ok {
+		return []byte(s), nil
+	}
+	return json.Marshal(value)
+}
+
+// ExpandRunID fills the run ID template for a run the trigger starts. Besides
+// the mapped params it may reference {{.trigger}}, {{.date}} (20060102),
+// {{.time}} (150405), {{.unix}}, and {{.nonce}}, a random hex string. Watch
+// triggers also pass the file name as {{.file}}.
+func (t *TriggerSpec) ExpandRunID(params map[string]string, at time.Time) (string, error) {
+	template := t.RunID
+	if template == "" {
+		template = DefaultTriggerRunID
+	}
+
+	nonce := make([]byte, 4)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate run ID: %w", err)
+	}
+
+	values := make(map[string]string, len(params)+5)
+	for name, value := range params {
+		values[name] = value
+	}
+	values["trigger"] = t.Name
+	values["date"] = at.Format("20060102")
+	values["time"] = at.Format("150405")
+	values["unix"] = strconv.FormatInt(at.Unix(), 10)
+	values["nonce"] = hex.EncodeToString(nonce)
+
+	id, err := ExpandParams(template, values)
+	if err != nil {
+		return "", err
+	}
+	if err := ValidateRunID(id); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// LoadTrigger searches the triggers/ directory of the search paths for the
+// named trigger and loads it
+func LoadTrigger(name string) (*TriggerSpec, error) {
+	if name == "" || strings.ContainsAny(name, "/\\") {
+		return nil, fmt.Errorf("invalid trigger name '%s'", name)
+	}
+
+	searchPaths := GetTriggerPaths()
+	for _, dir := range searchPaths {
+		path := filepath.Join(dir, name+".toml")
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+		return loadTrigger(path, name)
+	}
+
+	return nil, fmt.Errorf("trigger '%s' not found in any of the search paths: %v", name, searchPaths)
+}
+
+// ListTriggers returns every trigger found in the search paths, ordered by
+// name. Triggers in earlier paths take precedence. Trigger files that fail
+// to load are left out and reported together in the error, so one bad file
+// does not hide the others.
+func ListTriggers() ([]TriggerSpec, error) {
+	triggers := []TriggerSpec{}
+	seen := make(map[string]bool)
+	var errs []error
+
+	for _, dir := range GetTriggerPaths() {
+		entries, err := os.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error reading trigger directory %s: %w", dir, err))
+			continue
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() || filepath.Ext(entry.Name()) != ".toml" {
+				continue
+			}
+
+			name := strings.TrimSuffix(entry.Name(), ".toml")
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			trigger, err := loadTrigger(filepath.Join(dir, entry.Name()), name)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			triggers = append(triggers, *trigger)
+		}
+	}
+
+	slices.SortFunc(triggers, func(a, b TriggerSpec) int { return strings.Compare(a.Name, b.Name) })
+	return triggers, errors.Join(errs...)
+}
+
+// loadTrigger reads and validates a trigger file
+func loadTrigger(path, name string) (*TriggerSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading trigger file %s: %w", path, err)
+	}
+
+	var trigger TriggerSpec
+	if err := toml.Unmarshal(data, &trigger); err != nil {
+		return nil, fmt.Errorf("error parsing trigger file %s: %w", path, err)
+	}
+	trigger.Name = name
+	if err := trigger.Validate(); err != nil {
+		return nil, fmt.Errorf("error in trigger file %s: %w", path, err)
+	}
+	return &trigger, nil
+}
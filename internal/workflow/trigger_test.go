@@ -0,0 +1,144 @@
+package workflow
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestTriggerMapPayload(t *testing.T) {
+	trigger := TriggerSpec{
+		Name:     "push",
+		Workflow: "build",
+		Params: map[string]string{
+			"commit": "commits.0.id",
+			"size":   "size",
+			"forced": "forced",
+		},
+		Artifacts: map[string]string{"author": "commits.0.author"},
+	}
+
+	payload := []byte(`{"size": 12345678901, "forced": false, "commits": [{"id": "abc", "author": {"name": "alice"}}]}`)
+	params, artifacts, err := trigger.MapPayload(payload)
+	if err != nil {
+		t.Fatalf("MapPayload failed: %v", err)
+	}
+	if params["commit"] != "abc" || params["size"] != "12345678901" || params["forced"] != "false" {
+		t.Errorf("Unexpected params: %v", params)
+	}
+	if string(artifacts["author"]) != `{"name":"alice"}` {
+		t.Errorf("Expected the author as JSON, got %s", artifacts["author"])
+	}
+
+	for _, path := range []string{"commits.1.id", "commits.x", "size.digits", "missing"} {
+		trigger.Params = map[string]string{"value": path}
+		if _, _, err := trigger.MapPayload(payload); err == nil {
+			t.Errorf("Expected path %s to fail", path)
+		}
+	}
+
+	// The whole payload does not need to be JSON
+	whole := TriggerSpec{Name: "raw", Workflow: "build", Artifacts: map[string]string{"body": "."}}
+	_, artifacts, err = whole.MapPayload([]byte("plain text"))
+	if err != nil || string(artifacts["body"]) != "plain text" {
+		t.Errorf("Expected the raw payload, got %q (%v)", artifacts["body"], err)
+	}
+}
+
+func TestTriggerVerifySignature(t *testing.T) {
+	trigger := TriggerSpec{Name: "signed", Workflow: "build", SecretEnv: "TEST_SIGNED_SECRET"}
+	payload := []byte(`{"ok": true}`)
+	signature := hmacHex("other", payload)
+
+	if err := trigger.VerifySignature(payload, signature); err == nil || errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected an unset secret to be a configuration error, got %v", err)
+	}
+
+	t.Setenv("TEST_SIGNED_SECRET", "key")
+	if err := trigger.VerifySignature(payload, signature); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected a wrong signature to fail, got %v", err)
+	}
+	if err := trigger.VerifySignature(payload, "sha256="+hmacHex("key", payload)); err != nil {
+		t.Errorf("Expected a valid signature to pass, got %v", err)
+	}
+	if err := trigger.VerifySignature(payload, hmacHex("key", payload)); err != nil {
+		t.Errorf("Expected a signature without prefix to pass, got %v", err)
+	}
+
+	unsigned := TriggerSpec{Name: "open", Workflow: "build"}
+	if err := unsigned.VerifySignature(payload, ""); err != nil {
+		t.Errorf("Expected a trigger without a secret to accept any payload, got %v", err)
+	}
+}
+
+func TestTriggerExpandRunID(t *testing.T) {
+	trigger := TriggerSpec{Name: "push", Workflow: "build"}
+	at := time.Date(2025, 3, 1, 9, 30, 15, 0, time.UTC)
+
+	id, err := trigger.ExpandRunID(nil, at)
+	if err != nil || !regexp.MustCompile(`^push-20250301-093015-[0-9a-f]{8}$`).MatchString(id) {
+		t.Errorf("Unexpected default run ID %q (%v)", id, err)
+	}
+
+	trigger.RunID = "build-{{.commit}}"
+	if id, _ := trigger.ExpandRunID(map[string]string{"commit": "abc"}, at); id != "build-abc" {
+		t.Errorf("Expected build-abc, got %s", id)
+	}
+	if _, err := trigger.ExpandRunID(map[string]string{"commit": "../x"}, at); err == nil {
+		t.Error("Expected a run ID with a path separator to fail")
+	}
+
+	if err := (&TriggerSpec{Workflow: "build", Type: "email"}).Validate(); err == nil {
+		t.Error("Expected an unknown trigger type to fail")
+	}
+}
+
+// hmacHex returns the hex HMAC-SHA256 of the payload under the secret
+func hmacHex(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestWatchTrigger(t *testing.T) {
+	trigger := TriggerSpec{
+		Name:      "intake",
+		Type:      TriggerWatch,
+		Workflow:  "intake",
+		Dir:       "inbox",
+		Pattern:   "*.pdf",
+		Settle:    "30s",
+		FailedDir: "/srv/rejected",
+		Artifacts: map[string]string{"document": "."},
+	}
+	if err := trigger.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+
+	if !trigger.Watches("scan.pdf") || trigger.Watches("scan.txt") || trigger.Watches(".scan.pdf") {
+		t.Error("Expected only visible files matching the pattern to be watched")
+	}
+	now := time.Now()
+	if trigger.Settled(now.Add(-10*time.Second), now) || !trigger.Settled(now.Add(-30*time.Second), now) {
+		t.Error("Expected files to settle after 30s")
+	}
+	if trigger.DoneDir(false) != filepath.Join("inbox", "processed") || trigger.DoneDir(true) != "/srv/rejected" {
+		t.Errorf("Unexpected done directories %s and %s", trigger.DoneDir(false), trigger.DoneDir(true))
+	}
+
+	for _, invalid := range []TriggerSpec{
+		{Type: TriggerWatch, Workflow: "intake", Artifacts: map[string]string{"document": "."}},
+		{Type: TriggerWatch, Workflow: "intake", Dir: "inbox"},
+		{Type: TriggerWatch, Workflow: "intake", Dir: "inbox", Pattern: "[", Artifacts: map[string]string{"document": "."}},
+		{Type: TriggerWatch, Workflow: "intake", Dir: "inbox", Settle: "soon", Artifacts: map[string]string{"document": "."}},
+	} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("Expected %+v to be invalid", invalid)
+		}
+	}
+}